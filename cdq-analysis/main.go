@@ -57,6 +57,11 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// If the source repository is on a GitHub Enterprise Server instance, convert its URLs to the instance's raw file content URLs
+	if err := pkg.SetGitHubEnterpriseURLs(os.Getenv("GITHUB_URL"), os.Getenv("GITHUB_RAW_URL")); err != nil {
+		log.Fatal(err)
+	}
+
 	opts := zap.Options{
 		TimeEncoder: zapcore.ISO8601TimeEncoder,
 	}
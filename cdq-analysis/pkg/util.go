@@ -49,6 +49,42 @@ const (
 	AuthenticationFailedMsg = "Authentication failed .*"
 )
 
+// gitHubEnterpriseHost and gitHubEnterpriseRawURL are the host and raw file content URL of a GitHub Enterprise Server instance.
+// They are empty unless a GitHub Enterprise Server instance has been configured with SetGitHubEnterpriseURLs
+var gitHubEnterpriseHost, gitHubEnterpriseRawURL string
+
+// SetGitHubEnterpriseURLs configures the GitHub Enterprise Server instance whose URLs are converted to raw file content URLs by ConvertGitHubURL.
+// If rawURL is empty, it defaults to <baseURL>/raw. If baseURL is empty or github.com, the GitHub Enterprise Server instance is unset.
+func SetGitHubEnterpriseURLs(baseURL string, rawURL string) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	rawURL = strings.TrimSuffix(rawURL, "/")
+	if baseURL == "" {
+		gitHubEnterpriseHost, gitHubEnterpriseRawURL = "", ""
+		return nil
+	}
+	if rawURL == "" {
+		rawURL = baseURL + "/raw"
+	}
+	var host string
+	for _, u := range []string{rawURL, baseURL} {
+		parsedURL, err := url.Parse(u)
+		if err != nil {
+			return &InvalidURL{URL: u, Err: err}
+		}
+		if parsedURL.Host == "" {
+			return &InvalidURL{URL: u, Err: fmt.Errorf("the host must be set")}
+		}
+		host = parsedURL.Host
+	}
+	if strings.EqualFold(host, "github.com") {
+		// github.com isn't a GitHub Enterprise Server instance, its URLs are always converted to raw.githubusercontent.com
+		gitHubEnterpriseHost, gitHubEnterpriseRawURL = "", ""
+		return nil
+	}
+	gitHubEnterpriseHost, gitHubEnterpriseRawURL = host, rawURL
+	return nil
+}
+
 // CloneRepo clones the repoURL to specfied clonePath
 func CloneRepo(clonePath string, gitURL GitURL) error {
 	exist, err := IsExist(clonePath)
@@ -171,7 +207,13 @@ func ConvertGitHubURL(URL string, revision string, context string) (string, erro
 		return "", &InvalidURL{URL: URL, Err: err}
 	}
 
-	if strings.Contains(url.Host, "github") && !strings.Contains(url.Host, "raw") {
+	isEnterprise := gitHubEnterpriseHost != "" && strings.EqualFold(url.Host, gitHubEnterpriseHost)
+	if isEnterprise && strings.HasPrefix(url.Path, "/raw/") {
+		// Already a GitHub Enterprise Server raw URL
+		return URL, nil
+	}
+
+	if isEnterprise || (strings.Contains(url.Host, "github") && !strings.Contains(url.Host, "raw")) {
 		// Convert path part of the URL
 		URLSlice := strings.Split(URL, "/")
 		if len(URLSlice) > 2 && URLSlice[len(URLSlice)-2] == "tree" {
@@ -191,7 +233,9 @@ func ConvertGitHubURL(URL string, revision string, context string) (string, erro
 		}
 
 		// Convert host part of the URL
-		if url.Host == "github.com" {
+		if isEnterprise {
+			URL = strings.Replace(URL, url.Scheme+"://"+url.Host, gitHubEnterpriseRawURL, 1)
+		} else if url.Host == "github.com" {
 			URL = strings.Replace(URL, "github.com", "raw.githubusercontent.com", 1)
 		}
 	}
@@ -338,6 +338,85 @@ func TestConvertGitHubURL(t *testing.T) {
 	}
 }
 
+func TestConvertGitHubEnterpriseURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		rawURL   string
+		url      string
+		revision string
+		context  string
+		wantUrl  string
+		wantErr  bool
+	}{
+		{
+			name:    "Successfully convert a GitHub Enterprise url to raw url",
+			baseURL: "https://ghe.example.com",
+			url:     "https://ghe.example.com/devfile-samples/devfile-sample-java-springboot-basic",
+			wantUrl: "https://ghe.example.com/raw/devfile-samples/devfile-sample-java-springboot-basic/main",
+		},
+		{
+			name:     "Successfully convert a GitHub Enterprise url with revision, .git and a context to raw url",
+			baseURL:  "https://ghe.example.com/",
+			url:      "https://ghe.example.com/devfile-samples/devfile-sample-java-springboot-basic.git",
+			revision: "testbranch",
+			context:  "/testfolder",
+			wantUrl:  "https://ghe.example.com/raw/devfile-samples/devfile-sample-java-springboot-basic/testbranch/testfolder",
+		},
+		{
+			name:    "Successfully convert a non-main branch GitHub Enterprise url to raw url",
+			baseURL: "https://ghe.example.com",
+			url:     "https://ghe.example.com/devfile/api/tree/2.1.x",
+			wantUrl: "https://ghe.example.com/raw/devfile/api/2.1.x",
+		},
+		{
+			name:    "Successfully convert a GitHub Enterprise url to a raw url on a separate subdomain",
+			baseURL: "https://ghe.example.com",
+			rawURL:  "https://raw.ghe.example.com",
+			url:     "https://ghe.example.com/devfile-samples/devfile-sample-java-springboot-basic",
+			wantUrl: "https://raw.ghe.example.com/devfile-samples/devfile-sample-java-springboot-basic/main",
+		},
+		{
+			name:    "A GitHub Enterprise raw url",
+			baseURL: "https://ghe.example.com",
+			url:     "https://ghe.example.com/raw/devfile-samples/devfile-sample-java-springboot-basic/main/devfile.yaml",
+			wantUrl: "https://ghe.example.com/raw/devfile-samples/devfile-sample-java-springboot-basic/main/devfile.yaml",
+		},
+		{
+			name:    "github.com urls are still converted",
+			baseURL: "https://ghe.example.com",
+			url:     "https://github.com/devfile-samples/devfile-sample-java-springboot-basic",
+			wantUrl: "https://raw.githubusercontent.com/devfile-samples/devfile-sample-java-springboot-basic/main",
+		},
+		{
+			name:    "Invalid GitHub Enterprise url",
+			baseURL: "ghe.example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer SetGitHubEnterpriseURLs("", "")
+			err := SetGitHubEnterpriseURLs(tt.baseURL, tt.rawURL)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("wanted error but got nil")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("got unexpected error %v", err)
+			}
+			convertedUrl, err := ConvertGitHubURL(tt.url, tt.revision, tt.context)
+			if err != nil {
+				t.Errorf("got unexpected error %v", err)
+			} else if convertedUrl != tt.wantUrl {
+				t.Errorf("ConvertGitHubURL; expected %v got %v", tt.wantUrl, convertedUrl)
+			}
+		})
+	}
+}
+
 func TestGetContext(t *testing.T) {
 
 	localpath := "/tmp/path/to/a/dir"
@@ -49,6 +49,7 @@ import (
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	devfile "github.com/redhat-appstudio/application-service/pkg/devfile"
 	github "github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	logutil "github.com/redhat-appstudio/application-service/pkg/log"
 	util "github.com/redhat-appstudio/application-service/pkg/util"
 )
@@ -60,10 +61,36 @@ type ApplicationReconciler struct {
 	Log               logr.Logger
 	GitHubTokenClient github.GitHubToken
 	GitHubOrg         string
+
+	// GitLabClient is used to manage the GitOps repositories of Applications that use the GitLab provider. It is nil if GitLab isn't configured
+	GitLabClient gitprovider.GitProvider
+	GitLabGroup  string
+
+	// GitOpsProvider is the Git provider that generated GitOps repositories are created on, unless overridden on the Application
+	GitOpsProvider string
+
+	// GitOpsRepoOptions are the visibility and access settings of generated GitOps repositories, unless overridden on the Application
+	GitOpsRepoOptions gitprovider.RepositoryOptions
+
+	// GitOpsRepoDeletionPolicy is what happens to generated GitOps repositories when their Application is deleted, unless overridden on the Application
+	GitOpsRepoDeletionPolicy gitprovider.DeletionPolicy
+
+	// ArchivedRepositories tracks the GitOps repositories archived by the deletion policy. It is nil if the operator's namespace isn't known
+	ArchivedRepositories *ArchivedRepositoryTracker
 }
 
 const applicationName = "Application"
 
+const (
+	// pendingGitOpsRepositoryAnnotation is the annotation that records the URL of the GitOps repository of an Application while it's
+	// being generated from a template, so that it's finished rather than generated again on the next reconcile. It's ignored once the
+	// devfile of the Application has been generated
+	pendingGitOpsRepositoryAnnotation = "pendingGitOpsRepository"
+
+	// gitOpsRepositoryPendingRequeueAfter is how long to wait before checking again whether the template of a GitOps repository has been copied
+	gitOpsRepositoryPendingRequeueAfter = 5 * time.Second
+)
+
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=applications,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=applications/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=applications/finalizers,verbs=update
@@ -94,15 +121,25 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, err
 	}
 
-	ghClient, err := r.GitHubTokenClient.GetNewGitHubClient("")
-	if err != nil {
-		log.Error(err, "Unable to create Go-GitHub client due to error")
-		return reconcile.Result{}, err
+	isDeleting := !application.ObjectMeta.DeletionTimestamp.IsZero() && containsString(application.GetFinalizers(), appFinalizerName)
+	gitProvider, gitOpsOrg, gitProviderErr := r.getGitProvider(&application)
+	if gitProviderErr != nil {
+		if requeueAfter, isRateLimited := getRateLimitRequeueAfter(gitProviderErr); isRateLimited {
+			log.Info(fmt.Sprintf("All GitHub tokens are rate limited, requeueing %v in %v", req.NamespacedName, requeueAfter))
+			r.SetRateLimitedConditionAndUpdateCR(ctx, req, gitProviderErr, requeueAfter)
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+		log.Error(gitProviderErr, "Unable to create Git provider client due to error")
+		// An Application being deleted isn't left stuck on its finalizer when its Git provider isn't configured, as its GitOps
+		// repository is deleted through whichever configured Git provider hosts it
+		if !isDeleting {
+			return reconcile.Result{}, gitProviderErr
+		}
+	} else {
+		// Add the Git provider client name to the context
+		ctx = context.WithValue(ctx, github.GHClientKey, gitProvider.GetTokenName())
 	}
 
-	// Add the Go-GitHub client name to the context
-	ctx = context.WithValue(ctx, github.GHClientKey, ghClient.TokenName)
-
 	// Check if the Application CR is under deletion
 	// If so: Remove the GitOps repo (if generated) and remove the finalizer.
 	if application.ObjectMeta.DeletionTimestamp.IsZero() {
@@ -112,10 +149,17 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return ctrl.Result{}, err
 		}
 	} else {
-		if containsString(application.GetFinalizers(), appFinalizerName) {
+		if isDeleting {
 			metrics.ApplicationDeletionTotalReqs.Inc()
 			// A finalizer is present for the Application CR, so make sure we do the necessary cleanup steps
-			if err := r.Finalize(ctx, &application, ghClient); err != nil {
+			if finalizeErr := r.Finalize(ctx, &application); finalizeErr != nil {
+				if requeueAfter, isRateLimited := getRateLimitRequeueAfter(finalizeErr); isRateLimited {
+					// Being rate limited isn't a failure to delete the GitOps repository, so wait for the rate limit to reset without
+					// counting it towards the finalize attempts
+					log.Info(fmt.Sprintf("Rate limited while deleting the GitOps repository, requeueing %v in %v", req.NamespacedName, requeueAfter))
+					r.SetRateLimitedConditionAndUpdateCR(ctx, req, finalizeErr, requeueAfter)
+					return ctrl.Result{RequeueAfter: requeueAfter}, nil
+				}
 				finalizeCounter, err := getCounterAnnotation(finalizeCount, &application)
 				if err == nil && finalizeCounter < 5 {
 					// The Finalize function failed, so increment the finalize count and return
@@ -128,7 +172,7 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				} else {
 					// if fail to delete the external dependency here, log the error, but don't return error
 					// Don't want to get stuck in a cycle of repeatedly trying to delete the repository and failing
-					log.Error(err, "Unable to delete GitOps repository for application %v in namespace %v", application.GetName(), application.GetNamespace())
+					log.Error(finalizeErr, fmt.Sprintf("Unable to delete GitOps repository for application %v in namespace %v", application.GetName(), application.GetNamespace()))
 
 					// Increment the Application deletion failed metric as the application delete did not fully succeed
 					metrics.ApplicationDeletionFailed.Inc()
@@ -143,6 +187,9 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			} else {
 				metrics.ApplicationDeletionSucceeded.Inc()
 			}
+			if gitProviderErr != nil {
+				return ctrl.Result{}, nil
+			}
 		}
 	}
 
@@ -156,16 +203,67 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		appModelRepo := application.Spec.AppModelRepository.URL
 		if gitOpsRepo == "" {
 			// If both repositories are blank, just generate a single shared repository
-			uniqueHash := util.GenerateUniqueHashForWorkloadImageTag(application.Namespace)
-			repoName := github.GenerateNewRepositoryName(application.Name, uniqueHash)
-
-			// Generate the git repo in the redhat-appstudio-appdata org
-			// Not an SLI metric.  Used for determining the number of git operation requests
-			metricsLabel := prometheus.Labels{"controller": applicationName, "tokenName": ghClient.TokenName, "operation": "GenerateNewRepository"}
-			metrics.ControllerGitRequest.With(metricsLabel).Inc()
-			repoUrl, err := ghClient.GenerateNewRepository(ctx, r.GitHubOrg, repoName, "GitOps Repository")
+			repoOpts, err := r.GitOpsRepoOptions.WithOverrides(application.GetAnnotations())
+			if err != nil {
+				metrics.ApplicationCreationFailed.Inc()
+				log.Error(err, fmt.Sprintf("Unable to parse the GitOps repository settings of %v", req.NamespacedName))
+				r.SetCreateConditionAndUpdateCR(ctx, req, &application, err)
+				return reconcile.Result{}, err
+			}
+
+			var repoUrl string
+			var metricsLabel prometheus.Labels
+			pendingRepo := application.GetAnnotations()[pendingGitOpsRepositoryAnnotation]
+			if pendingRepo != "" {
+				// The repository was generated from a template on an earlier reconcile, so finish it once the template has been copied
+				repoUrl = pendingRepo
+				var repoName string
+				repoName, err = gitProvider.GetRepoNameFromURL(pendingRepo, gitOpsOrg)
+				if err != nil {
+					metrics.ApplicationCreationFailed.Inc()
+					log.Error(err, fmt.Sprintf("Unable to parse the pending GitOps repository of %v", req.NamespacedName))
+					r.SetCreateConditionAndUpdateCR(ctx, req, &application, err)
+					return reconcile.Result{}, err
+				}
+				metricsLabel = prometheus.Labels{"controller": applicationName, "tokenName": gitProvider.GetTokenName(), "operation": "FinishNewRepository"}
+				metrics.ControllerGitRequest.With(metricsLabel).Inc()
+				err = gitProvider.FinishNewRepository(ctx, gitOpsOrg, repoName, repoOpts)
+			} else {
+				uniqueHash := util.GenerateUniqueHashForWorkloadImageTag(application.Namespace)
+				repoName := github.GenerateNewRepositoryName(application.Name, uniqueHash)
+
+				// Generate the git repo in the redhat-appstudio-appdata org (or the configured org/group of the Git provider)
+				// Not an SLI metric.  Used for determining the number of git operation requests
+				metricsLabel = prometheus.Labels{"controller": applicationName, "tokenName": gitProvider.GetTokenName(), "operation": "GenerateNewRepository"}
+				metrics.ControllerGitRequest.With(metricsLabel).Inc()
+				repoUrl, err = gitProvider.GenerateNewRepository(ctx, gitOpsOrg, repoName, "GitOps Repository", repoOpts)
+			}
 			if err != nil {
 				metrics.HandleRateLimitMetrics(err, metricsLabel)
+				if gitprovider.IsRepositoryPending(err) {
+					// The Git provider is still copying the template into the repository, so check again later rather than waiting for it
+					log.Info(fmt.Sprintf("GitOps repository %v is being generated from its template, requeueing %v in %v", repoUrl, req.NamespacedName, gitOpsRepositoryPendingRequeueAfter))
+					if pendingRepo == "" {
+						if err := r.setPendingGitOpsRepository(ctx, &application, repoUrl); err != nil {
+							log.Error(err, fmt.Sprintf("Unable to record the pending GitOps repository %v of %v", repoUrl, req.NamespacedName))
+							return ctrl.Result{}, err
+						}
+					}
+					r.SetRepositoryPendingConditionAndUpdateCR(ctx, req, err)
+					return ctrl.Result{RequeueAfter: gitOpsRepositoryPendingRequeueAfter}, nil
+				}
+				if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+					// Wait for the rate limit to reset rather than failing the Application, the repository is generated on the next reconcile
+					log.Info(fmt.Sprintf("Rate limited while creating the GitOps repository, requeueing %v in %v", req.NamespacedName, requeueAfter))
+					r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+					return ctrl.Result{RequeueAfter: requeueAfter}, nil
+				}
+				if pendingRepo != "" {
+					// The pending repository was deleted, as its access settings couldn't be applied, so generate a new one on the next reconcile
+					if err := r.setPendingGitOpsRepository(ctx, &application, ""); err != nil {
+						log.Error(err, fmt.Sprintf("Unable to remove the pending GitOps repository %v of %v", repoUrl, req.NamespacedName))
+					}
+				}
 				metrics.ApplicationCreationFailed.Inc()
 				log.Error(err, fmt.Sprintf("Unable to create repository %v", repoUrl))
 				r.SetCreateConditionAndUpdateCR(ctx, req, &application, err)
@@ -276,6 +374,54 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
+// setPendingGitOpsRepository records the URL of the GitOps repository that's being generated from a template for the Application,
+// or removes it if repoURL is empty
+func (r *ApplicationReconciler) setPendingGitOpsRepository(ctx context.Context, application *appstudiov1alpha1.Application, repoURL string) error {
+	annotations := application.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	if repoURL == "" {
+		delete(annotations, pendingGitOpsRepositoryAnnotation)
+	} else {
+		annotations[pendingGitOpsRepositoryAnnotation] = repoURL
+	}
+	application.SetAnnotations(annotations)
+	return r.Update(ctx, application)
+}
+
+// getGitProvider returns the Git provider client used to manage the Application's GitOps repository, along with the
+// org (or group) that the GitOps repository is generated in. The provider is read from the Application's
+// gitops-provider annotation if set, and defaults to the operator's configured GitOps provider otherwise.
+func (r *ApplicationReconciler) getGitProvider(application *appstudiov1alpha1.Application) (gitprovider.GitProvider, string, error) {
+	providerName, err := r.getGitProviderName(application)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if providerName == gitprovider.GitLab {
+		if r.GitLabClient == nil {
+			return nil, "", fmt.Errorf("the GitLab provider was requested for application %s, but no GitLab token is configured", application.Name)
+		}
+		return r.GitLabClient, r.GitLabGroup, nil
+	}
+
+	ghClient, err := r.GitHubTokenClient.GetNewGitHubClient("")
+	if err != nil {
+		return nil, "", err
+	}
+	return ghClient, r.GitHubOrg, nil
+}
+
+// getGitProviderName returns the name of the Git provider that hosts the Application's GitOps repository
+func (r *ApplicationReconciler) getGitProviderName(application *appstudiov1alpha1.Application) (string, error) {
+	providerName := r.GitOpsProvider
+	if annotationValue := application.GetAnnotations()[gitprovider.GitOpsProviderAnnotation]; annotationValue != "" {
+		providerName = annotationValue
+	}
+	return gitprovider.ValidateProviderName(providerName)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ApplicationReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
 	gofakeit.New(0)
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	logutil "github.com/redhat-appstudio/application-service/pkg/log"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -48,6 +49,7 @@ func (r *ApplicationReconciler) SetCreateConditionAndUpdateCR(ctx context.Contex
 			Reason:  "OK",
 			Message: "Application has been successfully created",
 		}
+		clearRateLimitedCondition(&currentApplication.Status.Conditions)
 	} else {
 		condition = metav1.Condition{
 			Type:    "Created",
@@ -82,6 +84,7 @@ func (r *ApplicationReconciler) SetUpdateConditionAndUpdateCR(ctx context.Contex
 			Reason:  "OK",
 			Message: "Application has been successfully updated",
 		}
+		clearRateLimitedCondition(&currentApplication.Status.Conditions)
 	} else {
 		condition = metav1.Condition{
 			Type:    "Updated",
@@ -99,3 +102,45 @@ func (r *ApplicationReconciler) SetUpdateConditionAndUpdateCR(ctx context.Contex
 		log.Error(err, "Unable to update Application status")
 	}
 }
+
+// SetRateLimitedConditionAndUpdateCR sets the RateLimited condition on the Application, when its reconcile has been delayed by GitHub rate limits
+func (r *ApplicationReconciler) SetRateLimitedConditionAndUpdateCR(ctx context.Context, req ctrl.Request, rateLimitErr error, requeueAfter time.Duration) {
+	log := ctrl.LoggerFrom(ctx)
+	var currentApplication appstudiov1alpha1.Application
+	err := r.Get(ctx, req.NamespacedName, &currentApplication)
+	if err != nil {
+		log.Error(err, "Unable to get current Application status")
+		return
+	}
+	patch := client.MergeFrom(currentApplication.DeepCopy())
+
+	setRateLimitedCondition(&currentApplication.Status.Conditions, rateLimitErr, requeueAfter)
+	err = r.Client.Status().Patch(ctx, &currentApplication, patch)
+	if err != nil {
+		log.Error(err, "Unable to update Application status")
+	}
+}
+
+// SetRepositoryPendingConditionAndUpdateCR sets the Created condition of the Application to pending, while its GitOps repository is
+// still being generated from a template
+func (r *ApplicationReconciler) SetRepositoryPendingConditionAndUpdateCR(ctx context.Context, req ctrl.Request, pendingErr error) {
+	log := ctrl.LoggerFrom(ctx)
+	var currentApplication appstudiov1alpha1.Application
+	err := r.Get(ctx, req.NamespacedName, &currentApplication)
+	if err != nil {
+		log.Error(err, "Unable to get current Application status")
+		return
+	}
+	patch := client.MergeFrom(currentApplication.DeepCopy())
+
+	meta.SetStatusCondition(&currentApplication.Status.Conditions, metav1.Condition{
+		Type:    "Created",
+		Status:  metav1.ConditionFalse,
+		Reason:  "GitOpsRepositoryPending",
+		Message: fmt.Sprintf("Application create is pending: %v", pendingErr),
+	})
+	err = r.Client.Status().Patch(ctx, &currentApplication, patch)
+	if err != nil {
+		log.Error(err, "Unable to update Application status")
+	}
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -26,8 +27,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	cdqanalysis "github.com/redhat-appstudio/application-service/cdq-analysis/pkg"
-	github "github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -49,8 +53,30 @@ func (r *ApplicationReconciler) AddFinalizer(ctx context.Context, application *a
 	return r.Update(ctx, application)
 }
 
-// Finalize deletes the corresponding GitOps repo for the given Application CR.
-func (r *ApplicationReconciler) Finalize(ctx context.Context, application *appstudiov1alpha1.Application, ghClient *github.GitHubClient) error {
+// Finalize deletes, archives or retains the corresponding GitOps repo for the given Application CR, according to its deletion policy.
+// The repository is managed through the Git provider that hosts it, rather than the one the Application's annotation currently names,
+// and is left alone if no configured Git provider hosts it.
+func (r *ApplicationReconciler) Finalize(ctx context.Context, application *appstudiov1alpha1.Application) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	if pendingRepo := application.GetAnnotations()[pendingGitOpsRepositoryAnnotation]; pendingRepo != "" && application.Status.Devfile == "" {
+		// The Application was deleted while its GitOps repository was being generated from a template, so delete the unfinished repository
+		gitProvider, _, gitOpsOrg, err := r.getGitOpsRepositoryProvider(pendingRepo)
+		if err != nil || gitProvider == nil {
+			return err
+		}
+		ctx = context.WithValue(ctx, github.GHClientKey, gitProvider.GetTokenName())
+		repoName, err := gitProvider.GetRepoNameFromURL(pendingRepo, gitOpsOrg)
+		if err != nil {
+			return err
+		}
+		metricsLabel := prometheus.Labels{"controller": applicationName, "tokenName": gitProvider.GetTokenName(), "operation": "DeleteRepository"}
+		metrics.ControllerGitRequest.With(metricsLabel).Inc()
+		err = gitProvider.DeleteRepository(ctx, gitOpsOrg, repoName)
+		metrics.HandleRateLimitMetrics(err, metricsLabel)
+		return err
+	}
+
 	// Get the GitOps repository URL
 	devfileObj, err := cdqanalysis.ParseDevfileWithParserArgs(&parser.ParserArgs{Data: []byte(application.Status.Devfile)})
 	if err != nil {
@@ -62,21 +88,96 @@ func (r *ApplicationReconciler) Finalize(ctx context.Context, application *appst
 	}
 	gitOpsURL := devfileGitOps.(string)
 
+	gitProvider, providerName, gitOpsOrg, err := r.getGitOpsRepositoryProvider(gitOpsURL)
+	if err != nil {
+		return err
+	}
 	// Only delete the GitOps repo if we created it.
-	if strings.Contains(gitOpsURL, r.GitHubOrg) {
-		repoName, err := github.GetRepoNameFromURL(gitOpsURL, r.GitHubOrg)
+	if gitProvider == nil || gitOpsOrg == "" || !strings.Contains(gitOpsURL, gitOpsOrg) {
+		return nil
+	}
+	ctx = context.WithValue(ctx, github.GHClientKey, gitProvider.GetTokenName())
+	repoName, err := gitProvider.GetRepoNameFromURL(gitOpsURL, gitOpsOrg)
+	if err != nil {
+		return err
+	}
+
+	deletionPolicy, err := r.GitOpsRepoDeletionPolicy.WithOverrides(application.GetAnnotations())
+	if err != nil {
+		return err
+	}
+
+	switch deletionPolicy.Policy {
+	case gitprovider.DeletionPolicyRetain, gitprovider.DeletionPolicyArchive, gitprovider.DeletionPolicyDeleteAfterGracePeriod:
+		archivedRepo := ArchivedRepository{
+			Provider:    providerName,
+			Org:         gitOpsOrg,
+			Repo:        repoName,
+			URL:         gitOpsURL,
+			Application: application.Namespace + "/" + application.Name,
+			ArchivedAt:  metav1.Now(),
+		}
+		if deletionPolicy.Policy == gitprovider.DeletionPolicyRetain {
+			// Track retained repositories too, so that the orphaned repository garbage collector leaves them alone
+			archivedRepo.Retained = true
+			if r.ArchivedRepositories != nil {
+				if err := r.ArchivedRepositories.Add(ctx, archivedRepo); err != nil {
+					return err
+				}
+			}
+			log.Info(fmt.Sprintf("Retaining GitOps repository %v of application %v, as its deletion policy is %v", gitOpsURL, application.Name, deletionPolicy.Policy))
+			return nil
+		}
+		if deletionPolicy.Policy == gitprovider.DeletionPolicyDeleteAfterGracePeriod {
+			deleteAfter := metav1.NewTime(archivedRepo.ArchivedAt.Add(deletionPolicy.GracePeriod))
+			archivedRepo.DeleteAfter = &deleteAfter
+		}
+
+		// Track the repository before archiving it, so that it's never left archived without being deleted after its grace period
+		if r.ArchivedRepositories != nil {
+			if err := r.ArchivedRepositories.Add(ctx, archivedRepo); err != nil {
+				return err
+			}
+		} else if archivedRepo.DeleteAfter != nil {
+			return fmt.Errorf("unable to track GitOps repository %s for deletion after its grace period, the operator namespace isn't set", gitOpsURL)
+		}
+
+		metricsLabel := prometheus.Labels{"controller": applicationName, "tokenName": gitProvider.GetTokenName(), "operation": "ArchiveRepository"}
+		metrics.ControllerGitRequest.With(metricsLabel).Inc()
+		err = gitProvider.ArchiveRepository(ctx, gitOpsOrg, repoName)
+		metrics.HandleRateLimitMetrics(err, metricsLabel)
 		if err != nil {
 			return err
 		}
-
-		metricsLabel := prometheus.Labels{"controller": applicationName, "tokenName": ghClient.TokenName, "operation": "DeleteRepository"}
+		log.Info(fmt.Sprintf("Archived GitOps repository %v of application %v, as its deletion policy is %v", gitOpsURL, application.Name, deletionPolicy.Policy))
+		return nil
+	default:
+		metricsLabel := prometheus.Labels{"controller": applicationName, "tokenName": gitProvider.GetTokenName(), "operation": "DeleteRepository"}
 		metrics.ControllerGitRequest.With(metricsLabel).Inc()
-		err = ghClient.DeleteRepository(ctx, r.GitHubOrg, repoName)
+		err = gitProvider.DeleteRepository(ctx, gitOpsOrg, repoName)
 		metrics.HandleRateLimitMetrics(err, metricsLabel)
 		return err
+	}
+}
 
+// getGitOpsRepositoryProvider returns the client of the configured Git provider that hosts the given GitOps repository, along with
+// the provider's name and the org (or group) that GitOps repositories are generated in on it. The client is nil if no configured
+// Git provider hosts the repository
+func (r *ApplicationReconciler) getGitOpsRepositoryProvider(repoURL string) (gitprovider.GitProvider, string, string, error) {
+	if r.GitLabClient != nil && r.GitLabClient.HostsRepository(repoURL) {
+		return r.GitLabClient, gitprovider.GitLab, r.GitLabGroup, nil
+	}
+	if r.GitHubTokenClient == nil {
+		return nil, "", "", nil
+	}
+	ghClient, err := r.GitHubTokenClient.GetNewGitHubClient("")
+	if err != nil {
+		return nil, "", "", err
+	}
+	if ghClient.HostsRepository(repoURL) {
+		return ghClient, gitprovider.GitHub, r.GitHubOrg, nil
 	}
-	return nil
+	return nil, "", "", nil
 }
 
 // Helper functions to check and remove string from a slice of strings.
@@ -0,0 +1,491 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/devfile"
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitlab"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGetGitProvider(t *testing.T) {
+	gitLabClient := gitlab.NewGitLabClient("", "fake-token", "GITLAB_TOKEN")
+
+	tests := []struct {
+		name          string
+		reconciler    ApplicationReconciler
+		annotations   map[string]string
+		wantTokenName string
+		wantOrg       string
+		wantErr       bool
+	}{
+		{
+			name: "No provider set, defaults to GitHub",
+			reconciler: ApplicationReconciler{
+				GitHubTokenClient: github.MockGitHubTokenClient{},
+				GitHubOrg:         github.AppStudioAppDataOrg,
+			},
+			wantOrg: github.AppStudioAppDataOrg,
+		},
+		{
+			name: "GitLab set as the operator default",
+			reconciler: ApplicationReconciler{
+				GitHubTokenClient: github.MockGitHubTokenClient{},
+				GitHubOrg:         github.AppStudioAppDataOrg,
+				GitLabClient:      gitLabClient,
+				GitLabGroup:       "appdata-group",
+				GitOpsProvider:    gitprovider.GitLab,
+			},
+			wantTokenName: "GITLAB_TOKEN",
+			wantOrg:       "appdata-group",
+		},
+		{
+			name: "GitHub annotation overrides the GitLab operator default",
+			reconciler: ApplicationReconciler{
+				GitHubTokenClient: github.MockGitHubTokenClient{},
+				GitHubOrg:         github.AppStudioAppDataOrg,
+				GitLabClient:      gitLabClient,
+				GitLabGroup:       "appdata-group",
+				GitOpsProvider:    gitprovider.GitLab,
+			},
+			annotations: map[string]string{gitprovider.GitOpsProviderAnnotation: "github"},
+			wantOrg:     github.AppStudioAppDataOrg,
+		},
+		{
+			name: "GitLab annotation without a GitLab client",
+			reconciler: ApplicationReconciler{
+				GitHubTokenClient: github.MockGitHubTokenClient{},
+				GitHubOrg:         github.AppStudioAppDataOrg,
+			},
+			annotations: map[string]string{gitprovider.GitOpsProviderAnnotation: "gitlab"},
+			wantErr:     true,
+		},
+		{
+			name: "Unsupported provider annotation",
+			reconciler: ApplicationReconciler{
+				GitHubTokenClient: github.MockGitHubTokenClient{},
+				GitHubOrg:         github.AppStudioAppDataOrg,
+			},
+			annotations: map[string]string{gitprovider.GitOpsProviderAnnotation: "bitbucket"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			application := appstudiov1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-application",
+					Annotations: tt.annotations,
+				},
+			}
+			gitProvider, org, err := tt.reconciler.getGitProvider(&application)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestGetGitProvider() unexpected error value: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if org != tt.wantOrg {
+				t.Errorf("TestGetGitProvider() error: expected org %v got %v", tt.wantOrg, org)
+			}
+			if tt.wantTokenName != "" && gitProvider.GetTokenName() != tt.wantTokenName {
+				t.Errorf("TestGetGitProvider() error: expected token %v got %v", tt.wantTokenName, gitProvider.GetTokenName())
+			}
+		})
+	}
+}
+
+func TestFinalizeDeletionPolicy(t *testing.T) {
+	const gitOpsURL = "https://gitlab.com/appdata-group/test-application-repo"
+
+	tests := []struct {
+		name            string
+		deletionPolicy  gitprovider.DeletionPolicy
+		annotations     map[string]string
+		noTracker       bool
+		wantDeleted     []string
+		wantArchived    []string
+		wantDeleteAfter time.Duration
+		wantTracked     bool
+		wantErr         bool
+	}{
+		{
+			name:        "No deletion policy, the repository is deleted",
+			wantDeleted: []string{"appdata-group/test-application-repo"},
+		},
+		{
+			name:           "Repository is retained and tracked",
+			deletionPolicy: gitprovider.DeletionPolicy{Policy: gitprovider.DeletionPolicyRetain},
+			wantTracked:    true,
+		},
+		{
+			name:           "Repository is archived and tracked",
+			deletionPolicy: gitprovider.DeletionPolicy{Policy: gitprovider.DeletionPolicyArchive},
+			wantArchived:   []string{"appdata-group/test-application-repo"},
+			wantTracked:    true,
+		},
+		{
+			name:           "Annotation overrides the operator's deletion policy",
+			deletionPolicy: gitprovider.DeletionPolicy{Policy: gitprovider.DeletionPolicyDelete},
+			annotations: map[string]string{
+				gitprovider.RepoDeletionPolicyAnnotation:      gitprovider.DeletionPolicyDeleteAfterGracePeriod,
+				gitprovider.RepoDeletionGracePeriodAnnotation: "24h",
+			},
+			wantArchived:    []string{"appdata-group/test-application-repo"},
+			wantDeleteAfter: 24 * time.Hour,
+			wantTracked:     true,
+		},
+		{
+			name:           "Delete after a grace period without a tracker",
+			deletionPolicy: gitprovider.DeletionPolicy{Policy: gitprovider.DeletionPolicyDeleteAfterGracePeriod, GracePeriod: time.Hour},
+			noTracker:      true,
+			wantErr:        true,
+		},
+		{
+			name:        "Invalid deletion policy annotation",
+			annotations: map[string]string{gitprovider.RepoDeletionPolicyAnnotation: "orphan"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			annotations := map[string]string{gitprovider.GitOpsProviderAnnotation: gitprovider.GitLab}
+			for key, value := range tt.annotations {
+				annotations[key] = value
+			}
+			application := appstudiov1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-application",
+					Namespace:   "default",
+					Annotations: annotations,
+				},
+			}
+			devfileData, err := devfile.ConvertApplicationToDevfile(application, gitOpsURL, gitOpsURL)
+			if err != nil {
+				t.Fatalf("TestFinalizeDeletionPolicy() unexpected error: %v", err)
+			}
+			devfileYaml, err := yaml.Marshal(devfileData)
+			if err != nil {
+				t.Fatalf("TestFinalizeDeletionPolicy() unexpected error: %v", err)
+			}
+			application.Status.Devfile = string(devfileYaml)
+
+			gitLabClient := &fakeGitProvider{}
+			reconciler := ApplicationReconciler{
+				GitLabClient:             gitLabClient,
+				GitLabGroup:              "appdata-group",
+				GitOpsRepoDeletionPolicy: tt.deletionPolicy,
+			}
+			if !tt.noTracker {
+				reconciler.ArchivedRepositories = &ArchivedRepositoryTracker{Client: fake.NewClientBuilder().Build(), Namespace: "application-service"}
+			}
+
+			err = reconciler.Finalize(ctx, &application)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestFinalizeDeletionPolicy() unexpected error value: %v", err)
+			}
+			if !reflect.DeepEqual(gitLabClient.deleted, tt.wantDeleted) {
+				t.Errorf("TestFinalizeDeletionPolicy() error: expected deleted repositories %v got %v", tt.wantDeleted, gitLabClient.deleted)
+			}
+			if !reflect.DeepEqual(gitLabClient.archived, tt.wantArchived) {
+				t.Errorf("TestFinalizeDeletionPolicy() error: expected archived repositories %v got %v", tt.wantArchived, gitLabClient.archived)
+			}
+			if reconciler.ArchivedRepositories == nil {
+				return
+			}
+
+			repos, err := reconciler.ArchivedRepositories.List(ctx)
+			if err != nil {
+				t.Fatalf("TestFinalizeDeletionPolicy() unexpected error: %v", err)
+			}
+			if tt.wantTracked != (len(repos) == 1) {
+				t.Fatalf("TestFinalizeDeletionPolicy() error: expected tracked %v got %v", tt.wantTracked, repos)
+			}
+			if !tt.wantTracked {
+				return
+			}
+			if repos[0].Provider != gitprovider.GitLab || repos[0].URL != gitOpsURL || repos[0].Application != "default/test-application" {
+				t.Errorf("TestFinalizeDeletionPolicy() error: unexpected tracked repository %v", repos[0])
+			}
+			if tt.wantDeleteAfter == 0 {
+				if repos[0].DeleteAfter != nil {
+					t.Errorf("TestFinalizeDeletionPolicy() error: expected the repository to be kept indefinitely, got %v", repos[0].DeleteAfter)
+				}
+			} else if repos[0].DeleteAfter == nil || repos[0].DeleteAfter.Sub(repos[0].ArchivedAt.Time) != tt.wantDeleteAfter {
+				t.Errorf("TestFinalizeDeletionPolicy() error: expected the repository to be deleted after %v, got %v", tt.wantDeleteAfter, repos[0].DeleteAfter)
+			}
+		})
+	}
+}
+
+func TestFinalizeGitProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		gitOpsURL   string
+		pendingRepo string
+		wantDeleted []string
+	}{
+		{
+			name:        "Provider annotation changed after the repository was generated, the repository is deleted on the provider that hosts it",
+			annotations: map[string]string{gitprovider.GitOpsProviderAnnotation: gitprovider.GitHub},
+			gitOpsURL:   "https://gitlab.com/appdata-group/test-application-repo",
+			wantDeleted: []string{"appdata-group/test-application-repo"},
+		},
+		{
+			name:        "Repository on a Git provider that isn't configured is left alone",
+			annotations: map[string]string{gitprovider.GitOpsProviderAnnotation: gitprovider.GitLab},
+			gitOpsURL:   "https://git.example.com/appdata-group/test-application-repo",
+		},
+		{
+			name:        "Pending repository is deleted on the provider that hosts it",
+			pendingRepo: "https://gitlab.com/appdata-group/test-application-repo",
+			wantDeleted: []string{"appdata-group/test-application-repo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			application := appstudiov1alpha1.Application{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-application",
+					Namespace:   "default",
+					Annotations: map[string]string{},
+				},
+			}
+			for key, value := range tt.annotations {
+				application.Annotations[key] = value
+			}
+			if tt.pendingRepo != "" {
+				application.Annotations[pendingGitOpsRepositoryAnnotation] = tt.pendingRepo
+			} else {
+				devfileData, err := devfile.ConvertApplicationToDevfile(application, tt.gitOpsURL, tt.gitOpsURL)
+				if err != nil {
+					t.Fatalf("TestFinalizeGitProvider() unexpected error: %v", err)
+				}
+				devfileYaml, err := yaml.Marshal(devfileData)
+				if err != nil {
+					t.Fatalf("TestFinalizeGitProvider() unexpected error: %v", err)
+				}
+				application.Status.Devfile = string(devfileYaml)
+			}
+
+			gitLabClient := &fakeGitProvider{}
+			reconciler := ApplicationReconciler{
+				GitHubTokenClient: github.MockGitHubTokenClient{},
+				GitHubOrg:         "appdata-group",
+				GitLabClient:      gitLabClient,
+				GitLabGroup:       "appdata-group",
+				GitOpsProvider:    gitprovider.GitHub,
+			}
+			if err := reconciler.Finalize(context.Background(), &application); err != nil {
+				t.Errorf("TestFinalizeGitProvider() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(gitLabClient.deleted, tt.wantDeleted) {
+				t.Errorf("TestFinalizeGitProvider() error: expected deleted repositories %v got %v", tt.wantDeleted, gitLabClient.deleted)
+			}
+		})
+	}
+}
+
+func TestReconcileDeletionWithoutGitLabClient(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(appstudiov1alpha1.AddToScheme(scheme))
+	deletionTimestamp := metav1.Now()
+	application := &appstudiov1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-application",
+			Namespace:         "default",
+			Finalizers:        []string{appFinalizerName},
+			DeletionTimestamp: &deletionTimestamp,
+			Annotations: map[string]string{
+				gitprovider.GitOpsProviderAnnotation: gitprovider.GitLab,
+				finalizeCount:                        "0",
+			},
+		},
+	}
+	gitOpsURL := "https://gitlab.com/appdata-group/test-application-repo"
+	devfileData, err := devfile.ConvertApplicationToDevfile(*application, gitOpsURL, gitOpsURL)
+	if err != nil {
+		t.Fatalf("TestReconcileDeletionWithoutGitLabClient() unexpected error: %v", err)
+	}
+	devfileYaml, err := yaml.Marshal(devfileData)
+	if err != nil {
+		t.Fatalf("TestReconcileDeletionWithoutGitLabClient() unexpected error: %v", err)
+	}
+	application.Status.Devfile = string(devfileYaml)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(application).Build()
+	reconciler := ApplicationReconciler{
+		Client:            fakeClient,
+		GitHubTokenClient: github.MockGitHubTokenClient{},
+		GitHubOrg:         github.AppStudioAppDataOrg,
+	}
+
+	// No configured Git provider hosts the GitOps repository, so it's left alone and the Application isn't stuck on its finalizer
+	key := types.NamespacedName{Namespace: application.Namespace, Name: application.Name}
+	if _, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("TestReconcileDeletionWithoutGitLabClient() unexpected error: %v", err)
+	}
+
+	var updated appstudiov1alpha1.Application
+	if err := fakeClient.Get(ctx, key, &updated); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return
+		}
+		t.Fatalf("TestReconcileDeletionWithoutGitLabClient() unexpected error: %v", err)
+	}
+	if containsString(updated.GetFinalizers(), appFinalizerName) {
+		t.Errorf("TestReconcileDeletionWithoutGitLabClient() error: expected the finalizer to be removed, got %v", updated.GetFinalizers())
+	}
+}
+
+func TestReconcileDeletionRateLimited(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(appstudiov1alpha1.AddToScheme(scheme))
+	deletionTimestamp := metav1.Now()
+	application := &appstudiov1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-application",
+			Namespace:         "default",
+			Finalizers:        []string{appFinalizerName},
+			DeletionTimestamp: &deletionTimestamp,
+			Annotations: map[string]string{
+				gitprovider.GitOpsProviderAnnotation: gitprovider.GitLab,
+				finalizeCount:                        "4",
+			},
+		},
+	}
+	gitOpsURL := "https://gitlab.com/appdata-group/test-application-repo"
+	devfileData, err := devfile.ConvertApplicationToDevfile(*application, gitOpsURL, gitOpsURL)
+	if err != nil {
+		t.Fatalf("TestReconcileDeletionRateLimited() unexpected error: %v", err)
+	}
+	devfileYaml, err := yaml.Marshal(devfileData)
+	if err != nil {
+		t.Fatalf("TestReconcileDeletionRateLimited() unexpected error: %v", err)
+	}
+	application.Status.Devfile = string(devfileYaml)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(application).Build()
+	reconciler := ApplicationReconciler{
+		Client:            fakeClient,
+		GitHubTokenClient: github.MockGitHubTokenClient{},
+		GitLabClient:      &fakeGitProvider{err: &github.TokensExhaustedError{ResetTime: time.Now().Add(time.Minute)}},
+		GitLabGroup:       "appdata-group",
+	}
+
+	key := types.NamespacedName{Namespace: application.Namespace, Name: application.Name}
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("TestReconcileDeletionRateLimited() unexpected error: %v", err)
+	}
+	if result.RequeueAfter <= 0 {
+		t.Errorf("TestReconcileDeletionRateLimited() error: expected a requeue once the rate limit resets, got %v", result)
+	}
+
+	// The rate limited attempt doesn't count toward the finalize count, so the finalizer is kept
+	var updated appstudiov1alpha1.Application
+	if err := fakeClient.Get(ctx, key, &updated); err != nil {
+		t.Fatalf("TestReconcileDeletionRateLimited() unexpected error: %v", err)
+	}
+	if !containsString(updated.GetFinalizers(), appFinalizerName) || updated.GetAnnotations()[finalizeCount] != "4" {
+		t.Errorf("TestReconcileDeletionRateLimited() error: expected the finalizer and finalize count to be unchanged, got %v and %v", updated.GetFinalizers(), updated.GetAnnotations())
+	}
+}
+
+func TestReconcileGitOpsRepositoryPending(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(appstudiov1alpha1.AddToScheme(scheme))
+	application := &appstudiov1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-application",
+			Namespace:   "default",
+			Finalizers:  []string{appFinalizerName},
+			Annotations: map[string]string{gitprovider.GitOpsProviderAnnotation: gitprovider.GitLab},
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(application).Build()
+	gitLabClient := &fakeGitProvider{pending: true}
+	reconciler := ApplicationReconciler{
+		Client:            fakeClient,
+		GitHubTokenClient: github.MockGitHubTokenClient{},
+		GitLabClient:      gitLabClient,
+		GitLabGroup:       "appdata-group",
+	}
+	key := types.NamespacedName{Namespace: application.Namespace, Name: application.Name}
+
+	// The reconcile is requeued while the template is being copied into the repository, which is only generated once
+	for i := 0; i < 2; i++ {
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("TestReconcileGitOpsRepositoryPending() unexpected error: %v", err)
+		}
+		if result.RequeueAfter != gitOpsRepositoryPendingRequeueAfter {
+			t.Errorf("TestReconcileGitOpsRepositoryPending() error: expected a requeue after %v, got %v", gitOpsRepositoryPendingRequeueAfter, result)
+		}
+	}
+	if len(gitLabClient.generated) != 1 {
+		t.Fatalf("TestReconcileGitOpsRepositoryPending() error: expected a single repository to be generated, got %v", gitLabClient.generated)
+	}
+	repoURL := "https://gitlab.com/" + gitLabClient.generated[0]
+
+	var updated appstudiov1alpha1.Application
+	if err := fakeClient.Get(ctx, key, &updated); err != nil {
+		t.Fatalf("TestReconcileGitOpsRepositoryPending() unexpected error: %v", err)
+	}
+	condition := meta.FindStatusCondition(updated.Status.Conditions, "Created")
+	if updated.GetAnnotations()[pendingGitOpsRepositoryAnnotation] != repoURL || updated.Status.Devfile != "" || condition == nil || condition.Reason != "GitOpsRepositoryPending" {
+		t.Errorf("TestReconcileGitOpsRepositoryPending() error: expected the Application to be pending on %v, got %v and %v", repoURL, updated.GetAnnotations(), updated.Status)
+	}
+
+	// Once the template is copied, the pending repository is finished and used as the GitOps repository
+	gitLabClient.pending = false
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter != 0 {
+		t.Fatalf("TestReconcileGitOpsRepositoryPending() unexpected result: %v, %v", result, err)
+	}
+	if err := fakeClient.Get(ctx, key, &updated); err != nil {
+		t.Fatalf("TestReconcileGitOpsRepositoryPending() unexpected error: %v", err)
+	}
+	if !strings.Contains(updated.Status.Devfile, repoURL) || len(gitLabClient.generated) != 1 {
+		t.Errorf("TestReconcileGitOpsRepositoryPending() error: expected the Application to use %v, got %v", repoURL, updated.Status.Devfile)
+	}
+}
@@ -34,13 +34,17 @@ import (
 	devfileParser "github.com/devfile/library/v2/pkg/devfile/parser"
 	"github.com/go-logr/logr"
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
 	"github.com/redhat-appstudio/application-service/pkg/devfile"
 	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	logutil "github.com/redhat-appstudio/application-service/pkg/log"
 	"github.com/redhat-appstudio/application-service/pkg/util"
 	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
 	"github.com/spf13/afero"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -61,6 +65,15 @@ type SnapshotEnvironmentBindingReconciler struct {
 	AppFS             afero.Afero
 	Generator         gitopsgen.Generator
 	GitHubTokenClient github.GitHubToken
+
+	// GitLabClient, if set, is the client of the GitLab instance that GitOps repositories may be hosted on
+	GitLabClient gitprovider.GitProvider
+
+	// CommitMessages renders the messages of the commits pushed to GitOps repositories. Defaults to the default templates
+	CommitMessages *gitops.CommitMessages
+
+	// PostProcessors post-process the Kubernetes resources of the Components before their GitOps resources are generated
+	PostProcessors gitops.PostProcessors
 }
 
 const asebName = "SnapshotEnvironmentBinding"
@@ -70,6 +83,7 @@ const asebName = "SnapshotEnvironmentBinding"
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=snapshotenvironmentbindings/finalizers,verbs=update
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=snapshots,verbs=get;list;watch
 //+kubebuilder:rbac:groups=appstudio.redhat.com,resources=environments,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -101,6 +115,11 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 
 	ghClient, err := r.GitHubTokenClient.GetNewGitHubClient("")
 	if err != nil {
+		if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+			log.Info(fmt.Sprintf("All GitHub tokens are rate limited, requeueing %v in %v", req.NamespacedName, requeueAfter))
+			r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
 		log.Error(err, "Unable to create Go-GitHub client due to error")
 		return reconcile.Result{}, err
 	}
@@ -108,6 +127,36 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 	// Add the Go-GitHub client name to the context
 	ctx = context.WithValue(ctx, github.GHClientKey, ghClient.TokenName)
 
+	// If the binding's GitOps changes were opened in a pull request that hasn't been merged, they aren't regenerated until either the
+	// pull request is merged or the binding changes. Until then, poll the pull request while it's open.
+	if condition := meta.FindStatusCondition(appSnapshotEnvBinding.Status.GitOpsRepoConditions, gitOpsPullRequestConditionType); condition != nil &&
+		condition.Status != metav1.ConditionTrue && condition.ObservedGeneration == appSnapshotEnvBinding.Generation && len(appSnapshotEnvBinding.Status.Components) > 0 {
+		if !isGitOpsPullRequestOpen(appSnapshotEnvBinding.Status.GitOpsRepoConditions) {
+			log.Info(fmt.Sprintf("GitOps pull request %s was closed, skipping GitOps generation until %v is updated", condition.Message, req.NamespacedName))
+			return ctrl.Result{}, nil
+		}
+		gitOpsRepoURL := appSnapshotEnvBinding.Status.Components[0].GitOpsRepository.URL
+		pullRequest, err := refreshGitOpsPullRequest(ctx, asebName, gitOpsRepositoryProvider(ghClient, r.GitLabClient, gitOpsRepoURL), gitOpsRepoURL, &appSnapshotEnvBinding.Status.GitOpsRepoConditions)
+		if err != nil {
+			if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+				log.Info(fmt.Sprintf("Rate limited while checking the GitOps pull request, requeueing %v in %v", req.NamespacedName, requeueAfter))
+				r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
+			}
+			log.Error(err, fmt.Sprintf("Unable to check the GitOps pull request of %v", req.NamespacedName))
+			return ctrl.Result{RequeueAfter: gitOpsPullRequestPollInterval}, nil
+		}
+		switch pullRequest.State {
+		case gitprovider.PullRequestStateOpen:
+			return ctrl.Result{RequeueAfter: gitOpsPullRequestPollInterval}, nil
+		case gitprovider.PullRequestStateClosed:
+			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, nil)
+			return ctrl.Result{}, nil
+		}
+		// Once merged, regenerate the GitOps resources to record the commit they were merged at
+		log.Info(fmt.Sprintf("GitOps pull request %s was merged %v", pullRequest.URL, req.NamespacedName))
+	}
+
 	applicationName := appSnapshotEnvBinding.Spec.Application
 	environmentName := appSnapshotEnvBinding.Spec.Environment
 	snapshotName := appSnapshotEnvBinding.Spec.Snapshot
@@ -156,156 +205,125 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 		return ctrl.Result{}, err
 	}
 
-	componentGeneratedResources := make(map[string][]string)
-	var tempDir string
-	clone := true
+	// In pull request mode, the gitops resources are pushed to a work branch, and a pull request is opened against the GitOps branch
+	pullRequestMode, err := isPullRequestModeEnabled(ctx, r.Client, appSnapshotEnvBinding.Namespace, applicationName, &environment)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the Application %s %v", applicationName, req.NamespacedName))
+		r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
+		return ctrl.Result{}, err
+	}
+	outputFormat, err := getGitOpsOutputFormat(ctx, r.Client, appSnapshotEnvBinding.Namespace, applicationName)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the GitOps output format of the Application %s %v", applicationName, req.NamespacedName))
+		r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
+		return ctrl.Result{}, err
+	}
+	previousCommitIDs := make(map[string]string)
+	for _, componentStatus := range appSnapshotEnvBinding.Status.Components {
+		previousCommitIDs[componentStatus.Name] = componentStatus.GitOpsRepository.CommitID
+	}
+	var gitOpsRepoURL, gitOpsBaseBranch, pushBranch string
+	var gitOpsProvider gitprovider.GitProvider
 
+	// Work out the GitOps resources of each Component first, so that the GitOps repository is only cloned if they've changed
+	var bindingComponents []bindingComponentGitOps
 	for _, component := range components {
-		componentName := component.Name
-
-		// Get the Component CR
-		hasComponent := appstudiov1alpha1.Component{}
-		err = r.Get(ctx, types.NamespacedName{Name: componentName, Namespace: appSnapshotEnvBinding.Namespace}, &hasComponent)
+		bindingComponent, err := getBindingComponentGitOps(ctx, r.Client, &appSnapshotEnvBinding, component, &environment, &appSnapshot, ghClient, r.GitLabClient, r.PostProcessors)
 		if err != nil {
-			log.Error(err, fmt.Sprintf("unable to get the Component %s %v", componentName, req.NamespacedName))
 			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
 			return ctrl.Result{}, err
 		}
-
-		if hasComponent.Spec.SkipGitOpsResourceGeneration {
+		if bindingComponent == nil {
 			continue
 		}
 
-		// Sanity check to make sure the binding component has referenced the correct application
-		if hasComponent.Spec.Application != applicationName {
-			err := fmt.Errorf("component %s does not belong to the application %s", componentName, applicationName)
-			log.Error(err, "")
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-			return ctrl.Result{}, err
-		}
-
-		var clusterIngressDomain string
-		isKubernetesCluster := isKubernetesCluster(environment)
-		unsupportedConfig := environment.Spec.UnstableConfigurationFields
-		if unsupportedConfig != nil {
-			clusterIngressDomain = unsupportedConfig.IngressDomain
-		}
-
-		// Safeguard if Ingress Domain is empty on Kubernetes
-		if isKubernetesCluster && clusterIngressDomain == "" {
-			err = fmt.Errorf("ingress domain cannot be empty on a Kubernetes cluster")
-			log.Error(err, "unable to create an ingress resource on a Kubernetes cluster")
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-			return ctrl.Result{}, err
-		}
-
-		parserArgs := &devfileParser.ParserArgs{Data: []byte(hasComponent.Status.Devfile)}
-		var gitToken string
-		//get the token to pass into the parser
-		if hasComponent.Spec.Secret != "" {
-			gitSecret := corev1.Secret{}
-			namespacedName := types.NamespacedName{
-				Name:      hasComponent.Spec.Secret,
-				Namespace: hasComponent.Namespace,
-			}
-
-			err = r.Client.Get(ctx, namespacedName, &gitSecret)
-			if err != nil {
-				log.Error(err, fmt.Sprintf("Unable to retrieve Git secret %v, exiting reconcile loop %v", hasComponent.Spec.Secret, req.NamespacedName))
-				r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-				return ctrl.Result{}, err
-			}
-
-			gitToken = string(gitSecret.Data["password"])
+		gitOpsRepoURL, gitOpsBaseBranch, pushBranch = bindingComponent.component.Status.GitOps.RepositoryURL, bindingComponent.branch, bindingComponent.branch
+		gitOpsProvider = bindingComponent.gitProvider
+		if pullRequestMode {
+			pushBranch = gitprovider.PullRequestBranchName(asebName, appSnapshotEnvBinding.Name)
 		}
+		bindingComponents = append(bindingComponents, *bindingComponent)
+	}
 
-		parserArgs.Token = gitToken
-		compDevfileData, err := cdqanalysis.ParseDevfileWithParserArgs(parserArgs)
+	// In a dry run, the overlays are stored in a ConfigMap rather than pushed. As when they're generated, the Components' overlays are
+	// all in the repository of the first Component.
+	if dryRunMode := gitops.GetDryRunMode(appSnapshotEnvBinding.Annotations); dryRunMode != "" && len(bindingComponents) > 0 {
+		var copied, compared []string
+		for _, bindingComponent := range bindingComponents {
+			copied = append(copied, filepath.Join("components", bindingComponent.options.Name))
+			compared = append(compared, filepath.Join("components", bindingComponent.options.Name, "overlays", environmentName))
+		}
+		dryRun := gitOpsDryRun{client: r.Client, scheme: r.Scheme, appFS: r.AppFS, generator: r.Generator, controllerName: asebName}
+		condition, err := dryRun.run(ctx, bindingComponents[0].gitProvider, &appSnapshotEnvBinding, appSnapshotEnvBinding.Name+"-binding-gitops-dry-run", dryRunMode, gitOpsRender{
+			remote:   bindingComponents[0].remote,
+			branch:   bindingComponents[0].branch,
+			context:  bindingComponents[0].context,
+			copied:   copied,
+			compared: compared,
+			render:   renderBindingOverlays(bindingComponents, environmentName, outputFormat),
+		})
 		if err != nil {
-			errMsg := fmt.Sprintf("Unable to parse the devfile from Component status, exiting reconcile loop %v", req.NamespacedName)
-			log.Error(err, errMsg)
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, fmt.Errorf("%v: %v", errMsg, err))
+			// A failed dry run leaves the GitOps resources as they were
+			log.Error(err, fmt.Sprintf("unable to dry run the gitops resources %v", req.NamespacedName))
+			meta.SetStatusCondition(&appSnapshotEnvBinding.Status.GitOpsRepoConditions, gitOpsDryRunFailedCondition(err, appSnapshotEnvBinding.Generation))
+			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, nil)
 			return ctrl.Result{}, err
 		}
+		meta.SetStatusCondition(&appSnapshotEnvBinding.Status.GitOpsRepoConditions, condition)
+		r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, nil)
+		log.Info(fmt.Sprintf("Finished the GitOps dry run of %v", req.NamespacedName))
+		return ctrl.Result{}, nil
+	}
+	meta.RemoveStatusCondition(&appSnapshotEnvBinding.Status.GitOpsRepoConditions, gitOpsDryRunConditionType)
 
-		deployAssociatedComponents, err := devfileParser.GetDeployComponents(compDevfileData)
+	// Skip the clone and push if the rendered overlays haven't changed since they were last pushed, and only refresh the commit IDs
+	var resourcesHash string
+	if !pullRequestMode && len(bindingComponents) > 0 {
+		resourcesHash, err = hashBindingGitOpsResources(bindingComponents, environmentName, outputFormat)
 		if err != nil {
-			log.Error(err, "unable to get deploy components")
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-			return ctrl.Result{}, err
+			log.Error(err, fmt.Sprintf("unable to hash the gitops resources, pushing them %v", req.NamespacedName))
 		}
-
-		var hostname string
-		if isKubernetesCluster {
-			hostname, err = devfile.GetIngressHostName(hasComponent.Name, appSnapshotEnvBinding.Namespace, clusterIngressDomain)
-			if err != nil {
-				log.Error(err, fmt.Sprintf("unable to get generate a host name from an ingress domain for %s %v", hasComponent.Name, req.NamespacedName))
-				ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-				r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-				return ctrl.Result{}, err
-			}
-		}
-
-		// Generate a route name for the component
-
-		kubernetesResources, err := devfile.GetResourceFromDevfile(log, compDevfileData, deployAssociatedComponents, hasComponent.Name, hasComponent.Spec.Application, hasComponent.Spec.ContainerImage, hostname)
+	}
+	componentGeneratedResources := make(map[string][]string)
+	var tempDir string
+	clone := true
+	if isGitOpsResourcesUnchanged(&appSnapshotEnvBinding, appSnapshotEnvBinding.Status.GitOpsRepoConditions, resourcesHash) {
+		log.Info(fmt.Sprintf("GitOps resources are unchanged, skipping the push %v", req.NamespacedName))
+		commitID, err := latestGitOpsCommitID(ctx, asebName, gitOpsProvider, gitOpsRepoURL, gitOpsBaseBranch)
 		if err != nil {
-			log.Error(err, "unable to get kubernetes resources from the devfile outerloop components")
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
+			log.Error(err, fmt.Sprintf("unable to refresh the GitOps commit ID %v", req.NamespacedName))
 			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-			return ctrl.Result{}, err
-		}
-
-		// Create a random, generated name for the route
-		// ToDo: Ideally we wouldn't need to loop here, but since the Component status is a list, we can't avoid it
-		var routeName string
-		for _, compStatus := range appSnapshotEnvBinding.Status.Components {
-			if compStatus.Name == componentName {
-				if compStatus.GeneratedRouteName != "" {
-					routeName = compStatus.GeneratedRouteName
-					log.Info(fmt.Sprintf("route name for component is %s", routeName))
-				}
-				break
+			if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+				log.Info(fmt.Sprintf("Rate limited while refreshing the GitOps commit ID, requeueing %v in %v", req.NamespacedName, requeueAfter))
+				r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
 			}
+			return ctrl.Result{}, err
 		}
-		if routeName == "" {
-			routeName = util.GenerateRandomRouteName(hasComponent.Name)
-			log.Info(fmt.Sprintf("generated route name %s", routeName))
-		}
-
-		// If a route is present, update the first instance's name
-		if len(kubernetesResources.Routes) > 0 {
-			kubernetesResources.Routes[0].ObjectMeta.Name = routeName
-		}
-
-		var imageName string
-
-		for _, snapshotComponent := range appSnapshot.Spec.Components {
-			if snapshotComponent.Name == componentName {
-				imageName = snapshotComponent.ContainerImage
-				break
-			}
+		for i := range appSnapshotEnvBinding.Status.Components {
+			appSnapshotEnvBinding.Status.Components[i].GitOpsRepository.CommitID = commitID
 		}
+		bindingComponents = nil
+	}
 
-		if imageName == "" {
-			err := fmt.Errorf("application snapshot %s did not reference component %s", snapshotName, componentName)
-			log.Error(err, "")
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
+	// Start a new pull request from the GitOps branch, rather than from the work branch of the previous one
+	if pullRequestMode && len(bindingComponents) > 0 && !isGitOpsPullRequestOpen(appSnapshotEnvBinding.Status.GitOpsRepoConditions) {
+		if err := resetGitOpsWorkBranch(ctx, asebName, gitOpsProvider, gitOpsRepoURL, pushBranch, gitOpsBaseBranch); err != nil {
+			log.Error(err, fmt.Sprintf("unable to reset the GitOps work branch %v", req.NamespacedName))
 			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
+			if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+				log.Info(fmt.Sprintf("Rate limited while resetting the GitOps work branch, requeueing %v in %v", req.NamespacedName, requeueAfter))
+				r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
+			}
 			return ctrl.Result{}, err
 		}
+	}
 
-		gitOpsRemoteURL, gitOpsBranch, gitOpsContext, err := util.ProcessGitOpsStatus(hasComponent.Status.GitOps, ghClient.Token)
-		if err != nil {
-			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
-			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-			return ctrl.Result{}, err
-		}
+	for _, bindingComponent := range bindingComponents {
+		hasComponent, genOptions := bindingComponent.component, bindingComponent.options
+		componentName := genOptions.Name
+		gitOpsBranch, gitOpsContext := bindingComponent.branch, bindingComponent.context
 
 		if clone {
 			// Create a temp folder to create the gitops resources in
@@ -317,62 +335,26 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 			}
 		}
 
-		envVars := make([]corev1.EnvVar, 0)
-		for _, env := range component.Configuration.Env {
-			envVars = append(envVars, corev1.EnvVar{
-				Name:  env.Name,
-				Value: env.Value,
-			})
-		}
-
-		environmentConfigEnvVars := make([]corev1.EnvVar, 0)
-		for _, env := range environment.Spec.Configuration.Env {
-			environmentConfigEnvVars = append(environmentConfigEnvVars, corev1.EnvVar{
-				Name:  env.Name,
-				Value: env.Value,
+		//Gitops functions return sanitized error messages
+		metrics.ControllerGitRequest.With(prometheus.Labels{"controller": asebName, "tokenName": bindingComponent.gitProvider.GetTokenName(), "operation": "GenerateOverlaysAndPush"}).Inc()
+		err = gitops.GenerateOverlays(r.Generator, outputFormat, tempDir, clone, bindingComponent.remote, genOptions, bindingComponent.overlay, applicationName, environmentName, bindingComponent.imageName, "", r.AppFS, pushBranch, gitOpsContext, componentGeneratedResources)
+		if err == nil {
+			var commitMessage string
+			commitMessage, err = r.CommitMessages.BindingMessage(gitops.CommitMessageData{
+				Namespace:   appSnapshotEnvBinding.Namespace,
+				Application: applicationName,
+				Components:  []gitops.CommitComponent{bindingComponent.commit},
+				Environment: environmentName,
+				Snapshot:    snapshotName,
+				Reconciler:  asebName,
+				Resource:    appSnapshotEnvBinding.Name,
+				Generation:  appSnapshotEnvBinding.Generation,
 			})
+			if err == nil {
+				metrics.ControllerGitRequest.With(prometheus.Labels{"controller": asebName, "tokenName": bindingComponent.gitProvider.GetTokenName(), "operation": "CommitAndPush"}).Inc()
+				err = r.Generator.CommitAndPush(tempDir, applicationName, bindingComponent.remote, componentName, pushBranch, commitMessage)
+			}
 		}
-		componentResources := corev1.ResourceRequirements{}
-		if component.Configuration.Resources != nil {
-			componentResources = *component.Configuration.Resources
-		}
-
-		kubeLabels := map[string]string{
-			"app.kubernetes.io/name":       componentName,
-			"app.kubernetes.io/instance":   component.Name,
-			"app.kubernetes.io/part-of":    applicationName,
-			"app.kubernetes.io/managed-by": "kustomize",
-			"app.kubernetes.io/created-by": "application-service",
-		}
-		genOptions := gitopsgenv1alpha1.GeneratorOptions{
-			Name:                component.Name,
-			RouteName:           routeName,
-			Resources:           componentResources,
-			BaseEnvVar:          envVars,
-			OverlayEnvVar:       environmentConfigEnvVars,
-			K8sLabels:           kubeLabels,
-			IsKubernetesCluster: isKubernetesCluster,
-			TargetPort:          hasComponent.Spec.TargetPort, // pass the target port to the gitops gen library as they may generate a route/ingress based on the target port if the devfile does not have an ingress/route or an endpoint
-		}
-
-		if component.Configuration.Replicas != nil {
-			genOptions.Replicas = *component.Configuration.Replicas
-		}
-
-		if !reflect.DeepEqual(kubernetesResources, devfileParser.KubernetesResources{}) {
-			genOptions.KubernetesResources.Routes = append(genOptions.KubernetesResources.Routes, kubernetesResources.Routes...)
-			genOptions.KubernetesResources.Ingresses = append(genOptions.KubernetesResources.Ingresses, kubernetesResources.Ingresses...)
-		}
-
-		if isKubernetesCluster && len(genOptions.KubernetesResources.Ingresses) == 0 {
-			// provide the hostname for the component if there are no ingresses
-			// Gitops Generator Library will create the Ingress with the hostname
-			genOptions.Route = hostname
-		}
-
-		//Gitops functions return sanitized error messages
-		metrics.ControllerGitRequest.With(prometheus.Labels{"controller": asebName, "tokenName": ghClient.TokenName, "operation": "GenerateOverlaysAndPush"}).Inc()
-		err = r.Generator.GenerateOverlaysAndPush(tempDir, clone, gitOpsRemoteURL, genOptions, applicationName, environmentName, imageName, "", r.AppFS, gitOpsBranch, gitOpsContext, true, componentGeneratedResources)
 		if err != nil {
 			retErr := err
 			if strings.Contains(strings.ToLower(err.Error()), "github push protection") {
@@ -397,7 +379,7 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 		// Retrieve the commit ID
 		var commitID string
 		repoPath := filepath.Join(tempDir, applicationName)
-		metricsLabel := prometheus.Labels{"controller": asebName, "tokenName": ghClient.TokenName, "operation": "GetCommitIDFromRepo"}
+		metricsLabel := prometheus.Labels{"controller": asebName, "tokenName": bindingComponent.gitProvider.GetTokenName(), "operation": "GetCommitIDFromRepo"}
 		metrics.ControllerGitRequest.With(metricsLabel).Inc()
 		if commitID, err = r.Generator.GetCommitIDFromRepo(r.AppFS, repoPath); err != nil {
 			//gitops generator errors are sanitized
@@ -419,9 +401,9 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 		}
 
 		// On OpenShift, we generate a unique route name for each Component, so include that in the status
-		if !isKubernetesCluster {
-			componentStatus.GeneratedRouteName = routeName
-			log.Info(fmt.Sprintf("added RouteName %s for Component %s to status", routeName, componentName))
+		if !bindingComponent.isKubernetesCluster {
+			componentStatus.GeneratedRouteName = bindingComponent.routeName
+			log.Info(fmt.Sprintf("added RouteName %s for Component %s to status", bindingComponent.routeName, componentName))
 		}
 
 		if _, ok := componentGeneratedResources[componentName]; ok {
@@ -444,6 +426,41 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 		clone = false
 	}
 
+	if pullRequestMode && !clone {
+		pullRequest, baseCommitID, err := syncGitOpsPullRequest(ctx, asebName, gitOpsProvider, gitOpsRepoURL, gitprovider.PullRequestOptions{
+			Title:      fmt.Sprintf("Update GitOps resources of Application %s in Environment %s", applicationName, environmentName),
+			Body:       fmt.Sprintf("GitOps resources generated by application-service for Snapshot %s of Application %s in Environment %s, in namespace %s.", snapshotName, applicationName, environmentName, appSnapshotEnvBinding.Namespace),
+			HeadBranch: pushBranch,
+			BaseBranch: gitOpsBaseBranch,
+		})
+		if err != nil {
+			log.Error(err, fmt.Sprintf("unable to open the GitOps pull request %v", req.NamespacedName))
+			ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
+			r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, err)
+			if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+				log.Info(fmt.Sprintf("Rate limited while opening the GitOps pull request, requeueing %v in %v", req.NamespacedName, requeueAfter))
+				r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		recordGitOpsPullRequest(&appSnapshotEnvBinding.Status.GitOpsRepoConditions, pullRequest, appSnapshotEnvBinding.Generation)
+
+		// The commit IDs are only updated once the changes are merged into the GitOps branch
+		for i := range appSnapshotEnvBinding.Status.Components {
+			if pullRequest == nil {
+				appSnapshotEnvBinding.Status.Components[i].GitOpsRepository.CommitID = baseCommitID
+			} else {
+				appSnapshotEnvBinding.Status.Components[i].GitOpsRepository.CommitID = previousCommitIDs[appSnapshotEnvBinding.Status.Components[i].Name]
+			}
+		}
+	} else if !pullRequestMode {
+		meta.RemoveStatusCondition(&appSnapshotEnvBinding.Status.GitOpsRepoConditions, gitOpsPullRequestConditionType)
+	}
+	if err := recordGitOpsResourcesHash(ctx, r.Client, &appSnapshotEnvBinding, resourcesHash); err != nil {
+		log.Error(err, fmt.Sprintf("unable to record the hash of the gitops resources %v", req.NamespacedName))
+	}
+
 	// Remove the cloned path
 	err = r.AppFS.RemoveAll(tempDir)
 	if err != nil {
@@ -461,7 +478,334 @@ func (r *SnapshotEnvironmentBindingReconciler) Reconcile(ctx context.Context, re
 	r.SetConditionAndUpdateCR(ctx, req, &appSnapshotEnvBinding, nil)
 
 	log.Info(fmt.Sprintf("Finished reconcile loop for %v", req.NamespacedName))
-	return ctrl.Result{}, nil
+	return gitOpsPullRequestResult(appSnapshotEnvBinding.Status.GitOpsRepoConditions), nil
+}
+
+// bindingComponentGitOps is the information needed to generate the GitOps overlays of a Component of a SnapshotEnvironmentBinding
+type bindingComponentGitOps struct {
+	component *appstudiov1alpha1.Component
+	options   gitopsgenv1alpha1.GeneratorOptions
+	overlay   gitops.OverlayOptions
+	imageName string
+	routeName string
+
+	// commit describes the Component in the commit message
+	commit gitops.CommitComponent
+
+	// remote, branch and context locate the Component's GitOps resources in its GitOps repository
+	remote  string
+	branch  string
+	context string
+
+	// gitProvider is the client of the Git provider that hosts the GitOps repository
+	gitProvider gitprovider.GitProvider
+
+	isKubernetesCluster bool
+}
+
+// getBindingComponentGitOps works out what's needed to generate the GitOps overlays of the given Component of the
+// SnapshotEnvironmentBinding, in the given Environment and from the given Snapshot. It returns nil if the Component's GitOps resources
+// aren't generated. The Kubernetes resources of the Component in the Environment are post-processed by the given post-processors, and
+// its GitOps repository is accessed with the client of the GitHub or GitLab provider that hosts it.
+func getBindingComponentGitOps(ctx context.Context, c client.Client, binding *appstudiov1alpha1.SnapshotEnvironmentBinding, component appstudiov1alpha1.BindingComponent,
+	environment *appstudiov1alpha1.Environment, appSnapshot *appstudiov1alpha1.Snapshot, ghClient *github.GitHubClient, gitLabClient gitprovider.GitProvider,
+	postProcessors gitops.PostProcessors) (*bindingComponentGitOps, error) {
+	log := ctrl.LoggerFrom(ctx)
+	bindingKey := client.ObjectKeyFromObject(binding)
+	applicationName := binding.Spec.Application
+	environmentName := binding.Spec.Environment
+	snapshotName := binding.Spec.Snapshot
+	componentName := component.Name
+
+	// Get the Component CR
+	hasComponent := appstudiov1alpha1.Component{}
+	err := c.Get(ctx, types.NamespacedName{Name: componentName, Namespace: binding.Namespace}, &hasComponent)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the Component %s %v", componentName, bindingKey))
+		return nil, err
+	}
+
+	if hasComponent.Spec.SkipGitOpsResourceGeneration {
+		return nil, nil
+	}
+
+	// Sanity check to make sure the binding component has referenced the correct application
+	if hasComponent.Spec.Application != applicationName {
+		err := fmt.Errorf("component %s does not belong to the application %s", componentName, applicationName)
+		log.Error(err, "")
+		return nil, err
+	}
+
+	var clusterIngressDomain string
+	isKubernetesCluster := isKubernetesCluster(*environment)
+	unsupportedConfig := environment.Spec.UnstableConfigurationFields
+	if unsupportedConfig != nil {
+		clusterIngressDomain = unsupportedConfig.IngressDomain
+	}
+
+	// Safeguard if Ingress Domain is empty on Kubernetes
+	if isKubernetesCluster && clusterIngressDomain == "" {
+		err = fmt.Errorf("ingress domain cannot be empty on a Kubernetes cluster")
+		log.Error(err, "unable to create an ingress resource on a Kubernetes cluster")
+		return nil, err
+	}
+
+	parserArgs := &devfileParser.ParserArgs{Data: []byte(hasComponent.Status.Devfile)}
+	var gitToken string
+	//get the token to pass into the parser
+	if hasComponent.Spec.Secret != "" {
+		gitSecret := corev1.Secret{}
+		namespacedName := types.NamespacedName{
+			Name:      hasComponent.Spec.Secret,
+			Namespace: hasComponent.Namespace,
+		}
+
+		err = c.Get(ctx, namespacedName, &gitSecret)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Unable to retrieve Git secret %v, exiting reconcile loop %v", hasComponent.Spec.Secret, bindingKey))
+			return nil, err
+		}
+
+		gitToken = string(gitSecret.Data["password"])
+	}
+
+	parserArgs.Token = gitToken
+	compDevfileData, err := cdqanalysis.ParseDevfileWithParserArgs(parserArgs)
+	if err != nil {
+		errMsg := fmt.Sprintf("Unable to parse the devfile from Component status, exiting reconcile loop %v", bindingKey)
+		log.Error(err, errMsg)
+		return nil, fmt.Errorf("%v: %v", errMsg, err)
+	}
+
+	deployAssociatedComponents, err := devfileParser.GetDeployComponents(compDevfileData)
+	if err != nil {
+		log.Error(err, "unable to get deploy components")
+		return nil, err
+	}
+
+	var hostname string
+	if isKubernetesCluster {
+		hostname, err = devfile.GetIngressHostName(hasComponent.Name, binding.Namespace, clusterIngressDomain)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("unable to get generate a host name from an ingress domain for %s %v", hasComponent.Name, bindingKey))
+			return nil, err
+		}
+	}
+
+	// Generate a route name for the component
+
+	kubernetesResources, err := devfile.GetResourceFromDevfile(log, compDevfileData, deployAssociatedComponents, hasComponent.Name, hasComponent.Spec.Application, hasComponent.Spec.ContainerImage, hostname)
+	if err != nil {
+		log.Error(err, "unable to get kubernetes resources from the devfile outerloop components")
+		return nil, err
+	}
+
+	// Create a random, generated name for the route
+	// ToDo: Ideally we wouldn't need to loop here, but since the Component status is a list, we can't avoid it
+	var routeName string
+	for _, compStatus := range binding.Status.Components {
+		if compStatus.Name == componentName {
+			if compStatus.GeneratedRouteName != "" {
+				routeName = compStatus.GeneratedRouteName
+				log.Info(fmt.Sprintf("route name for component is %s", routeName))
+			}
+			break
+		}
+	}
+	if routeName == "" {
+		routeName = util.GenerateRouteName(hasComponent.Name, applicationName, environmentName)
+		log.Info(fmt.Sprintf("generated route name %s", routeName))
+	}
+
+	// If a route is present, update the first instance's name
+	if len(kubernetesResources.Routes) > 0 {
+		kubernetesResources.Routes[0].ObjectMeta.Name = routeName
+	}
+	if err := postProcessors.Apply(&kubernetesResources); err != nil {
+		log.Error(err, fmt.Sprintf("unable to post-process the kubernetes resources of the Component %s %v", componentName, bindingKey))
+		return nil, err
+	}
+
+	var imageName string
+	var commitComponent gitops.CommitComponent
+
+	for _, snapshotComponent := range appSnapshot.Spec.Components {
+		if snapshotComponent.Name == componentName {
+			imageName = snapshotComponent.ContainerImage
+			commitComponent = gitOpsSnapshotCommitComponent(&hasComponent, snapshotComponent)
+			break
+		}
+	}
+
+	if imageName == "" {
+		err := fmt.Errorf("application snapshot %s did not reference component %s", snapshotName, componentName)
+		log.Error(err, "")
+		return nil, err
+	}
+
+	gitProvider := gitOpsRepositoryProvider(ghClient, gitLabClient, hasComponent.Status.GitOps.RepositoryURL)
+	gitOpsRemoteURL, gitOpsBranch, gitOpsContext, err := util.ProcessGitOpsStatus(hasComponent.Status.GitOps, gitProvider.GetRemoteCredentials())
+	if err != nil {
+		return nil, err
+	}
+
+	envVars := make([]corev1.EnvVar, 0)
+	for _, env := range component.Configuration.Env {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  env.Name,
+			Value: env.Value,
+		})
+	}
+
+	environmentConfigEnvVars := make([]corev1.EnvVar, 0)
+	for _, env := range environment.Spec.Configuration.Env {
+		environmentConfigEnvVars = append(environmentConfigEnvVars, corev1.EnvVar{
+			Name:  env.Name,
+			Value: env.Value,
+		})
+	}
+	componentResources := corev1.ResourceRequirements{}
+	if component.Configuration.Resources != nil {
+		componentResources = *component.Configuration.Resources
+	}
+
+	kubeLabels := map[string]string{
+		"app.kubernetes.io/name":       componentName,
+		"app.kubernetes.io/instance":   component.Name,
+		"app.kubernetes.io/part-of":    applicationName,
+		"app.kubernetes.io/managed-by": "kustomize",
+		"app.kubernetes.io/created-by": "application-service",
+	}
+	genOptions := gitopsgenv1alpha1.GeneratorOptions{
+		Name:                component.Name,
+		RouteName:           routeName,
+		Resources:           componentResources,
+		BaseEnvVar:          envVars,
+		OverlayEnvVar:       environmentConfigEnvVars,
+		K8sLabels:           kubeLabels,
+		IsKubernetesCluster: isKubernetesCluster,
+		TargetPort:          hasComponent.Spec.TargetPort, // pass the target port to the gitops gen library as they may generate a route/ingress based on the target port if the devfile does not have an ingress/route or an endpoint
+	}
+
+	if component.Configuration.Replicas != nil {
+		genOptions.Replicas = *component.Configuration.Replicas
+	}
+
+	if !reflect.DeepEqual(kubernetesResources, devfileParser.KubernetesResources{}) {
+		genOptions.KubernetesResources.Routes = append(genOptions.KubernetesResources.Routes, kubernetesResources.Routes...)
+		genOptions.KubernetesResources.Ingresses = append(genOptions.KubernetesResources.Ingresses, kubernetesResources.Ingresses...)
+	}
+
+	if isKubernetesCluster && len(genOptions.KubernetesResources.Ingresses) == 0 {
+		// provide the hostname for the component if there are no ingresses
+		// Gitops Generator Library will create the Ingress with the hostname
+		genOptions.Route = hostname
+	}
+
+	// The autoscaling of the Component is overridden by the Environment, and then by the SnapshotEnvironmentBinding
+	environmentAutoscaling, err := devfile.GetAutoscalingFromAnnotations(environment.Annotations)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the autoscaling of the Environment %s %v", environmentName, bindingKey))
+		return nil, err
+	}
+	bindingAutoscaling, err := devfile.GetAutoscalingFromAnnotations(binding.Annotations)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the autoscaling of the binding %v", bindingKey))
+		return nil, err
+	}
+
+	// The policies of the Component default to those of the Environment, and its NetworkPolicy allows ingress from the other
+	// Components of the binding
+	environmentPolicies, err := devfile.GetPoliciesFromAnnotations(environment.Annotations)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the policies of the Environment %s %v", environmentName, bindingKey))
+		return nil, err
+	}
+	componentPolicies, err := devfile.GetPoliciesFromDevfile(compDevfileData, deployAssociatedComponents)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the policies of the Component %s %v", componentName, bindingKey))
+		return nil, err
+	}
+	var applicationComponents []string
+	for _, bindingComponent := range binding.Spec.Components {
+		applicationComponents = append(applicationComponents, bindingComponent.Name)
+	}
+
+	// The Secrets referenced by the Component come from the secret source of the Environment, overridden by the binding
+	environmentSecretSource, err := gitops.GetSecretSourceFromAnnotations(environment.Annotations)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the secret source of the Environment %s %v", environmentName, bindingKey))
+		return nil, err
+	}
+	bindingSecretSource, err := gitops.GetSecretSourceFromAnnotations(binding.Annotations)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the secret source of the binding %v", bindingKey))
+		return nil, err
+	}
+	secretSource := environmentSecretSource.Merge(bindingSecretSource)
+	var sealedSecrets map[string]map[string]interface{}
+	if secretSource.SealedSecretsConfigMap != "" {
+		var sealedSecretsConfigMap corev1.ConfigMap
+		if err := c.Get(ctx, types.NamespacedName{Namespace: binding.Namespace, Name: secretSource.SealedSecretsConfigMap}, &sealedSecretsConfigMap); err != nil {
+			log.Error(err, fmt.Sprintf("unable to get the SealedSecrets ConfigMap %s %v", secretSource.SealedSecretsConfigMap, bindingKey))
+			return nil, err
+		}
+		sealedSecrets, err = gitops.ParseSealedSecrets(sealedSecretsConfigMap.Data)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("unable to parse the SealedSecrets ConfigMap %s %v", secretSource.SealedSecretsConfigMap, bindingKey))
+			return nil, err
+		}
+	}
+
+	overlay, err := gitops.GetOverlayOptions(kubernetesResources, genOptions, gitops.OverlayConfiguration{
+		Autoscaling:           environmentAutoscaling.Merge(bindingAutoscaling),
+		Policies:              environmentPolicies.Merge(componentPolicies),
+		ApplicationComponents: applicationComponents,
+		SecretSource:          secretSource,
+		SealedSecrets:         sealedSecrets,
+	})
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to get the overlay options of the Component %s %v", componentName, bindingKey))
+		return nil, err
+	}
+
+	return &bindingComponentGitOps{
+		component:           &hasComponent,
+		options:             genOptions,
+		overlay:             overlay,
+		imageName:           imageName,
+		routeName:           routeName,
+		commit:              commitComponent,
+		remote:              gitOpsRemoteURL,
+		branch:              gitOpsBranch,
+		context:             gitOpsContext,
+		gitProvider:         gitProvider,
+		isKubernetesCluster: isKubernetesCluster,
+	}, nil
+}
+
+// hashBindingGitOpsResources returns the hash of the GitOps overlays of the given Components in the given Environment, rendered in the
+// given format. The overlays patch the Components' base resources, so the hash covers the hash of their base resources too, or their
+// commit if it isn't known.
+func hashBindingGitOpsResources(bindingComponents []bindingComponentGitOps, environmentName string, format gitops.OutputFormat) (string, error) {
+	target := []string{environmentName}
+	for _, bindingComponent := range bindingComponents {
+		base := bindingComponent.component.Status.GitOps.CommitID
+		if hash := bindingComponent.component.GetAnnotations()[gitOpsResourcesHashAnnotation]; hash != "" {
+			base = hash
+		}
+		target = append(target, bindingComponent.component.Status.GitOps.RepositoryURL, bindingComponent.branch, bindingComponent.context,
+			bindingComponent.options.Name, bindingComponent.imageName, base)
+	}
+	return hashGitOpsResources(target, renderedGitOpsFolder, func(fs afero.Afero, gitOpsFolder string) error {
+		for _, bindingComponent := range bindingComponents {
+			overlaysFolder := filepath.Join(gitOpsFolder, "components", bindingComponent.options.Name, "overlays", environmentName)
+			if err := gitops.RenderOverlays(fs, format, gitOpsFolder, overlaysFolder, bindingComponent.options, bindingComponent.overlay, bindingComponent.imageName, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // isKubernetesCluster checks if its either a Kubernetes or an OpenShift cluster
@@ -482,7 +826,7 @@ func isKubernetesCluster(environment appstudiov1alpha1.Environment) bool {
 func (r *SnapshotEnvironmentBindingReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
 	log := ctrl.LoggerFrom(ctx).WithName("controllers").WithName("Environment")
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&appstudiov1alpha1.SnapshotEnvironmentBinding{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		For(&appstudiov1alpha1.SnapshotEnvironmentBinding{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, gitOpsDryRunChangedPredicate, gitOpsAnnotationsChangedPredicate))).
 		// Watch for Environment CR updates and reconcile all the Bindings that reference the Environment
 		Watches(&source.Kind{Type: &appstudiov1alpha1.Environment{}},
 			handler.EnqueueRequestsFromMapFunc(MapToBindingByBoundObjectName(r.Client, "Environment", "appstudio.environment")), builder.WithPredicates(predicate.Funcs{
@@ -504,6 +848,24 @@ func (r *SnapshotEnvironmentBindingReconciler) SetupWithManager(ctx context.Cont
 				GenericFunc: func(e event.GenericEvent) bool {
 					return false
 				},
+			})).
+		// Watch for SealedSecrets ConfigMap updates and reconcile all the Bindings whose sealed Secrets come from the ConfigMap
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(MapSealedSecretsConfigMapToBindings(r.Client)), builder.WithPredicates(predicate.Funcs{
+				CreateFunc: func(e event.CreateEvent) bool {
+					return true
+				},
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					oldConfigMap, oldOk := e.ObjectOld.(*corev1.ConfigMap)
+					newConfigMap, newOk := e.ObjectNew.(*corev1.ConfigMap)
+					return oldOk && newOk && !reflect.DeepEqual(oldConfigMap.Data, newConfigMap.Data)
+				},
+				DeleteFunc: func(e event.DeleteEvent) bool {
+					return false
+				},
+				GenericFunc: func(e event.GenericEvent) bool {
+					return false
+				},
 			})).WithEventFilter(predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			log := log.WithValues("namespace", e.Object.GetNamespace())
@@ -18,6 +18,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	logutil "github.com/redhat-appstudio/application-service/pkg/log"
@@ -40,21 +41,34 @@ func (r *SnapshotEnvironmentBindingReconciler) SetConditionAndUpdateCR(ctx conte
 	condition := metav1.Condition{}
 	if createError == nil {
 		condition = metav1.Condition{
-			Type:    "GitOpsResourcesGenerated",
-			Status:  metav1.ConditionTrue,
-			Reason:  "OK",
-			Message: "GitOps repository sync successful",
+			Type:               "GitOpsResourcesGenerated",
+			Status:             metav1.ConditionTrue,
+			Reason:             "OK",
+			Message:            "GitOps repository sync successful",
+			ObservedGeneration: appSnapshotEnvBinding.Generation,
 		}
+		// In pull request mode, the GitOps resources aren't generated until their pull request is merged
+		if pendingCondition := pendingGitOpsGeneratedCondition(appSnapshotEnvBinding.Status.GitOpsRepoConditions); pendingCondition != nil {
+			condition = *pendingCondition
+			condition.ObservedGeneration = appSnapshotEnvBinding.Generation
+		}
+		clearRateLimitedCondition(&currentSEB.Status.GitOpsRepoConditions)
+		copyGitOpsPullRequestCondition(appSnapshotEnvBinding.Status.GitOpsRepoConditions, &currentSEB.Status.GitOpsRepoConditions)
+		copyGitOpsDryRunCondition(appSnapshotEnvBinding.Status.GitOpsRepoConditions, &currentSEB.Status.GitOpsRepoConditions)
 	} else {
 		condition = metav1.Condition{
-			Type:    "GitOpsResourcesGenerated",
-			Status:  metav1.ConditionFalse,
-			Reason:  "GenerateError",
-			Message: fmt.Sprintf("GitOps repository sync failed: %v", createError),
+			Type:               "GitOpsResourcesGenerated",
+			Status:             metav1.ConditionFalse,
+			Reason:             gitOpsGeneratedErrorReason(createError),
+			Message:            fmt.Sprintf("GitOps repository sync failed: %v", createError),
+			ObservedGeneration: appSnapshotEnvBinding.Generation,
 		}
 
 	}
-	meta.SetStatusCondition(&currentSEB.Status.GitOpsRepoConditions, condition)
+	// A dry run leaves the GitOps resources as they were, so it only records the GitOpsDryRun condition
+	if createError != nil || meta.FindStatusCondition(appSnapshotEnvBinding.Status.GitOpsRepoConditions, gitOpsDryRunConditionType) == nil {
+		meta.SetStatusCondition(&currentSEB.Status.GitOpsRepoConditions, condition)
+	}
 	logutil.LogAPIResourceChangeEvent(log, currentSEB.Name, "SnapshotEnvironmentBinding", logutil.ResourceCreate, createError)
 	currentSEB.Status.Components = appSnapshotEnvBinding.Status.Components
 
@@ -64,3 +78,22 @@ func (r *SnapshotEnvironmentBindingReconciler) SetConditionAndUpdateCR(ctx conte
 
 	}
 }
+
+// SetRateLimitedConditionAndUpdateCR sets the RateLimited condition on the SnapshotEnvironmentBinding, when its reconcile has been delayed by GitHub rate limits
+func (r *SnapshotEnvironmentBindingReconciler) SetRateLimitedConditionAndUpdateCR(ctx context.Context, req ctrl.Request, rateLimitErr error, requeueAfter time.Duration) {
+	log := r.Log.WithValues("namespace", req.NamespacedName.Namespace)
+
+	var currentSEB appstudiov1alpha1.SnapshotEnvironmentBinding
+	err := r.Get(ctx, req.NamespacedName, &currentSEB)
+	if err != nil {
+		return
+	}
+
+	patch := client.MergeFrom(currentSEB.DeepCopy())
+	setRateLimitedCondition(&currentSEB.Status.GitOpsRepoConditions, rateLimitErr, requeueAfter)
+
+	err = r.Client.Status().Patch(ctx, &currentSEB, patch)
+	if err != nil {
+		log.Error(err, "Unable to update application snapshot environment binding")
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitlab"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ArchivedRepositoriesConfigMapName is the name of the ConfigMap, in the operator's namespace, that archived GitOps repositories are tracked in
+	ArchivedRepositoriesConfigMapName = "archived-gitops-repositories"
+
+	// DefaultArchivedRepositorySweepInterval is how often the ArchivedRepositorySweeper checks for expired repositories, if no interval is set
+	DefaultArchivedRepositorySweepInterval = time.Hour
+
+	archivedRepositorySweeperName = "ArchivedRepositorySweeper"
+)
+
+// ArchivedRepository is a GitOps repository that was archived or retained, rather than deleted, when its Application was deleted
+type ArchivedRepository struct {
+	// Provider is the Git provider the repository is hosted on, github or gitlab
+	Provider string `json:"provider"`
+
+	// Org is the org (or group) of the repository
+	Org string `json:"org"`
+
+	// Repo is the name of the repository
+	Repo string `json:"repo"`
+
+	// URL is the URL of the repository
+	URL string `json:"url"`
+
+	// Application is the namespaced name of the deleted Application that the repository belonged to
+	Application string `json:"application"`
+
+	// ArchivedAt is when the repository was archived, or retained
+	ArchivedAt metav1.Time `json:"archivedAt"`
+
+	// Retained is true if the repository was left as is, rather than archived
+	Retained bool `json:"retained,omitempty"`
+
+	// DeleteAfter is when the repository can be deleted. The repository is kept indefinitely if it's unset
+	DeleteAfter *metav1.Time `json:"deleteAfter,omitempty"`
+}
+
+// IsExpired returns true if the repository's grace period has expired, and it can be deleted
+func (a ArchivedRepository) IsExpired(now time.Time) bool {
+	return a.DeleteAfter != nil && !now.Before(a.DeleteAfter.Time)
+}
+
+// key returns the key of the repository in the tracking ConfigMap. ConfigMap keys can't contain slashes, which GitLab subgroups do
+func (a ArchivedRepository) key() string {
+	return strings.ReplaceAll(a.Provider+"."+a.Org+"."+a.Repo, "/", ".")
+}
+
+// ArchivedRepositoryTracker records archived GitOps repositories in a ConfigMap, so that they can be deleted once their grace period expires
+type ArchivedRepositoryTracker struct {
+	Client client.Client
+
+	// Namespace is the namespace of the tracking ConfigMap, which is the operator's namespace
+	Namespace string
+}
+
+// Add records the given archived repository, replacing any existing record of it
+func (t *ArchivedRepositoryTracker) Add(ctx context.Context, repo ArchivedRepository) error {
+	value, err := json.Marshal(repo)
+	if err != nil {
+		return err
+	}
+	return t.update(ctx, func(data map[string]string) {
+		data[repo.key()] = string(value)
+	})
+}
+
+// Remove removes the record of the given archived repository, if there is one
+func (t *ArchivedRepositoryTracker) Remove(ctx context.Context, repo ArchivedRepository) error {
+	return t.update(ctx, func(data map[string]string) {
+		delete(data, repo.key())
+	})
+}
+
+// List returns the archived repositories, ordered by when they can be deleted
+func (t *ArchivedRepositoryTracker) List(ctx context.Context) ([]ArchivedRepository, error) {
+	var configMap corev1.ConfigMap
+	err := t.Client.Get(ctx, types.NamespacedName{Namespace: t.Namespace, Name: ArchivedRepositoriesConfigMapName}, &configMap)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	repos := make([]ArchivedRepository, 0, len(configMap.Data))
+	for key, value := range configMap.Data {
+		var repo ArchivedRepository
+		if err := json.Unmarshal([]byte(value), &repo); err != nil {
+			return nil, fmt.Errorf("unable to parse archived repository %s: %v", key, err)
+		}
+		repos = append(repos, repo)
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		if repos[i].DeleteAfter == nil || repos[j].DeleteAfter == nil {
+			return repos[j].DeleteAfter == nil && repos[i].DeleteAfter != nil
+		}
+		return repos[i].DeleteAfter.Before(repos[j].DeleteAfter)
+	})
+	return repos, nil
+}
+
+// update applies mutate to the data of the tracking ConfigMap, creating the ConfigMap if it doesn't exist yet.
+// Applications are finalized concurrently, so the update is retried on conflicts.
+func (t *ArchivedRepositoryTracker) update(ctx context.Context, mutate func(data map[string]string)) error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return k8sErrors.IsConflict(err) || k8sErrors.IsAlreadyExists(err)
+	}, func() error {
+		var configMap corev1.ConfigMap
+		err := t.Client.Get(ctx, types.NamespacedName{Namespace: t.Namespace, Name: ArchivedRepositoriesConfigMapName}, &configMap)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		exists := err == nil
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		mutate(configMap.Data)
+		if exists {
+			return t.Client.Update(ctx, &configMap)
+		}
+		configMap.ObjectMeta = metav1.ObjectMeta{Namespace: t.Namespace, Name: ArchivedRepositoriesConfigMapName}
+		return t.Client.Create(ctx, &configMap)
+	})
+}
+
+// ArchivedRepositorySweeper periodically deletes the archived GitOps repositories whose grace period has expired.
+// It implements controller-runtime's manager.Runnable, and only runs on the leader.
+type ArchivedRepositorySweeper struct {
+	Tracker           *ArchivedRepositoryTracker
+	GitHubTokenClient github.GitHubToken
+
+	// GitLabClient is used to delete repositories hosted on GitLab. It is nil if GitLab isn't configured
+	GitLabClient gitprovider.GitProvider
+
+	// Interval is how often expired repositories are deleted. Defaults to DefaultArchivedRepositorySweepInterval
+	Interval time.Duration
+}
+
+// Start deletes the expired repositories every interval, until the context is cancelled
+func (s *ArchivedRepositorySweeper) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("archived-repository-sweeper")
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultArchivedRepositorySweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			deleted, err := s.sweep(ctx, time.Now())
+			if len(deleted) > 0 {
+				log.Info(fmt.Sprintf("Deleted %v archived GitOps repositories whose grace period expired", len(deleted)), "repositories", deleted)
+			}
+			if err != nil {
+				// The remaining repositories are retried on the next tick
+				log.Error(err, "unable to delete all of the expired archived GitOps repositories")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns true, so that only one replica deletes repositories
+func (s *ArchivedRepositorySweeper) NeedLeaderElection() bool {
+	return true
+}
+
+// sweep deletes the repositories whose grace period has expired by now, and stops tracking them. Repositories that no longer
+// exist are no longer tracked either. It returns the URLs of the deleted repositories.
+func (s *ArchivedRepositorySweeper) sweep(ctx context.Context, now time.Time) ([]string, error) {
+	repos, err := s.Tracker.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	var errs []string
+	for _, repo := range repos {
+		if !repo.IsExpired(now) {
+			// The repositories are ordered by when they can be deleted, so none of the remaining ones have expired
+			break
+		}
+		if err := s.deleteRepository(ctx, repo); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", repo.URL, err))
+			continue
+		}
+		if err := s.Tracker.Remove(ctx, repo); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", repo.URL, err))
+			continue
+		}
+		deleted = append(deleted, repo.URL)
+	}
+	if len(errs) > 0 {
+		return deleted, fmt.Errorf("unable to delete archived repositories: %s", strings.Join(errs, "; "))
+	}
+	return deleted, nil
+}
+
+// deleteRepository deletes the given archived repository. It succeeds if the repository has already been deleted
+func (s *ArchivedRepositorySweeper) deleteRepository(ctx context.Context, repo ArchivedRepository) error {
+	var gitProvider gitprovider.GitProvider
+	if repo.Provider == gitprovider.GitLab {
+		if s.GitLabClient == nil {
+			return fmt.Errorf("the repository is hosted on GitLab, but no GitLab token is configured")
+		}
+		gitProvider = s.GitLabClient
+	} else {
+		ghClient, err := s.GitHubTokenClient.GetNewGitHubClient("")
+		if err != nil {
+			return err
+		}
+		gitProvider = ghClient
+	}
+	ctx = context.WithValue(ctx, github.GHClientKey, gitProvider.GetTokenName())
+
+	metricsLabel := prometheus.Labels{"controller": archivedRepositorySweeperName, "tokenName": gitProvider.GetTokenName(), "operation": "DeleteRepository"}
+	metrics.ControllerGitRequest.With(metricsLabel).Inc()
+	err := gitProvider.DeleteRepository(ctx, repo.Org, repo.Repo)
+	metrics.HandleRateLimitMetrics(err, metricsLabel)
+	if err != nil && !github.IsNotFound(err) && !gitlab.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
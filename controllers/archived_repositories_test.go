@@ -0,0 +1,286 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitlab"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeGitProvider is a GitProvider that lists the given repositories, and records the repositories it deletes and archives.
+// It returns the given pull request, if any, for every pull request that's opened or retrieved
+type fakeGitProvider struct {
+	gitprovider.GitProvider
+	repos        []gitprovider.Repository
+	deleted      []string
+	archived     []string
+	pullRequest  *gitprovider.PullRequest
+	latestCommit string
+	reset        []string
+	generated    []string
+	pending      bool
+	err          error
+}
+
+func (f *fakeGitProvider) GenerateNewRepository(ctx context.Context, orgName string, repoName string, description string, opts gitprovider.RepositoryOptions) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.generated = append(f.generated, orgName+"/"+repoName)
+	repoURL := "https://gitlab.com/" + orgName + "/" + repoName
+	if f.pending {
+		return repoURL, &gitprovider.RepositoryPendingError{RepoURL: repoURL}
+	}
+	return repoURL, nil
+}
+
+func (f *fakeGitProvider) FinishNewRepository(ctx context.Context, orgName string, repoName string, opts gitprovider.RepositoryOptions) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.pending {
+		return &gitprovider.RepositoryPendingError{RepoURL: "https://gitlab.com/" + orgName + "/" + repoName}
+	}
+	return nil
+}
+
+func (f *fakeGitProvider) ListRepositories(ctx context.Context, orgName string) ([]gitprovider.Repository, error) {
+	return f.repos, nil
+}
+
+func (f *fakeGitProvider) DeleteRepository(ctx context.Context, orgName string, repoName string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.deleted = append(f.deleted, orgName+"/"+repoName)
+	return nil
+}
+
+func (f *fakeGitProvider) ArchiveRepository(ctx context.Context, orgName string, repoName string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.archived = append(f.archived, orgName+"/"+repoName)
+	return nil
+}
+
+func (f *fakeGitProvider) CreateOrUpdatePullRequest(ctx context.Context, orgName string, repoName string, opts gitprovider.PullRequestOptions) (*gitprovider.PullRequest, error) {
+	return f.pullRequest, f.err
+}
+
+func (f *fakeGitProvider) GetPullRequest(ctx context.Context, orgName string, repoName string, number int) (*gitprovider.PullRequest, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.pullRequest == nil || f.pullRequest.Number != number {
+		return nil, fmt.Errorf("pull request %d not found", number)
+	}
+	return f.pullRequest, nil
+}
+
+func (f *fakeGitProvider) ResetBranch(ctx context.Context, orgName string, repoName string, branchName string, baseBranch string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.reset = append(f.reset, branchName)
+	return nil
+}
+
+func (f *fakeGitProvider) GetLatestCommitSHAFromRepository(ctx context.Context, repoName string, orgName string, branch string) (string, error) {
+	return f.latestCommit, f.err
+}
+
+func (f *fakeGitProvider) GetRepoNameFromURL(repoURL string, orgName string) (string, error) {
+	return gitlab.NewGitLabClient("", "", "").GetRepoNameFromURL(repoURL, orgName)
+}
+
+func (f *fakeGitProvider) GetRepoAndOrgFromURL(repoURL string) (string, string, error) {
+	return gitlab.GetRepoAndGroupFromURL(repoURL)
+}
+
+func (f *fakeGitProvider) HostsRepository(repoURL string) bool {
+	return strings.HasPrefix(repoURL, "https://gitlab.com/")
+}
+
+func (f *fakeGitProvider) GetRemoteCredentials() *url.Userinfo {
+	return url.UserPassword("oauth2", "fake-token")
+}
+
+func (f *fakeGitProvider) GetTokenName() string {
+	return "fake-gitlab-token"
+}
+
+func newArchivedRepository(provider string, repo string, deleteAfter *time.Time) ArchivedRepository {
+	archived := ArchivedRepository{
+		Provider:    provider,
+		Org:         "appdata-group/gitops",
+		Repo:        repo,
+		URL:         "https://gitlab.com/appdata-group/gitops/" + repo,
+		Application: "default/" + repo,
+		ArchivedAt:  metav1.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	if deleteAfter != nil {
+		t := metav1.NewTime(*deleteAfter)
+		archived.DeleteAfter = &t
+	}
+	return archived
+}
+
+func archivedRepositoryURLs(repos []ArchivedRepository) []string {
+	var urls []string
+	for _, repo := range repos {
+		urls = append(urls, repo.URL)
+	}
+	return urls
+}
+
+func TestArchivedRepositoryTracker(t *testing.T) {
+	ctx := context.Background()
+	tracker := &ArchivedRepositoryTracker{Client: fake.NewClientBuilder().Build(), Namespace: "application-service"}
+
+	repos, err := tracker.List(ctx)
+	if err != nil || len(repos) != 0 {
+		t.Fatalf("TestArchivedRepositoryTracker() error: expected no repositories got %v, %v", repos, err)
+	}
+
+	early := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	kept := newArchivedRepository(gitprovider.GitLab, "kept-repo", nil)
+	deletedLate := newArchivedRepository(gitprovider.GitLab, "late-repo", &late)
+	deletedEarly := newArchivedRepository(gitprovider.GitLab, "early-repo", &early)
+	for _, repo := range []ArchivedRepository{kept, deletedLate, deletedEarly} {
+		if err := tracker.Add(ctx, repo); err != nil {
+			t.Fatalf("TestArchivedRepositoryTracker() unexpected error adding %v: %v", repo.URL, err)
+		}
+	}
+
+	repos, err = tracker.List(ctx)
+	if err != nil {
+		t.Fatalf("TestArchivedRepositoryTracker() unexpected error: %v", err)
+	}
+	want := []string{deletedEarly.URL, deletedLate.URL, kept.URL}
+	if !reflect.DeepEqual(archivedRepositoryURLs(repos), want) {
+		t.Errorf("TestArchivedRepositoryTracker() error: expected %v got %v", want, archivedRepositoryURLs(repos))
+	}
+
+	if err := tracker.Remove(ctx, deletedEarly); err != nil {
+		t.Fatalf("TestArchivedRepositoryTracker() unexpected error: %v", err)
+	}
+	repos, err = tracker.List(ctx)
+	if err != nil {
+		t.Fatalf("TestArchivedRepositoryTracker() unexpected error: %v", err)
+	}
+	want = []string{deletedLate.URL, kept.URL}
+	if !reflect.DeepEqual(archivedRepositoryURLs(repos), want) {
+		t.Errorf("TestArchivedRepositoryTracker() error: expected %v got %v", want, archivedRepositoryURLs(repos))
+	}
+}
+
+func TestArchivedRepositorySweeper(t *testing.T) {
+	now := time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	notExpired := now.Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		repos        []ArchivedRepository
+		gitLabClient *fakeGitProvider
+		wantDeleted  []string
+		wantTracked  int
+		wantErr      bool
+	}{
+		{
+			name: "Only expired repositories are deleted",
+			repos: []ArchivedRepository{
+				newArchivedRepository(gitprovider.GitLab, "expired-repo", &expired),
+				newArchivedRepository(gitprovider.GitLab, "recent-repo", &notExpired),
+				newArchivedRepository(gitprovider.GitLab, "kept-repo", nil),
+			},
+			gitLabClient: &fakeGitProvider{},
+			wantDeleted:  []string{"appdata-group/gitops/expired-repo"},
+			wantTracked:  2,
+		},
+		{
+			name: "Repository that was already deleted",
+			repos: []ArchivedRepository{
+				newArchivedRepository(gitprovider.GitLab, "expired-repo", &expired),
+			},
+			gitLabClient: &fakeGitProvider{err: &gitlab.APIError{StatusCode: 404}},
+			wantTracked:  0,
+		},
+		{
+			name: "Repository hosted on GitHub",
+			repos: []ArchivedRepository{
+				newArchivedRepository(gitprovider.GitHub, "expired-repo", &expired),
+			},
+			gitLabClient: &fakeGitProvider{},
+			wantTracked:  0,
+		},
+		{
+			name: "Error deleting a repository, it's still tracked",
+			repos: []ArchivedRepository{
+				newArchivedRepository(gitprovider.GitLab, "expired-repo", &expired),
+			},
+			gitLabClient: &fakeGitProvider{err: fmt.Errorf("gitlab went belly up")},
+			wantTracked:  1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tracker := &ArchivedRepositoryTracker{Client: fake.NewClientBuilder().Build(), Namespace: "application-service"}
+			for _, repo := range tt.repos {
+				if err := tracker.Add(ctx, repo); err != nil {
+					t.Fatalf("TestArchivedRepositorySweeper() unexpected error: %v", err)
+				}
+			}
+
+			sweeper := &ArchivedRepositorySweeper{
+				Tracker:           tracker,
+				GitHubTokenClient: github.MockGitHubTokenClient{},
+				GitLabClient:      tt.gitLabClient,
+			}
+			_, err := sweeper.sweep(ctx, now)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestArchivedRepositorySweeper() unexpected error value: %v", err)
+			}
+			if !reflect.DeepEqual(tt.gitLabClient.deleted, tt.wantDeleted) {
+				t.Errorf("TestArchivedRepositorySweeper() error: expected deleted repositories %v got %v", tt.wantDeleted, tt.gitLabClient.deleted)
+			}
+			repos, err := tracker.List(ctx)
+			if err != nil {
+				t.Fatalf("TestArchivedRepositorySweeper() unexpected error: %v", err)
+			}
+			if len(repos) != tt.wantTracked {
+				t.Errorf("TestArchivedRepositorySweeper() error: expected %v tracked repositories got %v", tt.wantTracked, len(repos))
+			}
+		})
+	}
+}
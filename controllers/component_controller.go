@@ -31,6 +31,7 @@ import (
 	cdqanalysis "github.com/redhat-appstudio/application-service/cdq-analysis/pkg"
 	"github.com/redhat-appstudio/application-service/pkg/metrics"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -52,12 +53,15 @@ import (
 	"github.com/go-logr/logr"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
 	devfile "github.com/redhat-appstudio/application-service/pkg/devfile"
 	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	logutil "github.com/redhat-appstudio/application-service/pkg/log"
 	"github.com/redhat-appstudio/application-service/pkg/spi"
 	"github.com/redhat-appstudio/application-service/pkg/util"
 	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsgenv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
 	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
 	"github.com/spf13/afero"
 )
@@ -72,6 +76,21 @@ type ComponentReconciler struct {
 	AppFS             afero.Afero
 	SPIClient         spi.SPI
 	GitHubTokenClient github.GitHubToken
+
+	// GitLabClient, if set, is the client of the GitLab instance that GitOps repositories may be hosted on
+	GitLabClient gitprovider.GitProvider
+
+	// GitOpsWriteQueue, if set, coalesces the GitOps writes of the Components of an Application into a single commit
+	GitOpsWriteQueue *GitOpsWriteQueue
+
+	// MaxConcurrentReconciles is the number of Components reconciled concurrently. Defaults to 1
+	MaxConcurrentReconciles int
+
+	// CommitMessages renders the messages of the commits pushed to GitOps repositories. Defaults to the default templates
+	CommitMessages *gitops.CommitMessages
+
+	// PostProcessors post-process the Kubernetes resources of the Components before their GitOps resources are generated
+	PostProcessors gitops.PostProcessors
 }
 
 const (
@@ -134,6 +153,11 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	ghClient, err := r.GitHubTokenClient.GetNewGitHubClient("")
 	if err != nil {
+		if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+			log.Info(fmt.Sprintf("All GitHub tokens are rate limited, requeueing %v in %v", req.NamespacedName, requeueAfter))
+			_ = r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
 		log.Error(err, "Unable to create Go-GitHub client due to error")
 		return reconcile.Result{}, err
 	}
@@ -212,36 +236,55 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	log.Info(fmt.Sprintf("Starting reconcile loop for %v", req.NamespacedName))
 
-	// Check if GitOps generation has failed on a reconcile
+	// If the Component's GitOps resources are awaiting the merge of a pull request, check whether it has been merged (or closed)
+	if isGitOpsPullRequestOpen(component.Status.Conditions) {
+		if err := r.updateGitOpsPullRequestStatus(ctx, req, gitOpsRepositoryProvider(ghClient, r.GitLabClient, component.Status.GitOps.RepositoryURL), &component); err != nil {
+			// Don't fail the reconcile, the pull request is checked again on the next poll
+			log.Error(err, fmt.Sprintf("Unable to check the GitOps pull request of %v", req.NamespacedName))
+		}
+	}
+
+	// Check if GitOps generation has failed on a reconcile, or if its dry run mode has changed since it was last generated
 	// Attempt to generate GitOps and set appropriate conditions accordingly
 	isUpdateConditionPresent := false
 	isGitOpsRegenSuccessful := false
+	isGitOpsRegenNeeded := component.Status.Devfile != "" && !component.Spec.SkipGitOpsResourceGeneration &&
+		isGitOpsDryRunChanged(component.Annotations, component.Status.Conditions)
 	for _, condition := range component.Status.Conditions {
 		if condition.Type == "GitOpsResourcesGenerated" && condition.Reason == "GenerateError" && condition.Status == metav1.ConditionFalse {
-			log.Info(fmt.Sprintf("Re-attempting GitOps generation for %s", component.Name))
-			// Parse the Component Devfile
-			compDevfileData, err := cdqanalysis.ParseDevfileWithParserArgs(&devfileParser.ParserArgs{Data: []byte(component.Status.Devfile), Token: gitToken})
-			if err != nil {
-				errMsg := fmt.Sprintf("Unable to parse the devfile from Component status and re-attempt GitOps generation, exiting reconcile loop %v", req.NamespacedName)
-				log.Error(err, errMsg)
-				_ = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
-				return ctrl.Result{}, err
+			isGitOpsRegenNeeded = true
+		} else if condition.Type == "Updated" && condition.Reason == "Error" && condition.Status == metav1.ConditionFalse {
+			isUpdateConditionPresent = true
+		}
+	}
+	if isGitOpsRegenNeeded {
+		log.Info(fmt.Sprintf("Re-attempting GitOps generation for %s", component.Name))
+		// Parse the Component Devfile
+		compDevfileData, err := cdqanalysis.ParseDevfileWithParserArgs(&devfileParser.ParserArgs{Data: []byte(component.Status.Devfile), Token: gitToken})
+		if err != nil {
+			errMsg := fmt.Sprintf("Unable to parse the devfile from Component status and re-attempt GitOps generation, exiting reconcile loop %v", req.NamespacedName)
+			log.Error(err, errMsg)
+			_ = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
+			return ctrl.Result{}, err
+		}
+		if err := r.generateGitops(ctx, ghClient, &component, compDevfileData); err != nil {
+			errMsg := fmt.Sprintf("Unable to generate gitops resources for component %v", req.NamespacedName)
+			log.Error(err, errMsg)
+			_ = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
+			if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+				// The failed generation is re-attempted once the rate limit resets
+				log.Info(fmt.Sprintf("Rate limited while generating the GitOps resources, requeueing %v in %v", req.NamespacedName, requeueAfter))
+				_ = r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
 			}
-			if err := r.generateGitops(ctx, ghClient, &component, compDevfileData); err != nil {
-				errMsg := fmt.Sprintf("Unable to generate gitops resources for component %v", req.NamespacedName)
-				log.Error(err, errMsg)
-				_ = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
+			return ctrl.Result{}, err
+		} else {
+			log.Info(fmt.Sprintf("GitOps re-generation successful for %s", component.Name))
+			err := r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, nil)
+			if err != nil {
 				return ctrl.Result{}, err
-			} else {
-				log.Info(fmt.Sprintf("GitOps re-generation successful for %s", component.Name))
-				err := r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, nil)
-				if err != nil {
-					return ctrl.Result{}, err
-				}
-				isGitOpsRegenSuccessful = true
 			}
-		} else if condition.Type == "Updated" && condition.Reason == "Error" && condition.Status == metav1.ConditionFalse {
-			isUpdateConditionPresent = true
+			isGitOpsRegenSuccessful = true
 		}
 	}
 
@@ -250,13 +293,13 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if err != nil {
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
+		return gitOpsPullRequestResult(component.Status.Conditions), nil
 	} else if isGitOpsRegenSuccessful {
 		err = r.SetCreateConditionAndUpdateCR(ctx, req, &component, nil)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
+		return gitOpsPullRequestResult(component.Status.Conditions), nil
 	}
 
 	// If the devfile hasn't been populated, the CR was just created
@@ -291,6 +334,11 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 					_, err := ghClient.GetBranchFromURL(sourceURL, ctx, "main")
 					if err != nil {
 						metrics.HandleRateLimitMetrics(err, metricsLabel)
+						if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+							log.Info(fmt.Sprintf("Rate limited while looking up the default branch of %v, requeueing %v in %v", source.GitSource.URL, req.NamespacedName, requeueAfter))
+							_ = r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+							return ctrl.Result{RequeueAfter: requeueAfter}, nil
+						}
 						log.Error(err, fmt.Sprintf("Unable to get main branch of Github Repo %v ... %v", source.GitSource.URL, req.NamespacedName))
 						retErr := fmt.Errorf("unable to get default branch of Github Repo %v, try to fall back to main branch, failed to get main branch... %v", source.GitSource.URL, req.NamespacedName)
 						_ = r.SetCreateConditionAndUpdateCR(ctx, req, &component, retErr)
@@ -460,6 +508,11 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 					log.Error(err, errMsg)
 					_ = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
 					_ = r.SetCreateConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
+					if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+						log.Info(fmt.Sprintf("Rate limited while generating the GitOps resources, requeueing %v in %v", req.NamespacedName, requeueAfter))
+						_ = r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+						return ctrl.Result{RequeueAfter: requeueAfter}, nil
+					}
 					return ctrl.Result{}, err
 				} else {
 					err = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, nil)
@@ -547,6 +600,11 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 					log.Error(err, errMsg)
 					_ = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
 					_ = r.SetUpdateConditionAndUpdateCR(ctx, req, &component, fmt.Errorf("%v: %v", errMsg, err))
+					if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+						log.Info(fmt.Sprintf("Rate limited while generating the GitOps resources, requeueing %v in %v", req.NamespacedName, requeueAfter))
+						_ = r.SetRateLimitedConditionAndUpdateCR(ctx, req, err, requeueAfter)
+						return ctrl.Result{RequeueAfter: requeueAfter}, nil
+					}
 					return ctrl.Result{}, err
 				} else {
 					err = r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, &component, nil)
@@ -566,7 +624,23 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	log.Info(fmt.Sprintf("Finished reconcile loop for %v", req.NamespacedName))
-	return ctrl.Result{}, nil
+	return gitOpsPullRequestResult(component.Status.Conditions), nil
+}
+
+// updateGitOpsPullRequestStatus checks the state of the Component's open GitOps pull request. Once it's merged, the Component's GitOps
+// resources are marked as generated, at the pull request's merge commit.
+func (r *ComponentReconciler) updateGitOpsPullRequestStatus(ctx context.Context, req ctrl.Request, gitProvider gitprovider.GitProvider, component *appstudiov1alpha1.Component) error {
+	pullRequest, err := refreshGitOpsPullRequest(ctx, componentName, gitProvider, component.Status.GitOps.RepositoryURL, &component.Status.Conditions)
+	if err != nil {
+		return err
+	}
+	if pullRequest.State == gitprovider.PullRequestStateOpen {
+		return nil
+	}
+	if pullRequest.State == gitprovider.PullRequestStateMerged {
+		component.Status.GitOps.CommitID = pullRequest.MergeCommitSHA
+	}
+	return r.SetGitOpsGeneratedConditionAndUpdateCR(ctx, req, component, nil)
 }
 
 // generateGitops retrieves the necessary information about a Component's gitops repository (URL, branch, context)
@@ -574,97 +648,206 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 func (r *ComponentReconciler) generateGitops(ctx context.Context, ghClient *github.GitHubClient, component *appstudiov1alpha1.Component, compDevfileData data.DevfileData) error {
 	log := ctrl.LoggerFrom(ctx)
 
-	gitOpsURL, gitOpsBranch, gitOpsContext, err := util.ProcessGitOpsStatus(component.Status.GitOps, ghClient.Token)
+	gitProvider := gitOpsRepositoryProvider(ghClient, r.GitLabClient, component.Status.GitOps.RepositoryURL)
+	gitOpsURL, gitOpsBranch, gitOpsContext, err := util.ProcessGitOpsStatus(component.Status.GitOps, gitProvider.GetRemoteCredentials())
 	if err != nil {
 		return err
 	}
 
-	// Create a temp folder to create the gitops resources in
-	tempDir, err := ioutils.CreateTempPath(component.Name, r.AppFS)
+	mappedGitOpsComponent, err := gitops.ComponentGeneratorOptions(log, *component, compDevfileData, r.PostProcessors)
 	if err != nil {
-		log.Error(err, "unable to create temp directory for GitOps resources due to error")
-		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-		return fmt.Errorf("unable to create temp directory for GitOps resources due to error: %v", err)
+		return err
 	}
-
-	deployAssociatedComponents, err := devfileParser.GetDeployComponents(compDevfileData)
+	outputFormat, err := getGitOpsOutputFormat(ctx, r.Client, component.Namespace, component.Spec.Application)
 	if err != nil {
-		log.Error(err, "unable to get deploy components")
-		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
 		return err
 	}
+	baseFolder := filepath.Join("components", component.Name, "base")
+
+	// In a dry run, the gitops resources are stored in a ConfigMap rather than pushed
+	if dryRunMode := gitops.GetDryRunMode(component.Annotations); dryRunMode != "" {
+		dryRun := gitOpsDryRun{client: r.Client, scheme: r.Scheme, appFS: r.AppFS, generator: r.Generator, controllerName: componentName}
+		condition, err := dryRun.run(ctx, gitProvider, component, component.Name+"-component-gitops-dry-run", dryRunMode, gitOpsRender{
+			remote:   gitOpsURL,
+			branch:   gitOpsBranch,
+			context:  gitOpsContext,
+			compared: []string{baseFolder},
+			render: func(fs afero.Afero, gitOpsFolder string) error {
+				return outputFormat.Generate(fs, gitOpsFolder, filepath.Join(gitOpsFolder, baseFolder), mappedGitOpsComponent)
+			},
+		})
+		if err != nil {
+			// A failed dry run leaves the gitops resources as they were, and is retried on the next reconcile
+			log.Error(err, "unable to dry run the gitops resources")
+			condition = gitOpsDryRunFailedCondition(err, component.Generation)
+		}
+		meta.SetStatusCondition(&component.Status.Conditions, condition)
+		return nil
+	}
+	meta.RemoveStatusCondition(&component.Status.Conditions, gitOpsDryRunConditionType)
 
-	kubernetesResources, err := devfile.GetResourceFromDevfile(log, compDevfileData, deployAssociatedComponents, component.Name, component.Spec.Application, component.Spec.ContainerImage, "")
+	// In pull request mode, the gitops resources are pushed to a work branch, and a pull request is opened against the GitOps branch
+	pullRequestMode, err := isPullRequestModeEnabled(ctx, r.Client, component.Namespace, component.Spec.Application)
 	if err != nil {
-		log.Error(err, "unable to get kubernetes resources from the devfile outerloop components")
-		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
+		return fmt.Errorf("unable to get the Application %s: %v", component.Spec.Application, err)
+	}
+	pushBranch := gitOpsBranch
+	if pullRequestMode {
+		pushBranch = gitprovider.PullRequestBranchName(componentName, component.Name)
+		// Start a new pull request from the GitOps branch, rather than from the work branch of the previous one
+		if !isGitOpsPullRequestOpen(component.Status.Conditions) {
+			if err := resetGitOpsWorkBranch(ctx, componentName, gitProvider, component.Status.GitOps.RepositoryURL, pushBranch, gitOpsBranch); err != nil {
+				log.Error(err, "unable to reset the GitOps work branch")
+				return err
+			}
+		}
+	}
+
+	// Skip the clone and push if the rendered gitops resources haven't changed since they were last pushed, and only refresh the commit ID
+	var resourcesHash string
+	if !pullRequestMode {
+		resourcesHash, err = hashGitOpsResources([]string{component.Status.GitOps.RepositoryURL, gitOpsBranch, gitOpsContext},
+			filepath.Join(renderedGitOpsFolder, baseFolder), func(fs afero.Afero, gitOpsFolder string) error {
+				return outputFormat.Generate(fs, gitOpsFolder, filepath.Join(gitOpsFolder, baseFolder), mappedGitOpsComponent)
+			})
+		if err != nil {
+			log.Error(err, "unable to hash the gitops resources, pushing them")
+		}
+		if isGitOpsResourcesUnchanged(component, component.Status.Conditions, resourcesHash) {
+			log.Info("GitOps resources are unchanged, skipping the push")
+			commitID, err := latestGitOpsCommitID(ctx, componentName, gitProvider, component.Status.GitOps.RepositoryURL, gitOpsBranch)
+			if err != nil {
+				log.Error(err, "unable to refresh the GitOps commit ID")
+				return err
+			}
+			component.Status.GitOps.CommitID = commitID
+			return nil
+		}
+	}
+
+	var commitID string
+	if r.GitOpsWriteQueue != nil {
+		// Coalesce the write with those of the other Components of the Application
+		commitID, err = r.GitOpsWriteQueue.Write(ctx, gitOpsWrite{
+			repositoryURL: component.Status.GitOps.RepositoryURL,
+			remote:        gitOpsURL,
+			branch:        pushBranch,
+			context:       gitOpsContext,
+			tokenName:     gitProvider.GetTokenName(),
+			namespace:     component.Namespace,
+			component:     mappedGitOpsComponent,
+			format:        outputFormat,
+			commit:        gitOpsCommitComponent(component),
+			generation:    component.Generation,
+		})
+		if err != nil {
+			return handleGitOpsPushError(log, err, component.Status.GitOps.RepositoryURL, "generate and push")
+		}
+	} else if commitID, err = r.cloneGenerateAndPush(ctx, gitProvider, component, mappedGitOpsComponent, outputFormat, gitOpsURL, pushBranch, gitOpsContext); err != nil {
 		return err
 	}
 
-	// Generate and push the gitops resources
-	mappedGitOpsComponent := util.GetMappedGitOpsComponent(*component, kubernetesResources)
+	if pullRequestMode {
+		pullRequest, baseCommitID, err := syncGitOpsPullRequest(ctx, componentName, gitProvider, component.Status.GitOps.RepositoryURL, gitprovider.PullRequestOptions{
+			Title:      fmt.Sprintf("Update GitOps resources of Component %s", component.Name),
+			Body:       fmt.Sprintf("GitOps resources generated by application-service for Component %s of Application %s in namespace %s.", component.Name, component.Spec.Application, component.Namespace),
+			HeadBranch: pushBranch,
+			BaseBranch: gitOpsBranch,
+		})
+		if err != nil {
+			log.Error(err, "unable to open the GitOps pull request")
+			return err
+		}
+		recordGitOpsPullRequest(&component.Status.Conditions, pullRequest, component.Generation)
+		// The commit ID is only updated once the changes are merged into the GitOps branch
+		if pullRequest == nil {
+			component.Status.GitOps.CommitID = baseCommitID
+		}
+	} else {
+		meta.RemoveStatusCondition(&component.Status.Conditions, gitOpsPullRequestConditionType)
+		component.Status.GitOps.CommitID = commitID
+	}
+	// The hash isn't known in pull request mode, so it's removed. Failing to record it only means that unchanged resources are pushed again.
+	if err := recordGitOpsResourcesHash(ctx, r.Client, component, resourcesHash); err != nil {
+		log.Error(err, "unable to record the hash of the gitops resources")
+	}
+	return nil
+}
+
+// cloneGenerateAndPush clones the GitOps repository into a temp folder, generates the Component's gitops resources in it, and pushes
+// them to the given branch in their own commit. It returns the ID of the commit.
+func (r *ComponentReconciler) cloneGenerateAndPush(ctx context.Context, gitProvider gitprovider.GitProvider, component *appstudiov1alpha1.Component, mappedGitOpsComponent gitopsgenv1alpha1.GeneratorOptions, outputFormat gitops.OutputFormat, gitOpsURL string, gitOpsBranch string, gitOpsContext string) (string, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// Create a temp folder to create the gitops resources in
+	tempDir, err := ioutils.CreateTempPath(component.Name, r.AppFS)
+	if err != nil {
+		log.Error(err, "unable to create temp directory for GitOps resources due to error")
+		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
+		return "", fmt.Errorf("unable to create temp directory for GitOps resources due to error: %v", err)
+	}
 
 	//add the token name to the metrics.  When we add more tokens and rotate, we can determine how evenly distributed the requests are
-	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": componentName, "tokenName": ghClient.TokenName, "operation": "CloneGenerateAndPush"}).Inc()
-	err = r.Generator.CloneGenerateAndPush(tempDir, gitOpsURL, mappedGitOpsComponent, r.AppFS, gitOpsBranch, gitOpsContext, false)
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": componentName, "tokenName": gitProvider.GetTokenName(), "operation": "CloneGenerateAndPush"}).Inc()
+	err = gitops.CloneAndGenerate(r.Generator, outputFormat, tempDir, gitOpsURL, mappedGitOpsComponent, r.AppFS, gitOpsBranch, gitOpsContext)
 	if err != nil {
-		retErr := err
-		if strings.Contains(strings.ToLower(err.Error()), "github push protection") {
-			retErr = fmt.Errorf("potential secret leak caught by github push protection")
-			// to get the URL token
-			// e.g. <GitURL>/security/secret-scanning/unblock-secret/2WlUv72plUf05tgshlpRLzSlH4R        \n
-			var unblockURL string
-			splited := strings.Split(strings.ToLower(err.Error()), "unblock-secret/")
-			if len(splited) > 1 {
-				token := strings.Split(splited[1], " ")[0]
-				unblockURL = fmt.Sprintf("%v/security/secret-scanning/unblock-secret/%v", component.Status.GitOps.RepositoryURL, token)
-				log.Error(retErr, fmt.Sprintf("unable to generate gitops resources due to git push protecton error, follow the link to unblock the secret: %v", unblockURL))
-			}
-		} else {
-			log.Error(retErr, "unable to generate gitops resources due to error")
-		}
 		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-		return retErr
+		return "", handleGitOpsPushError(log, err, component.Status.GitOps.RepositoryURL, "generate")
+	}
+
+	commitMessage, err := r.CommitMessages.ComponentMessage(gitops.CommitMessageData{
+		Namespace:   component.Namespace,
+		Application: component.Spec.Application,
+		Components:  []gitops.CommitComponent{gitOpsCommitComponent(component)},
+		Reconciler:  componentName,
+		Resource:    component.Name,
+		Generation:  component.Generation,
+	})
+	if err != nil {
+		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
+		return "", err
 	}
 
 	//Gitops functions return sanitized error messages
-	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": componentName, "tokenName": ghClient.TokenName, "operation": "CommitAndPush"}).Inc()
-	err = r.Generator.CommitAndPush(tempDir, "", gitOpsURL, mappedGitOpsComponent.Name, gitOpsBranch, "Generating GitOps resources")
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": componentName, "tokenName": gitProvider.GetTokenName(), "operation": "CommitAndPush"}).Inc()
+	err = r.Generator.CommitAndPush(tempDir, "", gitOpsURL, mappedGitOpsComponent.Name, gitOpsBranch, commitMessage)
 	if err != nil {
-		retErr := err
-		if strings.Contains(strings.ToLower(err.Error()), "github push protection") {
-			retErr = fmt.Errorf("potential secret leak caught by github push protection")
-			// to get the URL token
-			// e.g. <GitURL>/security/secret-scanning/unblock-secret/2WlUv72plUf05tgshlpRLzSlH4R        \n
-			var unblockURL string
-			splited := strings.Split(strings.ToLower(err.Error()), "unblock-secret/")
-			if len(splited) > 1 {
-				token := strings.Split(splited[1], " ")[0]
-				unblockURL = fmt.Sprintf("%v/security/secret-scanning/unblock-secret/%v", component.Status.GitOps.RepositoryURL, token)
-				log.Error(retErr, fmt.Sprintf("unable to commit and push gitops resources due to git push protecton error, follow the link to unblock the secret: %v", unblockURL))
-			}
-		} else {
-			log.Error(retErr, "unable to commit and push gitops resources due to error")
-		}
 		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-		return retErr
+		return "", handleGitOpsPushError(log, err, component.Status.GitOps.RepositoryURL, "commit and push")
 	}
 
 	// Get the commit ID for the gitops repository
 	var commitID string
 	repoPath := filepath.Join(tempDir, component.Name)
-	metricsLabel := prometheus.Labels{"controller": componentName, "tokenName": ghClient.TokenName, "operation": "GetCommitIDFromRepo"}
+	metricsLabel := prometheus.Labels{"controller": componentName, "tokenName": gitProvider.GetTokenName(), "operation": "GetCommitIDFromRepo"}
 	metrics.ControllerGitRequest.With(metricsLabel).Inc()
 	if commitID, err = r.Generator.GetCommitIDFromRepo(r.AppFS, repoPath); err != nil {
 		log.Error(err, "")
 		ioutils.RemoveFolderAndLogError(log, r.AppFS, tempDir)
-		return err
+		return "", err
 	}
 
-	component.Status.GitOps.CommitID = commitID
-
 	// Remove the temp folder that was created
-	return r.AppFS.RemoveAll(tempDir)
+	return commitID, r.AppFS.RemoveAll(tempDir)
+}
+
+// handleGitOpsPushError returns the error to report for gitops resources that failed to be generated or pushed. If the push was
+// blocked by GitHub push protection, the error is replaced, and the link to unblock the secret is logged.
+func handleGitOpsPushError(log logr.Logger, err error, repoURL string, action string) error {
+	if !strings.Contains(strings.ToLower(err.Error()), "github push protection") {
+		log.Error(err, fmt.Sprintf("unable to %s gitops resources due to error", action))
+		return err
+	}
+	retErr := fmt.Errorf("potential secret leak caught by github push protection")
+	// to get the URL token
+	// e.g. <GitURL>/security/secret-scanning/unblock-secret/2WlUv72plUf05tgshlpRLzSlH4R        \n
+	splited := strings.Split(strings.ToLower(err.Error()), "unblock-secret/")
+	if len(splited) > 1 {
+		token := strings.Split(splited[1], " ")[0]
+		unblockURL := fmt.Sprintf("%v/security/secret-scanning/unblock-secret/%v", repoURL, token)
+		log.Error(retErr, fmt.Sprintf("unable to %s gitops resources due to git push protecton error, follow the link to unblock the secret: %v", action, unblockURL))
+	}
+	return retErr
 }
 
 // setGitopsStatus adds the necessary gitops info (url, branch, context) to the component CR status
@@ -709,9 +892,10 @@ func setGitopsStatus(component *appstudiov1alpha1.Component, devfileData data.De
 func (r *ComponentReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
 	log := ctrl.LoggerFrom(ctx).WithName("controllers").WithName("Component")
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&appstudiov1alpha1.Component{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		For(&appstudiov1alpha1.Component{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, gitOpsDryRunChangedPredicate, gitOpsAnnotationsChangedPredicate))).
 		WithOptions(controller.Options{
-			RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(time.Duration(500*time.Millisecond), time.Duration(1000*time.Second)),
+			RateLimiter:             workqueue.NewItemExponentialFailureRateLimiter(time.Duration(500*time.Millisecond), time.Duration(1000*time.Second)),
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
 		}).WithEventFilter(predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			log := log.WithValues("namespace", e.Object.GetNamespace())
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,9 +27,19 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
 	logutil "github.com/redhat-appstudio/application-service/pkg/log"
 )
 
+// gitOpsGeneratedErrorReason returns the reason of the GitOpsResourcesGenerated condition when the GitOps resources failed to
+// generate with the given error. Commits that couldn't be signed have their own reason, as they need the signing key to be fixed.
+func gitOpsGeneratedErrorReason(generateError error) string {
+	if gitops.IsCommitSigningError(generateError) {
+		return "SigningError"
+	}
+	return "GenerateError"
+}
+
 func (r *ComponentReconciler) SetCreateConditionAndUpdateCR(ctx context.Context, req ctrl.Request, component *appstudiov1alpha1.Component, createError error) error {
 	log := ctrl.LoggerFrom(ctx)
 
@@ -57,6 +68,9 @@ func (r *ComponentReconciler) SetCreateConditionAndUpdateCR(ctx context.Context,
 			return err
 		}
 		meta.SetStatusCondition(&currentComponent.Status.Conditions, condition)
+		if condition.Status == metav1.ConditionTrue {
+			clearRateLimitedCondition(&currentComponent.Status.Conditions)
+		}
 		currentComponent.Status.Devfile = component.Status.Devfile
 		currentComponent.Status.ContainerImage = component.Status.ContainerImage
 		currentComponent.Status.GitOps = component.Status.GitOps
@@ -98,6 +112,9 @@ func (r *ComponentReconciler) SetUpdateConditionAndUpdateCR(ctx context.Context,
 			return err
 		}
 		meta.SetStatusCondition(&currentComponent.Status.Conditions, condition)
+		if condition.Status == metav1.ConditionTrue {
+			clearRateLimitedCondition(&currentComponent.Status.Conditions)
+		}
 		currentComponent.Status.Devfile = component.Status.Devfile
 		currentComponent.Status.ContainerImage = component.Status.ContainerImage
 		currentComponent.Status.GitOps = component.Status.GitOps
@@ -118,17 +135,24 @@ func (r *ComponentReconciler) SetGitOpsGeneratedConditionAndUpdateCR(ctx context
 
 	if generateError == nil {
 		condition = metav1.Condition{
-			Type:    "GitOpsResourcesGenerated",
-			Status:  metav1.ConditionTrue,
-			Reason:  "OK",
-			Message: "GitOps resource generated successfully",
+			Type:               "GitOpsResourcesGenerated",
+			Status:             metav1.ConditionTrue,
+			Reason:             "OK",
+			Message:            "GitOps resource generated successfully",
+			ObservedGeneration: component.Generation,
+		}
+		// In pull request mode, the GitOps resources aren't generated until their pull request is merged
+		if pendingCondition := pendingGitOpsGeneratedCondition(component.Status.Conditions); pendingCondition != nil {
+			condition = *pendingCondition
+			condition.ObservedGeneration = component.Generation
 		}
 	} else {
 		condition = metav1.Condition{
-			Type:    "GitOpsResourcesGenerated",
-			Status:  metav1.ConditionFalse,
-			Reason:  "GenerateError",
-			Message: fmt.Sprintf("GitOps resources failed to generate: %v", generateError),
+			Type:               "GitOpsResourcesGenerated",
+			Status:             metav1.ConditionFalse,
+			Reason:             gitOpsGeneratedErrorReason(generateError),
+			Message:            fmt.Sprintf("GitOps resources failed to generate: %v", generateError),
+			ObservedGeneration: component.Generation,
 		}
 		logutil.LogAPIResourceChangeEvent(log, component.Name, "ComponentGitOpsResources", logutil.ResourceCreate, generateError)
 	}
@@ -138,7 +162,15 @@ func (r *ComponentReconciler) SetGitOpsGeneratedConditionAndUpdateCR(ctx context
 		if err != nil {
 			return err
 		}
-		meta.SetStatusCondition(&currentComponent.Status.Conditions, condition)
+		// A dry run leaves the GitOps resources as they were, so it only records the GitOpsDryRun condition
+		if generateError != nil || meta.FindStatusCondition(component.Status.Conditions, gitOpsDryRunConditionType) == nil {
+			meta.SetStatusCondition(&currentComponent.Status.Conditions, condition)
+		}
+		if generateError == nil {
+			clearRateLimitedCondition(&currentComponent.Status.Conditions)
+			copyGitOpsPullRequestCondition(component.Status.Conditions, &currentComponent.Status.Conditions)
+			copyGitOpsDryRunCondition(component.Status.Conditions, &currentComponent.Status.Conditions)
+		}
 		currentComponent.Status.Devfile = component.Status.Devfile
 		currentComponent.Status.ContainerImage = component.Status.ContainerImage
 		currentComponent.Status.GitOps = component.Status.GitOps
@@ -151,3 +183,22 @@ func (r *ComponentReconciler) SetGitOpsGeneratedConditionAndUpdateCR(ctx context
 	}
 	return nil
 }
+
+// SetRateLimitedConditionAndUpdateCR sets the RateLimited condition on the Component, when its reconcile has been delayed by GitHub rate limits
+func (r *ComponentReconciler) SetRateLimitedConditionAndUpdateCR(ctx context.Context, req ctrl.Request, rateLimitErr error, requeueAfter time.Duration) error {
+	log := ctrl.LoggerFrom(ctx)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var currentComponent appstudiov1alpha1.Component
+		err := r.Get(ctx, req.NamespacedName, &currentComponent)
+		if err != nil {
+			return err
+		}
+		setRateLimitedCondition(&currentComponent.Status.Conditions, rateLimitErr, requeueAfter)
+		return r.Client.Status().Update(ctx, &currentComponent)
+	})
+	if err != nil {
+		log.Error(err, "Unable to update Component")
+		return err
+	}
+	return nil
+}
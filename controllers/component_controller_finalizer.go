@@ -84,7 +84,8 @@ func (r *ComponentReconciler) Finalize(ctx context.Context, component *appstudio
 		log.Error(err, "Failed to update application in finalizer, will not retry to prevent finalizer loop")
 	}
 
-	gitOpsURL, gitOpsBranch, gitOpsContext, err := util.ProcessGitOpsStatus(component.Status.GitOps, ghClient.Token)
+	gitProvider := gitOpsRepositoryProvider(ghClient, r.GitLabClient, component.Status.GitOps.RepositoryURL)
+	gitOpsURL, gitOpsBranch, gitOpsContext, err := util.ProcessGitOpsStatus(component.Status.GitOps, gitProvider.GetRemoteCredentials())
 	if err != nil {
 		return err
 	}
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -2176,7 +2177,7 @@ func verifyHASComponentUpdates(devfile data.DevfileData, checklist updateCheckli
 			var containerENVs []corev1.EnvVar
 			err := componentAttributes.GetInto(devfilePkg.ContainerENVKey, &containerENVs)
 			for _, containerEnv := range containerENVs {
-				if containerEnv.Name == checklistEnv.Name && containerEnv.Value == checklistEnv.Value {
+				if containerEnv.Name == checklistEnv.Name && containerEnv.Value == checklistEnv.Value && reflect.DeepEqual(containerEnv.ValueFrom, checklistEnv.ValueFrom) {
 					isMatched = true
 				}
 			}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/redhat-appstudio/application-service/gitops"
@@ -33,10 +34,12 @@ import (
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
 	devfile "github.com/redhat-appstudio/application-service/pkg/devfile"
 	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
 	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/yaml"
 
 	devfileApi "github.com/devfile/api/v2/pkg/devfile"
@@ -158,7 +161,7 @@ func TestGenerateGitops(t *testing.T) {
 	readOnlyFs := ioutils.NewReadOnlyFs()
 	ctx := context.Background()
 
-	fakeClient := fake.NewClientBuilder().Build()
+	fakeClient := NewFakeClient(t)
 
 	r := &ComponentReconciler{
 		Log:               ctrl.Log.WithName("controllers").WithName("Component"),
@@ -565,3 +568,110 @@ func TestGenerateGitops(t *testing.T) {
 	}
 
 }
+
+func TestGitOpsGeneratedErrorReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "Commit couldn't be signed",
+			err:  errors.New("failed to commit files to repository \"/tmp/test\" \"error: gpg failed to sign the data\\nfatal: failed to write commit object\\n\": exit status 128"),
+			want: "SigningError",
+		},
+		{
+			name: "Other error",
+			err:  errors.New("failed to push to repository \"https://github.com/redhat-appstudio-appdata/test-application\""),
+			want: "GenerateError",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gitOpsGeneratedErrorReason(tt.err); got != tt.want {
+				t.Errorf("TestGitOpsGeneratedErrorReason() error: expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReconcileGitOpsRateLimited(t *testing.T) {
+	ctx := context.Background()
+	gitOpsURL := "https://gitlab.com/appdata-group/test-application-repo"
+
+	// GitOps resources are pushed in pull request mode, so the work branch is reset on the Git provider before they're generated
+	application := &appstudiov1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-application",
+			Namespace:   "default",
+			Annotations: map[string]string{gitprovider.PullRequestModeAnnotation: "true"},
+		},
+	}
+	applicationDevfile, err := devfile.ConvertApplicationToDevfile(*application, gitOpsURL, "")
+	if err != nil {
+		t.Fatalf("TestReconcileGitOpsRateLimited() unexpected error: %v", err)
+	}
+	applicationDevfileYaml, err := yaml.Marshal(applicationDevfile)
+	if err != nil {
+		t.Fatalf("TestReconcileGitOpsRateLimited() unexpected error: %v", err)
+	}
+	application.Status.Devfile = string(applicationDevfileYaml)
+
+	// A Component whose previous GitOps generation failed, so that it's re-attempted
+	component := &appstudiov1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default", Finalizers: []string{compFinalizerName}},
+		Spec: appstudiov1alpha1.ComponentSpec{
+			ComponentName:  "backend",
+			Application:    "test-application",
+			ContainerImage: "quay.io/test/backend:latest",
+		},
+	}
+	componentDevfile, err := devfile.ConvertImageComponentToDevfile(*component)
+	if err != nil {
+		t.Fatalf("TestReconcileGitOpsRateLimited() unexpected error: %v", err)
+	}
+	componentDevfileYaml, err := yaml.Marshal(componentDevfile)
+	if err != nil {
+		t.Fatalf("TestReconcileGitOpsRateLimited() unexpected error: %v", err)
+	}
+	component.Status = appstudiov1alpha1.ComponentStatus{
+		Devfile: string(componentDevfileYaml),
+		GitOps:  appstudiov1alpha1.GitOpsStatus{RepositoryURL: gitOpsURL, Branch: "main"},
+		Conditions: []metav1.Condition{
+			{Type: "Created", Status: metav1.ConditionTrue, Reason: "OK"},
+			{Type: "GitOpsResourcesGenerated", Status: metav1.ConditionFalse, Reason: "GenerateError"},
+		},
+	}
+
+	fakeClient := NewFakeClient(t, application, component)
+	r := &ComponentReconciler{
+		Log:               ctrl.Log.WithName("controllers").WithName("Component"),
+		Client:            fakeClient,
+		AppFS:             ioutils.NewMemoryFilesystem(),
+		Generator:         gitops.NewMockGenerator(),
+		GitHubTokenClient: github.MockGitHubTokenClient{},
+		GitLabClient:      &fakeGitProvider{err: &github.TokensExhaustedError{ResetTime: time.Now().Add(time.Minute)}},
+	}
+
+	key := types.NamespacedName{Namespace: component.Namespace, Name: component.Name}
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("TestReconcileGitOpsRateLimited() unexpected error: %v", err)
+	}
+	if result.RequeueAfter <= 0 {
+		t.Errorf("TestReconcileGitOpsRateLimited() error: expected a requeue once the rate limit resets, got %v", result)
+	}
+
+	// The RateLimited condition is set, and the generation is still marked as failed so that it's re-attempted on the requeue
+	var updated appstudiov1alpha1.Component
+	if err := fakeClient.Get(ctx, key, &updated); err != nil {
+		t.Fatalf("TestReconcileGitOpsRateLimited() unexpected error: %v", err)
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, rateLimitedConditionType); condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("TestReconcileGitOpsRateLimited() error: expected the RateLimited condition, got %v", updated.Status.Conditions)
+	}
+	if condition := meta.FindStatusCondition(updated.Status.Conditions, "GitOpsResourcesGenerated"); condition == nil || condition.Reason != "GenerateError" {
+		t.Errorf("TestReconcileGitOpsRateLimited() error: expected the GitOps generation to be re-attempted, got %v", condition)
+	}
+}
@@ -134,6 +134,11 @@ func (r *ComponentDetectionQueryReconciler) Reconcile(ctx context.Context, req c
 		// Create a Go-GitHub client for checking the default branch
 		ghClient, err := r.GitHubTokenClient.GetNewGitHubClient(gitToken)
 		if err != nil {
+			if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+				log.Info(fmt.Sprintf("All GitHub tokens are rate limited, requeueing %v in %v", req.NamespacedName, requeueAfter))
+				r.SetRateLimitedConditionAndUpdateCR(ctx, req, &componentDetectionQuery, err, requeueAfter)
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
+			}
 			log.Error(err, "Unable to create Go-GitHub client due to error")
 			return reconcile.Result{}, err
 		}
@@ -251,6 +256,12 @@ func (r *ComponentDetectionQueryReconciler) Reconcile(ctx context.Context, req c
 						BackoffLimit: &backOffLimit,
 					},
 				}
+				if gitHubURLs := github.GetGitHubURLs(); gitHubURLs.IsEnterprise() {
+					// Let the job convert GitHub Enterprise Server source URLs to raw file content URLs
+					jobSpec.Spec.Template.Spec.Containers[0].Env = append(jobSpec.Spec.Template.Spec.Containers[0].Env,
+						corev1.EnvVar{Name: "GITHUB_URL", Value: gitHubURLs.BaseURL},
+						corev1.EnvVar{Name: "GITHUB_RAW_URL", Value: gitHubURLs.RawURL})
+				}
 				err = r.Client.Create(ctx, jobSpec, &client.CreateOptions{})
 				if err != nil {
 					log.Error(err, fmt.Sprintf("Error creating cdq analysis job %s... %v", jobName, req.NamespacedName))
@@ -395,6 +406,11 @@ func (r *ComponentDetectionQueryReconciler) Reconcile(ctx context.Context, req c
 					_, err := ghClient.GetBranchFromURL(sourceURL, ctx, "main")
 					if err != nil {
 						metrics.HandleRateLimitMetrics(err, metricsLabel)
+						if requeueAfter, isRateLimited := getRateLimitRequeueAfter(err); isRateLimited {
+							log.Info(fmt.Sprintf("Rate limited while looking up the default branch of %v, requeueing %v in %v", source.URL, req.NamespacedName, requeueAfter))
+							r.SetRateLimitedConditionAndUpdateCR(ctx, req, &componentDetectionQuery, err, requeueAfter)
+							return ctrl.Result{RequeueAfter: requeueAfter}, nil
+						}
 						log.Error(err, fmt.Sprintf("Unable to get main branch of Github Repo %v ... %v", source.URL, req.NamespacedName))
 						retErr := fmt.Errorf("unable to get default branch of Github Repo %v, try to fall back to main branch, failed to get main branch... %v", source.URL, req.NamespacedName)
 						r.SetCompleteConditionAndUpdateCR(ctx, req, &componentDetectionQuery, copiedCDQ, retErr)
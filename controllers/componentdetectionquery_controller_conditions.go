@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -63,6 +64,7 @@ func (r *ComponentDetectionQueryReconciler) SetCompleteConditionAndUpdateCR(ctx
 			Reason:  "OK",
 			Message: message,
 		})
+		clearRateLimitedCondition(&componentDetectionQuery.Status.Conditions)
 		logutil.LogAPIResourceChangeEvent(log, componentDetectionQuery.Name, "ComponentDetectionQuery", logutil.ResourceComplete, nil)
 	} else {
 		meta.SetStatusCondition(&componentDetectionQuery.Status.Conditions, metav1.Condition{
@@ -93,3 +95,17 @@ func (r *ComponentDetectionQueryReconciler) SetCompleteConditionAndUpdateCR(ctx
 
 	}
 }
+
+// SetRateLimitedConditionAndUpdateCR sets the RateLimited condition on the ComponentDetectionQuery, when its reconcile has been delayed by GitHub rate limits
+func (r *ComponentDetectionQueryReconciler) SetRateLimitedConditionAndUpdateCR(ctx context.Context, req ctrl.Request, componentDetectionQuery *appstudiov1alpha1.ComponentDetectionQuery, rateLimitErr error, requeueAfter time.Duration) {
+	log := ctrl.LoggerFrom(ctx)
+
+	patch := client.MergeFrom(componentDetectionQuery.DeepCopy())
+
+	setRateLimitedCondition(&componentDetectionQuery.Status.Conditions, rateLimitErr, requeueAfter)
+
+	err := r.Client.Status().Patch(ctx, componentDetectionQuery, patch)
+	if err != nil {
+		log.Error(err, "Unable to update ComponentDetectionQuery")
+	}
+}
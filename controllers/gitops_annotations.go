@@ -0,0 +1,43 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/application-service/pkg/devfile"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// gitOpsAnnotations are the annotations of a resource that configure its GitOps resources
+var gitOpsAnnotations = append(append(append([]string{}, devfile.AutoscalingAnnotations...), devfile.PolicyAnnotations...), gitops.SecretSourceAnnotations...)
+
+// gitOpsAnnotationsChangedPredicate triggers a reconcile when the annotations that configure the GitOps resources of a resource, such
+// as its autoscaling, policies and secret source, are changed, as annotations don't change its generation
+var gitOpsAnnotationsChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if e.ObjectOld == nil || e.ObjectNew == nil {
+			return false
+		}
+		for _, annotation := range gitOpsAnnotations {
+			if e.ObjectOld.GetAnnotations()[annotation] != e.ObjectNew.GetAnnotations()[annotation] {
+				return true
+			}
+		}
+		return false
+	},
+}
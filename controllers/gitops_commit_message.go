@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
+)
+
+// gitOpsCommitComponent returns the Component, as it's described in the messages of GitOps commits. The source revision is the last
+// commit that the Component was built from, or the revision in its spec if it hasn't been built.
+func gitOpsCommitComponent(component *appstudiov1alpha1.Component) gitops.CommitComponent {
+	commitComponent := gitops.CommitComponent{
+		Name:           component.Name,
+		Image:          component.Spec.ContainerImage,
+		SourceRevision: component.Status.LastBuiltCommit,
+	}
+	if gitSource := component.Spec.Source.GitSource; gitSource != nil {
+		commitComponent.SourceURL = gitSource.URL
+		if commitComponent.SourceRevision == "" {
+			commitComponent.SourceRevision = gitSource.Revision
+		}
+	}
+	return commitComponent
+}
+
+// gitOpsSnapshotCommitComponent returns the Component, as it's described in the messages of GitOps commits, when deployed from the
+// given Snapshot. The Snapshot's image and source take precedence over the Component's.
+func gitOpsSnapshotCommitComponent(component *appstudiov1alpha1.Component, snapshotComponent appstudiov1alpha1.SnapshotComponent) gitops.CommitComponent {
+	commitComponent := gitOpsCommitComponent(component)
+	commitComponent.Image = snapshotComponent.ContainerImage
+	if gitSource := snapshotComponent.Source.GitSource; gitSource != nil {
+		if gitSource.URL != "" {
+			commitComponent.SourceURL = gitSource.URL
+		}
+		if gitSource.Revision != "" {
+			commitComponent.SourceRevision = gitSource.Revision
+		}
+	}
+	return commitComponent
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGitOpsSnapshotCommitComponent(t *testing.T) {
+	component := appstudiov1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default"},
+		Spec: appstudiov1alpha1.ComponentSpec{
+			ContainerImage: "quay.io/test/frontend:latest",
+			Source: appstudiov1alpha1.ComponentSource{
+				ComponentSourceUnion: appstudiov1alpha1.ComponentSourceUnion{
+					GitSource: &appstudiov1alpha1.GitSource{URL: "https://github.com/devfile-samples/frontend", Revision: "main"},
+				},
+			},
+		},
+	}
+	builtComponent := component.DeepCopy()
+	builtComponent.Status.LastBuiltCommit = "ca82a6dff817ec66f44342007202690a93763949"
+
+	tests := []struct {
+		name              string
+		component         *appstudiov1alpha1.Component
+		snapshotComponent appstudiov1alpha1.SnapshotComponent
+		want              gitops.CommitComponent
+	}{
+		{
+			name:              "Component that hasn't been built",
+			component:         &component,
+			snapshotComponent: appstudiov1alpha1.SnapshotComponent{Name: "frontend", ContainerImage: "quay.io/test/frontend:v1"},
+			want:              gitops.CommitComponent{Name: "frontend", Image: "quay.io/test/frontend:v1", SourceURL: "https://github.com/devfile-samples/frontend", SourceRevision: "main"},
+		},
+		{
+			name:              "Built Component",
+			component:         builtComponent,
+			snapshotComponent: appstudiov1alpha1.SnapshotComponent{Name: "frontend", ContainerImage: "quay.io/test/frontend:v1"},
+			want:              gitops.CommitComponent{Name: "frontend", Image: "quay.io/test/frontend:v1", SourceURL: "https://github.com/devfile-samples/frontend", SourceRevision: "ca82a6dff817ec66f44342007202690a93763949"},
+		},
+		{
+			name:      "Snapshot with the Component's source",
+			component: builtComponent,
+			snapshotComponent: appstudiov1alpha1.SnapshotComponent{Name: "frontend", ContainerImage: "quay.io/test/frontend:v2",
+				Source: appstudiov1alpha1.ComponentSource{
+					ComponentSourceUnion: appstudiov1alpha1.ComponentSourceUnion{
+						GitSource: &appstudiov1alpha1.GitSource{Revision: "0a93763949ca82a6dff817ec66f44342007202690"},
+					},
+				},
+			},
+			want: gitops.CommitComponent{Name: "frontend", Image: "quay.io/test/frontend:v2", SourceURL: "https://github.com/devfile-samples/frontend", SourceRevision: "0a93763949ca82a6dff817ec66f44342007202690"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gitOpsSnapshotCommitComponent(tt.component, tt.snapshotComponent); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TestGitOpsSnapshotCommitComponent() error: expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,562 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	devfileParser "github.com/devfile/library/v2/pkg/devfile/parser"
+	"github.com/prometheus/client_golang/prometheus"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	cdqanalysis "github.com/redhat-appstudio/application-service/cdq-analysis/pkg"
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	"github.com/redhat-appstudio/application-service/pkg/util"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultGitOpsDriftCheckInterval is how often the GitOpsDriftDetector runs, if no interval is set
+	DefaultGitOpsDriftCheckInterval = time.Hour
+
+	// gitOpsDriftedConditionType is the condition recording whether a resource's GitOps resources were changed in the GitOps repository,
+	// since they were generated. Its message lists the files that differ.
+	gitOpsDriftedConditionType = "GitOpsDrifted"
+
+	// maxGitOpsDriftedFiles is the number of differing files listed in the GitOpsDrifted condition's message
+	maxGitOpsDriftedFiles = 10
+
+	gitOpsDriftDetectorName = "GitOpsDriftDetector"
+)
+
+// GitOpsDriftDetector periodically re-renders the GitOps resources that would be generated for each Component and
+// SnapshotEnvironmentBinding, and compares them with the content of their GitOps repository's branch, to catch hand edits of the
+// Components' base resources and of the environment overlays. It records the result in the GitOpsDrifted condition, and optionally
+// pushes the rendered resources back to the repository.
+// Resources whose GitOps changes are opened as pull requests aren't checked, as their branch is only updated once a pull request is merged.
+// It implements controller-runtime's manager.Runnable, and only runs on the leader.
+type GitOpsDriftDetector struct {
+	// Client is used to list the Components and SnapshotEnvironmentBindings, across all namespaces
+	Client client.Client
+
+	AppFS             afero.Afero
+	Generator         gitopsgen.Generator
+	GitHubTokenClient github.GitHubToken
+
+	// GitLabClient, if set, is the client of the GitLab instance that GitOps repositories may be hosted on
+	GitLabClient gitprovider.GitProvider
+
+	// CommitMessages renders the messages of the commits that heal drift. Defaults to the default templates
+	CommitMessages *gitops.CommitMessages
+
+	// PostProcessors post-process the Kubernetes resources of the Components, as they are when the GitOps resources are generated
+	PostProcessors gitops.PostProcessors
+
+	// AutoHeal pushes the rendered resources over the drifted ones, rather than only reporting the drift
+	AutoHeal bool
+
+	// WriteQueue, if set, is the GitOps write queue of the Components. Drift is healed while holding the lock of the GitOps repository
+	// branch in the queue, so that the heal doesn't race the pushes of its batches
+	WriteQueue *GitOpsWriteQueue
+
+	// Interval is how often drift is checked. Defaults to DefaultGitOpsDriftCheckInterval
+	Interval time.Duration
+}
+
+// gitOpsRender describes how to render the GitOps resources of a resource over the content of its GitOps repository. Folders are
+// relative to the GitOps folder of the repository.
+type gitOpsRender struct {
+	// repositoryURL is the URL of the GitOps repository, without credentials
+	repositoryURL string
+
+	// remote, branch and context locate the GitOps resources
+	remote  string
+	branch  string
+	context string
+
+	// copied are the folders copied from the repository before rendering, which the render may read. compared are the folders
+	// that are rendered, and compared with the repository.
+	copied   []string
+	compared []string
+
+	// render renders the expected GitOps resources into the GitOps folder of the given filesystem
+	render func(fs afero.Afero, gitOpsFolder string) error
+}
+
+// gitOpsDriftResult is the outcome of a drift check
+type gitOpsDriftResult struct {
+	// files are the paths, relative to the root of the repository, of the files that differ from the rendered resources
+	files []string
+
+	// commitID is the commit that healed the drift, if it was healed
+	commitID string
+}
+
+// Start checks the Components and SnapshotEnvironmentBindings for drift every interval, until the context is cancelled
+func (d *GitOpsDriftDetector) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("gitops-drift-detector")
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultGitOpsDriftCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := d.checkAll(ctx); err != nil {
+				// Drift is checked again on the next tick
+				log.Error(err, "unable to check the GitOps repositories for drift")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns true, so that only one replica checks, and heals, the GitOps repositories
+func (d *GitOpsDriftDetector) NeedLeaderElection() bool {
+	return true
+}
+
+// checkAll checks the Components and SnapshotEnvironmentBindings in all namespaces for drift. A resource that can't be checked doesn't
+// stop the others from being checked.
+func (d *GitOpsDriftDetector) checkAll(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("gitops-drift-detector")
+
+	var components appstudiov1alpha1.ComponentList
+	if err := d.Client.List(ctx, &components); err != nil {
+		return err
+	}
+	for i := range components.Items {
+		if err := d.checkComponent(ctx, &components.Items[i]); err != nil {
+			log.Error(err, fmt.Sprintf("unable to record the GitOps drift of Component %s/%s", components.Items[i].Namespace, components.Items[i].Name))
+		}
+	}
+
+	var bindings appstudiov1alpha1.SnapshotEnvironmentBindingList
+	if err := d.Client.List(ctx, &bindings); err != nil {
+		return err
+	}
+	for i := range bindings.Items {
+		if err := d.checkBinding(ctx, &bindings.Items[i]); err != nil {
+			log.Error(err, fmt.Sprintf("unable to record the GitOps drift of SnapshotEnvironmentBinding %s/%s", bindings.Items[i].Namespace, bindings.Items[i].Name))
+		}
+	}
+	return nil
+}
+
+// checkComponent checks the base GitOps resources of the Component for drift, and records the result in its GitOpsDrifted condition
+func (d *GitOpsDriftDetector) checkComponent(ctx context.Context, component *appstudiov1alpha1.Component) error {
+	log := ctrl.LoggerFrom(ctx).WithName("gitops-drift-detector")
+	if component.Spec.SkipGitOpsResourceGeneration || component.Status.Devfile == "" || component.Status.GitOps.RepositoryURL == "" ||
+		!meta.IsStatusConditionTrue(component.Status.Conditions, "GitOpsResourcesGenerated") {
+		return nil
+	}
+	// The spec of a Component in dry run mode is only previewed, so it's expected to differ from the GitOps repository
+	if gitops.GetDryRunMode(component.Annotations) != "" {
+		return nil
+	}
+	pullRequestMode, err := isPullRequestModeEnabled(ctx, d.Client, component.Namespace, component.Spec.Application)
+	if err != nil || pullRequestMode {
+		return err
+	}
+
+	result, err := d.getComponentDrift(ctx, component)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to check the GitOps resources of Component %s/%s for drift", component.Namespace, component.Name))
+		return d.setComponentDriftCondition(ctx, component, gitOpsDriftCheckFailedCondition(err), "")
+	}
+	if len(result.files) > 0 {
+		log.Info(fmt.Sprintf("GitOps resources of Component %s/%s drifted: %s", component.Namespace, component.Name, strings.Join(result.files, ", ")))
+	}
+	return d.setComponentDriftCondition(ctx, component, gitOpsDriftCondition(result), result.commitID)
+}
+
+// getComponentDrift renders the base GitOps resources of the Component from its devfile, and compares them with its GitOps repository
+func (d *GitOpsDriftDetector) getComponentDrift(ctx context.Context, component *appstudiov1alpha1.Component) (*gitOpsDriftResult, error) {
+	log := ctrl.LoggerFrom(ctx).WithName("gitops-drift-detector")
+	ghClient, err := d.GitHubTokenClient.GetNewGitHubClient("")
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, github.GHClientKey, ghClient.TokenName)
+
+	gitToken, err := getComponentGitToken(ctx, d.Client, component)
+	if err != nil {
+		return nil, err
+	}
+	compDevfileData, err := cdqanalysis.ParseDevfileWithParserArgs(&devfileParser.ParserArgs{Data: []byte(component.Status.Devfile), Token: gitToken})
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the devfile from Component status: %v", err)
+	}
+	mappedGitOpsComponent, err := gitops.ComponentGeneratorOptions(log, *component, compDevfileData, d.PostProcessors)
+	if err != nil {
+		return nil, err
+	}
+	gitProvider := gitOpsRepositoryProvider(ghClient, d.GitLabClient, component.Status.GitOps.RepositoryURL)
+	gitOpsURL, gitOpsBranch, gitOpsContext, err := util.ProcessGitOpsStatus(component.Status.GitOps, gitProvider.GetRemoteCredentials())
+	if err != nil {
+		return nil, err
+	}
+
+	outputFormat, err := getGitOpsOutputFormat(ctx, d.Client, component.Namespace, component.Spec.Application)
+	if err != nil {
+		return nil, err
+	}
+
+	baseFolder := filepath.Join("components", component.Name, "base")
+	heal := d.AutoHeal && isGitOpsGeneratedForGeneration(component.Status.Conditions, component.Generation)
+	return d.checkDrift(ctx, gitProvider, heal, gitOpsRender{
+		repositoryURL: component.Status.GitOps.RepositoryURL,
+		remote:        gitOpsURL,
+		branch:        gitOpsBranch,
+		context:       gitOpsContext,
+		compared:      []string{baseFolder},
+		render: func(fs afero.Afero, gitOpsFolder string) error {
+			return outputFormat.Generate(fs, gitOpsFolder, filepath.Join(gitOpsFolder, baseFolder), mappedGitOpsComponent)
+		},
+	}, func() (string, error) {
+		return d.CommitMessages.ComponentMessage(gitops.CommitMessageData{
+			Namespace:   component.Namespace,
+			Application: component.Spec.Application,
+			Components:  []gitops.CommitComponent{gitOpsCommitComponent(component)},
+			Reconciler:  gitOpsDriftDetectorName,
+			Resource:    component.Name,
+			Generation:  component.Generation,
+		})
+	})
+}
+
+// checkBinding checks the environment overlays of the SnapshotEnvironmentBinding's Components for drift, and records the result in
+// its GitOpsDrifted condition
+func (d *GitOpsDriftDetector) checkBinding(ctx context.Context, binding *appstudiov1alpha1.SnapshotEnvironmentBinding) error {
+	log := ctrl.LoggerFrom(ctx).WithName("gitops-drift-detector")
+	if len(binding.Status.Components) == 0 || !meta.IsStatusConditionTrue(binding.Status.GitOpsRepoConditions, "GitOpsResourcesGenerated") {
+		return nil
+	}
+	// The spec of a SnapshotEnvironmentBinding in dry run mode is only previewed, so it's expected to differ from the GitOps repository
+	if gitops.GetDryRunMode(binding.Annotations) != "" {
+		return nil
+	}
+	var environment appstudiov1alpha1.Environment
+	if err := d.Client.Get(ctx, types.NamespacedName{Namespace: binding.Namespace, Name: binding.Spec.Environment}, &environment); err != nil {
+		return err
+	}
+	pullRequestMode, err := isPullRequestModeEnabled(ctx, d.Client, binding.Namespace, binding.Spec.Application, &environment)
+	if err != nil || pullRequestMode {
+		return err
+	}
+
+	result, err := d.getBindingDrift(ctx, binding, &environment)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to check the GitOps resources of SnapshotEnvironmentBinding %s/%s for drift", binding.Namespace, binding.Name))
+		return d.setBindingDriftCondition(ctx, binding, gitOpsDriftCheckFailedCondition(err), "")
+	}
+	if result == nil {
+		return nil
+	}
+	if len(result.files) > 0 {
+		log.Info(fmt.Sprintf("GitOps resources of SnapshotEnvironmentBinding %s/%s drifted: %s", binding.Namespace, binding.Name, strings.Join(result.files, ", ")))
+	}
+	return d.setBindingDriftCondition(ctx, binding, gitOpsDriftCondition(result), result.commitID)
+}
+
+// getBindingDrift renders the environment overlays of the SnapshotEnvironmentBinding's Components, over those in their GitOps
+// repository, the way the SnapshotEnvironmentBinding controller does, and compares them with the repository. It returns nil if none
+// of the Components' GitOps resources are generated.
+func (d *GitOpsDriftDetector) getBindingDrift(ctx context.Context, binding *appstudiov1alpha1.SnapshotEnvironmentBinding, environment *appstudiov1alpha1.Environment) (*gitOpsDriftResult, error) {
+	ghClient, err := d.GitHubTokenClient.GetNewGitHubClient("")
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, github.GHClientKey, ghClient.TokenName)
+
+	var appSnapshot appstudiov1alpha1.Snapshot
+	if err := d.Client.Get(ctx, types.NamespacedName{Namespace: binding.Namespace, Name: binding.Spec.Snapshot}, &appSnapshot); err != nil {
+		return nil, err
+	}
+
+	outputFormat, err := getGitOpsOutputFormat(ctx, d.Client, binding.Namespace, binding.Spec.Application)
+	if err != nil {
+		return nil, err
+	}
+
+	var bindingComponents []bindingComponentGitOps
+	var commitComponents []gitops.CommitComponent
+	var copied, compared []string
+	for _, component := range binding.Spec.Components {
+		bindingComponent, err := getBindingComponentGitOps(ctx, d.Client, binding, component, environment, &appSnapshot, ghClient, d.GitLabClient, d.PostProcessors)
+		if err != nil {
+			return nil, err
+		}
+		if bindingComponent == nil {
+			continue
+		}
+		bindingComponents = append(bindingComponents, *bindingComponent)
+		commitComponents = append(commitComponents, bindingComponent.commit)
+		copied = append(copied, filepath.Join("components", component.Name))
+		compared = append(compared, filepath.Join("components", component.Name, "overlays", binding.Spec.Environment))
+	}
+	if len(bindingComponents) == 0 {
+		return nil, nil
+	}
+
+	// As when they're generated, the Components' overlays are all in the repository of the first Component
+	heal := d.AutoHeal && isGitOpsGeneratedForGeneration(binding.Status.GitOpsRepoConditions, binding.Generation)
+	return d.checkDrift(ctx, bindingComponents[0].gitProvider, heal, gitOpsRender{
+		repositoryURL: bindingComponents[0].component.Status.GitOps.RepositoryURL,
+		remote:        bindingComponents[0].remote,
+		branch:        bindingComponents[0].branch,
+		context:       bindingComponents[0].context,
+		copied:        copied,
+		compared:      compared,
+		render:        renderBindingOverlays(bindingComponents, binding.Spec.Environment, outputFormat),
+	}, func() (string, error) {
+		return d.CommitMessages.BindingMessage(gitops.CommitMessageData{
+			Namespace:   binding.Namespace,
+			Application: binding.Spec.Application,
+			Components:  commitComponents,
+			Environment: binding.Spec.Environment,
+			Snapshot:    binding.Spec.Snapshot,
+			Reconciler:  gitOpsDriftDetectorName,
+			Resource:    binding.Name,
+			Generation:  binding.Generation,
+		})
+	})
+}
+
+// checkDrift clones the branch of the GitOps repository, renders the expected GitOps resources into an in-memory copy of it, and
+// returns the files that differ. If heal is true, the rendered resources are pushed over the drifted ones, in a commit with the
+// given message.
+func (d *GitOpsDriftDetector) checkDrift(ctx context.Context, gitProvider gitprovider.GitProvider, heal bool, render gitOpsRender, commitMessage func() (string, error)) (*gitOpsDriftResult, error) {
+	log := ctrl.LoggerFrom(ctx).WithName("gitops-drift-detector")
+	if heal && d.WriteQueue != nil {
+		// Hold the branch from the clone to the push of the heal, so that the Components' writes aren't pushed in between
+		unlock, err := d.WriteQueue.lockBranch(ctx, render.repositoryURL, render.branch)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	tempDir, err := ioutils.CreateTempPath("gitops-drift", d.AppFS)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temp directory for GitOps resources due to error: %v", err)
+	}
+	defer ioutils.RemoveFolderAndLogError(log, d.AppFS, tempDir)
+
+	expectedFs, repoPath, err := renderOverGitOpsRepository(gitOpsDriftDetectorName, gitProvider, d.Generator, d.AppFS, tempDir, render)
+	if err != nil {
+		return nil, err
+	}
+	gitOpsFolder := filepath.Join(repoPath, render.context)
+
+	result := &gitOpsDriftResult{}
+	for _, folder := range render.compared {
+		files, err := ioutils.DiffTrees(expectedFs, filepath.Join(renderedGitOpsFolder, folder), d.AppFS, filepath.Join(gitOpsFolder, folder))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			result.files = append(result.files, filepath.ToSlash(filepath.Join(render.context, folder, file)))
+		}
+	}
+	if len(result.files) == 0 || !heal {
+		return result, nil
+	}
+
+	// Replace the drifted folders with the rendered ones, and push them
+	for _, folder := range render.compared {
+		if err := d.AppFS.RemoveAll(filepath.Join(gitOpsFolder, folder)); err != nil {
+			return nil, err
+		}
+		if err := ioutils.CopyTree(expectedFs, filepath.Join(renderedGitOpsFolder, folder), d.AppFS, filepath.Join(gitOpsFolder, folder)); err != nil {
+			return nil, err
+		}
+	}
+	message, err := commitMessage()
+	if err != nil {
+		return nil, err
+	}
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": gitOpsDriftDetectorName, "tokenName": gitProvider.GetTokenName(), "operation": "CommitAndPush"}).Inc()
+	if err := d.Generator.CommitAndPush(tempDir, "", render.remote, gitOpsCloneName, render.branch, message); err != nil {
+		return nil, err
+	}
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": gitOpsDriftDetectorName, "tokenName": gitProvider.GetTokenName(), "operation": "GetCommitIDFromRepo"}).Inc()
+	if result.commitID, err = d.Generator.GetCommitIDFromRepo(d.AppFS, repoPath); err != nil {
+		return nil, err
+	}
+	log.Info(fmt.Sprintf("Healed the drift of GitOps repository %s in commit %s", render.remote, result.commitID))
+	return result, nil
+}
+
+// isGitOpsGeneratedForGeneration returns true if the GitOpsResourcesGenerated condition in the given conditions was set by the reconcile
+// of the given generation of the resource. Otherwise, the GitOps repository may not have caught up with the spec yet, and drift is only
+// reported, rather than healed with a spec that was never generated.
+func isGitOpsGeneratedForGeneration(conditions []metav1.Condition, generation int64) bool {
+	condition := meta.FindStatusCondition(conditions, "GitOpsResourcesGenerated")
+	return condition != nil && condition.ObservedGeneration == generation
+}
+
+// gitOpsCloneName is the folder that renderOverGitOpsRepository clones GitOps repositories into
+const gitOpsCloneName = "gitops"
+
+// renderOverGitOpsRepository clones the branch of the GitOps repository into tempDir, and renders the expected GitOps resources over
+// an in-memory copy of the copied folders of the clone, in the renderedGitOpsFolder of the returned filesystem. It also returns the
+// path of the clone.
+func renderOverGitOpsRepository(controllerName string, gitProvider gitprovider.GitProvider, generator gitopsgen.Generator, appFs afero.Afero, tempDir string, render gitOpsRender) (afero.Afero, string, error) {
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": controllerName, "tokenName": gitProvider.GetTokenName(), "operation": "CloneRepo"}).Inc()
+	if err := generator.CloneRepo(tempDir, render.remote, gitOpsCloneName, render.branch); err != nil {
+		return afero.Afero{}, "", err
+	}
+	repoPath := filepath.Join(tempDir, gitOpsCloneName)
+	gitOpsFolder := filepath.Join(repoPath, render.context)
+
+	// Render over a copy of the repository, so that the render sees what's already in it, as it does when it's generated
+	expectedFs := ioutils.NewMemoryFilesystem()
+	for _, folder := range render.copied {
+		if err := ioutils.CopyTree(appFs, filepath.Join(gitOpsFolder, folder), expectedFs, filepath.Join(renderedGitOpsFolder, folder)); err != nil {
+			return afero.Afero{}, "", err
+		}
+	}
+	if err := render.render(expectedFs, renderedGitOpsFolder); err != nil {
+		return afero.Afero{}, "", err
+	}
+	return expectedFs, repoPath, nil
+}
+
+// renderBindingOverlays returns the render of the environment overlays of the given Components of a SnapshotEnvironmentBinding, in the
+// given format
+func renderBindingOverlays(bindingComponents []bindingComponentGitOps, environmentName string, format gitops.OutputFormat) func(fs afero.Afero, gitOpsFolder string) error {
+	return func(fs afero.Afero, gitOpsFolder string) error {
+		for _, bindingComponent := range bindingComponents {
+			overlaysFolder := filepath.Join(gitOpsFolder, "components", bindingComponent.options.Name, "overlays", environmentName)
+			if err := gitops.RenderOverlays(fs, format, gitOpsFolder, overlaysFolder, bindingComponent.options, bindingComponent.overlay, bindingComponent.imageName, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// setComponentDriftCondition sets the GitOpsDrifted condition of the Component, along with the commit that healed the drift, if any
+func (d *GitOpsDriftDetector) setComponentDriftCondition(ctx context.Context, component *appstudiov1alpha1.Component, condition metav1.Condition, commitID string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var currentComponent appstudiov1alpha1.Component
+		if err := d.Client.Get(ctx, client.ObjectKeyFromObject(component), &currentComponent); err != nil {
+			return err
+		}
+		condition.ObservedGeneration = currentComponent.Generation
+		meta.SetStatusCondition(&currentComponent.Status.Conditions, condition)
+		if commitID != "" {
+			currentComponent.Status.GitOps.CommitID = commitID
+		}
+		return d.Client.Status().Update(ctx, &currentComponent)
+	})
+}
+
+// setBindingDriftCondition sets the GitOpsDrifted condition of the SnapshotEnvironmentBinding, along with the commit that healed the
+// drift, if any
+func (d *GitOpsDriftDetector) setBindingDriftCondition(ctx context.Context, binding *appstudiov1alpha1.SnapshotEnvironmentBinding, condition metav1.Condition, commitID string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var currentSEB appstudiov1alpha1.SnapshotEnvironmentBinding
+		if err := d.Client.Get(ctx, client.ObjectKeyFromObject(binding), &currentSEB); err != nil {
+			return err
+		}
+		condition.ObservedGeneration = currentSEB.Generation
+		meta.SetStatusCondition(&currentSEB.Status.GitOpsRepoConditions, condition)
+		if commitID != "" {
+			for i := range currentSEB.Status.Components {
+				currentSEB.Status.Components[i].GitOpsRepository.CommitID = commitID
+			}
+		}
+		return d.Client.Status().Update(ctx, &currentSEB)
+	})
+}
+
+// gitOpsDriftCondition returns the GitOpsDrifted condition for the result of a drift check
+func gitOpsDriftCondition(result *gitOpsDriftResult) metav1.Condition {
+	switch {
+	case len(result.files) == 0:
+		return metav1.Condition{
+			Type:    gitOpsDriftedConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "InSync",
+			Message: "GitOps repository matches the generated resources",
+		}
+	case result.commitID != "":
+		return metav1.Condition{
+			Type:    gitOpsDriftedConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Healed",
+			Message: fmt.Sprintf("GitOps repository drift reverted in commit %s: %s", result.commitID, listGitOpsDriftedFiles(result.files)),
+		}
+	default:
+		return metav1.Condition{
+			Type:    gitOpsDriftedConditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Drifted",
+			Message: fmt.Sprintf("GitOps repository differs from the generated resources: %s", listGitOpsDriftedFiles(result.files)),
+		}
+	}
+}
+
+// gitOpsDriftCheckFailedCondition returns the GitOpsDrifted condition for a drift check that failed
+func gitOpsDriftCheckFailedCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    gitOpsDriftedConditionType,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "CheckFailed",
+		Message: fmt.Sprintf("Unable to check the GitOps repository for drift: %v", err),
+	}
+}
+
+// listGitOpsDriftedFiles returns the comma separated list of the drifted files, up to maxGitOpsDriftedFiles of them
+func listGitOpsDriftedFiles(files []string) string {
+	if len(files) <= maxGitOpsDriftedFiles {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:maxGitOpsDriftedFiles], ", "), len(files)-maxGitOpsDriftedFiles)
+}
+
+// getComponentGitToken returns the token of the Component's Git secret, or an empty token if it doesn't have one
+func getComponentGitToken(ctx context.Context, c client.Client, component *appstudiov1alpha1.Component) (string, error) {
+	if component.Spec.Secret == "" {
+		return "", nil
+	}
+	var gitSecret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: component.Namespace, Name: component.Spec.Secret}, &gitSecret); err != nil {
+		return "", fmt.Errorf("unable to retrieve Git secret %v: %v", component.Spec.Secret, err)
+	}
+	return string(gitSecret.Data["password"]), nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGitOpsDriftCondition(t *testing.T) {
+	var manyFiles []string
+	for i := 0; i < maxGitOpsDriftedFiles+2; i++ {
+		manyFiles = append(manyFiles, fmt.Sprintf("components/backend/base/file-%02d.yaml", i))
+	}
+
+	tests := []struct {
+		name        string
+		result      *gitOpsDriftResult
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "In sync",
+			result:      &gitOpsDriftResult{},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  "InSync",
+			wantMessage: "GitOps repository matches the generated resources",
+		},
+		{
+			name:        "Drifted",
+			result:      &gitOpsDriftResult{files: []string{"components/backend/base/deployment.yaml", "components/backend/base/service.yaml"}},
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  "Drifted",
+			wantMessage: "GitOps repository differs from the generated resources: components/backend/base/deployment.yaml, components/backend/base/service.yaml",
+		},
+		{
+			name:       "Drifted, with more files than are listed",
+			result:     &gitOpsDriftResult{files: manyFiles},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: "Drifted",
+			wantMessage: "GitOps repository differs from the generated resources: components/backend/base/file-00.yaml, components/backend/base/file-01.yaml, " +
+				"components/backend/base/file-02.yaml, components/backend/base/file-03.yaml, components/backend/base/file-04.yaml, components/backend/base/file-05.yaml, " +
+				"components/backend/base/file-06.yaml, components/backend/base/file-07.yaml, components/backend/base/file-08.yaml, components/backend/base/file-09.yaml and 2 more",
+		},
+		{
+			name:        "Healed",
+			result:      &gitOpsDriftResult{files: []string{"components/backend/overlays/staging/deployment-patch.yaml"}, commitID: "ca82a6dff817ec66f44342007202690a93763949"},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  "Healed",
+			wantMessage: "GitOps repository drift reverted in commit ca82a6dff817ec66f44342007202690a93763949: components/backend/overlays/staging/deployment-patch.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition := gitOpsDriftCondition(tt.result)
+			if condition.Type != gitOpsDriftedConditionType || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("TestGitOpsDriftCondition() error: expected %v %v got %v %v", tt.wantStatus, tt.wantReason, condition.Status, condition.Reason)
+			}
+			if condition.Message != tt.wantMessage {
+				t.Errorf("TestGitOpsDriftCondition() error: expected message %q got %q", tt.wantMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestGitOpsDriftSkipsDryRun(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	utilruntime.Must(appstudiov1alpha1.AddToScheme(scheme))
+	generated := []metav1.Condition{{Type: "GitOpsResourcesGenerated", Status: metav1.ConditionTrue, Reason: "OK", ObservedGeneration: 2}}
+	component := &appstudiov1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default", Generation: 2, Annotations: map[string]string{gitops.DryRunAnnotation: "true"}},
+		Spec:       appstudiov1alpha1.ComponentSpec{ComponentName: "backend", Application: "test-application"},
+		Status: appstudiov1alpha1.ComponentStatus{
+			Devfile:    "schemaVersion: 2.2.0",
+			GitOps:     appstudiov1alpha1.GitOpsStatus{RepositoryURL: "https://github.com/redhat-appstudio-appdata/test-application-repo"},
+			Conditions: generated,
+		},
+	}
+	binding := &appstudiov1alpha1.SnapshotEnvironmentBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-binding", Namespace: "default", Generation: 2, Annotations: map[string]string{gitops.DryRunAnnotation: gitops.DryRunModeDiff}},
+		Spec:       appstudiov1alpha1.SnapshotEnvironmentBindingSpec{Application: "test-application", Environment: "staging", Snapshot: "test-snapshot"},
+		Status: appstudiov1alpha1.SnapshotEnvironmentBindingStatus{
+			Components:           []appstudiov1alpha1.BindingComponentStatus{{Name: "backend"}},
+			GitOpsRepoConditions: generated,
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(component, binding).Build()
+	appFS := ioutils.NewMemoryFilesystem()
+	generator := &recordingGenerator{appFS: appFS}
+	detector := &GitOpsDriftDetector{Client: fakeClient, AppFS: appFS, Generator: generator, AutoHeal: true}
+
+	// The previewed spec of a resource in dry run mode is never pushed by auto-heal, nor even checked
+	if err := detector.checkComponent(ctx, component); err != nil {
+		t.Fatalf("TestGitOpsDriftSkipsDryRun() unexpected error: %v", err)
+	}
+	if err := detector.checkBinding(ctx, binding); err != nil {
+		t.Fatalf("TestGitOpsDriftSkipsDryRun() unexpected error: %v", err)
+	}
+	if generator.clones != 0 || len(generator.pushedRemotes) != 0 {
+		t.Errorf("TestGitOpsDriftSkipsDryRun() error: expected no clones or pushes, got %v clones and pushes %v", generator.clones, generator.pushedRemotes)
+	}
+
+	var currentComponent appstudiov1alpha1.Component
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(component), &currentComponent); err != nil {
+		t.Fatalf("TestGitOpsDriftSkipsDryRun() unexpected error: %v", err)
+	}
+	var currentBinding appstudiov1alpha1.SnapshotEnvironmentBinding
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(binding), &currentBinding); err != nil {
+		t.Fatalf("TestGitOpsDriftSkipsDryRun() unexpected error: %v", err)
+	}
+	if meta.FindStatusCondition(currentComponent.Status.Conditions, gitOpsDriftedConditionType) != nil ||
+		meta.FindStatusCondition(currentBinding.Status.GitOpsRepoConditions, gitOpsDriftedConditionType) != nil {
+		t.Errorf("TestGitOpsDriftSkipsDryRun() error: expected no GitOpsDrifted conditions, got %v and %v", currentComponent.Status.Conditions, currentBinding.Status.GitOpsRepoConditions)
+	}
+}
+
+func TestIsGitOpsGeneratedForGeneration(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []metav1.Condition
+		want       bool
+	}{
+		{
+			name: "Generated for the current generation",
+			conditions: []metav1.Condition{
+				{Type: "GitOpsResourcesGenerated", Status: metav1.ConditionTrue, ObservedGeneration: 3},
+			},
+			want: true,
+		},
+		{
+			name: "Generated for an earlier generation",
+			conditions: []metav1.Condition{
+				{Type: "GitOpsResourcesGenerated", Status: metav1.ConditionTrue, ObservedGeneration: 2},
+			},
+		},
+		{
+			name: "Not generated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGitOpsGeneratedForGeneration(tt.conditions, 3); got != tt.want {
+				t.Errorf("TestIsGitOpsGeneratedForGeneration() error: expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
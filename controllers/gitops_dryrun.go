@@ -0,0 +1,283 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// gitOpsDryRunConditionType is the condition recording the last dry run of a resource's GitOps resources. Its message names the
+	// ConfigMap that the result is stored in.
+	gitOpsDryRunConditionType = "GitOpsDryRun"
+
+	// gitOpsDryRunLabel labels the ConfigMaps that dry runs are stored in. ConfigMaps without it are never overwritten.
+	gitOpsDryRunLabel = "appstudio.gitops-dry-run"
+
+	// gitOpsDryRunDiffKey is the key of the ConfigMap of a dry run in diff mode that holds the diff
+	gitOpsDryRunDiffKey = "diff"
+
+	// maxGitOpsDryRunDataSize is the size of the data stored in the ConfigMap of a dry run, below the 1 MiB limit of Kubernetes objects
+	// to leave room for its metadata
+	maxGitOpsDryRunDataSize = 1000 * 1000
+)
+
+// gitOpsDryRunReasons are the reasons of the GitOpsDryRun condition, for each dry run mode, and gitOpsDryRunTruncatedReasons are those
+// of dry runs that didn't fit in their ConfigMap
+var (
+	gitOpsDryRunReasons = map[string]string{
+		gitops.DryRunModeRender: "Rendered",
+		gitops.DryRunModeDiff:   "Diffed",
+	}
+	gitOpsDryRunTruncatedReasons = map[string]string{
+		gitops.DryRunModeRender: "RenderTruncated",
+		gitops.DryRunModeDiff:   "DiffTruncated",
+	}
+)
+
+// gitOpsDryRun stores the GitOps resources of a resource, or their diff against its GitOps repository, in a ConfigMap owned by the
+// resource, rather than pushing them
+type gitOpsDryRun struct {
+	client    client.Client
+	scheme    *runtime.Scheme
+	appFS     afero.Afero
+	generator gitopsgen.Generator
+
+	// controllerName labels the git requests of the dry run
+	controllerName string
+}
+
+// run renders the GitOps resources in the given dry run mode, stores them in the named ConfigMap, owned by owner, and returns the
+// GitOpsDryRun condition to set on the owner
+func (d gitOpsDryRun) run(ctx context.Context, gitProvider gitprovider.GitProvider, owner client.Object, configMapName string, mode string, render gitOpsRender) (metav1.Condition, error) {
+	var data map[string]string
+	var err error
+	if mode == gitops.DryRunModeDiff {
+		data, err = d.diff(ctx, gitProvider, render)
+	} else {
+		data, err = renderGitOpsDryRun(render)
+	}
+	if err != nil {
+		return metav1.Condition{}, err
+	}
+	truncated := limitGitOpsDryRunData(data)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: owner.GetNamespace(),
+		},
+	}
+	if err := d.client.Get(ctx, client.ObjectKeyFromObject(configMap), configMap); err != nil && !errors.IsNotFound(err) {
+		return metav1.Condition{}, err
+	}
+	// Only overwrite the ConfigMaps of previous dry runs of the owner
+	if configMap.ResourceVersion != "" && (configMap.Labels[gitOpsDryRunLabel] != "true" || !metav1.IsControlledBy(configMap, owner)) {
+		return metav1.Condition{}, fmt.Errorf("unable to store the GitOps dry run in ConfigMap %s, as it wasn't created by a dry run of %s", configMapName, owner.GetName())
+	}
+	if configMap.Labels == nil {
+		configMap.Labels = make(map[string]string)
+	}
+	configMap.Labels[gitOpsDryRunLabel] = "true"
+	configMap.Data = data
+	if err := controllerutil.SetControllerReference(owner, configMap, d.scheme); err != nil {
+		return metav1.Condition{}, err
+	}
+	if configMap.ResourceVersion == "" {
+		err = d.client.Create(ctx, configMap)
+	} else {
+		err = d.client.Update(ctx, configMap)
+	}
+	if err != nil {
+		return metav1.Condition{}, fmt.Errorf("unable to store the GitOps dry run in ConfigMap %s: %v", configMapName, err)
+	}
+
+	condition := metav1.Condition{
+		Type:               gitOpsDryRunConditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             gitOpsDryRunReasons[mode],
+		Message:            fmt.Sprintf("GitOps resources were not pushed, the dry run is stored in ConfigMap %s", configMapName),
+		ObservedGeneration: owner.GetGeneration(),
+	}
+	if truncated {
+		condition.Reason = gitOpsDryRunTruncatedReasons[mode]
+		condition.Message = fmt.Sprintf("GitOps resources were not pushed, the dry run is stored in ConfigMap %s, truncated to %d bytes to fit in it", configMapName, maxGitOpsDryRunDataSize)
+	}
+	return condition, nil
+}
+
+// diff clones the GitOps repository, renders the GitOps resources over it, and returns the unified diff of the compared folders
+func (d gitOpsDryRun) diff(ctx context.Context, gitProvider gitprovider.GitProvider, render gitOpsRender) (map[string]string, error) {
+	log := ctrl.LoggerFrom(ctx)
+	tempDir, err := ioutils.CreateTempPath("gitops-dry-run", d.appFS)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temp directory for GitOps resources due to error: %v", err)
+	}
+	defer ioutils.RemoveFolderAndLogError(log, d.appFS, tempDir)
+
+	renderedFs, repoPath, err := renderOverGitOpsRepository(d.controllerName, gitProvider, d.generator, d.appFS, tempDir, render)
+	if err != nil {
+		return nil, err
+	}
+	repoFiles, err := readGitOpsFolders(d.appFS, filepath.Join(repoPath, render.context), render.context, render.compared)
+	if err != nil {
+		return nil, err
+	}
+	renderedFiles, err := readGitOpsFolders(renderedFs, renderedGitOpsFolder, render.context, render.compared)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := gitops.UnifiedDiff(repoFiles, renderedFiles)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{gitOpsDryRunDiffKey: diff}, nil
+}
+
+// renderGitOpsDryRun renders the GitOps resources into an in-memory filesystem, and returns the files of the compared folders, keyed
+// by gitOpsDryRunConfigMapKey
+func renderGitOpsDryRun(render gitOpsRender) (map[string]string, error) {
+	fs := ioutils.NewMemoryFilesystem()
+	if err := render.render(fs, renderedGitOpsFolder); err != nil {
+		return nil, err
+	}
+	files, err := readGitOpsFolders(fs, renderedGitOpsFolder, render.context, render.compared)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string)
+	for file, content := range files {
+		data[gitOpsDryRunConfigMapKey(file)] = string(content)
+	}
+	return data, nil
+}
+
+// readGitOpsFolders returns the files of the given folders of the GitOps folder, keyed by their slash separated paths, relative to
+// the root of the GitOps repository
+func readGitOpsFolders(fs afero.Afero, gitOpsFolder string, context string, folders []string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, folder := range folders {
+		tree, err := ioutils.ReadTree(fs, filepath.Join(gitOpsFolder, folder))
+		if err != nil {
+			return nil, err
+		}
+		for file, content := range tree {
+			files[path.Join(filepath.ToSlash(context), filepath.ToSlash(folder), file)] = content
+		}
+	}
+	return files, nil
+}
+
+// gitOpsDryRunConfigMapKey returns the key of the given file in the ConfigMap of a dry run. ConfigMap keys can't contain slashes,
+// so they're replaced with underscores.
+func gitOpsDryRunConfigMapKey(file string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(file, "/"), "/", "_")
+}
+
+// limitGitOpsDryRunData keeps the data of a dry run within maxGitOpsDryRunDataSize, so that it fits in a ConfigMap. Files are kept in
+// order of their keys, and those that don't fit are left out, while a diff is cut after the last line that fits. It returns true if
+// any of the data was left out.
+func limitGitOpsDryRunData(data map[string]string) bool {
+	var keys []string
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	size := 0
+	truncated := false
+	for _, key := range keys {
+		value := data[key]
+		if size+len(key)+len(value) <= maxGitOpsDryRunDataSize {
+			size += len(key) + len(value)
+			continue
+		}
+		truncated = true
+		if key != gitOpsDryRunDiffKey || size+len(key) >= maxGitOpsDryRunDataSize {
+			delete(data, key)
+			continue
+		}
+		value = value[:maxGitOpsDryRunDataSize-size-len(key)]
+		value = value[:strings.LastIndex(value, "\n")+1]
+		data[key] = value
+		size += len(key) + len(value)
+	}
+	return truncated
+}
+
+// gitOpsDryRunFailedCondition returns the GitOpsDryRun condition for a dry run that failed
+func gitOpsDryRunFailedCondition(err error, observedGeneration int64) metav1.Condition {
+	return metav1.Condition{
+		Type:               gitOpsDryRunConditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             "DryRunError",
+		Message:            fmt.Sprintf("GitOps dry run failed: %v", err),
+		ObservedGeneration: observedGeneration,
+	}
+}
+
+// isGitOpsDryRunChanged returns true if the dry run mode set in the given annotations differs from the last dry run recorded in the
+// given conditions, including when a dry run was enabled or disabled
+func isGitOpsDryRunChanged(annotations map[string]string, conditions []metav1.Condition) bool {
+	mode := gitops.GetDryRunMode(annotations)
+	condition := meta.FindStatusCondition(conditions, gitOpsDryRunConditionType)
+	if mode == "" {
+		return condition != nil
+	}
+	return condition == nil || (condition.Reason != gitOpsDryRunReasons[mode] && condition.Reason != gitOpsDryRunTruncatedReasons[mode])
+}
+
+// copyGitOpsDryRunCondition copies the GitOpsDryRun condition between the given conditions, removing it if it isn't set
+func copyGitOpsDryRunCondition(from []metav1.Condition, to *[]metav1.Condition) {
+	if condition := meta.FindStatusCondition(from, gitOpsDryRunConditionType); condition != nil {
+		meta.SetStatusCondition(to, *condition)
+	} else {
+		meta.RemoveStatusCondition(to, gitOpsDryRunConditionType)
+	}
+}
+
+// gitOpsDryRunChangedPredicate triggers a reconcile when the dry run annotation of a resource is changed, as annotations don't change
+// its generation
+var gitOpsDryRunChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if e.ObjectOld == nil || e.ObjectNew == nil {
+			return false
+		}
+		return e.ObjectOld.GetAnnotations()[gitops.DryRunAnnotation] != e.ObjectNew.GetAnnotations()[gitops.DryRunAnnotation]
+	},
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/redhat-appstudio/application-service/gitops"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsGitOpsDryRunChanged(t *testing.T) {
+	rendered := []metav1.Condition{{Type: gitOpsDryRunConditionType, Status: metav1.ConditionTrue, Reason: "Rendered"}}
+	renderTruncated := []metav1.Condition{{Type: gitOpsDryRunConditionType, Status: metav1.ConditionTrue, Reason: "RenderTruncated"}}
+	failed := []metav1.Condition{{Type: gitOpsDryRunConditionType, Status: metav1.ConditionFalse, Reason: "DryRunError"}}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		conditions  []metav1.Condition
+		want        bool
+	}{
+		{
+			name: "No dry run",
+		},
+		{
+			name:        "Dry run enabled",
+			annotations: map[string]string{gitops.DryRunAnnotation: "true"},
+			want:        true,
+		},
+		{
+			name:       "Dry run disabled",
+			conditions: rendered,
+			want:       true,
+		},
+		{
+			name:        "Same dry run mode",
+			annotations: map[string]string{gitops.DryRunAnnotation: "render"},
+			conditions:  rendered,
+		},
+		{
+			name:        "Same dry run mode, truncated",
+			annotations: map[string]string{gitops.DryRunAnnotation: "render"},
+			conditions:  renderTruncated,
+		},
+		{
+			name:        "Dry run mode changed",
+			annotations: map[string]string{gitops.DryRunAnnotation: "diff"},
+			conditions:  rendered,
+			want:        true,
+		},
+		{
+			name:        "Failed dry run",
+			annotations: map[string]string{gitops.DryRunAnnotation: "render"},
+			conditions:  failed,
+			want:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isGitOpsDryRunChanged(tt.annotations, tt.conditions); got != tt.want {
+				t.Errorf("TestIsGitOpsDryRunChanged() error: expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLimitGitOpsDryRunData(t *testing.T) {
+	large := strings.Repeat("x", maxGitOpsDryRunDataSize/2)
+	diffLines := strings.Repeat(strings.Repeat("+", 99)+"\n", maxGitOpsDryRunDataSize/100+1)
+
+	tests := []struct {
+		name          string
+		data          map[string]string
+		wantKeys      []string
+		wantTruncated bool
+	}{
+		{
+			name:     "Data that fits",
+			data:     map[string]string{"components_backend_base_deployment.yaml": "kind: Deployment\n", "components_backend_base_service.yaml": "kind: Service\n"},
+			wantKeys: []string{"components_backend_base_deployment.yaml", "components_backend_base_service.yaml"},
+		},
+		{
+			name:          "Files that don't fit",
+			data:          map[string]string{"a.yaml": large, "b.yaml": large, "c.yaml": "kind: Service\n"},
+			wantKeys:      []string{"a.yaml", "c.yaml"},
+			wantTruncated: true,
+		},
+		{
+			name:          "Diff that doesn't fit",
+			data:          map[string]string{gitOpsDryRunDiffKey: diffLines},
+			wantKeys:      []string{gitOpsDryRunDiffKey},
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated := limitGitOpsDryRunData(tt.data)
+			if truncated != tt.wantTruncated {
+				t.Errorf("TestLimitGitOpsDryRunData() error: expected truncated %v got %v", tt.wantTruncated, truncated)
+			}
+			var keys []string
+			size := 0
+			for key, value := range tt.data {
+				keys = append(keys, key)
+				size += len(key) + len(value)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("TestLimitGitOpsDryRunData() error: expected keys %v got %v", tt.wantKeys, keys)
+			}
+			if size > maxGitOpsDryRunDataSize {
+				t.Errorf("TestLimitGitOpsDryRunData() error: expected at most %d bytes got %d", maxGitOpsDryRunDataSize, size)
+			}
+			if diff, ok := tt.data[gitOpsDryRunDiffKey]; ok && !strings.HasSuffix(diff, "\n") {
+				t.Errorf("TestLimitGitOpsDryRunData() error: expected the diff to be cut after a line")
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getGitOpsOutputFormat returns the format that the GitOps resources of the given Application's Components are rendered in, as set by
+// the output format annotation on the Application
+func getGitOpsOutputFormat(ctx context.Context, c client.Client, namespace string, applicationName string) (gitops.OutputFormat, error) {
+	var application appstudiov1alpha1.Application
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: applicationName}, &application); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	return gitops.GetOutputFormat(application.GetAnnotations())
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// gitOpsResourcesHashAnnotation is the annotation recording the hash of the GitOps resources last pushed for a resource,
+	// prefixed with "sha256:"
+	gitOpsResourcesHashAnnotation = "gitOpsResourcesHash"
+
+	// renderedGitOpsFolder is the folder of the in-memory filesystem that GitOps resources are rendered in, to be hashed
+	renderedGitOpsFolder = "/gitops"
+)
+
+// hashGitOpsResources renders GitOps resources into an in-memory filesystem, with the given render function, and returns the hash of
+// the tree under outputFolder. The hash also covers the given target, such as the repository and branch that the resources are pushed
+// to, so that the resources are pushed again if they move.
+func hashGitOpsResources(target []string, outputFolder string, render func(fs afero.Afero, gitOpsFolder string) error) (string, error) {
+	fs := ioutils.NewMemoryFilesystem()
+	if err := render(fs, renderedGitOpsFolder); err != nil {
+		return "", err
+	}
+	treeHash, err := ioutils.HashTree(fs, outputFolder)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(strings.Join(append(target, treeHash), "\x00")))
+	return "sha256:" + hex.EncodeToString(hash[:]), nil
+}
+
+// isGitOpsResourcesUnchanged returns true if the given object records that GitOps resources with the given hash were successfully
+// pushed, according to its conditions, in which case there's nothing to push
+func isGitOpsResourcesUnchanged(obj metav1.Object, conditions []metav1.Condition, hash string) bool {
+	return hash != "" && obj.GetAnnotations()[gitOpsResourcesHashAnnotation] == hash &&
+		meta.IsStatusConditionTrue(conditions, "GitOpsResourcesGenerated")
+}
+
+// recordGitOpsResourcesHash patches the gitOpsResourcesHash annotation of the given object to the hash of the GitOps resources that
+// were pushed, or removes it if the hash is empty. Only the annotation is patched, so the status of the object isn't overwritten.
+func recordGitOpsResourcesHash(ctx context.Context, cl client.Client, obj client.Object, hash string) error {
+	if obj.GetAnnotations()[gitOpsResourcesHashAnnotation] == hash {
+		return nil
+	}
+	patched := obj.DeepCopyObject().(client.Object)
+	annotations := patched.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	if hash == "" {
+		delete(annotations, gitOpsResourcesHashAnnotation)
+	} else {
+		annotations[gitOpsResourcesHashAnnotation] = hash
+	}
+	patched.SetAnnotations(annotations)
+	if err := cl.Patch(ctx, patched, client.MergeFrom(obj)); err != nil {
+		return err
+	}
+	obj.SetAnnotations(patched.GetAnnotations())
+	obj.SetResourceVersion(patched.GetResourceVersion())
+	return nil
+}
+
+// latestGitOpsCommitID returns the latest commit of the given branch of the GitOps repository, to refresh the commit ID of resources
+// whose GitOps resources didn't need to be pushed
+func latestGitOpsCommitID(ctx context.Context, controllerName string, gitProvider gitprovider.GitProvider, repoURL string, branch string) (string, error) {
+	repoName, orgName, err := gitProvider.GetRepoAndOrgFromURL(repoURL)
+	if err != nil {
+		return "", err
+	}
+	metricsLabel := prometheus.Labels{"controller": controllerName, "tokenName": gitProvider.GetTokenName(), "operation": "GetLatestCommitSHAFromRepository"}
+	metrics.ControllerGitRequest.With(metricsLabel).Inc()
+	commitID, err := gitProvider.GetLatestCommitSHAFromRepository(ctx, repoName, orgName, branch)
+	metrics.HandleRateLimitMetrics(err, metricsLabel)
+	if err != nil {
+		return "", fmt.Errorf("unable to get the latest commit of branch %s of GitOps repository %s: %w", branch, repoURL, err)
+	}
+	return commitID, nil
+}
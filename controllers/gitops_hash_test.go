@@ -0,0 +1,218 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
+	gitopsgenv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestHashBindingGitOpsResources(t *testing.T) {
+	component := &appstudiov1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-component", Namespace: "default"},
+		Status: appstudiov1alpha1.ComponentStatus{
+			GitOps: appstudiov1alpha1.GitOpsStatus{RepositoryURL: "https://github.com/redhat-appstudio-appdata/test-application-repo", CommitID: "ca82a6dff817ec66f44342007202690a93763949"},
+		},
+	}
+	bindingComponent := bindingComponentGitOps{
+		component: component,
+		options:   gitopsgenv1alpha1.GeneratorOptions{Name: "test-component", Replicas: 1},
+		imageName: "quay.io/test/test-component:v1",
+		branch:    "main",
+		context:   "/",
+	}
+	baseHash, err := hashBindingGitOpsResources([]bindingComponentGitOps{bindingComponent}, "staging", gitops.Kustomize{})
+	if err != nil {
+		t.Fatalf("TestHashBindingGitOpsResources() unexpected error: %v", err)
+	}
+
+	otherImage := bindingComponent
+	otherImage.imageName = "quay.io/test/test-component:v2"
+	otherReplicas := bindingComponent
+	otherReplicas.options.Replicas = 2
+	otherBase := bindingComponent
+	otherBase.component = component.DeepCopy()
+	otherBase.component.Status.GitOps.CommitID = "0a93763949ca82a6dff817ec66f44342007202690"
+	otherBaseHash := bindingComponent
+	otherBaseHash.component = component.DeepCopy()
+	otherBaseHash.component.Annotations = map[string]string{gitOpsResourcesHashAnnotation: "sha256:0a93763949ca82a6dff817ec66f44342007202690"}
+
+	tests := []struct {
+		name              string
+		bindingComponents []bindingComponentGitOps
+		environmentName   string
+		wantSame          bool
+	}{
+		{
+			name:              "Nothing changed",
+			bindingComponents: []bindingComponentGitOps{bindingComponent},
+			environmentName:   "staging",
+			wantSame:          true,
+		},
+		{
+			name:              "New image",
+			bindingComponents: []bindingComponentGitOps{otherImage},
+			environmentName:   "staging",
+		},
+		{
+			name:              "Overlay changed",
+			bindingComponents: []bindingComponentGitOps{otherReplicas},
+			environmentName:   "staging",
+		},
+		{
+			name:              "Base resources changed",
+			bindingComponents: []bindingComponentGitOps{otherBase},
+			environmentName:   "staging",
+		},
+		{
+			name:              "Base resources hash changed",
+			bindingComponents: []bindingComponentGitOps{otherBaseHash},
+			environmentName:   "staging",
+		},
+		{
+			name:              "Other Environment",
+			bindingComponents: []bindingComponentGitOps{bindingComponent},
+			environmentName:   "production",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := hashBindingGitOpsResources(tt.bindingComponents, tt.environmentName, gitops.Kustomize{})
+			if err != nil {
+				t.Fatalf("TestHashBindingGitOpsResources() unexpected error: %v", err)
+			}
+			if (hash == baseHash) != tt.wantSame {
+				t.Errorf("TestHashBindingGitOpsResources() error: expected the hash to be the same: %v", tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestIsGitOpsResourcesUnchanged(t *testing.T) {
+	hash := "sha256:0a93763949ca82a6dff817ec66f44342007202690"
+	generated := metav1.Condition{Type: "GitOpsResourcesGenerated", Status: metav1.ConditionTrue, Reason: "OK"}
+	failed := metav1.Condition{Type: "GitOpsResourcesGenerated", Status: metav1.ConditionFalse, Reason: "GenerateError"}
+
+	tests := []struct {
+		name         string
+		conditions   []metav1.Condition
+		recordedHash string
+		hash         string
+		want         bool
+	}{
+		{
+			name:         "Same hash was pushed",
+			conditions:   []metav1.Condition{generated},
+			recordedHash: hash,
+			hash:         hash,
+			want:         true,
+		},
+		{
+			name:         "Different hash was pushed",
+			conditions:   []metav1.Condition{generated},
+			recordedHash: hash,
+			hash:         "sha256:ca82a6dff817ec66f44342007202690a93763949",
+		},
+		{
+			name:         "Last generation failed",
+			conditions:   []metav1.Condition{failed},
+			recordedHash: hash,
+			hash:         hash,
+		},
+		{
+			name:         "Hash unknown",
+			conditions:   []metav1.Condition{generated},
+			recordedHash: hash,
+		},
+		{
+			name:       "Hash removed",
+			conditions: []metav1.Condition{generated},
+			hash:       hash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			component := &appstudiov1alpha1.Component{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-component", Namespace: "default", Annotations: map[string]string{gitOpsResourcesHashAnnotation: hash}},
+				Status:     appstudiov1alpha1.ComponentStatus{Conditions: tt.conditions},
+			}
+			fakeClient := NewFakeClient(t, component.DeepCopy())
+
+			if err := recordGitOpsResourcesHash(ctx, fakeClient, component, tt.recordedHash); err != nil {
+				t.Fatalf("TestIsGitOpsResourcesUnchanged() unexpected error: %v", err)
+			}
+			if got := isGitOpsResourcesUnchanged(component, component.Status.Conditions, tt.hash); got != tt.want {
+				t.Errorf("TestIsGitOpsResourcesUnchanged() error: expected %v got %v", tt.want, got)
+			}
+			var stored appstudiov1alpha1.Component
+			if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(component), &stored); err != nil {
+				t.Fatalf("TestIsGitOpsResourcesUnchanged() unexpected error: %v", err)
+			}
+			if stored.Annotations[gitOpsResourcesHashAnnotation] != tt.recordedHash {
+				t.Errorf("TestIsGitOpsResourcesUnchanged() error: expected the hash %q to be recorded, got %q", tt.recordedHash, stored.Annotations[gitOpsResourcesHashAnnotation])
+			}
+			if !reflect.DeepEqual(component.Status.Conditions, tt.conditions) {
+				t.Errorf("TestIsGitOpsResourcesUnchanged() error: expected the status to be left as it was, got %v", component.Status.Conditions)
+			}
+		})
+	}
+}
+
+func TestLatestGitOpsCommitID(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoURL string
+		wantErr bool
+	}{
+		{
+			name:    "GitHub repository",
+			repoURL: "https://github.com/redhat-appstudio-appdata/test-application-repo",
+		},
+		{
+			name:    "Repository in a GitLab subgroup",
+			repoURL: "https://gitlab.com/appdata-group/gitops/test-application-repo",
+		},
+		{
+			name:    "Invalid repository URL",
+			repoURL: "https://github.com/test-application-repo",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitProvider := &fakeGitProvider{latestCommit: "ca82a6dff817ec66f44342007202690a93763949"}
+			commitID, err := latestGitOpsCommitID(context.Background(), componentName, gitProvider, tt.repoURL, "main")
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestLatestGitOpsCommitID() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && commitID != gitProvider.latestCommit {
+				t.Errorf("TestLatestGitOpsCommitID() error: expected %v got %v", gitProvider.latestCommit, commitID)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+)
+
+// gitOpsRepositoryProvider returns the client of the Git provider that hosts the given GitOps repository, whose credentials are
+// used to clone and push it: the GitLab client, if one is configured and the repository is on its GitLab instance, or otherwise
+// the GitHub client
+func gitOpsRepositoryProvider(ghClient *github.GitHubClient, gitLabClient gitprovider.GitProvider, repoURL string) gitprovider.GitProvider {
+	if gitLabClient != nil && gitLabClient.HostsRepository(repoURL) {
+		return gitLabClient
+	}
+	return ghClient
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// gitOpsPullRequestConditionType is the condition recording the pull request that a resource's GitOps changes were opened in.
+	// Its reason is the state of the pull request, and its message the pull request's URL
+	gitOpsPullRequestConditionType = "GitOpsPullRequest"
+
+	// gitOpsPullRequestPollInterval is how often the state of an open GitOps pull request is checked
+	gitOpsPullRequestPollInterval = time.Minute
+)
+
+// gitOpsPullRequestReasons maps the state of a pull request to the reason of the GitOpsPullRequest condition
+var gitOpsPullRequestReasons = map[string]string{
+	gitprovider.PullRequestStateOpen:   "Open",
+	gitprovider.PullRequestStateMerged: "Merged",
+	gitprovider.PullRequestStateClosed: "Closed",
+}
+
+// isPullRequestModeEnabled returns true if the GitOps changes of the given Application should be opened as pull requests, rather than
+// pushed directly to its GitOps branch. Pull request mode is enabled by an annotation on the Application, or on any of the other given
+// resources, such as the Environment that the changes are for.
+func isPullRequestModeEnabled(ctx context.Context, c client.Client, namespace string, applicationName string, objs ...client.Object) (bool, error) {
+	var application appstudiov1alpha1.Application
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: applicationName}, &application); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	annotations := []map[string]string{application.GetAnnotations()}
+	for _, obj := range objs {
+		annotations = append(annotations, obj.GetAnnotations())
+	}
+	return gitprovider.IsPullRequestModeEnabled(annotations...), nil
+}
+
+// syncGitOpsPullRequest opens (or updates) the pull request for the changes pushed to the work branch of the given GitOps repository.
+// If the work branch has no changes to review, no pull request is returned, along with the latest commit of the base branch instead.
+func syncGitOpsPullRequest(ctx context.Context, controllerName string, gitProvider gitprovider.GitProvider, repoURL string, opts gitprovider.PullRequestOptions) (*gitprovider.PullRequest, string, error) {
+	repoName, orgName, err := gitProvider.GetRepoAndOrgFromURL(repoURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	metricsLabel := prometheus.Labels{"controller": controllerName, "tokenName": gitProvider.GetTokenName(), "operation": "CreateOrUpdatePullRequest"}
+	metrics.ControllerGitRequest.With(metricsLabel).Inc()
+	pullRequest, err := gitProvider.CreateOrUpdatePullRequest(ctx, orgName, repoName, opts)
+	metrics.HandleRateLimitMetrics(err, metricsLabel)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to open a pull request from %s to %s in GitOps repository %s: %w", opts.HeadBranch, opts.BaseBranch, repoURL, err)
+	}
+	if pullRequest != nil {
+		return pullRequest, "", nil
+	}
+
+	commitID, err := latestGitOpsCommitID(ctx, controllerName, gitProvider, repoURL, opts.BaseBranch)
+	if err != nil {
+		return nil, "", err
+	}
+	return nil, commitID, nil
+}
+
+// resetGitOpsWorkBranch points the work branch of the given GitOps repository at the latest commit of its base branch, so that the
+// changes of a pull request that was merged or closed aren't carried over to the next one
+func resetGitOpsWorkBranch(ctx context.Context, controllerName string, gitProvider gitprovider.GitProvider, repoURL string, workBranch string, baseBranch string) error {
+	repoName, orgName, err := gitProvider.GetRepoAndOrgFromURL(repoURL)
+	if err != nil {
+		return err
+	}
+
+	metricsLabel := prometheus.Labels{"controller": controllerName, "tokenName": gitProvider.GetTokenName(), "operation": "ResetBranch"}
+	metrics.ControllerGitRequest.With(metricsLabel).Inc()
+	err = gitProvider.ResetBranch(ctx, orgName, repoName, workBranch, baseBranch)
+	metrics.HandleRateLimitMetrics(err, metricsLabel)
+	if err != nil {
+		return fmt.Errorf("unable to reset branch %s to %s in GitOps repository %s: %w", workBranch, baseBranch, repoURL, err)
+	}
+	return nil
+}
+
+// refreshGitOpsPullRequest retrieves the current state of the open pull request recorded in the given conditions, and updates its condition
+func refreshGitOpsPullRequest(ctx context.Context, controllerName string, gitProvider gitprovider.GitProvider, repoURL string, conditions *[]metav1.Condition) (*gitprovider.PullRequest, error) {
+	condition := meta.FindStatusCondition(*conditions, gitOpsPullRequestConditionType)
+	if condition == nil {
+		return nil, fmt.Errorf("no GitOps pull request is recorded")
+	}
+	number, err := gitprovider.PullRequestNumberFromURL(condition.Message)
+	if err != nil {
+		return nil, err
+	}
+	repoName, orgName, err := gitProvider.GetRepoAndOrgFromURL(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	metricsLabel := prometheus.Labels{"controller": controllerName, "tokenName": gitProvider.GetTokenName(), "operation": "GetPullRequest"}
+	metrics.ControllerGitRequest.With(metricsLabel).Inc()
+	pullRequest, err := gitProvider.GetPullRequest(ctx, orgName, repoName, number)
+	metrics.HandleRateLimitMetrics(err, metricsLabel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pull request %s: %w", condition.Message, err)
+	}
+	recordGitOpsPullRequest(conditions, pullRequest, condition.ObservedGeneration)
+	return pullRequest, nil
+}
+
+// recordGitOpsPullRequest sets the GitOpsPullRequest condition to the given pull request. If there's no pull request, because there were
+// no changes to review, the condition of any pull request that wasn't merged is removed.
+func recordGitOpsPullRequest(conditions *[]metav1.Condition, pullRequest *gitprovider.PullRequest, observedGeneration int64) {
+	if pullRequest == nil {
+		if condition := meta.FindStatusCondition(*conditions, gitOpsPullRequestConditionType); condition != nil && condition.Status != metav1.ConditionTrue {
+			meta.RemoveStatusCondition(conditions, gitOpsPullRequestConditionType)
+		}
+		return
+	}
+	status := metav1.ConditionFalse
+	if pullRequest.State == gitprovider.PullRequestStateMerged {
+		status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               gitOpsPullRequestConditionType,
+		Status:             status,
+		Reason:             gitOpsPullRequestReasons[pullRequest.State],
+		Message:            pullRequest.URL,
+		ObservedGeneration: observedGeneration,
+	})
+}
+
+// isGitOpsPullRequestOpen returns true if the given conditions record a GitOps pull request that is still open
+func isGitOpsPullRequestOpen(conditions []metav1.Condition) bool {
+	condition := meta.FindStatusCondition(conditions, gitOpsPullRequestConditionType)
+	return condition != nil && condition.Reason == gitOpsPullRequestReasons[gitprovider.PullRequestStateOpen]
+}
+
+// gitOpsPullRequestResult returns the result of a reconcile, which is requeued to poll the GitOps pull request recorded in the
+// given conditions while it's open
+func gitOpsPullRequestResult(conditions []metav1.Condition) ctrl.Result {
+	if isGitOpsPullRequestOpen(conditions) {
+		return ctrl.Result{RequeueAfter: gitOpsPullRequestPollInterval}
+	}
+	return ctrl.Result{}
+}
+
+// pendingGitOpsGeneratedCondition returns the GitOpsResourcesGenerated condition to set while the GitOps pull request recorded in the
+// given conditions hasn't been merged, or nil if there's no such pull request
+func pendingGitOpsGeneratedCondition(conditions []metav1.Condition) *metav1.Condition {
+	condition := meta.FindStatusCondition(conditions, gitOpsPullRequestConditionType)
+	if condition == nil || condition.Status == metav1.ConditionTrue {
+		return nil
+	}
+	if condition.Reason == gitOpsPullRequestReasons[gitprovider.PullRequestStateClosed] {
+		return &metav1.Condition{
+			Type:    "GitOpsResourcesGenerated",
+			Status:  metav1.ConditionFalse,
+			Reason:  "PullRequestClosed",
+			Message: fmt.Sprintf("GitOps pull request %s was closed without being merged", condition.Message),
+		}
+	}
+	return &metav1.Condition{
+		Type:    "GitOpsResourcesGenerated",
+		Status:  metav1.ConditionFalse,
+		Reason:  "PullRequestOpen",
+		Message: fmt.Sprintf("GitOps resources are awaiting the merge of pull request %s", condition.Message),
+	}
+}
+
+// copyGitOpsPullRequestCondition copies the GitOpsPullRequest condition between the given conditions, removing it if it isn't set
+func copyGitOpsPullRequestCondition(from []metav1.Condition, to *[]metav1.Condition) {
+	if condition := meta.FindStatusCondition(from, gitOpsPullRequestConditionType); condition != nil {
+		meta.SetStatusCondition(to, *condition)
+	} else {
+		meta.RemoveStatusCondition(to, gitOpsPullRequestConditionType)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const testPullRequestURL = "https://github.com/redhat-appstudio-appdata/test-application-repo/pull/3"
+
+func openPullRequestConditions() []metav1.Condition {
+	var conditions []metav1.Condition
+	recordGitOpsPullRequest(&conditions, &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateOpen}, 1)
+	return conditions
+}
+
+func TestSyncGitOpsPullRequest(t *testing.T) {
+	openPullRequest := &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateOpen}
+
+	tests := []struct {
+		name            string
+		gitProvider     *fakeGitProvider
+		repoURL         string
+		wantPullRequest *gitprovider.PullRequest
+		wantCommitID    string
+		wantErr         bool
+	}{
+		{
+			name:            "Changes to review, the pull request is returned",
+			gitProvider:     &fakeGitProvider{pullRequest: openPullRequest},
+			repoURL:         "https://github.com/redhat-appstudio-appdata/test-application-repo",
+			wantPullRequest: openPullRequest,
+		},
+		{
+			name:         "No changes to review, the latest commit of the GitOps branch is returned",
+			gitProvider:  &fakeGitProvider{latestCommit: "ca82a6dff817ec66f44342007202690a93763949"},
+			repoURL:      "https://github.com/redhat-appstudio-appdata/test-application-repo",
+			wantCommitID: "ca82a6dff817ec66f44342007202690a93763949",
+		},
+		{
+			name:            "Repository in a GitLab subgroup",
+			gitProvider:     &fakeGitProvider{pullRequest: openPullRequest},
+			repoURL:         "https://gitlab.com/appdata-group/gitops/test-application-repo",
+			wantPullRequest: openPullRequest,
+		},
+		{
+			name:        "Error opening the pull request",
+			gitProvider: &fakeGitProvider{err: fmt.Errorf("forbidden")},
+			repoURL:     "https://github.com/redhat-appstudio-appdata/test-application-repo",
+			wantErr:     true,
+		},
+		{
+			name:        "Invalid repository URL",
+			gitProvider: &fakeGitProvider{pullRequest: openPullRequest},
+			repoURL:     "https://github.com/test-application-repo",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pullRequest, commitID, err := syncGitOpsPullRequest(context.Background(), componentName, tt.gitProvider, tt.repoURL, gitprovider.PullRequestOptions{HeadBranch: "appstudio/component/test-component", BaseBranch: "main"})
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestSyncGitOpsPullRequest() unexpected error value: %v", err)
+			}
+			if !reflect.DeepEqual(pullRequest, tt.wantPullRequest) {
+				t.Errorf("TestSyncGitOpsPullRequest() error: expected pull request %v got %v", tt.wantPullRequest, pullRequest)
+			}
+			if commitID != tt.wantCommitID {
+				t.Errorf("TestSyncGitOpsPullRequest() error: expected commit %v got %v", tt.wantCommitID, commitID)
+			}
+		})
+	}
+}
+
+func TestResetGitOpsWorkBranch(t *testing.T) {
+	gitProvider := &fakeGitProvider{}
+	if err := resetGitOpsWorkBranch(context.Background(), componentName, gitProvider, "https://gitlab.com/appdata-group/gitops/test-application-repo", "appstudio/component/test-component", "main"); err != nil {
+		t.Fatalf("TestResetGitOpsWorkBranch() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(gitProvider.reset, []string{"appstudio/component/test-component"}) {
+		t.Errorf("TestResetGitOpsWorkBranch() error: unexpected reset branches %v", gitProvider.reset)
+	}
+
+	gitProvider = &fakeGitProvider{err: fmt.Errorf("forbidden")}
+	if err := resetGitOpsWorkBranch(context.Background(), componentName, gitProvider, "https://github.com/redhat-appstudio-appdata/test-application-repo", "appstudio/component/test-component", "main"); err == nil {
+		t.Errorf("TestResetGitOpsWorkBranch() error: expected an error resetting the branch")
+	}
+}
+
+func TestRecordGitOpsPullRequest(t *testing.T) {
+	tests := []struct {
+		name              string
+		conditions        []metav1.Condition
+		pullRequest       *gitprovider.PullRequest
+		wantReason        string
+		wantOpen          bool
+		wantPendingReason string
+	}{
+		{
+			name:              "Pull request opened",
+			pullRequest:       &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateOpen},
+			wantReason:        "Open",
+			wantOpen:          true,
+			wantPendingReason: "PullRequestOpen",
+		},
+		{
+			name:        "Pull request merged",
+			conditions:  openPullRequestConditions(),
+			pullRequest: &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateMerged, MergeCommitSHA: "ca82a6dff817ec66f44342007202690a93763949"},
+			wantReason:  "Merged",
+		},
+		{
+			name:              "Pull request closed",
+			conditions:        openPullRequestConditions(),
+			pullRequest:       &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateClosed},
+			wantReason:        "Closed",
+			wantPendingReason: "PullRequestClosed",
+		},
+		{
+			name:       "No changes to review, the open pull request is forgotten",
+			conditions: openPullRequestConditions(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions := tt.conditions
+			recordGitOpsPullRequest(&conditions, tt.pullRequest, 2)
+
+			condition := meta.FindStatusCondition(conditions, gitOpsPullRequestConditionType)
+			if tt.wantReason == "" {
+				if condition != nil {
+					t.Errorf("TestRecordGitOpsPullRequest() error: expected no pull request condition got %v", condition)
+				}
+			} else if condition == nil || condition.Reason != tt.wantReason || condition.Message != testPullRequestURL || condition.ObservedGeneration != 2 {
+				t.Errorf("TestRecordGitOpsPullRequest() error: expected a pull request condition with reason %v got %v", tt.wantReason, condition)
+			}
+			if isGitOpsPullRequestOpen(conditions) != tt.wantOpen {
+				t.Errorf("TestRecordGitOpsPullRequest() error: expected the pull request to be open: %v", tt.wantOpen)
+			}
+
+			pendingCondition := pendingGitOpsGeneratedCondition(conditions)
+			if tt.wantPendingReason == "" && pendingCondition != nil {
+				t.Errorf("TestRecordGitOpsPullRequest() error: expected the GitOps resources to be generated got %v", pendingCondition)
+			}
+			if tt.wantPendingReason != "" && (pendingCondition == nil || pendingCondition.Reason != tt.wantPendingReason || pendingCondition.Status != metav1.ConditionFalse) {
+				t.Errorf("TestRecordGitOpsPullRequest() error: expected the GitOps resources to be pending with reason %v got %v", tt.wantPendingReason, pendingCondition)
+			}
+		})
+	}
+}
+
+func TestUpdateGitOpsPullRequestStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		pullRequest   *gitprovider.PullRequest
+		wantCommitID  string
+		wantGenerated *metav1.Condition
+		wantErr       bool
+	}{
+		{
+			name:        "Pull request still open, the Component is unchanged",
+			pullRequest: &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateOpen},
+		},
+		{
+			name:          "Pull request merged, the GitOps resources are generated at the merge commit",
+			pullRequest:   &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateMerged, MergeCommitSHA: "ca82a6dff817ec66f44342007202690a93763949"},
+			wantCommitID:  "ca82a6dff817ec66f44342007202690a93763949",
+			wantGenerated: &metav1.Condition{Status: metav1.ConditionTrue, Reason: "OK"},
+		},
+		{
+			name:          "Pull request closed",
+			pullRequest:   &gitprovider.PullRequest{Number: 3, URL: testPullRequestURL, State: gitprovider.PullRequestStateClosed},
+			wantGenerated: &metav1.Condition{Status: metav1.ConditionFalse, Reason: "PullRequestClosed"},
+		},
+		{
+			name:    "Pull request not found",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			component := &appstudiov1alpha1.Component{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-component", Namespace: "default"},
+				Spec:       appstudiov1alpha1.ComponentSpec{ComponentName: "test-component", Application: "test-application"},
+				Status: appstudiov1alpha1.ComponentStatus{
+					GitOps:     appstudiov1alpha1.GitOpsStatus{RepositoryURL: "https://github.com/redhat-appstudio-appdata/test-application-repo"},
+					Conditions: openPullRequestConditions(),
+				},
+			}
+			r := &ComponentReconciler{Client: NewFakeClient(t, component.DeepCopy())}
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: component.Name, Namespace: component.Namespace}}
+
+			err := r.updateGitOpsPullRequestStatus(context.Background(), req, &fakeGitProvider{pullRequest: tt.pullRequest}, component)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestUpdateGitOpsPullRequestStatus() unexpected error value: %v", err)
+			}
+
+			var updated appstudiov1alpha1.Component
+			if err := r.Get(context.Background(), req.NamespacedName, &updated); err != nil {
+				t.Fatalf("TestUpdateGitOpsPullRequestStatus() unexpected error getting the Component: %v", err)
+			}
+			if updated.Status.GitOps.CommitID != tt.wantCommitID {
+				t.Errorf("TestUpdateGitOpsPullRequestStatus() error: expected commit %v got %v", tt.wantCommitID, updated.Status.GitOps.CommitID)
+			}
+			generated := meta.FindStatusCondition(updated.Status.Conditions, "GitOpsResourcesGenerated")
+			if tt.wantGenerated == nil && generated != nil {
+				t.Errorf("TestUpdateGitOpsPullRequestStatus() error: expected no GitOpsResourcesGenerated condition got %v", generated)
+			}
+			if tt.wantGenerated != nil && (generated == nil || generated.Status != tt.wantGenerated.Status || generated.Reason != tt.wantGenerated.Reason) {
+				t.Errorf("TestUpdateGitOpsPullRequestStatus() error: expected GitOpsResourcesGenerated condition %v got %v", tt.wantGenerated, generated)
+			}
+			if tt.wantGenerated != nil && meta.FindStatusCondition(updated.Status.Conditions, gitOpsPullRequestConditionType).Reason != gitOpsPullRequestReasons[tt.pullRequest.State] {
+				t.Errorf("TestUpdateGitOpsPullRequestStatus() error: expected the pull request condition to be updated")
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsgenv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/spf13/afero"
+)
+
+// DefaultGitOpsWriteBatchWindow is how long the GitOps write queue waits for the changes of other Components, before writing a batch
+const DefaultGitOpsWriteBatchWindow = 2 * time.Second
+
+// gitOpsWrite is the generation of a Component's GitOps resources in the GitOps repository of its Application
+type gitOpsWrite struct {
+	// repositoryURL is the URL of the GitOps repository, without credentials
+	repositoryURL string
+
+	// remote is the URL that the GitOps repository is cloned from and pushed to, with credentials
+	remote string
+
+	branch    string
+	context   string
+	tokenName string
+	namespace string
+
+	// component is the Component whose GitOps resources are generated, in the given format
+	component gitopsgenv1alpha1.GeneratorOptions
+	format    gitops.OutputFormat
+
+	// commit describes the Component in the commit message, and generation is the generation of the Component being reconciled
+	commit     gitops.CommitComponent
+	generation int64
+}
+
+// gitOpsWriteKey identifies the GitOps repository branch (and context) that writes are batched for
+type gitOpsWriteKey struct {
+	repositoryURL string
+	branch        string
+	context       string
+}
+
+// gitOpsBranchKey identifies the GitOps repository branch that's locked while it's written, whatever the context of the write
+type gitOpsBranchKey struct {
+	repositoryURL string
+	branch        string
+}
+
+// gitOpsWriteResult is the result of a Component's write: the commit its GitOps resources were pushed in, or why they weren't
+type gitOpsWriteResult struct {
+	commitID string
+	err      error
+}
+
+type pendingGitOpsWrite struct {
+	gitOpsWrite
+	result chan gitOpsWriteResult
+}
+
+// GitOpsWriteQueue coalesces the GitOps writes of the Components of an Application. Writes to the same GitOps repository branch that are
+// queued within the batch window are generated in a single clone of the repository, and pushed in a single commit. Batches for the same
+// branch are never written concurrently, so they don't race to push, and others that push to the branch, such as the drift detector,
+// lock it in the queue too. A write blocks its reconcile until its batch is pushed, so writes are only batched together if the
+// Components' controller has more than one concurrent reconcile.
+type GitOpsWriteQueue struct {
+	Generator gitopsgen.Generator
+	AppFS     afero.Afero
+	Log       logr.Logger
+
+	// Window is how long to wait for other writes after the first write of a batch is queued
+	Window time.Duration
+
+	// CommitMessages renders the messages of the commits of the batches. Defaults to the default templates
+	CommitMessages *gitops.CommitMessages
+
+	mu       sync.Mutex
+	pending  map[gitOpsWriteKey][]*pendingGitOpsWrite
+	flushing map[gitOpsWriteKey]bool
+
+	// locked are the branches being written, each with a channel that's closed once the branch is unlocked
+	locked map[gitOpsBranchKey]chan struct{}
+}
+
+// NewGitOpsWriteQueue returns a GitOps write queue that batches the writes queued within the given window
+func NewGitOpsWriteQueue(generator gitopsgen.Generator, appFS afero.Afero, log logr.Logger, window time.Duration) *GitOpsWriteQueue {
+	return &GitOpsWriteQueue{
+		Generator: generator,
+		AppFS:     appFS,
+		Log:       log,
+		Window:    window,
+		pending:   make(map[gitOpsWriteKey][]*pendingGitOpsWrite),
+		flushing:  make(map[gitOpsWriteKey]bool),
+		locked:    make(map[gitOpsBranchKey]chan struct{}),
+	}
+}
+
+// lockBranch waits until the given branch of the GitOps repository isn't being written, by a batch or by another holder of its lock,
+// and locks it. It returns the function that unlocks the branch.
+func (q *GitOpsWriteQueue) lockBranch(ctx context.Context, repositoryURL string, branch string) (func(), error) {
+	key := gitOpsBranchKey{repositoryURL: repositoryURL, branch: branch}
+	for {
+		q.mu.Lock()
+		unlocked, isLocked := q.locked[key]
+		if !isLocked {
+			unlocked = make(chan struct{})
+			q.locked[key] = unlocked
+			q.mu.Unlock()
+			return func() {
+				q.mu.Lock()
+				delete(q.locked, key)
+				q.mu.Unlock()
+				close(unlocked)
+			}, nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-unlocked:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Write queues the given write, and waits for the batch it's part of to be pushed. It returns the ID of the commit that the Component's
+// GitOps resources were pushed in.
+func (q *GitOpsWriteQueue) Write(ctx context.Context, write gitOpsWrite) (string, error) {
+	key := gitOpsWriteKey{repositoryURL: write.repositoryURL, branch: write.branch, context: write.context}
+	pending := &pendingGitOpsWrite{gitOpsWrite: write, result: make(chan gitOpsWriteResult, 1)}
+
+	q.mu.Lock()
+	if len(q.pending[key]) == 0 {
+		time.AfterFunc(q.Window, func() { q.flush(key) })
+	}
+	q.pending[key] = append(q.pending[key], pending)
+	q.mu.Unlock()
+
+	select {
+	case result := <-pending.result:
+		return result.commitID, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// flush writes the pending batch for the given key, unless a batch for the key is already being written. In that case, the pending
+// batch is written once the current one is done.
+func (q *GitOpsWriteQueue) flush(key gitOpsWriteKey) {
+	q.mu.Lock()
+	batch := q.pending[key]
+	if q.flushing[key] || len(batch) == 0 {
+		q.mu.Unlock()
+		return
+	}
+	delete(q.pending, key)
+	q.flushing[key] = true
+	q.mu.Unlock()
+
+	// Batches aren't cancelled, so waiting for the lock can't fail
+	unlock, _ := q.lockBranch(context.Background(), key.repositoryURL, key.branch)
+	results := q.writeBatch(batch)
+	unlock()
+	for i, write := range batch {
+		write.result <- results[i]
+	}
+
+	q.mu.Lock()
+	delete(q.flushing, key)
+	morePending := len(q.pending[key]) > 0
+	q.mu.Unlock()
+	if morePending {
+		go q.flush(key)
+	}
+}
+
+// writeBatch writes the batch with the credentials of its first write. The Components in the batch may have been reconciled with
+// different tokens, so if the GitOps repository can't be cloned or pushed with those credentials, the batch is written again with
+// the credentials of each of the next writes, until one succeeds.
+func (q *GitOpsWriteQueue) writeBatch(batch []*pendingGitOpsWrite) []gitOpsWriteResult {
+	var results []gitOpsWriteResult
+	tried := make(map[string]bool)
+	for _, credentials := range batch {
+		if tried[credentials.remote] {
+			continue
+		}
+		tried[credentials.remote] = true
+		var retry bool
+		if results, retry = q.writeBatchWithCredentials(batch, credentials); !retry {
+			break
+		}
+		q.Log.Info(fmt.Sprintf("Unable to write the GitOps resources of %d components with token %s, retrying with the next token of the batch", len(batch), credentials.tokenName),
+			"repository", credentials.repositoryURL, "branch", credentials.branch)
+	}
+	return results
+}
+
+// writeBatchWithCredentials clones the GitOps repository once, with the remote of the given write, generates the GitOps resources of
+// each Component in the batch, and pushes them in a single commit. A Component whose resources fail to generate gets its own error,
+// without failing the rest of the batch. It also returns true if the repository couldn't be cloned or pushed, so the batch may be
+// retried with other credentials.
+func (q *GitOpsWriteQueue) writeBatchWithCredentials(batch []*pendingGitOpsWrite, credentials *pendingGitOpsWrite) ([]gitOpsWriteResult, bool) {
+	results := make([]gitOpsWriteResult, len(batch))
+	failAll := func(err error) []gitOpsWriteResult {
+		for i := range results {
+			if results[i].err == nil {
+				results[i].err = err
+			}
+		}
+		return results
+	}
+
+	first := batch[0]
+	log := q.Log.WithValues("repository", first.repositoryURL, "branch", first.branch)
+	tempDir, err := ioutils.CreateTempPath(first.component.Application, q.AppFS)
+	if err != nil {
+		return failAll(fmt.Errorf("unable to create temp directory for GitOps resources due to error: %v", err)), false
+	}
+	defer ioutils.RemoveFolderAndLogError(log, q.AppFS, tempDir)
+
+	// Clone the repository into a folder named after the Application, as it's shared by the Components in the batch
+	repoDir := first.component.Application
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": componentName, "tokenName": credentials.tokenName, "operation": "CloneRepo"}).Inc()
+	if err := q.Generator.CloneRepo(tempDir, credentials.remote, repoDir, first.branch); err != nil {
+		return failAll(err), true
+	}
+
+	repoPath := filepath.Join(tempDir, repoDir)
+	gitOpsFolder := filepath.Join(repoPath, first.context)
+	var generated []gitops.CommitComponent
+	for i, write := range batch {
+		format := write.format
+		if format == nil {
+			format = gitops.Kustomize{}
+		}
+		if err := gitops.GenerateBase(q.AppFS, format, gitOpsFolder, write.component); err != nil {
+			results[i].err = err
+			continue
+		}
+		generated = append(generated, write.commit)
+	}
+	if len(generated) == 0 {
+		return results, false
+	}
+
+	sort.Slice(generated, func(i, j int) bool { return generated[i].Name < generated[j].Name })
+	commitData := gitops.CommitMessageData{
+		Namespace:   first.namespace,
+		Application: first.component.Application,
+		Components:  generated,
+		Reconciler:  componentName,
+	}
+	// A Component that's written on its own is identified in the commit, as it would be without batching
+	if len(batch) == 1 {
+		commitData.Resource, commitData.Generation = first.component.Name, first.generation
+	}
+	commitMessage, err := q.CommitMessages.ComponentMessage(commitData)
+	if err != nil {
+		return failAll(err), false
+	}
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": componentName, "tokenName": credentials.tokenName, "operation": "CommitAndPush"}).Inc()
+	if err := q.Generator.CommitAndPush(tempDir, repoDir, credentials.remote, repoDir, first.branch, commitMessage); err != nil {
+		// Other credentials won't help a commit that can't be signed
+		return failAll(err), !gitops.IsCommitSigningError(err)
+	}
+
+	metrics.ControllerGitRequest.With(prometheus.Labels{"controller": componentName, "tokenName": credentials.tokenName, "operation": "GetCommitIDFromRepo"}).Inc()
+	commitID, err := q.Generator.GetCommitIDFromRepo(q.AppFS, repoPath)
+	if err != nil {
+		return failAll(err), false
+	}
+	log.Info(fmt.Sprintf("Pushed the GitOps resources of %d components in commit %s", len(generated), commitID))
+	for i := range results {
+		if results[i].err == nil {
+			results[i].commitID = commitID
+		}
+	}
+	return results, false
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsgenv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/spf13/afero"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// recordingGenerator is a gitops generator that records the clones and pushes of a GitOps repository
+type recordingGenerator struct {
+	gitopsgen.Generator
+	appFS afero.Afero
+
+	mu             sync.Mutex
+	clones         int
+	commitMessages []string
+	cloneErr       error
+
+	// pushedRemotes are the remotes that were pushed to, and pushes to any of the failingRemotes fail
+	pushedRemotes  []string
+	failingRemotes map[string]bool
+}
+
+func (g *recordingGenerator) CloneRepo(outputPath string, remote string, componentName string, branch string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.clones++
+	if g.cloneErr != nil {
+		return g.cloneErr
+	}
+	return g.appFS.MkdirAll(filepath.Join(outputPath, componentName), 0755)
+}
+
+func (g *recordingGenerator) CommitAndPush(outputPath string, repoPathOverride string, remote string, componentName string, branch string, commitMessage string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pushedRemotes = append(g.pushedRemotes, remote)
+	if g.failingRemotes[remote] {
+		return fmt.Errorf("failed to push to %s: authentication failed", remote)
+	}
+	g.commitMessages = append(g.commitMessages, commitMessage)
+	return nil
+}
+
+func (g *recordingGenerator) GetCommitIDFromRepo(fs afero.Afero, repoPath string) (string, error) {
+	return "ca82a6dff817ec66f44342007202690a93763949", nil
+}
+
+func TestGitOpsWriteQueue(t *testing.T) {
+	tests := []struct {
+		name              string
+		components        []string
+		cloneErr          error
+		wantClones        int
+		wantCommitMessage string
+		wantErr           bool
+	}{
+		{
+			name:              "Single Component",
+			components:        []string{"frontend"},
+			wantClones:        1,
+			wantCommitMessage: "Generate GitOps resources for component frontend\n\nApplication: test-application\nComponent: frontend\nNamespace: test-ns\nGenerated-By: application-service Component frontend generation 1",
+		},
+		{
+			name:              "Components written within the batch window are pushed in one commit",
+			components:        []string{"backend", "frontend", "worker"},
+			wantClones:        1,
+			wantCommitMessage: "Generate GitOps resources for components backend, frontend, worker\n\nApplication: test-application\nComponent: backend\nComponent: frontend\nComponent: worker\nNamespace: test-ns\nGenerated-By: application-service Component",
+		},
+		{
+			name:       "Failure to clone the GitOps repository fails every write",
+			components: []string{"backend", "frontend"},
+			cloneErr:   fmt.Errorf("authentication failed"),
+			wantClones: 1,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appFS := ioutils.NewMemoryFilesystem()
+			generator := &recordingGenerator{appFS: appFS, cloneErr: tt.cloneErr}
+			queue := NewGitOpsWriteQueue(generator, appFS, ctrl.Log.WithName("controllers").WithName("GitOpsWriteQueue"), 200*time.Millisecond)
+
+			var wg sync.WaitGroup
+			commitIDs := make([]string, len(tt.components))
+			errs := make([]error, len(tt.components))
+			for i, name := range tt.components {
+				wg.Add(1)
+				go func(i int, name string) {
+					defer wg.Done()
+					commitIDs[i], errs[i] = queue.Write(context.Background(), gitOpsWrite{
+						repositoryURL: "https://github.com/redhat-appstudio-appdata/test-application-repo",
+						remote:        "https://token@github.com/redhat-appstudio-appdata/test-application-repo",
+						branch:        "main",
+						context:       "/",
+						namespace:     "test-ns",
+						component: gitopsgenv1alpha1.GeneratorOptions{
+							Name:           name,
+							Application:    "test-application",
+							ContainerImage: "quay.io/test/" + name,
+							TargetPort:     8080,
+						},
+						commit:     gitops.CommitComponent{Name: name},
+						generation: 1,
+					})
+				}(i, name)
+			}
+			wg.Wait()
+
+			if generator.clones != tt.wantClones {
+				t.Errorf("TestGitOpsWriteQueue() error: expected %v clones got %v", tt.wantClones, generator.clones)
+			}
+			for i := range tt.components {
+				if tt.wantErr != (errs[i] != nil) {
+					t.Errorf("TestGitOpsWriteQueue() unexpected error value for component %v: %v", tt.components[i], errs[i])
+				}
+				if !tt.wantErr && commitIDs[i] != "ca82a6dff817ec66f44342007202690a93763949" {
+					t.Errorf("TestGitOpsWriteQueue() error: expected component %v to be pushed got commit %v", tt.components[i], commitIDs[i])
+				}
+			}
+			if tt.wantErr {
+				if len(generator.commitMessages) != 0 {
+					t.Errorf("TestGitOpsWriteQueue() error: expected no commits got %v", generator.commitMessages)
+				}
+			} else if len(generator.commitMessages) != 1 || generator.commitMessages[0] != tt.wantCommitMessage {
+				t.Errorf("TestGitOpsWriteQueue() error: expected a single commit %q got %v", tt.wantCommitMessage, generator.commitMessages)
+			}
+		})
+	}
+}
+
+func TestGitOpsWriteQueueRetriesWithNextCredentials(t *testing.T) {
+	const expiredRemote = "https://expired-token@github.com/redhat-appstudio-appdata/test-application-repo"
+	const validRemote = "https://valid-token@github.com/redhat-appstudio-appdata/test-application-repo"
+	appFS := ioutils.NewMemoryFilesystem()
+	generator := &recordingGenerator{appFS: appFS, failingRemotes: map[string]bool{expiredRemote: true}}
+	queue := NewGitOpsWriteQueue(generator, appFS, ctrl.Log.WithName("controllers").WithName("GitOpsWriteQueue"), 200*time.Millisecond)
+
+	var wg sync.WaitGroup
+	components := []string{"backend", "frontend", "worker"}
+	remotes := []string{expiredRemote, expiredRemote, validRemote}
+	errs := make([]error, len(components))
+	for i, name := range components {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			_, errs[i] = queue.Write(context.Background(), gitOpsWrite{
+				repositoryURL: "https://github.com/redhat-appstudio-appdata/test-application-repo",
+				remote:        remotes[i],
+				branch:        "main",
+				context:       "/",
+				namespace:     "test-ns",
+				component: gitopsgenv1alpha1.GeneratorOptions{
+					Name:           name,
+					Application:    "test-application",
+					ContainerImage: "quay.io/test/" + name,
+					TargetPort:     8080,
+				},
+				commit:     gitops.CommitComponent{Name: name},
+				generation: 1,
+			})
+		}(i, name)
+		// Queue the writes in order, so that the batch is first written with the expired token
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("TestGitOpsWriteQueueRetriesWithNextCredentials() unexpected error for component %v: %v", components[i], err)
+		}
+	}
+	// The remote of each token is only tried once
+	wantRemotes := []string{expiredRemote, validRemote}
+	if generator.clones != 2 || !reflect.DeepEqual(generator.pushedRemotes, wantRemotes) || len(generator.commitMessages) != 1 {
+		t.Errorf("TestGitOpsWriteQueueRetriesWithNextCredentials() error: expected pushes to %v in 2 clones, got pushes to %v in %v clones", wantRemotes, generator.pushedRemotes, generator.clones)
+	}
+}
+
+func TestGitOpsWriteQueueLockBranch(t *testing.T) {
+	appFS := ioutils.NewMemoryFilesystem()
+	generator := &recordingGenerator{appFS: appFS}
+	queue := NewGitOpsWriteQueue(generator, appFS, ctrl.Log.WithName("controllers").WithName("GitOpsWriteQueue"), 10*time.Millisecond)
+	repositoryURL := "https://github.com/redhat-appstudio-appdata/test-application-repo"
+
+	// A batch isn't written while its branch is locked, by the drift detector for instance
+	unlock, err := queue.lockBranch(context.Background(), repositoryURL, "main")
+	if err != nil {
+		t.Fatalf("TestGitOpsWriteQueueLockBranch() unexpected error: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := queue.Write(context.Background(), gitOpsWrite{
+			repositoryURL: repositoryURL,
+			remote:        "https://token@github.com/redhat-appstudio-appdata/test-application-repo",
+			branch:        "main",
+			context:       "/",
+			namespace:     "test-ns",
+			component:     gitopsgenv1alpha1.GeneratorOptions{Name: "frontend", Application: "test-application", ContainerImage: "quay.io/test/frontend", TargetPort: 8080},
+			commit:        gitops.CommitComponent{Name: "frontend"},
+			generation:    1,
+		})
+		done <- err
+	}()
+
+	// Waiting for the lock of a locked branch stops when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := queue.lockBranch(ctx, repositoryURL, "main"); err == nil {
+		t.Errorf("TestGitOpsWriteQueueLockBranch() error: expected the branch to stay locked")
+	}
+	generator.mu.Lock()
+	clones := generator.clones
+	generator.mu.Unlock()
+	if clones != 0 {
+		t.Errorf("TestGitOpsWriteQueueLockBranch() error: expected no clones while the branch is locked, got %v", clones)
+	}
+
+	unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("TestGitOpsWriteQueueLockBranch() unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TestGitOpsWriteQueueLockBranch() error: the batch wasn't written once the branch was unlocked")
+	}
+	if generator.clones != 1 || len(generator.commitMessages) != 1 {
+		t.Errorf("TestGitOpsWriteQueueLockBranch() error: expected the batch to be pushed once, got %v clones and commits %v", generator.clones, generator.commitMessages)
+	}
+}
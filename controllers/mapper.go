@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -64,6 +65,47 @@ func MapToBindingByBoundObjectName(cl client.Client, objectType, label string) f
 	}
 }
 
+// MapSealedSecretsConfigMapToBindings maps a ConfigMap to the Bindings whose SealedSecrets ConfigMap it is: those that name it in their
+// sealed-secrets-configmap annotation, or whose Environment does.
+func MapSealedSecretsConfigMapToBindings(cl client.Client) func(object client.Object) []reconcile.Request {
+	return func(obj client.Object) []reconcile.Request {
+		log := ctrl.Log.WithName("MapSealedSecretsConfigMapToBindings").WithValues("name", obj.GetName()).WithValues("namespace", obj.GetNamespace())
+		ctx := context.Background()
+
+		environmentList := &appstudiov1alpha1.EnvironmentList{}
+		if err := cl.List(ctx, environmentList, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.Error(err, fmt.Sprintf("unable to list Environments for a ConfigMap object %s", obj.GetName()))
+			return []reconcile.Request{}
+		}
+		environments := make(map[string]bool)
+		for _, environment := range environmentList.Items {
+			if environment.GetAnnotations()[gitops.SealedSecretsConfigMapAnnotation] == obj.GetName() {
+				environments[environment.Name] = true
+			}
+		}
+
+		bindingList := &appstudiov1alpha1.SnapshotEnvironmentBindingList{}
+		if err := cl.List(ctx, bindingList, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.Error(err, fmt.Sprintf("unable to list SnapshotEnvironmentBinding for a ConfigMap object %s", obj.GetName()))
+			return []reconcile.Request{}
+		}
+		var req []reconcile.Request
+		for _, item := range bindingList.Items {
+			if item.GetAnnotations()[gitops.SealedSecretsConfigMapAnnotation] != obj.GetName() && !environments[item.Spec.Environment] {
+				continue
+			}
+			req = append(req, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: item.Namespace,
+					Name:      item.Name,
+				},
+			})
+			log.Info(fmt.Sprintf("The corresponding SnapshotEnvironmentBinding %s will be reconciled", item.Name))
+		}
+		return req
+	}
+}
+
 // MapComponentToApplication returns an event handler that will convert events on a Component CR to events on its parent Application
 func MapComponentToApplication() func(object client.Object) []reconcile.Request {
 	return func(obj client.Object) []reconcile.Request {
@@ -21,8 +21,10 @@ import (
 	"testing"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/gitops"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -250,6 +252,65 @@ func TestMapToBindingByBoundObject(t *testing.T) {
 	})
 }
 
+func TestMapSealedSecretsConfigMapToBindings(t *testing.T) {
+	newEnvironment := func(name string, configMap string) *appstudiov1alpha1.Environment {
+		environment := &appstudiov1alpha1.Environment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		if configMap != "" {
+			environment.Annotations = map[string]string{gitops.SealedSecretsConfigMapAnnotation: configMap}
+		}
+		return environment
+	}
+	newBinding := func(name string, environment string, configMap string) *appstudiov1alpha1.SnapshotEnvironmentBinding {
+		binding := &appstudiov1alpha1.SnapshotEnvironmentBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       appstudiov1alpha1.SnapshotEnvironmentBindingSpec{Application: "test-app", Environment: environment, Snapshot: "test-snapshot"},
+		}
+		if configMap != "" {
+			binding.Annotations = map[string]string{gitops.SealedSecretsConfigMapAnnotation: configMap}
+		}
+		return binding
+	}
+
+	fakeClient := NewFakeClient(t,
+		newEnvironment("staging", "staging-secrets"),
+		newEnvironment("prod", ""),
+		newBinding("staging-binding", "staging", ""),
+		newBinding("prod-binding", "prod", ""),
+		newBinding("prod-binding-with-secrets", "prod", "staging-secrets"),
+		newBinding("other-binding", "prod", "other-secrets"),
+	)
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "staging-secrets", Namespace: "default"}}
+
+	t.Run("should return the Bindings whose Environment or own annotation names the ConfigMap", func(t *testing.T) {
+		// when
+		requests := MapSealedSecretsConfigMapToBindings(fakeClient)(configMap)
+
+		// then
+		require.Len(t, requests, 2)
+		assert.Contains(t, requests, newRequest("staging-binding"))
+		assert.Contains(t, requests, newRequest("prod-binding-with-secrets"))
+	})
+
+	t.Run("should return no Binding requests for an unreferenced ConfigMap", func(t *testing.T) {
+		// when
+		requests := MapSealedSecretsConfigMapToBindings(fakeClient)(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unused", Namespace: "default"}})
+
+		// then
+		require.Empty(t, requests)
+	})
+
+	t.Run("should return no Binding requests when the list fails", func(t *testing.T) {
+		fakeClient.MockList = func(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+			return fmt.Errorf("some error")
+		}
+		// when
+		requests := MapSealedSecretsConfigMapToBindings(fakeClient)(configMap)
+
+		// then
+		require.Empty(t, requests)
+	})
+}
+
 func TestMapApplicationToComponent(t *testing.T) {
 
 	const (
@@ -0,0 +1,330 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devfile/library/v2/pkg/devfile/parser"
+	"github.com/prometheus/client_golang/prometheus"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	cdqanalysis "github.com/redhat-appstudio/application-service/cdq-analysis/pkg"
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	"github.com/redhat-appstudio/application-service/pkg/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Actions the OrphanedRepositoryCollector takes on orphaned GitOps repositories, once they're older than its minimum age
+	OrphanedRepositoryActionNone    = "none"
+	OrphanedRepositoryActionArchive = "archive"
+	OrphanedRepositoryActionDelete  = "delete"
+
+	// DefaultOrphanedRepositoryCollectionInterval is how often the OrphanedRepositoryCollector runs, if no interval is set
+	DefaultOrphanedRepositoryCollectionInterval = 6 * time.Hour
+
+	// DefaultOrphanedRepositoryMinAge is how old an orphaned repository must be before it's archived or deleted, if no minimum age is set
+	DefaultOrphanedRepositoryMinAge = 30 * 24 * time.Hour
+
+	// orphanedRepositorySettlePeriod is how old a repository must be to be considered orphaned. A repository is generated before
+	// the Application's status records it, so newer repositories may still be in the process of being claimed.
+	orphanedRepositorySettlePeriod = time.Hour
+
+	orphanedRepositoryCollectorName = "OrphanedRepositoryCollector"
+)
+
+// OrphanedRepositoryCollector periodically looks for generated GitOps repositories that no longer belong to an Application, such as
+// those left behind when an Application's finalizer gave up on deleting its repository. It reports the number of orphaned repositories
+// as a metric, and optionally archives or deletes them once they're old enough.
+// It implements controller-runtime's manager.Runnable, and only runs on the leader.
+type OrphanedRepositoryCollector struct {
+	// Client is used to list the Applications, across all namespaces
+	Client client.Client
+
+	// ArchivedRepositories tracks the repositories archived or retained by the Applications' deletion policy, which are never
+	// collected. It may be nil
+	ArchivedRepositories *ArchivedRepositoryTracker
+
+	GitHubTokenClient github.GitHubToken
+	GitHubOrg         string
+
+	// GitLabClient is used to collect repositories in GitLabGroup. It is nil if GitLab isn't configured
+	GitLabClient gitprovider.GitProvider
+	GitLabGroup  string
+
+	// OwnerTopic is the topic that this instance of the operator adds to the repositories it generates. Only repositories with the
+	// topic are collected, as the org may be shared with other instances whose Applications aren't in this cluster. Repositories
+	// are never archived or deleted if it's empty
+	OwnerTopic string
+
+	// Action is what's done with orphaned repositories that are older than MinAge: none, archive or delete
+	Action string
+
+	// MinAge is how old an orphaned repository must be before it's archived or deleted. Defaults to DefaultOrphanedRepositoryMinAge
+	MinAge time.Duration
+
+	// DryRun logs the orphaned repositories that would be archived or deleted, without archiving or deleting them
+	DryRun bool
+
+	// Interval is how often orphaned repositories are collected. Defaults to DefaultOrphanedRepositoryCollectionInterval
+	Interval time.Duration
+}
+
+// ValidateOrphanedRepositoryAction validates the action taken on orphaned repositories, defaulting to none if it's empty
+func ValidateOrphanedRepositoryAction(action string) (string, error) {
+	action = strings.ToLower(strings.TrimSpace(action))
+	switch action {
+	case "":
+		return OrphanedRepositoryActionNone, nil
+	case OrphanedRepositoryActionNone, OrphanedRepositoryActionArchive, OrphanedRepositoryActionDelete:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unsupported orphaned repository action %q, must be one of %q, %q or %q", action,
+			OrphanedRepositoryActionNone, OrphanedRepositoryActionArchive, OrphanedRepositoryActionDelete)
+	}
+}
+
+// ValidateOrphanedRepositoryOwnerTopic validates that an owner topic is set if orphaned repositories are archived or deleted, as
+// repositories generated by other instances of the operator sharing the org would otherwise be collected
+func ValidateOrphanedRepositoryOwnerTopic(action string, ownerTopic string) error {
+	if ownerTopic == "" && (action == OrphanedRepositoryActionArchive || action == OrphanedRepositoryActionDelete) {
+		return fmt.Errorf("an owner topic must be set to %s orphaned repositories", action)
+	}
+	return nil
+}
+
+// Start collects the orphaned repositories every interval, until the context is cancelled
+func (c *OrphanedRepositoryCollector) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("orphaned-repository-collector")
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultOrphanedRepositoryCollectionInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.collect(ctx, time.Now()); err != nil {
+				// Collection is retried on the next tick
+				log.Error(err, "unable to collect the orphaned GitOps repositories")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns true, so that only one replica lists and collects repositories
+func (c *OrphanedRepositoryCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// collect finds the orphaned repositories in the GitHub org and GitLab group, as of now, and archives or deletes those older than MinAge
+func (c *OrphanedRepositoryCollector) collect(ctx context.Context, now time.Time) error {
+	owned, err := c.getOwnedRepositories(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	if c.GitHubOrg != "" {
+		ghClient, err := c.GitHubTokenClient.GetNewGitHubClient("")
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else if err := c.collectOrg(ctx, now, gitprovider.GitHub, ghClient, c.GitHubOrg, owned); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if c.GitLabClient != nil && c.GitLabGroup != "" {
+		if err := c.collectOrg(ctx, now, gitprovider.GitLab, c.GitLabClient, c.GitLabGroup, owned); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// collectOrg collects the orphaned repositories in the given org (or group) of the given Git provider
+func (c *OrphanedRepositoryCollector) collectOrg(ctx context.Context, now time.Time, providerName string, gitProvider gitprovider.GitProvider, org string, owned *ownedRepositories) error {
+	log := ctrl.LoggerFrom(ctx).WithName("orphaned-repository-collector")
+	ctx = context.WithValue(ctx, github.GHClientKey, gitProvider.GetTokenName())
+
+	metricsLabel := prometheus.Labels{"controller": orphanedRepositoryCollectorName, "tokenName": gitProvider.GetTokenName(), "operation": "ListRepositories"}
+	metrics.ControllerGitRequest.With(metricsLabel).Inc()
+	repos, err := gitProvider.ListRepositories(ctx, org)
+	metrics.HandleRateLimitMetrics(err, metricsLabel)
+	if err != nil {
+		return err
+	}
+
+	minAge := c.MinAge
+	if minAge <= 0 {
+		minAge = DefaultOrphanedRepositoryMinAge
+	}
+
+	orphaned := 0
+	var errs []string
+	for _, repo := range repos {
+		// Archived repositories are either kept on purpose by a deletion policy, or have already been collected
+		if repo.Archived || !github.IsGeneratedRepositoryName(repo.Name) || !c.isOwnedByInstance(repo) || owned.contains(providerName, org, repo) {
+			continue
+		}
+		age := now.Sub(repo.CreatedAt)
+		if age < orphanedRepositorySettlePeriod {
+			continue
+		}
+		orphaned++
+		if c.Action == OrphanedRepositoryActionNone || c.Action == "" || c.OwnerTopic == "" || age < minAge {
+			continue
+		}
+		if c.DryRun {
+			log.Info(fmt.Sprintf("Dry run, would %s orphaned GitOps repository %v created %v ago", c.Action, repo.URL, age.Round(time.Hour)))
+			continue
+		}
+
+		operation := "DeleteRepository"
+		if c.Action == OrphanedRepositoryActionArchive {
+			operation = "ArchiveRepository"
+		}
+		metricsLabel := prometheus.Labels{"controller": orphanedRepositoryCollectorName, "tokenName": gitProvider.GetTokenName(), "operation": operation}
+		metrics.ControllerGitRequest.With(metricsLabel).Inc()
+		if c.Action == OrphanedRepositoryActionArchive {
+			err = gitProvider.ArchiveRepository(ctx, org, repo.Name)
+		} else {
+			err = gitProvider.DeleteRepository(ctx, org, repo.Name)
+		}
+		metrics.HandleRateLimitMetrics(err, metricsLabel)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unable to %s orphaned repository %s: %v", c.Action, repo.URL, err))
+			continue
+		}
+		metrics.OrphanedGitOpsReposCollected.With(prometheus.Labels{"provider": providerName, "action": c.Action}).Inc()
+		log.Info(fmt.Sprintf("Collected orphaned GitOps repository %v created %v ago, action: %s", repo.URL, age.Round(time.Hour), c.Action))
+	}
+	metrics.OrphanedGitOpsRepos.With(prometheus.Labels{"provider": providerName, "org": org}).Set(float64(orphaned))
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ownedRepositories are the GitOps repositories that belong to live Applications, or that are tracked by the deletion policy
+type ownedRepositories struct {
+	// urls are the normalized URLs of the Applications' GitOps repositories
+	urls map[string]bool
+
+	// namePrefixes are the name prefixes of the repositories generated for Applications whose GitOps repository isn't known yet
+	namePrefixes []string
+
+	// tracked are the keys of the repositories tracked by the deletion policy
+	tracked map[string]bool
+}
+
+// contains returns true if the given repository, in the given org of the given provider, belongs to a live Application or is tracked
+func (o *ownedRepositories) contains(providerName string, org string, repo gitprovider.Repository) bool {
+	if o.urls[normalizeRepositoryURL(repo.URL)] {
+		return true
+	}
+	if o.tracked[ArchivedRepository{Provider: providerName, Org: org, Repo: repo.Name}.key()] {
+		return true
+	}
+	for _, prefix := range o.namePrefixes {
+		if strings.HasPrefix(repo.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// getOwnedRepositories returns the GitOps repositories of the Applications in all namespaces, along with those tracked by the deletion policy
+func (c *OrphanedRepositoryCollector) getOwnedRepositories(ctx context.Context) (*ownedRepositories, error) {
+	var applications appstudiov1alpha1.ApplicationList
+	if err := c.Client.List(ctx, &applications); err != nil {
+		return nil, err
+	}
+
+	owned := &ownedRepositories{urls: make(map[string]bool), tracked: make(map[string]bool)}
+	for _, application := range applications.Items {
+		if application.Spec.GitOpsRepository.URL != "" {
+			owned.urls[normalizeRepositoryURL(application.Spec.GitOpsRepository.URL)] = true
+		}
+		if gitOpsURL := getApplicationGitOpsURL(application); gitOpsURL != "" {
+			owned.urls[normalizeRepositoryURL(gitOpsURL)] = true
+		} else {
+			// The repository may have been generated, but not yet recorded in the Application's status
+			uniqueHash := util.GenerateUniqueHashForWorkloadImageTag(application.Namespace)
+			owned.namePrefixes = append(owned.namePrefixes, util.SanitizeName(application.Name)+"-"+uniqueHash+"-")
+		}
+	}
+
+	if c.ArchivedRepositories != nil {
+		trackedRepos, err := c.ArchivedRepositories.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range trackedRepos {
+			owned.tracked[repo.key()] = true
+		}
+	}
+	return owned, nil
+}
+
+// getApplicationGitOpsURL returns the URL of the Application's GitOps repository, as recorded in its devfile. It returns an empty
+// string if the devfile hasn't been generated yet, or can't be parsed
+func getApplicationGitOpsURL(application appstudiov1alpha1.Application) string {
+	if application.Status.Devfile == "" {
+		return ""
+	}
+	devfileObj, err := cdqanalysis.ParseDevfileWithParserArgs(&parser.ParserArgs{Data: []byte(application.Status.Devfile)})
+	if err != nil {
+		return ""
+	}
+	gitOpsURL, ok := devfileObj.GetMetadata().Attributes.Get("gitOpsRepository.url", &err).(string)
+	if err != nil || !ok {
+		return ""
+	}
+	return gitOpsURL
+}
+
+// normalizeRepositoryURL normalizes a repository URL, so that URLs of the same repository can be compared
+func normalizeRepositoryURL(repoURL string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(repoURL)), "/"), ".git")
+}
+
+// isOwnedByInstance returns true if the repository has the owner topic, or if no owner topic is set
+func (c *OrphanedRepositoryCollector) isOwnedByInstance(repo gitprovider.Repository) bool {
+	if c.OwnerTopic == "" {
+		return true
+	}
+	for _, topic := range repo.Topics {
+		if strings.EqualFold(topic, c.OwnerTopic) {
+			return true
+		}
+	}
+	return false
+}
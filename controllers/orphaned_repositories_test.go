@@ -0,0 +1,229 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/devfile"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+	"github.com/redhat-appstudio/application-service/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestOrphanedRepositoryCollector(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-60 * 24 * time.Hour)
+	recent := now.Add(-2 * time.Hour)
+	hash := util.GenerateUniqueHashForWorkloadImageTag("default")
+
+	// An Application whose GitOps repository is recorded in its devfile
+	application := appstudiov1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "petclinic", Namespace: "default"}}
+	devfileData, err := devfile.ConvertApplicationToDevfile(application, "https://gitlab.com/appdata/petclinic-"+hash+"-run-jump", "")
+	if err != nil {
+		t.Fatalf("TestOrphanedRepositoryCollector() unexpected error: %v", err)
+	}
+	devfileYaml, err := yaml.Marshal(devfileData)
+	if err != nil {
+		t.Fatalf("TestOrphanedRepositoryCollector() unexpected error: %v", err)
+	}
+	application.Status.Devfile = string(devfileYaml)
+
+	// An Application whose GitOps repository is being generated
+	newApplication := appstudiov1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "new-app", Namespace: "default"}}
+
+	repo := func(name string, createdAt time.Time, archived bool) gitprovider.Repository {
+		return gitprovider.Repository{Name: name, URL: "https://gitlab.com/appdata/" + name, CreatedAt: createdAt, Archived: archived, Topics: []string{"gitops", "has-cluster-a"}}
+	}
+	// A repository generated by another instance of the operator, sharing the group
+	otherInstanceRepo := gitprovider.Repository{Name: "other-app-" + hash + "-sing-swim", URL: "https://gitlab.com/appdata/other-app-" + hash + "-sing-swim", CreatedAt: old, Topics: []string{"has-cluster-b"}}
+	repos := []gitprovider.Repository{
+		repo("petclinic-"+hash+"-run-jump", old, false),
+		repo("new-app-"+hash+"-walk-talk", old, false),
+		repo("deleted-app-"+hash+"-sing-swim", old, false),
+		repo("recent-app-"+hash+"-sing-swim", recent, false),
+		repo("just-created-"+hash+"-sing-swim", now, false),
+		repo("archived-app-"+hash+"-sing-swim", old, true),
+		repo("retained-app-"+hash+"-sing-swim", old, false),
+		repo("gitops-template", old, false),
+		otherInstanceRepo,
+	}
+
+	tests := []struct {
+		name         string
+		action       string
+		ownerTopic   string
+		dryRun       bool
+		wantDeleted  []string
+		wantArchived []string
+		wantOrphaned float64
+	}{
+		{
+			name:         "Orphaned repositories are only reported",
+			action:       OrphanedRepositoryActionNone,
+			ownerTopic:   "has-cluster-a",
+			wantOrphaned: 2,
+		},
+		{
+			name:         "Old orphaned repositories are deleted",
+			action:       OrphanedRepositoryActionDelete,
+			ownerTopic:   "has-cluster-a",
+			wantDeleted:  []string{"appdata/deleted-app-" + hash + "-sing-swim"},
+			wantOrphaned: 2,
+		},
+		{
+			name:         "Old orphaned repositories are archived",
+			action:       OrphanedRepositoryActionArchive,
+			ownerTopic:   "has-cluster-a",
+			wantArchived: []string{"appdata/deleted-app-" + hash + "-sing-swim"},
+			wantOrphaned: 2,
+		},
+		{
+			name:         "Dry run",
+			action:       OrphanedRepositoryActionDelete,
+			ownerTopic:   "has-cluster-a",
+			dryRun:       true,
+			wantOrphaned: 2,
+		},
+		{
+			name:         "No owner topic, repositories of other instances are reported but nothing is deleted",
+			action:       OrphanedRepositoryActionDelete,
+			wantOrphaned: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fakeClient := NewFakeClient(t, application.DeepCopy(), newApplication.DeepCopy())
+			tracker := &ArchivedRepositoryTracker{Client: fakeClient, Namespace: "application-service"}
+			retained := ArchivedRepository{Provider: gitprovider.GitLab, Org: "appdata", Repo: "retained-app-" + hash + "-sing-swim", Retained: true}
+			if err := tracker.Add(ctx, retained); err != nil {
+				t.Fatalf("TestOrphanedRepositoryCollector() unexpected error: %v", err)
+			}
+
+			gitLabClient := &fakeGitProvider{repos: repos}
+			collector := &OrphanedRepositoryCollector{
+				Client:               fakeClient,
+				ArchivedRepositories: tracker,
+				GitLabClient:         gitLabClient,
+				GitLabGroup:          "appdata",
+				OwnerTopic:           tt.ownerTopic,
+				Action:               tt.action,
+				MinAge:               30 * 24 * time.Hour,
+				DryRun:               tt.dryRun,
+			}
+			if err := collector.collect(ctx, now); err != nil {
+				t.Errorf("TestOrphanedRepositoryCollector() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(gitLabClient.deleted, tt.wantDeleted) {
+				t.Errorf("TestOrphanedRepositoryCollector() error: expected deleted repositories %v got %v", tt.wantDeleted, gitLabClient.deleted)
+			}
+			if !reflect.DeepEqual(gitLabClient.archived, tt.wantArchived) {
+				t.Errorf("TestOrphanedRepositoryCollector() error: expected archived repositories %v got %v", tt.wantArchived, gitLabClient.archived)
+			}
+			// The deleted application's repository, and the recent one that isn't old enough to be collected yet
+			orphaned := testutil.ToFloat64(metrics.OrphanedGitOpsRepos.With(prometheus.Labels{"provider": gitprovider.GitLab, "org": "appdata"}))
+			if orphaned != tt.wantOrphaned {
+				t.Errorf("TestOrphanedRepositoryCollector() error: expected %v orphaned repositories got %v", tt.wantOrphaned, orphaned)
+			}
+		})
+	}
+}
+
+func TestValidateOrphanedRepositoryAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "No action, orphaned repositories are only reported",
+			want: OrphanedRepositoryActionNone,
+		},
+		{
+			name:   "Archive",
+			action: " Archive ",
+			want:   OrphanedRepositoryActionArchive,
+		},
+		{
+			name:    "Invalid action",
+			action:  "retain",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := ValidateOrphanedRepositoryAction(tt.action)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestValidateOrphanedRepositoryAction() unexpected error value: %v", err)
+			}
+			if action != tt.want {
+				t.Errorf("TestValidateOrphanedRepositoryAction() error: expected %v got %v", tt.want, action)
+			}
+		})
+	}
+}
+
+func TestValidateOrphanedRepositoryOwnerTopic(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		ownerTopic string
+		wantErr    bool
+	}{
+		{
+			name:   "Orphaned repositories are only reported, no owner topic is needed",
+			action: OrphanedRepositoryActionNone,
+		},
+		{
+			name:       "Delete with an owner topic",
+			action:     OrphanedRepositoryActionDelete,
+			ownerTopic: "has-cluster-a",
+		},
+		{
+			name:    "Archive without an owner topic",
+			action:  OrphanedRepositoryActionArchive,
+			wantErr: true,
+		},
+		{
+			name:    "Delete without an owner topic",
+			action:  OrphanedRepositoryActionDelete,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOrphanedRepositoryOwnerTopic(tt.action, tt.ownerTopic)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestValidateOrphanedRepositoryOwnerTopic() unexpected error value: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// rateLimitedConditionType is the condition set on resources whose reconcile has been delayed by GitHub rate limits
+	rateLimitedConditionType = "RateLimited"
+
+	// defaultRateLimitRequeueAfter is how long to wait before retrying if the GitHub rate limit's reset time is unknown
+	defaultRateLimitRequeueAfter = time.Minute
+
+	// maxRateLimitRequeueAfter caps the requeue delay. GitHub's primary rate limits reset every hour
+	maxRateLimitRequeueAfter = time.Hour
+)
+
+// getRateLimitRequeueAfter returns true if err was caused by GitHub rate limits, along with how long to wait before the request
+// should be requeued: until the rate limit resets, or defaultRateLimitRequeueAfter if the reset time is unknown.
+func getRateLimitRequeueAfter(err error) (time.Duration, bool) {
+	resetTime, isRateLimited := github.GetRateLimitResetTime(err)
+	if !isRateLimited {
+		return 0, false
+	}
+
+	requeueAfter := time.Until(resetTime)
+	if resetTime.IsZero() || requeueAfter <= 0 {
+		requeueAfter = defaultRateLimitRequeueAfter
+	} else if requeueAfter > maxRateLimitRequeueAfter {
+		requeueAfter = maxRateLimitRequeueAfter
+	}
+	// Give GitHub a moment to reset the rate limit before retrying
+	return requeueAfter + time.Second, true
+}
+
+// setRateLimitedCondition sets the RateLimited condition on the given conditions, to let users know why the resource's reconcile is delayed
+func setRateLimitedCondition(conditions *[]metav1.Condition, rateLimitErr error, requeueAfter time.Duration) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:    rateLimitedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "GitHubRateLimited",
+		Message: fmt.Sprintf("GitHub API rate limit reached, retrying in %v: %v", requeueAfter.Round(time.Second), rateLimitErr),
+	})
+}
+
+// clearRateLimitedCondition removes the RateLimited condition from the given conditions, once the resource has reconciled successfully
+func clearRateLimitedCondition(conditions *[]metav1.Condition) {
+	meta.RemoveStatusCondition(conditions, rateLimitedConditionType)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v52/github"
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetRateLimitRequeueAfter(t *testing.T) {
+	retryAfter := 30 * time.Second
+
+	tests := []struct {
+		name             string
+		err              error
+		wantRateLimited  bool
+		wantRequeueAfter time.Duration
+	}{
+		{
+			name: "Not a rate limit error",
+			err:  fmt.Errorf("some error"),
+		},
+		{
+			name:             "All tokens exhausted",
+			err:              &github.TokensExhaustedError{ResetTime: time.Now().Add(10 * time.Minute)},
+			wantRateLimited:  true,
+			wantRequeueAfter: 10 * time.Minute,
+		},
+		{
+			name:             "All tokens exhausted, unknown reset time",
+			err:              &github.TokensExhaustedError{},
+			wantRateLimited:  true,
+			wantRequeueAfter: defaultRateLimitRequeueAfter,
+		},
+		{
+			name:             "Primary rate limit, wrapped",
+			err:              fmt.Errorf("unable to create repository: %w", &gh.RateLimitError{Rate: gh.Rate{Reset: gh.Timestamp{Time: time.Now().Add(20 * time.Minute)}}}),
+			wantRateLimited:  true,
+			wantRequeueAfter: 20 * time.Minute,
+		},
+		{
+			name:             "Primary rate limit, reset time too far in the future",
+			err:              &gh.RateLimitError{Rate: gh.Rate{Reset: gh.Timestamp{Time: time.Now().Add(5 * time.Hour)}}},
+			wantRateLimited:  true,
+			wantRequeueAfter: maxRateLimitRequeueAfter,
+		},
+		{
+			name:             "Secondary rate limit",
+			err:              &gh.AbuseRateLimitError{RetryAfter: &retryAfter},
+			wantRateLimited:  true,
+			wantRequeueAfter: retryAfter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requeueAfter, isRateLimited := getRateLimitRequeueAfter(tt.err)
+			if isRateLimited != tt.wantRateLimited {
+				t.Errorf("TestGetRateLimitRequeueAfter() error: expected rate limited %v got %v", tt.wantRateLimited, isRateLimited)
+			}
+			// Allow for the time elapsed while running the test, and the extra second added to the requeue
+			if tt.wantRateLimited && (requeueAfter < tt.wantRequeueAfter-time.Second || requeueAfter > tt.wantRequeueAfter+time.Second) {
+				t.Errorf("TestGetRateLimitRequeueAfter() error: expected requeue after %v got %v", tt.wantRequeueAfter, requeueAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimitedCondition(t *testing.T) {
+	var conditions []metav1.Condition
+	setRateLimitedCondition(&conditions, &github.TokensExhaustedError{}, time.Minute)
+	condition := meta.FindStatusCondition(conditions, rateLimitedConditionType)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("TestRateLimitedCondition() error: expected the %v condition to be set, got %v", rateLimitedConditionType, conditions)
+	}
+
+	clearRateLimitedCondition(&conditions)
+	if meta.FindStatusCondition(conditions, rateLimitedConditionType) != nil {
+		t.Errorf("TestRateLimitedCondition() error: expected the %v condition to be removed, got %v", rateLimitedConditionType, conditions)
+	}
+}
@@ -111,6 +111,40 @@ func (r *ComponentReconciler) updateComponentDevfileModel(req ctrl.Request, hasC
 			compUpdateRequired = true
 		}
 
+		// Update for Autoscaling
+		autoscaling, err := devfile.GetAutoscalingFromAnnotations(component.Annotations)
+		if err != nil {
+			return err
+		}
+		if autoscaling.IsSet() {
+			currentAutoscaling, err := devfile.GetAutoscalingFromAttributes(kubernetesComponent.Attributes)
+			if err != nil {
+				return err
+			}
+			if !currentAutoscaling.Merge(autoscaling).Equal(currentAutoscaling) {
+				log.Info(fmt.Sprintf("setting devfile component %s autoscaling attributes from the Component annotations", kubernetesComponent.Name))
+				kubernetesComponent.Attributes = autoscaling.PutAttributes(kubernetesComponent.Attributes)
+				compUpdateRequired = true
+			}
+		}
+
+		// Update for Policies
+		policies, err := devfile.GetPoliciesFromAnnotations(component.Annotations)
+		if err != nil {
+			return err
+		}
+		if policies.IsSet() {
+			currentPolicies, err := devfile.GetPoliciesFromAttributes(kubernetesComponent.Attributes)
+			if err != nil {
+				return err
+			}
+			if !currentPolicies.Merge(policies).Equal(currentPolicies) {
+				log.Info(fmt.Sprintf("setting devfile component %s policy attributes from the Component annotations", kubernetesComponent.Name))
+				kubernetesComponent.Attributes = policies.PutAttributes(kubernetesComponent.Attributes)
+				compUpdateRequired = true
+			}
+		}
+
 		// Update for Env
 		currentENV := []corev1.EnvVar{}
 		err = kubernetesComponent.Attributes.GetInto(devfile.ContainerENVKey, &currentENV)
@@ -120,8 +154,8 @@ func (r *ComponentReconciler) updateComponentDevfileModel(req ctrl.Request, hasC
 			}
 		}
 		for _, env := range component.Spec.Env {
-			if env.ValueFrom != nil {
-				return fmt.Errorf("env.ValueFrom is not supported at the moment, use env.value")
+			if err := devfile.ValidateEnvVarSource(env); err != nil {
+				return err
 			}
 
 			name := env.Name
@@ -133,6 +167,7 @@ func (r *ComponentReconciler) updateComponentDevfileModel(req ctrl.Request, hasC
 					isPresent = true
 					log.Info(fmt.Sprintf("setting devfileComponent %s env %s", kubernetesComponent.Name, devfileEnv.Name))
 					devfileEnv.Value = value
+					devfileEnv.ValueFrom = env.ValueFrom
 					currentENV[i] = devfileEnv
 				}
 			}
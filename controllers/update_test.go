@@ -46,6 +46,14 @@ import (
 
 var numReplica = 1
 
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 func TestUpdateApplicationDevfileModel(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -725,7 +733,7 @@ func TestUpdateComponentDevfileModel(t *testing.T) {
 			updateExpected: true,
 		},
 		{
-			name: "Component with envFrom component - should error out as it's not supported right now",
+			name: "Component with env from Secret and ConfigMap keys",
 			components: []devfileAPIV1.Component{
 				{
 					Name:       "component1",
@@ -738,14 +746,26 @@ func TestUpdateComponentDevfileModel(t *testing.T) {
 			component: appstudiov1alpha1.Component{
 				Spec: appstudiov1alpha1.ComponentSpec{
 					ComponentName: "component1",
+					Replicas:      &numReplica,
 					Env: []corev1.EnvVar{
 						{
-							Name:  "FOO",
-							Value: "foo",
+							Name: "FOO",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: "secret",
+									},
+									Key: "test",
+								},
+							},
 						},
 						{
+							Name: "BAR",
 							ValueFrom: &corev1.EnvVarSource{
-								SecretKeyRef: &corev1.SecretKeySelector{
+								ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: "configmap",
+									},
 									Key: "test",
 								},
 							},
@@ -753,6 +773,34 @@ func TestUpdateComponentDevfileModel(t *testing.T) {
 					},
 				},
 			},
+			updateExpected: true,
+		},
+		{
+			name: "Component with env from a field - should error out as only Secret and ConfigMap keys are supported",
+			components: []devfileAPIV1.Component{
+				{
+					Name:       "component1",
+					Attributes: envAttributes,
+					ComponentUnion: devfileAPIV1.ComponentUnion{
+						Kubernetes: &devfileAPIV1.KubernetesComponent{},
+					},
+				},
+			},
+			component: appstudiov1alpha1.Component{
+				Spec: appstudiov1alpha1.ComponentSpec{
+					ComponentName: "component1",
+					Env: []corev1.EnvVar{
+						{
+							Name: "FOO",
+							ValueFrom: &corev1.EnvVarSource{
+								FieldRef: &corev1.ObjectFieldSelector{
+									FieldPath: "metadata.name",
+								},
+							},
+						},
+					},
+				},
+			},
 			wantErr: true,
 		},
 		{
@@ -926,6 +974,112 @@ func TestUpdateComponentDevfileModel(t *testing.T) {
 	}
 }
 
+func TestUpdateComponentDevfileModelAutoscaling(t *testing.T) {
+	tests := []struct {
+		name            string
+		attributes      attributes.Attributes
+		annotations     map[string]string
+		wantAutoscaling devfilePkg.Autoscaling
+		wantPolicies    devfilePkg.Policies
+		wantErr         bool
+	}{
+		{
+			name: "No autoscaling annotations",
+		},
+		{
+			name: "Autoscaling annotations",
+			annotations: map[string]string{
+				devfilePkg.MinReplicasAnnotation:          "2",
+				devfilePkg.MaxReplicasAnnotation:          "5",
+				devfilePkg.TargetCPUUtilizationAnnotation: "75",
+			},
+			wantAutoscaling: devfilePkg.Autoscaling{MinReplicas: int32Ptr(2), MaxReplicas: int32Ptr(5), TargetCPUUtilization: int32Ptr(75)},
+		},
+		{
+			name:            "Annotations override the devfile attributes",
+			attributes:      attributes.Attributes{}.PutInteger(devfilePkg.MaxReplicasKey, 3).PutInteger(devfilePkg.TargetMemoryUtilizationKey, 80),
+			annotations:     map[string]string{devfilePkg.MaxReplicasAnnotation: "6"},
+			wantAutoscaling: devfilePkg.Autoscaling{MaxReplicas: int32Ptr(6), TargetMemoryUtilization: int32Ptr(80)},
+		},
+		{
+			name:        "Invalid autoscaling annotation",
+			annotations: map[string]string{devfilePkg.MaxReplicasAnnotation: "many"},
+			wantErr:     true,
+		},
+		{
+			name:         "Policy annotations override the devfile attributes",
+			attributes:   attributes.Attributes{}.PutBoolean(devfilePkg.PodDisruptionBudgetKey, true).PutBoolean(devfilePkg.NetworkPolicyKey, true),
+			annotations:  map[string]string{devfilePkg.NetworkPolicyAnnotation: "false"},
+			wantPolicies: devfilePkg.Policies{PodDisruptionBudget: boolPtr(true), NetworkPolicy: boolPtr(false)},
+		},
+		{
+			name:        "Invalid policy annotation",
+			annotations: map[string]string{devfilePkg.PodDisruptionBudgetAnnotation: "maybe"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devfileData := &v2.DevfileV2{
+				Devfile: devfileAPIV1.Devfile{
+					DevWorkspaceTemplateSpec: devfileAPIV1.DevWorkspaceTemplateSpec{
+						DevWorkspaceTemplateSpecContent: devfileAPIV1.DevWorkspaceTemplateSpecContent{
+							Components: []devfileAPIV1.Component{
+								{
+									Name:       "component1",
+									Attributes: tt.attributes,
+									ComponentUnion: devfileAPIV1.ComponentUnion{
+										Kubernetes: &devfileAPIV1.KubernetesComponent{},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			component := appstudiov1alpha1.Component{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tt.annotations,
+				},
+				Spec: appstudiov1alpha1.ComponentSpec{
+					ComponentName: "componentName",
+					Application:   "applicationName",
+				},
+			}
+
+			r := ComponentReconciler{
+				Log: ctrl.Log.WithName("TestUpdateComponentDevfileModelAutoscaling"),
+			}
+			err := r.updateComponentDevfileModel(ctrl.Request{}, devfileData, component)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("unexpected error value: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			components, err := devfileData.GetComponents(common.DevfileOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error getting the devfile components: %v", err)
+			}
+			autoscaling, err := devfilePkg.GetAutoscalingFromAttributes(components[0].Attributes)
+			if err != nil {
+				t.Fatalf("unexpected error getting the autoscaling attributes: %v", err)
+			}
+			if !autoscaling.Equal(tt.wantAutoscaling) {
+				t.Errorf("expected autoscaling %+v, got %+v", tt.wantAutoscaling, autoscaling)
+			}
+			policies, err := devfilePkg.GetPoliciesFromAttributes(components[0].Attributes)
+			if err != nil {
+				t.Fatalf("unexpected error getting the policy attributes: %v", err)
+			}
+			if !policies.Equal(tt.wantPolicies) {
+				t.Errorf("expected policies %+v, got %+v", tt.wantPolicies, policies)
+			}
+		})
+	}
+}
+
 func TestUpdateComponentStub(t *testing.T) {
 	var err error
 	envAttributes := attributes.Attributes{}.FromMap(map[string]interface{}{devfilePkg.ContainerENVKey: []corev1.EnvVar{{Name: "name1", Value: "value1"}}}, &err)
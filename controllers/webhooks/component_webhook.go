@@ -24,6 +24,8 @@ import (
 	"strings"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/github"
+	"golang.org/x/exp/slices"
 
 	"github.com/go-logr/logr"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
@@ -65,6 +67,16 @@ func (r *ComponentWebhook) Default(ctx context.Context, obj runtime.Object) erro
 // Github is the only supported vendor right now
 const SupportedGitRepo = "github.com"
 
+// supportedGitHosts returns the hosts that Component git sources can be on: github.com, and the
+// GitHub Enterprise Server instance, if one is configured
+func supportedGitHosts() []string {
+	hosts := []string{SupportedGitRepo}
+	if gitHubHost := github.GetGitHubURLs().Host(); gitHubHost != "" && gitHubHost != SupportedGitRepo {
+		hosts = append(hosts, gitHubHost)
+	}
+	return hosts
+}
+
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
 // +kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1alpha1-component,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=components,verbs=create;update,versions=v1alpha1,name=vcomponent.kb.io,admissionReviewVersions=v1
 
@@ -83,8 +95,8 @@ func (r *ComponentWebhook) ValidateCreate(ctx context.Context, obj runtime.Objec
 	if comp.Spec.Source.GitSource != nil && comp.Spec.Source.GitSource.URL != "" {
 		if gitsourceURL, err := url.ParseRequestURI(comp.Spec.Source.GitSource.URL); err != nil {
 			return fmt.Errorf(err.Error() + appstudiov1alpha1.InvalidSchemeGitSourceURL)
-		} else if SupportedGitRepo != strings.ToLower(gitsourceURL.Host) {
-			return fmt.Errorf(appstudiov1alpha1.InvalidGithubVendorURL, gitsourceURL, SupportedGitRepo)
+		} else if hosts := supportedGitHosts(); !slices.Contains(hosts, strings.ToLower(gitsourceURL.Host)) {
+			return fmt.Errorf(appstudiov1alpha1.InvalidGithubVendorURL, gitsourceURL, strings.Join(hosts, ", "))
 		}
 
 		sourceSpecified = true
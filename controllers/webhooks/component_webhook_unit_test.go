@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/github"
 	"go.uber.org/zap/zapcore"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -37,9 +38,10 @@ import (
 func TestComponentCreateValidatingWebhook(t *testing.T) {
 
 	tests := []struct {
-		name    string
-		newComp appstudiov1alpha1.Component
-		err     string
+		name      string
+		newComp   appstudiov1alpha1.Component
+		gitHubURL string
+		err       string
 	}{
 		{
 			name: "component metadata.name is invalid",
@@ -112,6 +114,47 @@ func TestComponentCreateValidatingWebhook(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "valid component with GitHub Enterprise git src",
+			gitHubURL: "https://ghe.example.com",
+			newComp: appstudiov1alpha1.Component{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "test-component",
+				},
+				Spec: appstudiov1alpha1.ComponentSpec{
+					ComponentName: "component1",
+					Application:   "application1",
+					Source: appstudiov1alpha1.ComponentSource{
+						ComponentSourceUnion: appstudiov1alpha1.ComponentSourceUnion{
+							GitSource: &appstudiov1alpha1.GitSource{
+								URL: "https://ghe.example.com/devfile-samples/devfile-sample-java-springboot-basic",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:      "valid component with invalid git vendor src, GitHub Enterprise configured",
+			gitHubURL: "https://ghe.example.com",
+			err:       fmt.Errorf(appstudiov1alpha1.InvalidGithubVendorURL, "http://url", SupportedGitRepo+", ghe.example.com").Error(),
+			newComp: appstudiov1alpha1.Component{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "test-component",
+				},
+				Spec: appstudiov1alpha1.ComponentSpec{
+					ComponentName: "component1",
+					Application:   "application1",
+					Source: appstudiov1alpha1.ComponentSource{
+						ComponentSourceUnion: appstudiov1alpha1.ComponentSourceUnion{
+							GitSource: &appstudiov1alpha1.GitSource{
+								URL: "http://url",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "valid component with invalid git scheme src",
 			err:  "invalid URI for request" + appstudiov1alpha1.InvalidSchemeGitSourceURL,
@@ -148,6 +191,12 @@ func TestComponentCreateValidatingWebhook(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			if test.gitHubURL != "" {
+				gitHubURLs, err := github.NewGitHubURLs(test.gitHubURL, "", "")
+				require.NoError(t, err)
+				github.SetGitHubURLs(gitHubURLs)
+				defer github.SetGitHubURLs(github.GitHubURLs{BaseURL: github.DefaultGitHubURL, RawURL: github.DefaultGitHubRawURL})
+			}
 			compWebhook := ComponentWebhook{
 				log: zap.New(zap.UseFlagOptions(&zap.Options{
 					Development: true,
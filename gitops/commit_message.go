@@ -0,0 +1,182 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// DefaultComponentCommitMessageTemplate is the template of the message of commits of Components' base GitOps resources
+	DefaultComponentCommitMessageTemplate = `Generate GitOps resources for {{if eq (len .Components) 1}}component{{else}}components{{end}} {{.ComponentNames}}`
+
+	// DefaultBindingCommitMessageTemplate is the template of the message of commits of the environment overlays of SnapshotEnvironmentBindings
+	DefaultBindingCommitMessageTemplate = `Generate {{.Environment}} environment overlays for {{if eq (len .Components) 1}}component{{else}}components{{end}} {{.ComponentNames}}
+
+Deploy Snapshot {{.Snapshot}} of Application {{.Application}} to Environment {{.Environment}}.`
+)
+
+// CommitComponent is a Component whose GitOps resources are in a commit
+type CommitComponent struct {
+	Name string
+
+	// Image is the container image of the Component, if known
+	Image string
+
+	// SourceURL and SourceRevision are the git repository and revision the Component was built from, if known
+	SourceURL      string
+	SourceRevision string
+}
+
+// CommitMessageData is the data that commit message templates are rendered with
+type CommitMessageData struct {
+	Namespace   string
+	Application string
+	Components  []CommitComponent
+
+	// Environment and Snapshot are only set for the commits of SnapshotEnvironmentBindings
+	Environment string
+	Snapshot    string
+
+	// Reconciler is the kind of resource whose reconcile made the commit, and Resource and Generation identify the resource, when the
+	// commit was made for a single one
+	Reconciler string
+	Resource   string
+	Generation int64
+}
+
+// ComponentNames returns the comma separated names of the Components in the commit
+func (d CommitMessageData) ComponentNames() string {
+	names := make([]string, len(d.Components))
+	for i, component := range d.Components {
+		names[i] = component.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Trailers returns the git trailers that identify what produced a commit, so that tools can trace GitOps commits back to it
+func (d CommitMessageData) Trailers() []string {
+	var trailers []string
+	addTrailer := func(key string, value string) {
+		if value != "" {
+			// Trailers are line based, so a value can't span several lines
+			trailers = append(trailers, fmt.Sprintf("%s: %s", key, strings.Join(strings.Fields(value), " ")))
+		}
+	}
+	addTrailer("Application", d.Application)
+	for _, component := range d.Components {
+		addTrailer("Component", component.Name)
+	}
+	addTrailer("Environment", d.Environment)
+	addTrailer("Snapshot", d.Snapshot)
+	for _, component := range d.Components {
+		if component.SourceURL != "" && component.SourceRevision != "" {
+			addTrailer("Source-Commit", fmt.Sprintf("%s %s@%s", component.Name, component.SourceURL, component.SourceRevision))
+		}
+	}
+	addTrailer("Namespace", d.Namespace)
+	generatedBy := []string{"application-service"}
+	if d.Reconciler != "" {
+		generatedBy = append(generatedBy, d.Reconciler)
+	}
+	if d.Resource != "" {
+		generatedBy = append(generatedBy, d.Resource, fmt.Sprintf("generation %d", d.Generation))
+	}
+	addTrailer("Generated-By", strings.Join(generatedBy, " "))
+	return trailers
+}
+
+// CommitMessages renders the messages of the commits pushed to GitOps repositories from templates. The templates are Go text
+// templates, rendered with CommitMessageData. The trailers of the commit are always appended to the rendered message.
+type CommitMessages struct {
+	component *template.Template
+	binding   *template.Template
+}
+
+var defaultCommitMessages = mustNewCommitMessages(DefaultComponentCommitMessageTemplate, DefaultBindingCommitMessageTemplate)
+
+// NewCommitMessages returns the commit messages rendered from the given templates. An empty template uses the default one.
+func NewCommitMessages(componentTemplate string, bindingTemplate string) (*CommitMessages, error) {
+	if componentTemplate == "" {
+		componentTemplate = DefaultComponentCommitMessageTemplate
+	}
+	if bindingTemplate == "" {
+		bindingTemplate = DefaultBindingCommitMessageTemplate
+	}
+	component, err := parseCommitMessageTemplate("component", componentTemplate)
+	if err != nil {
+		return nil, err
+	}
+	binding, err := parseCommitMessageTemplate("binding", bindingTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &CommitMessages{component: component, binding: binding}, nil
+}
+
+func mustNewCommitMessages(componentTemplate string, bindingTemplate string) *CommitMessages {
+	messages, err := NewCommitMessages(componentTemplate, bindingTemplate)
+	if err != nil {
+		panic(err)
+	}
+	return messages
+}
+
+// parseCommitMessageTemplate parses the template, and renders it once so that templates that can't be rendered with
+// CommitMessageData, for example because they use a field that doesn't exist, are rejected up front
+func parseCommitMessageTemplate(name string, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s commit message template: %v", name, err)
+	}
+	if _, err := renderCommitMessage(tmpl, CommitMessageData{Components: []CommitComponent{{Name: "component"}}}); err != nil {
+		return nil, fmt.Errorf("invalid %s commit message template: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// ComponentMessage returns the message of a commit of the base GitOps resources of Components. A nil CommitMessages uses the default
+// templates.
+func (m *CommitMessages) ComponentMessage(data CommitMessageData) (string, error) {
+	if m == nil {
+		m = defaultCommitMessages
+	}
+	return renderCommitMessage(m.component, data)
+}
+
+// BindingMessage returns the message of a commit of the environment overlays of a SnapshotEnvironmentBinding. A nil CommitMessages
+// uses the default templates.
+func (m *CommitMessages) BindingMessage(data CommitMessageData) (string, error) {
+	if m == nil {
+		m = defaultCommitMessages
+	}
+	return renderCommitMessage(m.binding, data)
+}
+
+func renderCommitMessage(tmpl *template.Template, data CommitMessageData) (string, error) {
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", fmt.Errorf("unable to render the commit message: %v", err)
+	}
+	text := strings.TrimSpace(message.String())
+	if text == "" {
+		return "", fmt.Errorf("unable to render the commit message: the template rendered an empty message")
+	}
+	return text + "\n\n" + strings.Join(data.Trailers(), "\n"), nil
+}
@@ -0,0 +1,138 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"testing"
+)
+
+func TestCommitMessages(t *testing.T) {
+	frontend := CommitComponent{Name: "frontend", SourceURL: "https://github.com/devfile-samples/frontend", SourceRevision: "ca82a6dff817ec66f44342007202690a93763949"}
+	backend := CommitComponent{Name: "backend"}
+
+	tests := []struct {
+		name              string
+		componentTemplate string
+		bindingTemplate   string
+		binding           bool
+		data              CommitMessageData
+		want              string
+		wantErr           bool
+	}{
+		{
+			name: "Default Component template",
+			data: CommitMessageData{Namespace: "test-ns", Application: "test-application", Components: []CommitComponent{frontend}, Reconciler: "Component", Resource: "frontend", Generation: 2},
+			want: `Generate GitOps resources for component frontend
+
+Application: test-application
+Component: frontend
+Source-Commit: frontend https://github.com/devfile-samples/frontend@ca82a6dff817ec66f44342007202690a93763949
+Namespace: test-ns
+Generated-By: application-service Component frontend generation 2`,
+		},
+		{
+			name: "Default Component template, batch of Components",
+			data: CommitMessageData{Namespace: "test-ns", Application: "test-application", Components: []CommitComponent{backend, frontend}, Reconciler: "Component"},
+			want: `Generate GitOps resources for components backend, frontend
+
+Application: test-application
+Component: backend
+Component: frontend
+Source-Commit: frontend https://github.com/devfile-samples/frontend@ca82a6dff817ec66f44342007202690a93763949
+Namespace: test-ns
+Generated-By: application-service Component`,
+		},
+		{
+			name:    "Default binding template",
+			binding: true,
+			data:    CommitMessageData{Namespace: "test-ns", Application: "test-application", Components: []CommitComponent{backend}, Environment: "staging", Snapshot: "test-snapshot", Reconciler: "SnapshotEnvironmentBinding", Resource: "test-binding", Generation: 1},
+			want: `Generate staging environment overlays for component backend
+
+Deploy Snapshot test-snapshot of Application test-application to Environment staging.
+
+Application: test-application
+Component: backend
+Environment: staging
+Snapshot: test-snapshot
+Namespace: test-ns
+Generated-By: application-service SnapshotEnvironmentBinding test-binding generation 1`,
+		},
+		{
+			name:            "Custom binding template",
+			bindingTemplate: `chore({{.Environment}}): deploy {{.Snapshot}}{{range .Components}}{{if .SourceRevision}} {{.Name}}@{{.SourceRevision}}{{end}}{{end}}`,
+			binding:         true,
+			data:            CommitMessageData{Application: "test-application", Components: []CommitComponent{frontend}, Environment: "staging", Snapshot: "test-snapshot"},
+			want: `chore(staging): deploy test-snapshot frontend@ca82a6dff817ec66f44342007202690a93763949
+
+Application: test-application
+Component: frontend
+Environment: staging
+Snapshot: test-snapshot
+Source-Commit: frontend https://github.com/devfile-samples/frontend@ca82a6dff817ec66f44342007202690a93763949
+Generated-By: application-service`,
+		},
+		{
+			name:              "Invalid template",
+			componentTemplate: `Generate {{.Component`,
+			wantErr:           true,
+		},
+		{
+			name:              "Template with a field that doesn't exist",
+			componentTemplate: `Generate {{.Component}}`,
+			wantErr:           true,
+		},
+		{
+			name:              "Template that renders an empty message",
+			componentTemplate: `{{if .Snapshot}}Deploy{{end}}`,
+			wantErr:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := NewCommitMessages(tt.componentTemplate, tt.bindingTemplate)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestCommitMessages() unexpected error value: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			var got string
+			if tt.binding {
+				got, err = messages.BindingMessage(tt.data)
+			} else {
+				got, err = messages.ComponentMessage(tt.data)
+			}
+			if err != nil {
+				t.Fatalf("TestCommitMessages() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TestCommitMessages() error: expected\n%v\ngot\n%v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultCommitMessages(t *testing.T) {
+	var messages *CommitMessages
+	got, err := messages.ComponentMessage(CommitMessageData{Components: []CommitComponent{{Name: "frontend"}}})
+	if err != nil {
+		t.Fatalf("TestDefaultCommitMessages() unexpected error: %v", err)
+	}
+	if want := "Generate GitOps resources for component frontend\n\nComponent: frontend\nGenerated-By: application-service"; got != want {
+		t.Errorf("TestDefaultCommitMessages() error: expected %q got %q", want, got)
+	}
+}
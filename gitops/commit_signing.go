@@ -0,0 +1,158 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CommitOptions are the identity that the commits of the GitOps repositories are made with, and the key that they're signed with
+type CommitOptions struct {
+	// SigningKey is the private key that commits are signed with, either an SSH key or an ASCII armored GPG key. It mustn't have a
+	// passphrase. Commits aren't signed if it's empty.
+	SigningKey []byte
+
+	// AuthorName and AuthorEmail override the author of the commits, which otherwise comes from the git config
+	AuthorName  string
+	AuthorEmail string
+
+	// CommitterName and CommitterEmail override the committer of the commits, and default to the author
+	CommitterName  string
+	CommitterEmail string
+}
+
+// signingFailureMessages are the messages that git and the signing programs fail with when a commit can't be signed
+var signingFailureMessages = []string{
+	"failed to sign the data",
+	"couldn't load public key",
+	"load key",
+}
+
+// CommitConfig returns the git config that makes git commit with the given options, as key=value entries. The signing key is
+// written to the given directory, which must only be readable by the current user. The config is set on each GitOps repository
+// that's checked out, by a CommitConfigGenerator, so that it only applies to the commits made by the gitops generator.
+func CommitConfig(options CommitOptions, dir string) ([]string, error) {
+	var config []string
+	committerName, committerEmail := options.CommitterName, options.CommitterEmail
+	if committerName == "" {
+		committerName = options.AuthorName
+	}
+	if committerEmail == "" {
+		committerEmail = options.AuthorEmail
+	}
+	for _, entry := range [][2]string{
+		{"author.name", options.AuthorName},
+		{"author.email", options.AuthorEmail},
+		{"committer.name", committerName},
+		{"committer.email", committerEmail},
+	} {
+		if entry[1] != "" {
+			config = append(config, entry[0]+"="+entry[1])
+		}
+	}
+	if len(options.SigningKey) == 0 {
+		return config, nil
+	}
+
+	if bytes.Contains(options.SigningKey, []byte("BEGIN PGP PRIVATE KEY BLOCK")) {
+		gnupgHome := filepath.Join(dir, "gnupg")
+		if err := os.MkdirAll(gnupgHome, 0700); err != nil {
+			return nil, err
+		}
+		fingerprint, err := importGPGKey(gnupgHome, options.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		// git runs gpg with its own environment, so point gpg at the keyring through a wrapper rather than GNUPGHOME
+		gpgProgram := filepath.Join(dir, "gpg")
+		script := fmt.Sprintf("#!/bin/sh\nexec gpg --homedir '%s' \"$@\"\n", gnupgHome)
+		/* #nosec G306 -- the wrapper has to be executable, and holds no secret */
+		if err := os.WriteFile(gpgProgram, []byte(script), 0700); err != nil {
+			return nil, err
+		}
+		config = append(config, "gpg.format=openpgp", "gpg.program="+gpgProgram, "user.signingkey="+fingerprint)
+	} else {
+		keyPath := filepath.Join(dir, "signing-key")
+		key := options.SigningKey
+		// ssh-keygen rejects private keys without a trailing newline, which is easily lost when the key is put in a Secret
+		if !bytes.HasSuffix(key, []byte("\n")) {
+			key = append(append([]byte{}, key...), '\n')
+		}
+		if err := os.WriteFile(keyPath, key, 0600); err != nil {
+			return nil, err
+		}
+		config = append(config, "gpg.format=ssh", "user.signingkey="+keyPath)
+	}
+	return append(config, "commit.gpgsign=true"), nil
+}
+
+// SetRepositoryConfig sets the given key=value entries in the local git config of the repository at repoPath
+func SetRepositoryConfig(repoPath string, config []string) error {
+	for _, entry := range config {
+		key, value, _ := strings.Cut(entry, "=")
+		/* #nosec G204 -- the config entries come from the manager's own settings */
+		cmd := exec.Command("git", "config", "--local", key, value)
+		cmd.Dir = repoPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("unable to set %s in the git config of %s: %v: %s", key, repoPath, err, out)
+		}
+	}
+	return nil
+}
+
+// importGPGKey imports the GPG private key into the keyring in gnupgHome, and returns its fingerprint
+func importGPGKey(gnupgHome string, key []byte) (string, error) {
+	/* #nosec G204 -- the gpg arguments are fixed */
+	importCmd := exec.Command("gpg", "--batch", "--import")
+	importCmd.Env = append(os.Environ(), "GNUPGHOME="+gnupgHome)
+	importCmd.Stdin = bytes.NewReader(key)
+	if out, err := importCmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("unable to import the GPG signing key: %v: %s", err, out)
+	}
+
+	/* #nosec G204 -- the gpg arguments are fixed */
+	listCmd := exec.Command("gpg", "--batch", "--with-colons", "--list-secret-keys")
+	listCmd.Env = append(os.Environ(), "GNUPGHOME="+gnupgHome)
+	out, err := listCmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to list the GPG signing key: %v", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Split(line, ":"); len(fields) > 9 && fields[0] == "fpr" {
+			return fields[9], nil
+		}
+	}
+	return "", fmt.Errorf("the GPG signing key doesn't contain a secret key")
+}
+
+// IsCommitSigningError returns true if the given error is from a git commit that couldn't be signed
+func IsCommitSigningError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, failure := range signingFailureMessages {
+		if strings.Contains(message, failure) {
+			return true
+		}
+	}
+	return false
+}
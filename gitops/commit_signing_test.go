@@ -0,0 +1,204 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newSSHSigningKey generates an SSH private key without a passphrase
+func newSSHSigningKey(t *testing.T) []byte {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen isn't installed")
+	}
+	keyPath := filepath.Join(t.TempDir(), "key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("unable to generate an SSH key: %v: %s", err, out)
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// newGPGSigningKey generates an ASCII armored GPG private key without a passphrase
+func newGPGSigningKey(t *testing.T) []byte {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg isn't installed")
+	}
+	gnupgHome := t.TempDir()
+	genCmd := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "AppData Robot <appdata@example.com>", "ed25519", "sign", "never")
+	genCmd.Env = append(os.Environ(), "GNUPGHOME="+gnupgHome)
+	if out, err := genCmd.CombinedOutput(); err != nil {
+		t.Fatalf("unable to generate a GPG key: %v: %s", err, out)
+	}
+	exportCmd := exec.Command("gpg", "--batch", "--armor", "--export-secret-keys")
+	exportCmd.Env = append(os.Environ(), "GNUPGHOME="+gnupgHome)
+	key, err := exportCmd.Output()
+	if err != nil {
+		t.Fatalf("unable to export the GPG key: %v", err)
+	}
+	return key
+}
+
+// commit makes a commit in a new repository, with the given git config set on it, and returns the raw commit object
+func commit(t *testing.T, config []string) (string, error) {
+	repoPath := t.TempDir()
+	if out, err := exec.Command("git", "init", "--quiet", repoPath).CombinedOutput(); err != nil {
+		return "", errors.New(string(out))
+	}
+	if err := SetRepositoryConfig(repoPath, config); err != nil {
+		return "", err
+	}
+	cmd := exec.Command("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "Generate GitOps resources")
+	cmd.Dir = repoPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", errors.New(string(out))
+	}
+	cmd = exec.Command("git", "cat-file", "-p", "HEAD")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func TestCommitConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       func(t *testing.T) CommitOptions
+		wantSignature string
+		wantAuthor    string
+		wantCommitter string
+	}{
+		{
+			name:          "No options",
+			options:       func(t *testing.T) CommitOptions { return CommitOptions{} },
+			wantAuthor:    "author test <test@example.com>",
+			wantCommitter: "committer test <test@example.com>",
+		},
+		{
+			name: "Author, committer defaults to it",
+			options: func(t *testing.T) CommitOptions {
+				return CommitOptions{AuthorName: "AppData Robot", AuthorEmail: "appdata@example.com"}
+			},
+			wantAuthor:    "author AppData Robot <appdata@example.com>",
+			wantCommitter: "committer AppData Robot <appdata@example.com>",
+		},
+		{
+			name: "Author and committer",
+			options: func(t *testing.T) CommitOptions {
+				return CommitOptions{AuthorName: "AppData Robot", AuthorEmail: "appdata@example.com", CommitterName: "application-service", CommitterEmail: "has@example.com"}
+			},
+			wantAuthor:    "author AppData Robot <appdata@example.com>",
+			wantCommitter: "committer application-service <has@example.com>",
+		},
+		{
+			name: "SSH signing key",
+			options: func(t *testing.T) CommitOptions {
+				return CommitOptions{SigningKey: newSSHSigningKey(t)}
+			},
+			wantSignature: "-----BEGIN SSH SIGNATURE-----",
+			wantAuthor:    "author test <test@example.com>",
+			wantCommitter: "committer test <test@example.com>",
+		},
+		{
+			name: "SSH signing key without a trailing newline",
+			options: func(t *testing.T) CommitOptions {
+				return CommitOptions{SigningKey: []byte(strings.TrimSpace(string(newSSHSigningKey(t))))}
+			},
+			wantSignature: "-----BEGIN SSH SIGNATURE-----",
+			wantAuthor:    "author test <test@example.com>",
+			wantCommitter: "committer test <test@example.com>",
+		},
+		{
+			name: "GPG signing key",
+			options: func(t *testing.T) CommitOptions {
+				return CommitOptions{SigningKey: newGPGSigningKey(t), AuthorName: "AppData Robot", AuthorEmail: "appdata@example.com"}
+			},
+			wantSignature: "-----BEGIN PGP SIGNATURE-----",
+			wantAuthor:    "author AppData Robot <appdata@example.com>",
+			wantCommitter: "committer AppData Robot <appdata@example.com>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := CommitConfig(tt.options(t), t.TempDir())
+			if err != nil {
+				t.Fatalf("TestCommitConfig() unexpected error: %v", err)
+			}
+			commitObject, err := commit(t, config)
+			if err != nil {
+				t.Fatalf("TestCommitConfig() unexpected error committing: %v", err)
+			}
+			if tt.wantSignature != "" && !strings.Contains(commitObject, tt.wantSignature) {
+				t.Errorf("TestCommitConfig() error: expected the commit to be signed with %v, got %v", tt.wantSignature, commitObject)
+			}
+			if tt.wantSignature == "" && strings.Contains(commitObject, "gpgsig") {
+				t.Errorf("TestCommitConfig() error: expected the commit not to be signed, got %v", commitObject)
+			}
+			if !strings.Contains(commitObject, tt.wantAuthor) || !strings.Contains(commitObject, tt.wantCommitter) {
+				t.Errorf("TestCommitConfig() error: expected the commit to have %v and %v, got %v", tt.wantAuthor, tt.wantCommitter, commitObject)
+			}
+		})
+	}
+}
+
+func TestIsCommitSigningError(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CommitOptions
+		err     error
+		want    bool
+	}{
+		{
+			name:    "Invalid SSH signing key",
+			options: CommitOptions{SigningKey: []byte("not a key")},
+			want:    true,
+		},
+		{
+			name: "Other git error",
+			err:  errors.New("failed to push to repository \"https://github.com/redhat-appstudio-appdata/test-application\""),
+		},
+		{
+			name: "No error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err
+			if len(tt.options.SigningKey) > 0 {
+				if _, lookErr := exec.LookPath("ssh-keygen"); lookErr != nil {
+					t.Skip("ssh-keygen isn't installed")
+				}
+				config, configErr := CommitConfig(tt.options, t.TempDir())
+				if configErr != nil {
+					t.Fatalf("TestIsCommitSigningError() unexpected error: %v", configErr)
+				}
+				_, err = commit(t, config)
+			}
+			if got := IsCommitSigningError(err); got != tt.want {
+				t.Errorf("TestIsCommitSigningError() error: expected %v got %v for error %v", tt.want, got, err)
+			}
+		})
+	}
+}
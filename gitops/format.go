@@ -0,0 +1,85 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"fmt"
+	"strings"
+
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/spf13/afero"
+)
+
+const (
+	// OutputFormatAnnotation is the annotation on an Application that sets the format of the GitOps resources of its Components.
+	// Its value is OutputFormatKustomize, the default, or OutputFormatHelm.
+	OutputFormatAnnotation = "gitops-output-format"
+
+	// OutputFormatKustomize renders each Component as a kustomize base, patched by an overlay per Environment
+	OutputFormatKustomize = "kustomize"
+
+	// OutputFormatHelm renders each Component as a Helm chart, with a values file per Environment
+	OutputFormatHelm = "helm"
+)
+
+// OutputFormat renders the GitOps resources of a Component in the format of a deployment tool. Whatever the format, the base
+// resources of a Component are rendered in components/<name>/base of the GitOps repository, and its resources in an Environment in
+// components/<name>/overlays/<environment>.
+type OutputFormat interface {
+	// Name returns the value of the OutputFormatAnnotation that selects the format
+	Name() string
+
+	// Generate renders the base resources of the Component into outputFolder
+	Generate(fs afero.Afero, gitOpsFolder string, outputFolder string, options gitopsv1alpha1.GeneratorOptions) error
+
+	// GenerateOverlays renders the resources of the Component in an Environment into outputFolder, on top of its base resources
+	GenerateOverlays(fs afero.Afero, gitOpsFolder string, outputFolder string, options gitopsv1alpha1.GeneratorOptions, imageName, namespace string, componentGeneratedResources map[string][]string) error
+
+	// GenerateOverlayOptions renders the overlay options of the Component in an Environment on top of its resources in outputFolder,
+	// rendered by GenerateOverlays
+	GenerateOverlayOptions(fs afero.Afero, outputFolder string, options gitopsv1alpha1.GeneratorOptions, overlay OverlayOptions) error
+}
+
+// Kustomize is the default OutputFormat, rendered by the gitops generator
+type Kustomize struct{}
+
+// Name returns OutputFormatKustomize
+func (Kustomize) Name() string {
+	return OutputFormatKustomize
+}
+
+// Generate renders the kustomize base of the Component
+func (Kustomize) Generate(fs afero.Afero, gitOpsFolder string, outputFolder string, options gitopsv1alpha1.GeneratorOptions) error {
+	return gitopsgen.Generate(fs, gitOpsFolder, outputFolder, options)
+}
+
+// GenerateOverlays renders the kustomize overlay of the Component, keeping the custom patches of an existing overlay
+func (Kustomize) GenerateOverlays(fs afero.Afero, gitOpsFolder string, outputFolder string, options gitopsv1alpha1.GeneratorOptions, imageName, namespace string, componentGeneratedResources map[string][]string) error {
+	return gitopsgen.GenerateOverlays(fs, gitOpsFolder, outputFolder, options, imageName, namespace, componentGeneratedResources)
+}
+
+// GetOutputFormat returns the OutputFormat set by the OutputFormatAnnotation in the given annotations, or Kustomize if it isn't set
+func GetOutputFormat(annotations map[string]string) (OutputFormat, error) {
+	switch value := strings.ToLower(strings.TrimSpace(annotations[OutputFormatAnnotation])); value {
+	case "", OutputFormatKustomize:
+		return Kustomize{}, nil
+	case OutputFormatHelm:
+		return Helm{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported GitOps output format %q, expected %s or %s", value, OutputFormatKustomize, OutputFormatHelm)
+	}
+}
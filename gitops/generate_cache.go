@@ -0,0 +1,95 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/redhat-appstudio/application-service/pkg/gitcache"
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/redhat-developer/gitops-generator/pkg/util"
+	"github.com/spf13/afero"
+)
+
+// CachingGenerator is a gitops generator that checks out GitOps repositories from a cache of their mirrors, rather than cloning
+// them from scratch. Committing, pushing and generating new repositories are left to the wrapped generator.
+type CachingGenerator struct {
+	gitopsgen.Generator
+	Cache *gitcache.Cache
+}
+
+// NewCachingGenerator returns a gitops generator that checks out GitOps repositories from the given cache
+func NewCachingGenerator(generator gitopsgen.Generator, cache *gitcache.Cache) *CachingGenerator {
+	return &CachingGenerator{Generator: generator, Cache: cache}
+}
+
+// CloneRepo checks out the branch of the repo into the componentName folder of outputPath
+func (g *CachingGenerator) CloneRepo(outputPath string, remote string, componentName string, branch string) error {
+	if err := util.ValidateRemote(remote); err != nil {
+		return err
+	}
+	if err := g.Cache.Checkout(remote, outputPath, componentName, branch); err != nil {
+		return util.SanitizeErrorMessage(fmt.Errorf("failed to check out the GitOps repository: %v", err))
+	}
+	return nil
+}
+
+// CloneGenerateAndPush checks out the repo and generates the gitops resources of the component in it, like the wrapped generator
+func (g *CachingGenerator) CloneGenerateAndPush(outputPath string, remote string, options gitopsv1alpha1.GeneratorOptions, appFs afero.Afero, branch string, context string, doPush bool) error {
+	componentName := options.Name
+	if err := g.CloneRepo(outputPath, remote, componentName, branch); err != nil {
+		return err
+	}
+
+	gitopsFolder := filepath.Join(outputPath, componentName, context)
+	componentPath := filepath.Join(gitopsFolder, "components", componentName, "base")
+	if err := appFs.RemoveAll(componentPath); err != nil {
+		return fmt.Errorf("failed to delete %s folder in repository in %s: %v", componentPath, filepath.Join(outputPath, componentName), err)
+	}
+	if err := gitopsgen.Generate(appFs, gitopsFolder, componentPath, options); err != nil {
+		return util.SanitizeErrorMessage(fmt.Errorf("failed to generate the gitops resources in %q for component %q: %s", componentPath, componentName, err))
+	}
+
+	if doPush {
+		return g.CommitAndPush(outputPath, "", remote, componentName, branch, fmt.Sprintf("Generate GitOps base resources for component %s", componentName))
+	}
+	return nil
+}
+
+// GenerateOverlaysAndPush checks out the repo if needed, and generates the environment overlays of the component with the wrapped generator
+func (g *CachingGenerator) GenerateOverlaysAndPush(outputPath string, clone bool, remote string, options gitopsv1alpha1.GeneratorOptions, applicationName, environmentName, imageName, namespace string, appFs afero.Afero, branch string, context string, doPush bool, componentGeneratedResources map[string][]string) error {
+	if clone {
+		if err := g.CloneRepo(outputPath, remote, applicationName, branch); err != nil {
+			return err
+		}
+	}
+	return g.Generator.GenerateOverlaysAndPush(outputPath, false, remote, options, applicationName, environmentName, imageName, namespace, appFs, branch, context, doPush, componentGeneratedResources)
+}
+
+// GitRemoveComponent checks out the repo, removes the component from it, and pushes the change
+func (g *CachingGenerator) GitRemoveComponent(outputPath string, remote string, componentName string, branch string, context string) error {
+	if err := g.CloneRepo(outputPath, remote, componentName, branch); err != nil {
+		return err
+	}
+	componentPath := filepath.Join(outputPath, componentName, context, "components", componentName)
+	if err := os.RemoveAll(componentPath); err != nil {
+		return fmt.Errorf("failed to delete %s folder in repository in %s: %v", componentPath, filepath.Join(outputPath, componentName), err)
+	}
+	return g.CommitAndPush(outputPath, "", remote, componentName, branch, fmt.Sprintf("Removed component %s", componentName))
+}
@@ -0,0 +1,88 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/spf13/afero"
+)
+
+// CommitConfigGenerator is a gitops generator that sets the given git config, such as the commit signing key, on each GitOps
+// repository before committing to it, so that the config is scoped to the repositories rather than to the environment of the
+// manager. Checking out repositories and generating new ones are left to the wrapped generator.
+type CommitConfigGenerator struct {
+	gitopsgen.Generator
+	Config []string
+}
+
+// NewCommitConfigGenerator returns a gitops generator that commits with the given git config, or the given generator if there's no config
+func NewCommitConfigGenerator(generator gitopsgen.Generator, config []string) gitopsgen.Generator {
+	if len(config) == 0 {
+		return generator
+	}
+	return &CommitConfigGenerator{Generator: generator, Config: config}
+}
+
+// CommitAndPush sets the git config on the checked out repo, and commits and pushes its changes with the wrapped generator
+func (g *CommitConfigGenerator) CommitAndPush(outputPath string, repoPathOverride string, remote string, componentName string, branch string, commitMessage string) error {
+	repoPath := filepath.Join(outputPath, componentName)
+	if repoPathOverride != "" {
+		repoPath = filepath.Join(outputPath, repoPathOverride)
+	}
+	if err := SetRepositoryConfig(repoPath, g.Config); err != nil {
+		return err
+	}
+	return g.Generator.CommitAndPush(outputPath, repoPathOverride, remote, componentName, branch, commitMessage)
+}
+
+// CloneGenerateAndPush checks out the repo and generates the gitops resources of the component in it with the wrapped generator
+func (g *CommitConfigGenerator) CloneGenerateAndPush(outputPath string, remote string, options gitopsv1alpha1.GeneratorOptions, appFs afero.Afero, branch string, context string, doPush bool) error {
+	if err := g.Generator.CloneGenerateAndPush(outputPath, remote, options, appFs, branch, context, false); err != nil {
+		return err
+	}
+	if doPush {
+		return g.CommitAndPush(outputPath, "", remote, options.Name, branch, fmt.Sprintf("Generate GitOps base resources for component %s", options.Name))
+	}
+	return nil
+}
+
+// GenerateOverlaysAndPush generates the environment overlays of the component with the wrapped generator
+func (g *CommitConfigGenerator) GenerateOverlaysAndPush(outputPath string, clone bool, remote string, options gitopsv1alpha1.GeneratorOptions, applicationName, environmentName, imageName, namespace string, appFs afero.Afero, branch string, context string, doPush bool, componentGeneratedResources map[string][]string) error {
+	if err := g.Generator.GenerateOverlaysAndPush(outputPath, clone, remote, options, applicationName, environmentName, imageName, namespace, appFs, branch, context, false, componentGeneratedResources); err != nil {
+		return err
+	}
+	if doPush {
+		return g.CommitAndPush(outputPath, applicationName, remote, options.Name, branch, fmt.Sprintf("Generate %s environment overlays for component %s", environmentName, options.Name))
+	}
+	return nil
+}
+
+// GitRemoveComponent checks out the repo, removes the component from it, and pushes the change
+func (g *CommitConfigGenerator) GitRemoveComponent(outputPath string, remote string, componentName string, branch string, context string) error {
+	if err := g.CloneRepo(outputPath, remote, componentName, branch); err != nil {
+		return err
+	}
+	componentPath := filepath.Join(outputPath, componentName, context, "components", componentName)
+	if err := os.RemoveAll(componentPath); err != nil {
+		return fmt.Errorf("failed to delete %s folder in repository in %s: %v", componentPath, filepath.Join(outputPath, componentName), err)
+	}
+	return g.CommitAndPush(outputPath, "", remote, componentName, branch, fmt.Sprintf("Removed component %s", componentName))
+}
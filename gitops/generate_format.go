@@ -0,0 +1,87 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"fmt"
+	"path/filepath"
+
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/spf13/afero"
+)
+
+// CloneAndGenerate checks out the branch of the GitOps repository into the component's folder of outputPath, and generates the base
+// resources of the component in it in the given format, without pushing them. Kustomize bases are generated by the generator itself.
+func CloneAndGenerate(generator gitopsgen.Generator, format OutputFormat, outputPath string, remote string, options gitopsv1alpha1.GeneratorOptions, appFs afero.Afero, branch string, context string) error {
+	if format.Name() == OutputFormatKustomize {
+		return generator.CloneGenerateAndPush(outputPath, remote, options, appFs, branch, context, false)
+	}
+	if err := generator.CloneRepo(outputPath, remote, options.Name, branch); err != nil {
+		return err
+	}
+	return GenerateBase(appFs, format, filepath.Join(outputPath, options.Name, context), options)
+}
+
+// GenerateBase replaces the base resources of the component in the given GitOps folder with those generated in the given format
+func GenerateBase(appFs afero.Afero, format OutputFormat, gitOpsFolder string, options gitopsv1alpha1.GeneratorOptions) error {
+	componentPath := filepath.Join(gitOpsFolder, "components", options.Name, "base")
+	if err := appFs.RemoveAll(componentPath); err != nil {
+		return fmt.Errorf("unable to remove the previous GitOps resources of component %s: %v", options.Name, err)
+	}
+	if err := format.Generate(appFs, gitOpsFolder, componentPath, options); err != nil {
+		return fmt.Errorf("failed to generate the gitops resources of component %s: %v", options.Name, err)
+	}
+	return nil
+}
+
+// GenerateOverlays generates the environment overlays of the component in the given format, with the given overlay options, in the
+// GitOps repository checked out into the applicationName folder of outputPath, checking it out first if clone is set. The overlays
+// aren't pushed. Kustomize overlays are generated by the generator itself, so that their custom patches are kept.
+func GenerateOverlays(generator gitopsgen.Generator, format OutputFormat, outputPath string, clone bool, remote string, options gitopsv1alpha1.GeneratorOptions, overlay OverlayOptions, applicationName, environmentName, imageName, namespace string, appFs afero.Afero, branch string, context string, componentGeneratedResources map[string][]string) error {
+	gitOpsFolder := filepath.Join(outputPath, applicationName, context)
+	overlaysPath := filepath.Join(gitOpsFolder, "components", options.Name, "overlays", environmentName)
+	if format.Name() == OutputFormatKustomize {
+		if err := generator.GenerateOverlaysAndPush(outputPath, clone, remote, options, applicationName, environmentName, imageName, namespace, appFs, branch, context, false, componentGeneratedResources); err != nil {
+			return err
+		}
+	} else {
+		if clone {
+			if err := generator.CloneRepo(outputPath, remote, applicationName, branch); err != nil {
+				return err
+			}
+		}
+		if err := appFs.RemoveAll(overlaysPath); err != nil {
+			return fmt.Errorf("unable to remove the previous %s environment overlays of component %s: %v", environmentName, options.Name, err)
+		}
+		if err := format.GenerateOverlays(appFs, gitOpsFolder, overlaysPath, options, imageName, namespace, componentGeneratedResources); err != nil {
+			return fmt.Errorf("failed to generate the %s environment overlays of component %s: %v", environmentName, options.Name, err)
+		}
+	}
+	if err := format.GenerateOverlayOptions(appFs, overlaysPath, options, overlay); err != nil {
+		return fmt.Errorf("failed to generate the %s environment overlay options of component %s: %v", environmentName, options.Name, err)
+	}
+	return nil
+}
+
+// RenderOverlays renders the environment overlays of the component in the given format, with the given overlay options, into the
+// overlaysFolder of the given GitOps folder
+func RenderOverlays(fs afero.Afero, format OutputFormat, gitOpsFolder string, overlaysFolder string, options gitopsv1alpha1.GeneratorOptions, overlay OverlayOptions, imageName, namespace string) error {
+	if err := format.GenerateOverlays(fs, gitOpsFolder, overlaysFolder, options, imageName, namespace, map[string][]string{}); err != nil {
+		return err
+	}
+	return format.GenerateOverlayOptions(fs, overlaysFolder, options, overlay)
+}
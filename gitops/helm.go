@@ -0,0 +1,367 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	gitopsgen "github.com/redhat-developer/gitops-generator/pkg"
+	"github.com/redhat-developer/gitops-generator/pkg/resources"
+	"github.com/spf13/afero"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	helmChartFileName          = "Chart.yaml"
+	helmValuesFileName         = "values.yaml"
+	helmTemplatesFolder        = "templates"
+	helmExtraResourcesFileName = "extra-resources.yaml"
+
+	// helmValuePlaceholderPrefix marks the fields of the workload template that are replaced by values
+	helmValuePlaceholderPrefix = "helm-values-"
+)
+
+// helmExtraResourcesTemplate renders the resources listed in the extraResources value, such as the Route of an Environment
+const helmExtraResourcesTemplate = `{{- range .Values.extraResources }}
+---
+{{ toYaml . }}
+{{- end }}
+`
+
+// helmBlockPlaceholder matches the fields of a template whose values are YAML blocks, such as the env of the container
+var helmBlockPlaceholder = regexp.MustCompile(`(?m)^((?:- | )*)(\w+): ` + helmValuePlaceholderPrefix + `(\w+)$`)
+
+// Helm is the OutputFormat that renders each Component as a Helm chart. The chart is rendered from the same resources as the kustomize
+// base, with the image, replicas, resources and env of the workload's container, and the spec of its autoscaler, taken from its values.
+// Each Environment gets a values file in the Component's overlays, which also holds the Environment's Route or Ingress and the
+// Component's policies and secrets in the Environment.
+type Helm struct{}
+
+// Name returns OutputFormatHelm
+func (Helm) Name() string {
+	return OutputFormatHelm
+}
+
+// Generate renders the Helm chart of the Component
+func (Helm) Generate(fs afero.Afero, gitOpsFolder string, outputFolder string, options gitopsv1alpha1.GeneratorOptions) error {
+	// The autoscaler is templated separately, so that Environments can set its values
+	autoscaler, others, err := splitAutoscaler(options.KubernetesResources.Others)
+	if err != nil {
+		return err
+	}
+	options.KubernetesResources.Others = others
+
+	kustomizeFs := ioutils.NewMemoryFilesystem()
+	if err := gitopsgen.Generate(kustomizeFs, "/", "/base", options); err != nil {
+		return err
+	}
+	var kustomization resources.Kustomization
+	if err := readYAMLFile(kustomizeFs, "/base/kustomization.yaml", &kustomization); err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{
+		"extraResources": []interface{}{},
+	}
+	for _, file := range kustomization.Resources {
+		content, err := kustomizeFs.ReadFile(filepath.Join("/base", file))
+		if err != nil {
+			return err
+		}
+		if workloadFiles[file] {
+			if content, err = templateHelmWorkload(content, values); err != nil {
+				return fmt.Errorf("unable to render the Helm template of %s: %v", file, err)
+			}
+		} else {
+			content = escapeHelmTemplate(content)
+		}
+		if err := writeFile(fs, filepath.Join(outputFolder, helmTemplatesFolder, file), content); err != nil {
+			return err
+		}
+	}
+	if err := writeFile(fs, filepath.Join(outputFolder, helmTemplatesFolder, helmExtraResourcesFileName), []byte(helmExtraResourcesTemplate)); err != nil {
+		return err
+	}
+	autoscalerTemplate, err := templateHelmAutoscaler(options, autoscaler, values)
+	if err != nil {
+		return fmt.Errorf("unable to render the Helm template of %s: %v", autoscalerFileName, err)
+	}
+	if err := writeFile(fs, filepath.Join(outputFolder, helmTemplatesFolder, autoscalerFileName), autoscalerTemplate); err != nil {
+		return err
+	}
+
+	chart := map[string]interface{}{
+		"apiVersion":  "v2",
+		"name":        options.Name,
+		"description": fmt.Sprintf("GitOps resources of Component %s of Application %s", options.Name, options.Application),
+		"type":        "application",
+		"version":     "0.1.0",
+	}
+	if err := writeYAMLFile(fs, filepath.Join(outputFolder, helmChartFileName), chart); err != nil {
+		return err
+	}
+	return writeYAMLFile(fs, filepath.Join(outputFolder, helmValuesFileName), values)
+}
+
+// GenerateOverlays renders the values file of the Component's chart in an Environment. The values are those of the kustomize overlay
+// of the Environment: the image, replicas, resources and env of its patch, and its Route or Ingress.
+func (Helm) GenerateOverlays(fs afero.Afero, gitOpsFolder string, outputFolder string, options gitopsv1alpha1.GeneratorOptions, imageName, namespace string, componentGeneratedResources map[string][]string) error {
+	kustomizeFs := ioutils.NewMemoryFilesystem()
+	if err := gitopsgen.Generate(kustomizeFs, "/", "/base", options); err != nil {
+		return err
+	}
+	if err := gitopsgen.GenerateOverlays(kustomizeFs, "/", "/overlays/environment", options, imageName, namespace, componentGeneratedResources); err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{
+		"extraResources": []interface{}{},
+	}
+	for file := range workloadFiles {
+		workload, err := readWorkload(kustomizeFs, filepath.Join("/base", file))
+		if err != nil || workload == nil {
+			continue
+		}
+		patch, err := readWorkload(kustomizeFs, filepath.Join("/overlays/environment", workloadPatchFileName(file)))
+		if err != nil {
+			return err
+		}
+		setHelmWorkloadValues(workload, values)
+		if patch != nil {
+			patchValues := make(map[string]interface{})
+			setHelmWorkloadValues(patch, patchValues)
+			for _, key := range []string{"image", "replicas", "resources"} {
+				if value, ok := patchValues[key]; ok && !isEmptyHelmValue(value) {
+					values[key] = value
+				}
+			}
+			values["env"] = mergeHelmEnv(values["env"], patchValues["env"])
+		}
+		break
+	}
+
+	var extraResources []interface{}
+	for _, file := range []string{"route.yaml", "ingress.yaml"} {
+		var resource map[string]interface{}
+		if exists, err := kustomizeFs.Exists(filepath.Join("/overlays/environment", file)); err != nil || !exists {
+			continue
+		}
+		if err := readYAMLFile(kustomizeFs, filepath.Join("/overlays/environment", file), &resource); err != nil {
+			return err
+		}
+		extraResources = append(extraResources, resource)
+	}
+	if len(extraResources) > 0 {
+		values["extraResources"] = extraResources
+	}
+	return writeYAMLFile(fs, filepath.Join(outputFolder, helmValuesFileName), values)
+}
+
+// GenerateOverlayOptions sets the overlay options in the values file of the Component's chart in an Environment, generated in
+// outputFolder. The autoscaler of the Environment replaces the autoscaling values, and its policies and secrets are added to the extra
+// resources.
+func (Helm) GenerateOverlayOptions(fs afero.Afero, outputFolder string, options gitopsv1alpha1.GeneratorOptions, overlay OverlayOptions) error {
+	var values map[string]interface{}
+	if err := readYAMLFile(fs, filepath.Join(outputFolder, helmValuesFileName), &values); err != nil {
+		return err
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	if overlay.Autoscaler != nil {
+		autoscaling, err := helmAutoscalingValues(overlay.Autoscaler)
+		if err != nil {
+			return err
+		}
+		values["autoscaling"] = autoscaling
+	}
+	policies, err := overlay.policies()
+	if err != nil {
+		return err
+	}
+	extraResources, _ := values["extraResources"].([]interface{})
+	for _, file := range []string{podDisruptionBudgetFileName, networkPolicyFileName} {
+		if policy, ok := policies[file]; ok {
+			extraResources = append(extraResources, policy)
+		}
+	}
+	for _, secret := range overlay.Secrets {
+		extraResources = append(extraResources, secret)
+	}
+	values["extraResources"] = extraResources
+	return writeYAMLFile(fs, filepath.Join(outputFolder, helmValuesFileName), values)
+}
+
+// templateHelmAutoscaler returns the Helm template of the HorizontalPodAutoscaler of the Component, rendered if the autoscaling value is
+// set, with its spec taken from the value. The value is set to the spec of the given autoscaler, or left empty if the Component has none.
+func templateHelmAutoscaler(options gitopsv1alpha1.GeneratorOptions, autoscaler *autoscalingv2.HorizontalPodAutoscaler, values map[string]interface{}) ([]byte, error) {
+	values["autoscaling"] = map[string]interface{}{}
+	if autoscaler != nil {
+		autoscaling, err := helmAutoscalingValues(autoscaler)
+		if err != nil {
+			return nil, err
+		}
+		values["autoscaling"] = autoscaling
+	} else {
+		autoscaler = &autoscalingv2.HorizontalPodAutoscaler{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "HorizontalPodAutoscaler",
+				APIVersion: "autoscaling/v2",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   options.Name,
+				Labels: options.K8sLabels,
+			},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       options.Name,
+				},
+			},
+		}
+	}
+
+	resource, err := resourceToMap(autoscaler)
+	if err != nil {
+		return nil, err
+	}
+	spec, _ := resource["spec"].(map[string]interface{})
+	resource["spec"] = map[string]interface{}{"scaleTargetRef": spec["scaleTargetRef"]}
+	content, err := yaml.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	// The spec is the last field, so the rest of it is rendered at its end
+	return []byte("{{- with .Values.autoscaling }}\n" + string(escapeHelmTemplate(content)) + "  {{- toYaml . | nindent 2 }}\n{{- end }}\n"), nil
+}
+
+// helmAutoscalingValues returns the autoscaling values of the given autoscaler, which are its spec without its target
+func helmAutoscalingValues(autoscaler *autoscalingv2.HorizontalPodAutoscaler) (map[string]interface{}, error) {
+	resource, err := resourceToMap(autoscaler)
+	if err != nil {
+		return nil, err
+	}
+	spec, _ := resource["spec"].(map[string]interface{})
+	delete(spec, "scaleTargetRef")
+	return spec, nil
+}
+
+// templateHelmWorkload returns the Helm template of the given workload, with the fields of its first container, and its replicas,
+// replaced by values. The values are set to those of the workload.
+func templateHelmWorkload(content []byte, values map[string]interface{}) ([]byte, error) {
+	var workload map[string]interface{}
+	if err := yaml.Unmarshal(content, &workload); err != nil {
+		return nil, err
+	}
+	setHelmWorkloadValues(workload, values)
+	if spec, ok := workload["spec"].(map[string]interface{}); ok {
+		if _, ok := spec["replicas"]; ok {
+			spec["replicas"] = helmValuePlaceholderPrefix + "replicas"
+		}
+	}
+	if container := workloadContainer(workload); container != nil {
+		for _, key := range []string{"image", "resources", "env"} {
+			container[key] = helmValuePlaceholderPrefix + key
+		}
+	}
+
+	template, err := yaml.Marshal(workload)
+	if err != nil {
+		return nil, err
+	}
+	template = escapeHelmTemplate(template)
+	// Blocks are rendered on the lines below their field, indented under it
+	template = helmBlockPlaceholder.ReplaceAllFunc(template, func(match []byte) []byte {
+		groups := helmBlockPlaceholder.FindSubmatch(match)
+		prefix, field, value := string(groups[1]), string(groups[2]), string(groups[3])
+		if value == "image" || value == "replicas" {
+			return []byte(fmt.Sprintf("%s%s: {{ .Values.%s }}", prefix, field, value))
+		}
+		indent := len(prefix) + 2
+		return []byte(fmt.Sprintf("%s%s:\n%s{{- toYaml .Values.%s | nindent %d }}", prefix, field, strings.Repeat(" ", indent), value, indent))
+	})
+	return template, nil
+}
+
+// setHelmWorkloadValues sets the image, replicas, resources and env values to those of the given workload
+func setHelmWorkloadValues(workload map[string]interface{}, values map[string]interface{}) {
+	if spec, ok := workload["spec"].(map[string]interface{}); ok {
+		if replicas, ok := spec["replicas"]; ok {
+			values["replicas"] = replicas
+		}
+	}
+	container := workloadContainer(workload)
+	if container == nil {
+		return
+	}
+	values["image"] = container["image"]
+	values["resources"] = map[string]interface{}{}
+	if resources, ok := container["resources"]; ok && resources != nil {
+		values["resources"] = resources
+	}
+	values["env"] = []interface{}{}
+	if env, ok := container["env"]; ok && env != nil {
+		values["env"] = env
+	}
+}
+
+// mergeHelmEnv returns the env of the base values, with the variables of the overlay's env added or replaced by name
+func mergeHelmEnv(base interface{}, overlay interface{}) []interface{} {
+	baseEnv, _ := base.([]interface{})
+	overlayEnv, _ := overlay.([]interface{})
+	env := append([]interface{}{}, baseEnv...)
+	for _, variable := range overlayEnv {
+		name := envVarName(variable)
+		replaced := false
+		for i := range env {
+			if envVarName(env[i]) == name {
+				env[i] = variable
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			env = append(env, variable)
+		}
+	}
+	return env
+}
+
+// isEmptyHelmValue returns true if the given value isn't set
+func isEmptyHelmValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// escapeHelmTemplate escapes the template delimiters in the given content, so that Helm renders them as they are
+func escapeHelmTemplate(content []byte) []byte {
+	return []byte(strings.ReplaceAll(string(content), "{{", `{{ "{{" }}`))
+}
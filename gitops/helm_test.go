@@ -0,0 +1,152 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGetOutputFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name: "No annotations",
+			want: OutputFormatKustomize,
+		},
+		{
+			name:        "Kustomize",
+			annotations: map[string]string{OutputFormatAnnotation: "kustomize"},
+			want:        OutputFormatKustomize,
+		},
+		{
+			name:        "Helm",
+			annotations: map[string]string{OutputFormatAnnotation: " Helm "},
+			want:        OutputFormatHelm,
+		},
+		{
+			name:        "Unsupported format",
+			annotations: map[string]string{OutputFormatAnnotation: "jsonnet"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := GetOutputFormat(tt.annotations)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestGetOutputFormat() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && format.Name() != tt.want {
+				t.Errorf("TestGetOutputFormat() error: expected %v got %v", tt.want, format.Name())
+			}
+		})
+	}
+}
+
+func TestHelm(t *testing.T) {
+	options := gitopsv1alpha1.GeneratorOptions{
+		Name:           "backend",
+		Application:    "petclinic",
+		ContainerImage: "quay.io/test/backend:v1",
+		TargetPort:     8080,
+		Replicas:       2,
+		BaseEnvVar:     []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}},
+	}
+	overlayOptions := options
+	overlayOptions.Replicas = 3
+	overlayOptions.OverlayEnvVar = []corev1.EnvVar{{Name: "DB_HOST", Value: "db.staging"}}
+
+	fs := ioutils.NewMemoryFilesystem()
+	gitOpsFolder := "/gitops"
+	baseFolder := "/gitops/components/backend/base"
+	overlaysFolder := "/gitops/components/backend/overlays/staging"
+	if err := (Helm{}).Generate(fs, gitOpsFolder, baseFolder, options); err != nil {
+		t.Fatalf("TestHelm() unexpected error generating the chart: %v", err)
+	}
+	if err := (Helm{}).GenerateOverlays(fs, gitOpsFolder, overlaysFolder, overlayOptions, "quay.io/test/backend:v2", "", nil); err != nil {
+		t.Fatalf("TestHelm() unexpected error generating the overlays: %v", err)
+	}
+
+	files, err := ioutils.ReadTree(fs, "/gitops/components/backend")
+	if err != nil {
+		t.Fatalf("TestHelm() unexpected error reading the chart: %v", err)
+	}
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	wantPaths := []string{"base/Chart.yaml", "base/templates/deployment.yaml", "base/templates/extra-resources.yaml", "base/templates/horizontalpodautoscaler.yaml",
+		"base/templates/service.yaml", "base/values.yaml", "overlays/staging/values.yaml"}
+	sort.Strings(paths)
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("TestHelm() error: expected files %v got %v", wantPaths, paths)
+	}
+
+	deployment := string(files["base/templates/deployment.yaml"])
+	for _, want := range []string{"replicas: {{ .Values.replicas }}", "image: {{ .Values.image }}", "env:\n", "{{- toYaml .Values.env | nindent ", "{{- toYaml .Values.resources | nindent "} {
+		if !strings.Contains(deployment, want) {
+			t.Errorf("TestHelm() error: expected the deployment template to contain %q, got\n%s", want, deployment)
+		}
+	}
+	if strings.Contains(deployment, helmValuePlaceholderPrefix) {
+		t.Errorf("TestHelm() error: expected no placeholders in the deployment template, got\n%s", deployment)
+	}
+
+	var baseValues, overlayValues map[string]interface{}
+	if err := yaml.Unmarshal(files["base/values.yaml"], &baseValues); err != nil {
+		t.Fatalf("TestHelm() unexpected error reading the base values: %v", err)
+	}
+	if err := yaml.Unmarshal(files["overlays/staging/values.yaml"], &overlayValues); err != nil {
+		t.Fatalf("TestHelm() unexpected error reading the overlay values: %v", err)
+	}
+	if baseValues["image"] != "quay.io/test/backend:v1" || baseValues["replicas"] != float64(2) {
+		t.Errorf("TestHelm() error: unexpected base values %v", baseValues)
+	}
+	if overlayValues["image"] != "quay.io/test/backend:v2" || overlayValues["replicas"] != float64(3) {
+		t.Errorf("TestHelm() error: unexpected overlay values %v", overlayValues)
+	}
+	wantEnv := []interface{}{
+		map[string]interface{}{"name": "LOG_LEVEL", "value": "info"},
+		map[string]interface{}{"name": "DB_HOST", "value": "db.staging"},
+	}
+	if !reflect.DeepEqual(overlayValues["env"], wantEnv) {
+		t.Errorf("TestHelm() error: expected overlay env %v got %v", wantEnv, overlayValues["env"])
+	}
+	extraResources, _ := overlayValues["extraResources"].([]interface{})
+	if len(extraResources) != 1 || extraResources[0].(map[string]interface{})["kind"] != "Route" {
+		t.Errorf("TestHelm() error: expected the Route of the Environment in the extra resources, got %v", extraResources)
+	}
+}
+
+func TestEscapeHelmTemplate(t *testing.T) {
+	got := string(escapeHelmTemplate([]byte("message: '{{ not a template }}'\n")))
+	want := "message: '{{ \"{{\" }} not a template }}'\n"
+	if got != want {
+		t.Errorf("TestEscapeHelmTemplate() error: expected %q got %q", want, got)
+	}
+}
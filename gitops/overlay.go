@@ -0,0 +1,370 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/devfile/library/v2/pkg/devfile/parser"
+	"github.com/redhat-appstudio/application-service/pkg/devfile"
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	"github.com/redhat-developer/gitops-generator/pkg/resources"
+	"github.com/spf13/afero"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kustomizationFileName       = "kustomization.yaml"
+	autoscalerFileName          = "horizontalpodautoscaler.yaml"
+	autoscalerPatchFileName     = "horizontalpodautoscaler-patch.yaml"
+	podDisruptionBudgetFileName = "poddisruptionbudget.yaml"
+	networkPolicyFileName       = "networkpolicy.yaml"
+)
+
+// overlayOptionsFiles are the files that the overlay options are rendered in, in the kustomize overlay of a Component. They're removed
+// before the overlay options are rendered again, as the gitops generator keeps the patches of an overlay that it doesn't know about.
+var overlayOptionsFiles = []string{autoscalerFileName, autoscalerPatchFileName, podDisruptionBudgetFileName, networkPolicyFileName, secretsFileName}
+
+// OverlayOptions are the options of the resources of a Component in an Environment that the gitops generator doesn't render, rendered
+// on top of its overlay by OutputFormat.GenerateOverlayOptions
+type OverlayOptions struct {
+	// Autoscaler is the HorizontalPodAutoscaler of the Component in the Environment, if the Environment overrides its autoscaling
+	Autoscaler *autoscalingv2.HorizontalPodAutoscaler
+
+	// AutoscalerInBase is true if the base resources of the Component have a HorizontalPodAutoscaler, which Autoscaler replaces
+	AutoscalerInBase bool
+
+	// PodDisruptionBudget is the PodDisruptionBudget of the Component in the Environment, if it has one
+	PodDisruptionBudget *policyv1.PodDisruptionBudget
+
+	// NetworkPolicy is the NetworkPolicy of the Component in the Environment, if it has one
+	NetworkPolicy *networkingv1.NetworkPolicy
+
+	// Secrets are the ExternalSecrets and SealedSecrets that provide the Secrets referenced by the Component in the Environment
+	Secrets []map[string]interface{}
+
+	// ReferencedEnv are the names of the env vars of the base workload of the Component that reference a Secret or ConfigMap key. The
+	// values that the Environment sets for them replace the references, rather than being merged with them.
+	ReferencedEnv []string
+}
+
+// OverlayConfiguration is the configuration of a Component in an Environment that's rendered as overlay options
+type OverlayConfiguration struct {
+	// Autoscaling overrides the autoscaling of the Component
+	Autoscaling devfile.Autoscaling
+
+	// Policies are whether the Component gets a PodDisruptionBudget and a NetworkPolicy
+	Policies devfile.Policies
+
+	// ApplicationComponents are the names of the Components of the Application deployed to the Environment, whose pods the
+	// NetworkPolicy of the Component allows ingress from
+	ApplicationComponents []string
+	// SecretSource is where the Secrets referenced by the Component come from
+	SecretSource SecretSource
+
+	// SealedSecrets are the SealedSecrets of the secret source, by name
+	SealedSecrets map[string]map[string]interface{}
+}
+
+// GetOverlayOptions returns the overlay options of the Component with the given base resources and generator options, with the given
+// configuration in an Environment. A Component that isn't autoscaled in its base resources is autoscaled in the Environment if the
+// Environment sets its maximum replicas.
+func GetOverlayOptions(baseResources parser.KubernetesResources, options gitopsv1alpha1.GeneratorOptions, configuration OverlayConfiguration) (OverlayOptions, error) {
+	var overlay OverlayOptions
+	autoscaler, _, err := splitAutoscaler(baseResources.Others)
+	if err != nil {
+		return OverlayOptions{}, err
+	}
+	if autoscaling := configuration.Autoscaling; autoscaling.IsSet() {
+		if autoscaler != nil {
+			autoscaling.ApplyTo(autoscaler)
+			if err := devfile.ValidateHorizontalPodAutoscaler(*autoscaler); err != nil {
+				return OverlayOptions{}, err
+			}
+			overlay.AutoscalerInBase = true
+		} else {
+			environmentAutoscaler, err := devfile.GetHorizontalPodAutoscaler(options.Name, options.K8sLabels, autoscaling)
+			if err != nil {
+				return OverlayOptions{}, err
+			}
+			autoscaler = &environmentAutoscaler
+		}
+		overlay.Autoscaler = autoscaler
+	}
+
+	for _, deployment := range baseResources.Deployments {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			for _, env := range container.Env {
+				if env.ValueFrom != nil {
+					overlay.ReferencedEnv = append(overlay.ReferencedEnv, env.Name)
+				}
+			}
+		}
+	}
+
+	if enabled := configuration.Policies.PodDisruptionBudget; enabled != nil && *enabled {
+		pdb := devfile.GetPodDisruptionBudget(options.Name, options.K8sLabels, getEnvironmentReplicas(baseResources, options, autoscaler))
+		overlay.PodDisruptionBudget = &pdb
+	}
+	if enabled := configuration.Policies.NetworkPolicy; enabled != nil && *enabled {
+		networkPolicy := devfile.GetNetworkPolicy(options.Name, options.K8sLabels, getExposedPorts(baseResources, options), configuration.ApplicationComponents)
+		overlay.NetworkPolicy = &networkPolicy
+	}
+	overlay.Secrets = getSecrets(baseResources, configuration.SecretSource, configuration.SealedSecrets, options.K8sLabels)
+	return overlay, nil
+}
+
+// GenerateOverlayOptions renders the overlay options into the kustomize overlay generated in outputFolder, if there is one. The
+// autoscaler of the Environment patches the autoscaler of the base, or is added to the overlay if the base has none, and the policies
+// and secrets are added to the overlay.
+func (Kustomize) GenerateOverlayOptions(fs afero.Afero, outputFolder string, options gitopsv1alpha1.GeneratorOptions, overlay OverlayOptions) error {
+	kustomizationFile := filepath.Join(outputFolder, kustomizationFileName)
+	if exists, err := fs.Exists(kustomizationFile); err != nil || !exists {
+		return err
+	}
+	var k resources.Kustomization
+	if err := readYAMLFile(fs, kustomizationFile, &k); err != nil {
+		return err
+	}
+	removed := make(map[string]bool)
+	for _, file := range overlayOptionsFiles {
+		removed[file] = true
+		if exists, err := fs.Exists(filepath.Join(outputFolder, file)); err != nil {
+			return err
+		} else if exists {
+			if err := fs.Remove(filepath.Join(outputFolder, file)); err != nil {
+				return err
+			}
+		}
+	}
+	var patches []resources.Patch
+	for _, patch := range k.Patches {
+		if !removed[patch.Path] {
+			patches = append(patches, patch)
+		}
+	}
+	k.Patches = patches
+	var kustomizationResources []string
+	for _, resource := range k.Resources {
+		if !removed[resource] {
+			kustomizationResources = append(kustomizationResources, resource)
+		}
+	}
+	k.Resources = kustomizationResources
+
+	if overlay.Autoscaler != nil {
+		if overlay.AutoscalerInBase {
+			patch := map[string]interface{}{
+				"apiVersion": overlay.Autoscaler.APIVersion,
+				"kind":       overlay.Autoscaler.Kind,
+				"metadata":   map[string]interface{}{"name": overlay.Autoscaler.Name},
+				"spec":       overlay.Autoscaler.Spec,
+			}
+			if err := writeYAMLFile(fs, filepath.Join(outputFolder, autoscalerPatchFileName), patch); err != nil {
+				return err
+			}
+			k.AddPatches(autoscalerPatchFileName)
+		} else {
+			autoscaler, err := resourceToMap(overlay.Autoscaler)
+			if err != nil {
+				return err
+			}
+			if err := writeYAMLFile(fs, filepath.Join(outputFolder, autoscalerFileName), autoscaler); err != nil {
+				return err
+			}
+			k.AddResources(autoscalerFileName)
+		}
+	}
+	if err := replaceReferencedEnv(fs, outputFolder, overlay.ReferencedEnv); err != nil {
+		return err
+	}
+	policies, err := overlay.policies()
+	if err != nil {
+		return err
+	}
+	for file, resource := range policies {
+		if err := writeYAMLFile(fs, filepath.Join(outputFolder, file), resource); err != nil {
+			return err
+		}
+		k.AddResources(file)
+	}
+	if len(overlay.Secrets) > 0 {
+		var documents []string
+		for _, secret := range overlay.Secrets {
+			document, err := yaml.Marshal(secret)
+			if err != nil {
+				return err
+			}
+			documents = append(documents, string(document))
+		}
+		if err := writeFile(fs, filepath.Join(outputFolder, secretsFileName), []byte(strings.Join(documents, "---\n"))); err != nil {
+			return err
+		}
+		k.AddResources(secretsFileName)
+	}
+	return writeYAMLFile(fs, kustomizationFile, k)
+}
+
+// policies returns the policy resources of the overlay options that are set, by the file that they're rendered in
+func (overlay OverlayOptions) policies() (map[string]map[string]interface{}, error) {
+	policies := make(map[string]map[string]interface{})
+	if overlay.PodDisruptionBudget != nil {
+		pdb, err := resourceToMap(overlay.PodDisruptionBudget)
+		if err != nil {
+			return nil, err
+		}
+		policies[podDisruptionBudgetFileName] = pdb
+	}
+	if overlay.NetworkPolicy != nil {
+		networkPolicy, err := resourceToMap(overlay.NetworkPolicy)
+		if err != nil {
+			return nil, err
+		}
+		policies[networkPolicyFileName] = networkPolicy
+	}
+	return policies, nil
+}
+
+// replaceReferencedEnv removes the Secret and ConfigMap key references of the given env vars in the patches of the workloads of the
+// kustomize overlay generated in outputFolder, as the gitops generator only patches their values, and a strategic merge patch would
+// otherwise keep both
+func replaceReferencedEnv(fs afero.Afero, outputFolder string, referencedEnv []string) error {
+	if len(referencedEnv) == 0 {
+		return nil
+	}
+	referenced := make(map[string]bool)
+	for _, name := range referencedEnv {
+		referenced[name] = true
+	}
+	for file := range workloadFiles {
+		patchFile := filepath.Join(outputFolder, workloadPatchFileName(file))
+		patch, err := readWorkload(fs, patchFile)
+		if err != nil {
+			return err
+		}
+		container := workloadContainer(patch)
+		if container == nil {
+			continue
+		}
+		env, _ := container["env"].([]interface{})
+		replaced := false
+		for _, variable := range env {
+			if fields, ok := variable.(map[string]interface{}); ok && referenced[envVarName(variable)] {
+				fields["valueFrom"] = nil
+				replaced = true
+			}
+		}
+		if replaced {
+			if err := writeYAMLFile(fs, patchFile, patch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getEnvironmentReplicas returns the number of replicas that the Component with the given base resources and generator options has at
+// least in the Environment. An autoscaled Component has its minimum replicas, defaulting to one.
+func getEnvironmentReplicas(baseResources parser.KubernetesResources, options gitopsv1alpha1.GeneratorOptions, autoscaler *autoscalingv2.HorizontalPodAutoscaler) int32 {
+	if autoscaler != nil {
+		if autoscaler.Spec.MinReplicas != nil {
+			return *autoscaler.Spec.MinReplicas
+		}
+		return 1
+	}
+	if options.Replicas > 0 {
+		return int32(options.Replicas)
+	}
+	if len(baseResources.Deployments) > 0 && baseResources.Deployments[0].Spec.Replicas != nil {
+		return *baseResources.Deployments[0].Spec.Replicas
+	}
+	return 1
+}
+
+// getExposedPorts returns the ports that the Component with the given base resources and generator options exposes: its target port,
+// and the ports that its routes and ingresses target
+func getExposedPorts(baseResources parser.KubernetesResources, options gitopsv1alpha1.GeneratorOptions) []int32 {
+	ports := []int32{int32(options.TargetPort)}
+	for _, route := range baseResources.Routes {
+		if route.Spec.Port != nil && route.Spec.Port.TargetPort.Type == intstr.Int {
+			ports = append(ports, route.Spec.Port.TargetPort.IntVal)
+		}
+	}
+	for _, ingress := range baseResources.Ingresses {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service != nil {
+					ports = append(ports, path.Backend.Service.Port.Number)
+				}
+			}
+		}
+	}
+	return ports
+}
+
+// splitAutoscaler returns the first autoscaling/v2 HorizontalPodAutoscaler in the given resources, and the other resources
+func splitAutoscaler(others []interface{}) (*autoscalingv2.HorizontalPodAutoscaler, []interface{}, error) {
+	var autoscaler *autoscalingv2.HorizontalPodAutoscaler
+	var rest []interface{}
+	for _, other := range others {
+		document, err := json.Marshal(other)
+		if err != nil {
+			return nil, nil, err
+		}
+		var object struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := json.Unmarshal(document, &object); err != nil {
+			return nil, nil, err
+		}
+		if autoscaler != nil || object.APIVersion != "autoscaling/v2" || object.Kind != "HorizontalPodAutoscaler" {
+			rest = append(rest, other)
+			continue
+		}
+		autoscaler = &autoscalingv2.HorizontalPodAutoscaler{}
+		if err := json.Unmarshal(document, autoscaler); err != nil {
+			return nil, nil, err
+		}
+	}
+	return autoscaler, rest, nil
+}
+
+// resourceToMap returns the given resource as a map, without its status and unset creation timestamp
+func resourceToMap(resource interface{}) (map[string]interface{}, error) {
+	document, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(document, &object); err != nil {
+		return nil, err
+	}
+	delete(object, "status")
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok && metadata["creationTimestamp"] == nil {
+		delete(metadata, "creationTimestamp")
+	}
+	return object, nil
+}
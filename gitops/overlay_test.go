@@ -0,0 +1,434 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/devfile/library/v2/pkg/devfile/parser"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/redhat-appstudio/application-service/pkg/devfile"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	"github.com/redhat-developer/gitops-generator/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestGetOverlayOptions(t *testing.T) {
+	baseAutoscaler, err := devfile.GetHorizontalPodAutoscaler("backend", nil, devfile.Autoscaling{MinReplicas: int32Ptr(2), MaxReplicas: int32Ptr(4), TargetCPUUtilization: int32Ptr(80)})
+	if err != nil {
+		t.Fatalf("TestGetOverlayOptions() unexpected error: %v", err)
+	}
+	// Post-processed resources are untyped
+	postProcessedAutoscaler, err := resourceToMap(baseAutoscaler)
+	if err != nil {
+		t.Fatalf("TestGetOverlayOptions() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		baseResources   parser.KubernetesResources
+		autoscaling     devfile.Autoscaling
+		wantAutoscaler  bool
+		wantInBase      bool
+		wantMinReplicas int32
+		wantMaxReplicas int32
+		wantErr         bool
+	}{
+		{
+			name:          "Autoscaling not overridden",
+			baseResources: parser.KubernetesResources{Others: []interface{}{baseAutoscaler}},
+		},
+		{
+			name:            "Autoscaler of the base overridden",
+			baseResources:   parser.KubernetesResources{Others: []interface{}{postProcessedAutoscaler}},
+			autoscaling:     devfile.Autoscaling{MaxReplicas: int32Ptr(10)},
+			wantAutoscaler:  true,
+			wantInBase:      true,
+			wantMinReplicas: 2,
+			wantMaxReplicas: 10,
+		},
+		{
+			name:            "Autoscaler added by the Environment",
+			autoscaling:     devfile.Autoscaling{MinReplicas: int32Ptr(3), MaxReplicas: int32Ptr(6)},
+			wantAutoscaler:  true,
+			wantMinReplicas: 3,
+			wantMaxReplicas: 6,
+		},
+		{
+			name:        "Environment without maximum replicas",
+			autoscaling: devfile.Autoscaling{MinReplicas: int32Ptr(3)},
+			wantErr:     true,
+		},
+		{
+			name:          "Override with minimum greater than maximum",
+			baseResources: parser.KubernetesResources{Others: []interface{}{baseAutoscaler}},
+			autoscaling:   devfile.Autoscaling{MinReplicas: int32Ptr(5)},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlay, err := GetOverlayOptions(tt.baseResources, gitopsv1alpha1.GeneratorOptions{Name: "backend"}, OverlayConfiguration{Autoscaling: tt.autoscaling})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestGetOverlayOptions() unexpected error value: %v", err)
+			}
+			if tt.wantAutoscaler != (overlay.Autoscaler != nil) || tt.wantInBase != overlay.AutoscalerInBase {
+				t.Fatalf("TestGetOverlayOptions() error: unexpected overlay options %+v", overlay)
+			}
+			if overlay.Autoscaler != nil && (*overlay.Autoscaler.Spec.MinReplicas != tt.wantMinReplicas || overlay.Autoscaler.Spec.MaxReplicas != tt.wantMaxReplicas) {
+				t.Errorf("TestGetOverlayOptions() error: unexpected autoscaler replicas %v-%v", *overlay.Autoscaler.Spec.MinReplicas, overlay.Autoscaler.Spec.MaxReplicas)
+			}
+		})
+	}
+}
+
+func TestGetOverlayOptionsPolicies(t *testing.T) {
+	enabled, disabled := true, false
+	baseDeployment := appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(3)}}
+	baseRoute := routev1.Route{Spec: routev1.RouteSpec{Port: &routev1.RoutePort{TargetPort: intstr.FromInt(8081)}}}
+	baseAutoscaler, err := devfile.GetHorizontalPodAutoscaler("backend", nil, devfile.Autoscaling{MinReplicas: int32Ptr(4), MaxReplicas: int32Ptr(8)})
+	if err != nil {
+		t.Fatalf("TestGetOverlayOptionsPolicies() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		baseResources     parser.KubernetesResources
+		options           gitopsv1alpha1.GeneratorOptions
+		configuration     OverlayConfiguration
+		wantMinAvailable  *intstr.IntOrString
+		wantNetworkPolicy bool
+		wantPorts         []int32
+		wantFrom          []string
+	}{
+		{
+			name:          "Policies not enabled",
+			baseResources: parser.KubernetesResources{Deployments: []appsv1.Deployment{baseDeployment}},
+			configuration: OverlayConfiguration{Policies: devfile.Policies{PodDisruptionBudget: &disabled}},
+		},
+		{
+			name:             "Replicas of the base",
+			baseResources:    parser.KubernetesResources{Deployments: []appsv1.Deployment{baseDeployment}},
+			configuration:    OverlayConfiguration{Policies: devfile.Policies{PodDisruptionBudget: &enabled}},
+			wantMinAvailable: &intstr.IntOrString{IntVal: 2},
+		},
+		{
+			name:             "Replicas of the Environment",
+			baseResources:    parser.KubernetesResources{Deployments: []appsv1.Deployment{baseDeployment}},
+			options:          gitopsv1alpha1.GeneratorOptions{Replicas: 6},
+			configuration:    OverlayConfiguration{Policies: devfile.Policies{PodDisruptionBudget: &enabled}},
+			wantMinAvailable: &intstr.IntOrString{IntVal: 3},
+		},
+		{
+			name:             "Minimum replicas of the autoscaler",
+			baseResources:    parser.KubernetesResources{Deployments: []appsv1.Deployment{baseDeployment}, Others: []interface{}{baseAutoscaler}},
+			options:          gitopsv1alpha1.GeneratorOptions{Replicas: 6},
+			configuration:    OverlayConfiguration{Policies: devfile.Policies{PodDisruptionBudget: &enabled}, Autoscaling: devfile.Autoscaling{MinReplicas: int32Ptr(5)}},
+			wantMinAvailable: &intstr.IntOrString{IntVal: 3},
+		},
+		{
+			name:              "Network policy of the exposed ports and the other Components",
+			baseResources:     parser.KubernetesResources{Routes: []routev1.Route{baseRoute}},
+			options:           gitopsv1alpha1.GeneratorOptions{TargetPort: 8080},
+			configuration:     OverlayConfiguration{Policies: devfile.Policies{NetworkPolicy: &enabled}, ApplicationComponents: []string{"frontend", "backend"}},
+			wantNetworkPolicy: true,
+			wantPorts:         []int32{8080, 8081},
+			wantFrom:          []string{"frontend"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.Name = "backend"
+			overlay, err := GetOverlayOptions(tt.baseResources, tt.options, tt.configuration)
+			if err != nil {
+				t.Fatalf("TestGetOverlayOptionsPolicies() unexpected error: %v", err)
+			}
+			if tt.wantMinAvailable == nil && overlay.PodDisruptionBudget != nil {
+				t.Errorf("TestGetOverlayOptionsPolicies() error: unexpected pod disruption budget %+v", overlay.PodDisruptionBudget)
+			} else if tt.wantMinAvailable != nil && (overlay.PodDisruptionBudget == nil || !reflect.DeepEqual(overlay.PodDisruptionBudget.Spec.MinAvailable, tt.wantMinAvailable)) {
+				t.Errorf("TestGetOverlayOptionsPolicies() error: expected the minimum available %v got %+v", tt.wantMinAvailable, overlay.PodDisruptionBudget)
+			}
+			if tt.wantNetworkPolicy != (overlay.NetworkPolicy != nil) {
+				t.Fatalf("TestGetOverlayOptionsPolicies() error: unexpected network policy %+v", overlay.NetworkPolicy)
+			}
+			if overlay.NetworkPolicy == nil {
+				return
+			}
+			var ports []int32
+			for _, port := range overlay.NetworkPolicy.Spec.Ingress[0].Ports {
+				ports = append(ports, port.Port.IntVal)
+			}
+			if !reflect.DeepEqual(ports, tt.wantPorts) {
+				t.Errorf("TestGetOverlayOptionsPolicies() error: expected the ports %v got %v", tt.wantPorts, ports)
+			}
+			if from := overlay.NetworkPolicy.Spec.Ingress[1].From[0].PodSelector.MatchExpressions[0].Values; !reflect.DeepEqual(from, tt.wantFrom) {
+				t.Errorf("TestGetOverlayOptionsPolicies() error: expected ingress from %v got %v", tt.wantFrom, from)
+			}
+		})
+	}
+}
+
+func TestKustomizeGenerateOverlayOptions(t *testing.T) {
+	fs := ioutils.NewMemoryFilesystem()
+	outputFolder := "/gitops/components/backend/overlays/staging"
+	options := gitopsv1alpha1.GeneratorOptions{Name: "backend"}
+	autoscaler, err := devfile.GetHorizontalPodAutoscaler("backend", nil, devfile.Autoscaling{MaxReplicas: int32Ptr(5)})
+	if err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+	readKustomization := func() resources.Kustomization {
+		var k resources.Kustomization
+		if err := readYAMLFile(fs, outputFolder+"/kustomization.yaml", &k); err != nil {
+			t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+		}
+		return k
+	}
+
+	// Nothing is rendered without an overlay
+	if err := (Kustomize{}).GenerateOverlayOptions(fs, outputFolder, options, OverlayOptions{Autoscaler: &autoscaler}); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+	if exists, _ := fs.DirExists(outputFolder); exists {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: expected nothing to be rendered without an overlay")
+	}
+
+	k := resources.Kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	k.AddResources("../../base")
+	k.AddPatches("deployment-patch.yaml", "custom-patch.yaml")
+	if err := writeYAMLFile(fs, outputFolder+"/kustomization.yaml", k); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+
+	// The autoscaler of the base is patched
+	if err := (Kustomize{}).GenerateOverlayOptions(fs, outputFolder, options, OverlayOptions{Autoscaler: &autoscaler, AutoscalerInBase: true}); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+	k = readKustomization()
+	if !reflect.DeepEqual(getPatchPaths(k), []string{"custom-patch.yaml", "deployment-patch.yaml", autoscalerPatchFileName}) {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: unexpected patches %v", k.Patches)
+	}
+	patch, err := fs.ReadFile(outputFolder + "/" + autoscalerPatchFileName)
+	if err != nil || !strings.Contains(string(patch), "maxReplicas: 5") || strings.Contains(string(patch), "labels") {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: unexpected autoscaler patch %s: %v", patch, err)
+	}
+
+	// The autoscaler is added to the overlay, replacing the previous patch
+	if err := (Kustomize{}).GenerateOverlayOptions(fs, outputFolder, options, OverlayOptions{Autoscaler: &autoscaler}); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+	k = readKustomization()
+	if !reflect.DeepEqual(k.Resources, []string{"../../base", autoscalerFileName}) || len(k.Patches) != 2 {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: unexpected kustomization %+v", k)
+	}
+	if exists, _ := fs.Exists(outputFolder + "/" + autoscalerPatchFileName); exists {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: expected the previous autoscaler patch to be removed")
+	}
+	var added map[string]interface{}
+	if err := readYAMLFile(fs, outputFolder+"/"+autoscalerFileName, &added); err != nil || added["kind"] != "HorizontalPodAutoscaler" || added["status"] != nil {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: unexpected autoscaler %v: %v", added, err)
+	}
+
+	// The policies are added to the overlay
+	enabled := true
+	policies, err := GetOverlayOptions(parser.KubernetesResources{}, options, OverlayConfiguration{Policies: devfile.Policies{PodDisruptionBudget: &enabled, NetworkPolicy: &enabled}})
+	if err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+	if err := (Kustomize{}).GenerateOverlayOptions(fs, outputFolder, options, policies); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+	k = readKustomization()
+	if !reflect.DeepEqual(k.Resources, []string{"../../base", networkPolicyFileName, podDisruptionBudgetFileName}) {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: unexpected resources %v", k.Resources)
+	}
+
+	// Without overlay options, the files of the previous ones are removed
+	if err := (Kustomize{}).GenerateOverlayOptions(fs, outputFolder, options, OverlayOptions{}); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlayOptions() unexpected error: %v", err)
+	}
+	for _, file := range []string{autoscalerFileName, podDisruptionBudgetFileName, networkPolicyFileName} {
+		if exists, _ := fs.Exists(outputFolder + "/" + file); exists {
+			t.Errorf("TestKustomizeGenerateOverlayOptions() error: expected the previous %s to be removed", file)
+		}
+	}
+	if k = readKustomization(); !reflect.DeepEqual(k.Resources, []string{"../../base"}) {
+		t.Errorf("TestKustomizeGenerateOverlayOptions() error: unexpected resources %v", k.Resources)
+	}
+}
+
+func TestKustomizeReferencedEnv(t *testing.T) {
+	baseDeployment := appsv1.Deployment{}
+	baseDeployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name: "backend",
+			Env: []corev1.EnvVar{
+				{Name: "MODE", Value: "production"},
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password"}}},
+			},
+		},
+	}
+	options := gitopsv1alpha1.GeneratorOptions{Name: "backend"}
+	overlay, err := GetOverlayOptions(parser.KubernetesResources{Deployments: []appsv1.Deployment{baseDeployment}}, options, OverlayConfiguration{})
+	if err != nil {
+		t.Fatalf("TestKustomizeReferencedEnv() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(overlay.ReferencedEnv, []string{"PASSWORD"}) {
+		t.Fatalf("TestKustomizeReferencedEnv() error: unexpected referenced env %v", overlay.ReferencedEnv)
+	}
+
+	fs := ioutils.NewMemoryFilesystem()
+	outputFolder := "/gitops/components/backend/overlays/staging"
+	k := resources.Kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	k.AddResources("../../base")
+	k.AddPatches("deployment-patch.yaml")
+	if err := writeYAMLFile(fs, outputFolder+"/kustomization.yaml", k); err != nil {
+		t.Fatalf("TestKustomizeReferencedEnv() unexpected error: %v", err)
+	}
+	patch := appsv1.Deployment{}
+	patch.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name: "backend",
+			Env:  []corev1.EnvVar{{Name: "MODE", Value: "staging"}, {Name: "PASSWORD", Value: "staging-password"}},
+		},
+	}
+	if err := writeYAMLFile(fs, outputFolder+"/deployment-patch.yaml", patch); err != nil {
+		t.Fatalf("TestKustomizeReferencedEnv() unexpected error: %v", err)
+	}
+
+	// The value that the Environment sets replaces the reference of the base
+	if err := (Kustomize{}).GenerateOverlayOptions(fs, outputFolder, options, overlay); err != nil {
+		t.Fatalf("TestKustomizeReferencedEnv() unexpected error: %v", err)
+	}
+	content, err := fs.ReadFile(outputFolder + "/deployment-patch.yaml")
+	if err != nil {
+		t.Fatalf("TestKustomizeReferencedEnv() unexpected error: %v", err)
+	}
+	if strings.Count(string(content), "valueFrom: null") != 1 || !strings.Contains(string(content), "value: staging-password") {
+		t.Errorf("TestKustomizeReferencedEnv() error: unexpected deployment patch\n%s", content)
+	}
+}
+
+func TestHelmAutoscaling(t *testing.T) {
+	autoscaler, err := devfile.GetHorizontalPodAutoscaler("backend", nil, devfile.Autoscaling{MinReplicas: int32Ptr(2), MaxReplicas: int32Ptr(4)})
+	if err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error: %v", err)
+	}
+	options := gitopsv1alpha1.GeneratorOptions{
+		Name:           "backend",
+		Application:    "petclinic",
+		ContainerImage: "quay.io/test/backend:v1",
+		KubernetesResources: gitopsv1alpha1.KubernetesResources{
+			Others: []interface{}{autoscaler},
+		},
+	}
+
+	fs := ioutils.NewMemoryFilesystem()
+	baseFolder := "/gitops/components/backend/base"
+	overlaysFolder := "/gitops/components/backend/overlays/staging"
+	if err := (Helm{}).Generate(fs, "/gitops", baseFolder, options); err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error generating the chart: %v", err)
+	}
+	template, err := fs.ReadFile(baseFolder + "/templates/" + autoscalerFileName)
+	if err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error reading the autoscaler template: %v", err)
+	}
+	for _, want := range []string{"{{- with .Values.autoscaling }}\n", "scaleTargetRef:", "  {{- toYaml . | nindent 2 }}\n{{- end }}\n"} {
+		if !strings.Contains(string(template), want) {
+			t.Errorf("TestHelmAutoscaling() error: expected the autoscaler template to contain %q, got\n%s", want, template)
+		}
+	}
+	if other, _ := fs.Exists(baseFolder + "/templates/other_resources.yaml"); other {
+		t.Errorf("TestHelmAutoscaling() error: expected the autoscaler to only be rendered by its template")
+	}
+	var baseValues map[string]interface{}
+	if err := readYAMLFile(fs, baseFolder+"/values.yaml", &baseValues); err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error reading the base values: %v", err)
+	}
+	wantAutoscaling := map[string]interface{}{"minReplicas": float64(2), "maxReplicas": float64(4)}
+	if !reflect.DeepEqual(baseValues["autoscaling"], wantAutoscaling) {
+		t.Errorf("TestHelmAutoscaling() error: expected the autoscaling values %v got %v", wantAutoscaling, baseValues["autoscaling"])
+	}
+
+	if err := writeYAMLFile(fs, overlaysFolder+"/values.yaml", map[string]interface{}{"image": "quay.io/test/backend:v2"}); err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error: %v", err)
+	}
+	devfile.Autoscaling{MaxReplicas: int32Ptr(8)}.ApplyTo(&autoscaler)
+	if err := (Helm{}).GenerateOverlayOptions(fs, overlaysFolder, options, OverlayOptions{Autoscaler: &autoscaler, AutoscalerInBase: true}); err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error generating the overlay options: %v", err)
+	}
+	content, err := fs.ReadFile(overlaysFolder + "/values.yaml")
+	if err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error reading the overlay values: %v", err)
+	}
+	var overlayValues map[string]interface{}
+	if err := yaml.Unmarshal(content, &overlayValues); err != nil {
+		t.Fatalf("TestHelmAutoscaling() unexpected error reading the overlay values: %v", err)
+	}
+	wantAutoscaling["maxReplicas"] = float64(8)
+	if overlayValues["image"] != "quay.io/test/backend:v2" || !reflect.DeepEqual(overlayValues["autoscaling"], wantAutoscaling) {
+		t.Errorf("TestHelmAutoscaling() error: unexpected overlay values %v", overlayValues)
+	}
+}
+
+func TestHelmPolicies(t *testing.T) {
+	fs := ioutils.NewMemoryFilesystem()
+	overlaysFolder := "/gitops/components/backend/overlays/staging"
+	route := map[string]interface{}{"kind": "Route"}
+	if err := writeYAMLFile(fs, overlaysFolder+"/values.yaml", map[string]interface{}{"extraResources": []interface{}{route}}); err != nil {
+		t.Fatalf("TestHelmPolicies() unexpected error: %v", err)
+	}
+	enabled := true
+	options := gitopsv1alpha1.GeneratorOptions{Name: "backend"}
+	overlay, err := GetOverlayOptions(parser.KubernetesResources{}, options, OverlayConfiguration{Policies: devfile.Policies{PodDisruptionBudget: &enabled, NetworkPolicy: &enabled}})
+	if err != nil {
+		t.Fatalf("TestHelmPolicies() unexpected error: %v", err)
+	}
+	if err := (Helm{}).GenerateOverlayOptions(fs, overlaysFolder, options, overlay); err != nil {
+		t.Fatalf("TestHelmPolicies() unexpected error generating the overlay options: %v", err)
+	}
+	var values map[string]interface{}
+	if err := readYAMLFile(fs, overlaysFolder+"/values.yaml", &values); err != nil {
+		t.Fatalf("TestHelmPolicies() unexpected error reading the overlay values: %v", err)
+	}
+	var kinds []interface{}
+	for _, resource := range values["extraResources"].([]interface{}) {
+		kinds = append(kinds, resource.(map[string]interface{})["kind"])
+	}
+	if !reflect.DeepEqual(kinds, []interface{}{"Route", "PodDisruptionBudget", "NetworkPolicy"}) {
+		t.Errorf("TestHelmPolicies() error: unexpected extra resources %v", kinds)
+	}
+}
+
+// getPatchPaths returns the paths of the patches of the given kustomization
+func getPatchPaths(k resources.Kustomization) []string {
+	var paths []string
+	for _, patch := range k.Patches {
+		paths = append(paths, patch.Path)
+	}
+	return paths
+}
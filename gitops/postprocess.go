@@ -0,0 +1,247 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/devfile/library/v2/pkg/devfile/parser"
+	jsonpatch "github.com/evanphx/json-patch"
+	routev1 "github.com/openshift/api/route/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// PostProcessor is a step of the chain that the Kubernetes resources of Components are post-processed with, before their GitOps
+// resources are generated. Exactly one of Labels, Annotations, JSONPatch and StrategicMergePatch is set.
+type PostProcessor struct {
+	// Name identifies the post-processor in errors
+	Name string `json:"name,omitempty"`
+
+	// Kinds are the kinds of resources, such as Deployment, that the post-processor applies to. It applies to all resources if unset.
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Labels and Annotations are added to the metadata of the resources, replacing those with the same keys
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// JSONPatch is an RFC 6902 JSON patch applied to the resources
+	JSONPatch []map[string]interface{} `json:"jsonPatch,omitempty"`
+
+	// StrategicMergePatch is a strategic merge patch applied to the resources. Resources of kinds without patch strategies, such as
+	// those of custom resources, are patched with a JSON merge patch instead.
+	StrategicMergePatch map[string]interface{} `json:"strategicMergePatch,omitempty"`
+}
+
+// PostProcessors is the ordered chain of post-processors that the Kubernetes resources of Components are post-processed with
+type PostProcessors []PostProcessor
+
+// postProcessorsConfig is the format of the file that post-processors are configured in, usually mounted from a ConfigMap
+type postProcessorsConfig struct {
+	PostProcessors PostProcessors `json:"postProcessors"`
+}
+
+// LoadPostProcessors returns the post-processors configured in the given file, or none if no file is given
+func LoadPostProcessors(path string) (PostProcessors, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the GitOps post-processors from %s: %v", path, err)
+	}
+	return NewPostProcessors(content)
+}
+
+// NewPostProcessors returns the post-processors configured in the given YAML, checking that each of them is valid
+func NewPostProcessors(config []byte) (PostProcessors, error) {
+	var parsed postProcessorsConfig
+	if err := yaml.UnmarshalStrict(config, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to parse the GitOps post-processors: %v", err)
+	}
+	for i, postProcessor := range parsed.PostProcessors {
+		operations := 0
+		for _, set := range []bool{len(postProcessor.Labels) > 0, len(postProcessor.Annotations) > 0, len(postProcessor.JSONPatch) > 0, len(postProcessor.StrategicMergePatch) > 0} {
+			if set {
+				operations++
+			}
+		}
+		if operations != 1 {
+			return nil, fmt.Errorf("GitOps post-processor %s must set exactly one of labels, annotations, jsonPatch and strategicMergePatch", postProcessor.name(i))
+		}
+		if len(postProcessor.JSONPatch) > 0 {
+			if _, err := postProcessor.jsonPatch(); err != nil {
+				return nil, fmt.Errorf("GitOps post-processor %s has an invalid JSON patch: %v", postProcessor.name(i), err)
+			}
+		}
+	}
+	return parsed.PostProcessors, nil
+}
+
+// Apply post-processes the given Kubernetes resources with each of the post-processors in turn
+func (p PostProcessors) Apply(resources *parser.KubernetesResources) error {
+	if len(p) == 0 {
+		return nil
+	}
+	for i := range resources.Deployments {
+		var processed appsv1.Deployment
+		if err := p.apply("Deployment", resources.Deployments[i], &processed); err != nil {
+			return err
+		}
+		resources.Deployments[i] = processed
+	}
+	for i := range resources.Services {
+		var processed corev1.Service
+		if err := p.apply("Service", resources.Services[i], &processed); err != nil {
+			return err
+		}
+		resources.Services[i] = processed
+	}
+	for i := range resources.Routes {
+		var processed routev1.Route
+		if err := p.apply("Route", resources.Routes[i], &processed); err != nil {
+			return err
+		}
+		resources.Routes[i] = processed
+	}
+	for i := range resources.Ingresses {
+		var processed networkingv1.Ingress
+		if err := p.apply("Ingress", resources.Ingresses[i], &processed); err != nil {
+			return err
+		}
+		resources.Ingresses[i] = processed
+	}
+	for i := range resources.Others {
+		var processed map[string]interface{}
+		if err := p.apply("", resources.Others[i], &processed); err != nil {
+			return err
+		}
+		resources.Others[i] = processed
+	}
+	return nil
+}
+
+// apply post-processes the given resource of the given kind into processed, which is a pointer to a resource of the same type. The
+// kind of untyped resources is read from the resources themselves.
+func (p PostProcessors) apply(kind string, resource interface{}, processed interface{}) error {
+	document, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	var typed interface{}
+	if kind == "" {
+		var object struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(document, &object); err != nil {
+			return err
+		}
+		kind = object.Kind
+	} else {
+		typed = processed
+	}
+
+	for i, postProcessor := range p {
+		if !postProcessor.appliesTo(kind) {
+			continue
+		}
+		if document, err = postProcessor.patch(document, typed); err != nil {
+			return fmt.Errorf("unable to apply GitOps post-processor %s to %s: %v", postProcessor.name(i), kind, err)
+		}
+	}
+	return json.Unmarshal(document, processed)
+}
+
+// patch returns the given resource document, patched by the post-processor. Resources of the type of typed are patched with strategic
+// merge patches, and untyped resources, for which typed is nil, with JSON merge patches.
+func (p PostProcessor) patch(document []byte, typed interface{}) ([]byte, error) {
+	switch {
+	case len(p.Labels) > 0:
+		return mergePatch(document, map[string]interface{}{"metadata": map[string]interface{}{"labels": p.Labels}})
+	case len(p.Annotations) > 0:
+		return mergePatch(document, map[string]interface{}{"metadata": map[string]interface{}{"annotations": p.Annotations}})
+	case len(p.JSONPatch) > 0:
+		patch, err := p.jsonPatch()
+		if err != nil {
+			return nil, err
+		}
+		return patch.Apply(document)
+	default:
+		if typed == nil {
+			return mergePatch(document, p.StrategicMergePatch)
+		}
+		patch, err := json.Marshal(p.StrategicMergePatch)
+		if err != nil {
+			return nil, err
+		}
+		return strategicpatch.StrategicMergePatch(document, patch, typed)
+	}
+}
+
+// jsonPatch returns the decoded JSON patch of the post-processor
+func (p PostProcessor) jsonPatch() (jsonpatch.Patch, error) {
+	patch, err := json.Marshal(p.JSONPatch)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	for _, operation := range decoded {
+		switch operation.Kind() {
+		case "add", "remove", "replace", "move", "copy", "test":
+		default:
+			return nil, fmt.Errorf("unsupported operation %q", operation.Kind())
+		}
+	}
+	return decoded, nil
+}
+
+// appliesTo returns true if the post-processor applies to resources of the given kind
+func (p PostProcessor) appliesTo(kind string) bool {
+	if len(p.Kinds) == 0 {
+		return true
+	}
+	for _, k := range p.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the name of the post-processor, or its position in the chain if it has none
+func (p PostProcessor) name(index int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+// mergePatch returns the given document patched with the given JSON merge patch
+func mergePatch(document []byte, patch map[string]interface{}) ([]byte, error) {
+	patchDocument, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.MergePatch(document, patchDocument)
+}
@@ -0,0 +1,191 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/devfile/library/v2/pkg/devfile/parser"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewPostProcessors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:   "No post-processors",
+			config: "postProcessors: []\n",
+		},
+		{
+			name: "Valid post-processors",
+			config: `postProcessors:
+- name: cost-center
+  labels:
+    cost-center: "1234"
+- kinds: [Deployment]
+  jsonPatch:
+  - op: add
+    path: /spec/template/spec/securityContext
+    value:
+      runAsNonRoot: true
+- kinds: [Deployment]
+  strategicMergePatch:
+    spec:
+      template:
+        spec:
+          tolerations:
+          - key: dedicated
+            operator: Exists
+`,
+			want: 3,
+		},
+		{
+			name: "Post-processor without an operation",
+			config: `postProcessors:
+- name: empty
+  kinds: [Deployment]
+`,
+			wantErr: true,
+		},
+		{
+			name: "Post-processor with several operations",
+			config: `postProcessors:
+- labels:
+    cost-center: "1234"
+  annotations:
+    owner: team-a
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid JSON patch",
+			config: `postProcessors:
+- jsonPatch:
+  - op: frobnicate
+    path: /spec
+`,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown field",
+			config:  "postProcessors:\n- label:\n    cost-center: \"1234\"\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postProcessors, err := NewPostProcessors([]byte(tt.config))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestNewPostProcessors() unexpected error value: %v", err)
+			}
+			if len(postProcessors) != tt.want {
+				t.Errorf("TestNewPostProcessors() error: expected %d post-processors got %d", tt.want, len(postProcessors))
+			}
+		})
+	}
+}
+
+func TestPostProcessorsApply(t *testing.T) {
+	postProcessors, err := NewPostProcessors([]byte(`postProcessors:
+- labels:
+    cost-center: "1234"
+- kinds: [Deployment]
+  annotations:
+    owner: team-a
+- kinds: [Deployment]
+  jsonPatch:
+  - op: add
+    path: /spec/template/spec/securityContext
+    value:
+      runAsNonRoot: true
+- kinds: [Deployment]
+  strategicMergePatch:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: container-image
+            imagePullPolicy: IfNotPresent
+- kinds: [ConfigMap]
+  strategicMergePatch:
+    data:
+      region: eu
+`))
+	if err != nil {
+		t.Fatalf("TestPostProcessorsApply() unexpected error: %v", err)
+	}
+
+	resources := parser.KubernetesResources{
+		Deployments: []appsv1.Deployment{{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "backend", Labels: map[string]string{"app": "backend"}},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Name: "container-image", Image: "quay.io/test/backend", ImagePullPolicy: corev1.PullAlways},
+							{Name: "sidecar", Image: "quay.io/test/sidecar"},
+						},
+					},
+				},
+			},
+		}},
+		Services: []corev1.Service{{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: "backend"},
+		}},
+		Others: []interface{}{
+			map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "backend"}, "data": map[string]interface{}{"mode": "prod"}},
+		},
+	}
+	if err := postProcessors.Apply(&resources); err != nil {
+		t.Fatalf("TestPostProcessorsApply() unexpected error: %v", err)
+	}
+
+	deployment := resources.Deployments[0]
+	if !reflect.DeepEqual(deployment.Labels, map[string]string{"app": "backend", "cost-center": "1234"}) {
+		t.Errorf("TestPostProcessorsApply() error: unexpected deployment labels %v", deployment.Labels)
+	}
+	if deployment.Annotations["owner"] != "team-a" {
+		t.Errorf("TestPostProcessorsApply() error: unexpected deployment annotations %v", deployment.Annotations)
+	}
+	podSpec := deployment.Spec.Template.Spec
+	if podSpec.SecurityContext == nil || podSpec.SecurityContext.RunAsNonRoot == nil || !*podSpec.SecurityContext.RunAsNonRoot {
+		t.Errorf("TestPostProcessorsApply() error: expected the JSON patch to set the security context, got %v", podSpec.SecurityContext)
+	}
+	// The strategic merge patch merges containers by name, rather than replacing the list
+	if len(podSpec.Containers) != 2 || podSpec.Containers[0].ImagePullPolicy != corev1.PullIfNotPresent || podSpec.Containers[0].Image != "quay.io/test/backend" {
+		t.Errorf("TestPostProcessorsApply() error: unexpected containers %v", podSpec.Containers)
+	}
+
+	service := resources.Services[0]
+	if service.Labels["cost-center"] != "1234" || len(service.Annotations) != 0 {
+		t.Errorf("TestPostProcessorsApply() error: expected only the labels to be injected in the service, got %v %v", service.Labels, service.Annotations)
+	}
+
+	configMap := resources.Others[0].(map[string]interface{})
+	if !reflect.DeepEqual(configMap["data"], map[string]interface{}{"mode": "prod", "region": "eu"}) {
+		t.Errorf("TestPostProcessorsApply() error: unexpected config map data %v", configMap["data"])
+	}
+}
@@ -0,0 +1,155 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	devfileParser "github.com/devfile/library/v2/pkg/devfile/parser"
+	"github.com/devfile/library/v2/pkg/devfile/parser/data"
+	"github.com/go-logr/logr"
+	"github.com/pmezard/go-difflib/difflib"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/devfile"
+	"github.com/redhat-appstudio/application-service/pkg/util"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	"github.com/spf13/afero"
+)
+
+const (
+	// DryRunAnnotation is the annotation on a Component or SnapshotEnvironmentBinding that, when set, makes HAS render its GitOps
+	// resources into a ConfigMap, rather than push them. Its value is the dry run mode, DryRunModeRender or DryRunModeDiff, and true
+	// is the same as DryRunModeRender.
+	DryRunAnnotation = "gitops-dry-run"
+
+	// DryRunModeRender stores the rendered GitOps resources
+	DryRunModeRender = "render"
+
+	// DryRunModeDiff stores the unified diff of the rendered GitOps resources against the GitOps repository
+	DryRunModeDiff = "diff"
+)
+
+// GetDryRunMode returns the dry run mode set by the DryRunAnnotation in the given annotations, or an empty string if the GitOps
+// resources are pushed. Values other than false and the dry run modes are rendered, so that a mistyped value never pushes.
+func GetDryRunMode(annotations map[string]string) string {
+	value := strings.ToLower(strings.TrimSpace(annotations[DryRunAnnotation]))
+	if value == "" {
+		return ""
+	}
+	if value == DryRunModeDiff {
+		return DryRunModeDiff
+	}
+	if enabled, err := strconv.ParseBool(value); err == nil && !enabled {
+		return ""
+	}
+	return DryRunModeRender
+}
+
+// ComponentGeneratorOptions returns the options that the base GitOps resources of the Component are generated with, from the
+// Kubernetes resources of the outerloop components of its devfile, post-processed by the given post-processors
+func ComponentGeneratorOptions(log logr.Logger, component appstudiov1alpha1.Component, devfileData data.DevfileData, postProcessors PostProcessors) (gitopsv1alpha1.GeneratorOptions, error) {
+	deployAssociatedComponents, err := devfileParser.GetDeployComponents(devfileData)
+	if err != nil {
+		log.Error(err, "unable to get deploy components")
+		return gitopsv1alpha1.GeneratorOptions{}, err
+	}
+
+	kubernetesResources, err := devfile.GetResourceFromDevfile(log, devfileData, deployAssociatedComponents, component.Name, component.Spec.Application, component.Spec.ContainerImage, "")
+	if err != nil {
+		log.Error(err, "unable to get kubernetes resources from the devfile outerloop components")
+		return gitopsv1alpha1.GeneratorOptions{}, err
+	}
+	if err := postProcessors.Apply(&kubernetesResources); err != nil {
+		return gitopsv1alpha1.GeneratorOptions{}, err
+	}
+	return util.GetMappedGitOpsComponent(component, kubernetesResources), nil
+}
+
+// RenderComponent renders the base GitOps resources of the Component, from its devfile, in the given format into a new in-memory
+// filesystem, under components/<name>/base. Nothing is cloned or pushed.
+func RenderComponent(log logr.Logger, component appstudiov1alpha1.Component, devfileData data.DevfileData, postProcessors PostProcessors, format OutputFormat) (afero.Afero, error) {
+	options, err := ComponentGeneratorOptions(log, component, devfileData, postProcessors)
+	if err != nil {
+		return afero.Afero{}, err
+	}
+	fs := ioutils.NewMemoryFilesystem()
+	if err := GenerateBase(fs, format, string(filepath.Separator), options); err != nil {
+		return afero.Afero{}, err
+	}
+	return fs, nil
+}
+
+// UnifiedDiff returns the unified diff, in the format of git diff, between the given files, keyed by their paths. Files that are
+// only in from are deleted, and files that are only in to are created.
+func UnifiedDiff(from map[string][]byte, to map[string][]byte) (string, error) {
+	var paths []string
+	for path := range from {
+		paths = append(paths, path)
+	}
+	for path := range to {
+		if _, ok := from[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var diff strings.Builder
+	for _, path := range paths {
+		fromContent, inFrom := from[path]
+		toContent, inTo := to[path]
+		if inFrom && inTo && bytes.Equal(fromContent, toContent) {
+			continue
+		}
+		fileDiff := difflib.UnifiedDiff{
+			A:        splitLines(fromContent),
+			B:        splitLines(toContent),
+			FromFile: "a/" + path,
+			ToFile:   "b/" + path,
+			Context:  3,
+		}
+		if !inFrom {
+			fileDiff.FromFile = "/dev/null"
+		}
+		if !inTo {
+			fileDiff.ToFile = "/dev/null"
+		}
+		text, err := difflib.GetUnifiedDiffString(fileDiff)
+		if err != nil {
+			return "", err
+		}
+		diff.WriteString(text)
+	}
+	return diff.String(), nil
+}
+
+// splitLines splits the content into lines, each ending with a newline
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n"
+	}
+	return lines
+}
@@ -0,0 +1,172 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	appstudiov1alpha1 "github.com/redhat-appstudio/application-api/api/v1alpha1"
+	"github.com/redhat-appstudio/application-service/pkg/devfile"
+)
+
+func TestGetDryRunMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name: "No annotations",
+		},
+		{
+			name:        "Disabled",
+			annotations: map[string]string{DryRunAnnotation: "false"},
+		},
+		{
+			name:        "Enabled",
+			annotations: map[string]string{DryRunAnnotation: "true"},
+			want:        DryRunModeRender,
+		},
+		{
+			name:        "Render",
+			annotations: map[string]string{DryRunAnnotation: "render"},
+			want:        DryRunModeRender,
+		},
+		{
+			name:        "Diff",
+			annotations: map[string]string{DryRunAnnotation: " Diff "},
+			want:        DryRunModeDiff,
+		},
+		{
+			name:        "Mistyped mode",
+			annotations: map[string]string{DryRunAnnotation: "dif"},
+			want:        DryRunModeRender,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetDryRunMode(tt.annotations); got != tt.want {
+				t.Errorf("TestGetDryRunMode() error: expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from map[string][]byte
+		to   map[string][]byte
+		want string
+	}{
+		{
+			name: "No changes",
+			from: map[string][]byte{"components/backend/base/kustomization.yaml": []byte("resources:\n- deployment.yaml\n")},
+			to:   map[string][]byte{"components/backend/base/kustomization.yaml": []byte("resources:\n- deployment.yaml\n")},
+		},
+		{
+			name: "Modified, created and deleted files",
+			from: map[string][]byte{
+				"components/backend/base/deployment.yaml": []byte("kind: Deployment\nspec:\n  replicas: 1\n"),
+				"components/backend/base/route.yaml":      []byte("kind: Route\n"),
+			},
+			to: map[string][]byte{
+				"components/backend/base/deployment.yaml": []byte("kind: Deployment\nspec:\n  replicas: 2\n"),
+				"components/backend/base/service.yaml":    []byte("kind: Service"),
+			},
+			want: `--- a/components/backend/base/deployment.yaml
++++ b/components/backend/base/deployment.yaml
+@@ -1,3 +1,3 @@
+ kind: Deployment
+ spec:
+-  replicas: 1
++  replicas: 2
+--- a/components/backend/base/route.yaml
++++ /dev/null
+@@ -1 +0,0 @@
+-kind: Route
+--- /dev/null
++++ b/components/backend/base/service.yaml
+@@ -0,0 +1 @@
++kind: Service
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnifiedDiff(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("TestUnifiedDiff() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TestUnifiedDiff() error: expected\n%v\ngot\n%v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderComponent(t *testing.T) {
+	component := appstudiov1alpha1.Component{
+		Spec: appstudiov1alpha1.ComponentSpec{
+			ComponentName:  "backend",
+			Application:    "petclinic",
+			ContainerImage: "quay.io/test/backend:v1",
+		},
+	}
+	component.Name = "backend"
+	devfileData, err := devfile.ConvertImageComponentToDevfile(component)
+	if err != nil {
+		t.Fatalf("TestRenderComponent() unexpected error converting the component to a devfile: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		format      OutputFormat
+		wantFile    string
+		notWantFile string
+	}{
+		{
+			name:        "Kustomize",
+			format:      Kustomize{},
+			wantFile:    "/components/backend/base/kustomization.yaml",
+			notWantFile: "/components/backend/base/Chart.yaml",
+		},
+		{
+			name:        "Helm",
+			format:      Helm{},
+			wantFile:    "/components/backend/base/Chart.yaml",
+			notWantFile: "/components/backend/base/kustomization.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, err := RenderComponent(logr.Discard(), component, devfileData, nil, tt.format)
+			if err != nil {
+				t.Fatalf("TestRenderComponent() unexpected error: %v", err)
+			}
+			if exists, _ := fs.Exists(tt.wantFile); !exists {
+				t.Errorf("TestRenderComponent() error: expected %s to be rendered", tt.wantFile)
+			}
+			if exists, _ := fs.Exists(tt.notWantFile); exists {
+				t.Errorf("TestRenderComponent() error: expected %s not to be rendered", tt.notWantFile)
+			}
+		})
+	}
+}
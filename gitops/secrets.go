@@ -0,0 +1,260 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/devfile/library/v2/pkg/devfile/parser"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ExternalSecretsStoreAnnotation is the annotation of an Environment or SnapshotEnvironmentBinding that names the SecretStore that
+	// the Secrets referenced by its Components are synced from, with ExternalSecrets of the external-secrets operator
+	ExternalSecretsStoreAnnotation = "external-secrets-store"
+
+	// ExternalSecretsStoreKindAnnotation is the annotation that sets the kind of the SecretStore, SecretStore or ClusterSecretStore
+	ExternalSecretsStoreKindAnnotation = "external-secrets-store-kind"
+
+	// ExternalSecretsRefreshIntervalAnnotation is the annotation that sets how often the ExternalSecrets are synced
+	ExternalSecretsRefreshIntervalAnnotation = "external-secrets-refresh-interval"
+
+	// ExternalSecretsKeyPrefixAnnotation is the annotation that sets the prefix of the keys of the Secrets in the SecretStore
+	ExternalSecretsKeyPrefixAnnotation = "external-secrets-key-prefix"
+
+	// SealedSecretsConfigMapAnnotation is the annotation of an Environment or SnapshotEnvironmentBinding that names the ConfigMap, in
+	// the namespace of the binding, whose entries are the SealedSecrets of the Secrets referenced by its Components
+	SealedSecretsConfigMapAnnotation = "sealed-secrets-configmap"
+
+	externalSecretAPIVersion       = "external-secrets.io/v1beta1"
+	defaultExternalSecretsStore    = "SecretStore"
+	defaultExternalSecretsInterval = "1h"
+	sealedSecretAPIVersion         = "bitnami.com/v1alpha1"
+	secretsFileName                = "secrets.yaml"
+)
+
+// SecretSourceAnnotations are the annotations that configure the secret source of an Environment
+var SecretSourceAnnotations = []string{
+	ExternalSecretsStoreAnnotation,
+	ExternalSecretsStoreKindAnnotation,
+	ExternalSecretsRefreshIntervalAnnotation,
+	ExternalSecretsKeyPrefixAnnotation,
+	SealedSecretsConfigMapAnnotation,
+}
+
+// SecretSource is where the Secrets referenced by the Components deployed to an Environment come from. The Secrets are rendered in
+// their overlays as ExternalSecrets of the store, or as the given SealedSecrets, so that the GitOps repository never holds their
+// values.
+type SecretSource struct {
+	// Store is the name of the SecretStore that ExternalSecrets sync the Secrets from
+	Store string
+
+	// StoreKind is the kind of the store, SecretStore or ClusterSecretStore
+	StoreKind string
+
+	// RefreshInterval is how often the ExternalSecrets are synced
+	RefreshInterval string
+
+	// KeyPrefix is prepended to the name of a Secret to get its key in the store
+	KeyPrefix string
+
+	// SealedSecretsConfigMap is the name of the ConfigMap that holds the SealedSecrets of the Secrets
+	SealedSecretsConfigMap string
+}
+
+// GetSecretSourceFromAnnotations returns the secret source set by the given annotations
+func GetSecretSourceFromAnnotations(annotations map[string]string) (SecretSource, error) {
+	source := SecretSource{
+		Store:                  strings.TrimSpace(annotations[ExternalSecretsStoreAnnotation]),
+		StoreKind:              strings.TrimSpace(annotations[ExternalSecretsStoreKindAnnotation]),
+		RefreshInterval:        strings.TrimSpace(annotations[ExternalSecretsRefreshIntervalAnnotation]),
+		KeyPrefix:              strings.TrimSpace(annotations[ExternalSecretsKeyPrefixAnnotation]),
+		SealedSecretsConfigMap: strings.TrimSpace(annotations[SealedSecretsConfigMapAnnotation]),
+	}
+	if source.StoreKind != "" && source.StoreKind != defaultExternalSecretsStore && source.StoreKind != "ClusterSecretStore" {
+		return SecretSource{}, fmt.Errorf("the %s annotation must be SecretStore or ClusterSecretStore, got %q", ExternalSecretsStoreKindAnnotation, source.StoreKind)
+	}
+	return source, nil
+}
+
+// Merge returns the secret source with the fields that are set in override replaced by them
+func (s SecretSource) Merge(override SecretSource) SecretSource {
+	for _, field := range []struct{ value, override *string }{
+		{&s.Store, &override.Store},
+		{&s.StoreKind, &override.StoreKind},
+		{&s.RefreshInterval, &override.RefreshInterval},
+		{&s.KeyPrefix, &override.KeyPrefix},
+		{&s.SealedSecretsConfigMap, &override.SealedSecretsConfigMap},
+	} {
+		if *field.override != "" {
+			*field.value = *field.override
+		}
+	}
+	return s
+}
+
+// ParseSealedSecrets returns the SealedSecrets in the entries of the given ConfigMap data, by their name. Each entry holds a single
+// SealedSecret, and anything else is an error, so that a plain Secret is never committed.
+func ParseSealedSecrets(data map[string]string) (map[string]map[string]interface{}, error) {
+	sealedSecrets := make(map[string]map[string]interface{})
+	for entry, content := range data {
+		var sealedSecret map[string]interface{}
+		if err := yaml.Unmarshal([]byte(content), &sealedSecret); err != nil {
+			return nil, fmt.Errorf("unable to parse the SealedSecret in %s: %v", entry, err)
+		}
+		if sealedSecret["apiVersion"] != sealedSecretAPIVersion || sealedSecret["kind"] != "SealedSecret" {
+			return nil, fmt.Errorf("%s must hold a %s SealedSecret", entry, sealedSecretAPIVersion)
+		}
+		metadata, _ := sealedSecret["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("the SealedSecret in %s has no name", entry)
+		}
+		delete(metadata, "namespace")
+		delete(sealedSecret, "status")
+		sealedSecrets[name] = sealedSecret
+	}
+	return sealedSecrets, nil
+}
+
+// getSecrets returns the resources that provide the Secrets referenced by the workloads of the given base resources: their
+// SealedSecret if one is given, or otherwise an ExternalSecret if the secret source has a store. Secrets without either are left to
+// exist in the namespace of the Environment.
+func getSecrets(baseResources parser.KubernetesResources, source SecretSource, sealedSecrets map[string]map[string]interface{}, labels map[string]string) []map[string]interface{} {
+	referencedSecrets := getReferencedSecrets(baseResources)
+	var names []string
+	for name := range referencedSecrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var secrets []map[string]interface{}
+	for _, name := range names {
+		if sealedSecret, ok := sealedSecrets[name]; ok {
+			secrets = append(secrets, sealedSecret)
+		} else if source.Store != "" {
+			secrets = append(secrets, getExternalSecret(name, referencedSecrets[name], source, labels))
+		}
+	}
+	return secrets
+}
+
+// getExternalSecret returns the ExternalSecret that syncs the given keys of the Secret with the given name from the store of the
+// secret source. A Secret without keys is referenced as a whole, and all of its keys are synced.
+func getExternalSecret(name string, keys []string, source SecretSource, labels map[string]string) map[string]interface{} {
+	storeKind, refreshInterval := source.StoreKind, source.RefreshInterval
+	if storeKind == "" {
+		storeKind = defaultExternalSecretsStore
+	}
+	if refreshInterval == "" {
+		refreshInterval = defaultExternalSecretsInterval
+	}
+	remoteKey := source.KeyPrefix + name
+
+	spec := map[string]interface{}{
+		"refreshInterval": refreshInterval,
+		"secretStoreRef": map[string]interface{}{
+			"name": source.Store,
+			"kind": storeKind,
+		},
+		"target": map[string]interface{}{
+			"name":           name,
+			"creationPolicy": "Owner",
+		},
+	}
+	if len(keys) == 0 {
+		spec["dataFrom"] = []interface{}{
+			map[string]interface{}{
+				"extract": map[string]interface{}{"key": remoteKey},
+			},
+		}
+	} else {
+		var data []interface{}
+		for _, key := range keys {
+			data = append(data, map[string]interface{}{
+				"secretKey": key,
+				"remoteRef": map[string]interface{}{"key": remoteKey, "property": key},
+			})
+		}
+		spec["data"] = data
+	}
+
+	metadata := map[string]interface{}{"name": name}
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	return map[string]interface{}{
+		"apiVersion": externalSecretAPIVersion,
+		"kind":       "ExternalSecret",
+		"metadata":   metadata,
+		"spec":       spec,
+	}
+}
+
+// getReferencedSecrets returns the keys of the Secrets referenced by the containers of the workloads of the given base resources, by
+// the name of the Secret. Secrets referenced as a whole, by envFrom, have no keys.
+func getReferencedSecrets(baseResources parser.KubernetesResources) map[string][]string {
+	keys := make(map[string]map[string]bool)
+	for _, deployment := range baseResources.Deployments {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			for _, env := range container.Env {
+				if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil || env.ValueFrom.SecretKeyRef.Name == "" {
+					continue
+				}
+				addSecretKey(keys, env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Key)
+			}
+			for _, envFrom := range container.EnvFrom {
+				if envFrom.SecretRef != nil && envFrom.SecretRef.Name != "" {
+					addSecretKey(keys, envFrom.SecretRef.Name, "")
+				}
+			}
+		}
+	}
+
+	referencedSecrets := make(map[string][]string)
+	for name, secretKeys := range keys {
+		referencedSecrets[name] = nil
+		if secretKeys == nil {
+			continue
+		}
+		for key := range secretKeys {
+			referencedSecrets[name] = append(referencedSecrets[name], key)
+		}
+		sort.Strings(referencedSecrets[name])
+	}
+	return referencedSecrets
+}
+
+// addSecretKey adds the given key of the Secret with the given name to the keys. An empty key references the whole Secret, which
+// then has no keys.
+func addSecretKey(keys map[string]map[string]bool, name, key string) {
+	secretKeys, ok := keys[name]
+	if ok && secretKeys == nil {
+		return
+	}
+	if key == "" {
+		keys[name] = nil
+		return
+	}
+	if !ok {
+		secretKeys = make(map[string]bool)
+		keys[name] = secretKeys
+	}
+	secretKeys[key] = true
+}
@@ -0,0 +1,184 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/devfile/library/v2/pkg/devfile/parser"
+	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
+	gitopsv1alpha1 "github.com/redhat-developer/gitops-generator/api/v1alpha1"
+	"github.com/redhat-developer/gitops-generator/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const testSealedSecret = `apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: db
+  namespace: staging
+spec:
+  encryptedData:
+    password: AgBy3i4OJSWK
+`
+
+func TestGetSecretSourceFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        SecretSource
+		wantErr     bool
+	}{
+		{
+			name: "No annotations",
+		},
+		{
+			name: "External secrets",
+			annotations: map[string]string{
+				ExternalSecretsStoreAnnotation:     " vault ",
+				ExternalSecretsStoreKindAnnotation: "ClusterSecretStore",
+				ExternalSecretsKeyPrefixAnnotation: "staging/",
+			},
+			want: SecretSource{Store: "vault", StoreKind: "ClusterSecretStore", KeyPrefix: "staging/"},
+		},
+		{
+			name:        "Invalid store kind",
+			annotations: map[string]string{ExternalSecretsStoreKindAnnotation: "Vault"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := GetSecretSourceFromAnnotations(tt.annotations)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestGetSecretSourceFromAnnotations() unexpected error value: %v", err)
+			}
+			if source != tt.want {
+				t.Errorf("TestGetSecretSourceFromAnnotations() error: expected %+v got %+v", tt.want, source)
+			}
+		})
+	}
+
+	// The secret source of a binding overrides that of its Environment
+	merged := SecretSource{Store: "vault", KeyPrefix: "staging/"}.Merge(SecretSource{KeyPrefix: "team/"})
+	if merged != (SecretSource{Store: "vault", KeyPrefix: "team/"}) {
+		t.Errorf("TestGetSecretSourceFromAnnotations() error: unexpected merged secret source %+v", merged)
+	}
+}
+
+func TestParseSealedSecrets(t *testing.T) {
+	sealedSecrets, err := ParseSealedSecrets(map[string]string{"db.yaml": testSealedSecret})
+	if err != nil {
+		t.Fatalf("TestParseSealedSecrets() unexpected error: %v", err)
+	}
+	metadata := sealedSecrets["db"]["metadata"].(map[string]interface{})
+	if len(sealedSecrets) != 1 || metadata["namespace"] != nil {
+		t.Errorf("TestParseSealedSecrets() error: unexpected SealedSecrets %v", sealedSecrets)
+	}
+
+	// A plain Secret is never rendered
+	plainSecret := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: password\n"
+	if _, err := ParseSealedSecrets(map[string]string{"db.yaml": plainSecret}); err == nil {
+		t.Errorf("TestParseSealedSecrets() error: expected an error for a plain Secret")
+	}
+}
+
+func TestGetSecrets(t *testing.T) {
+	deployment := appsv1.Deployment{}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{
+			Name: "backend",
+			Env: []corev1.EnvVar{
+				{Name: "API_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "api"}, Key: "token"}}},
+				{Name: "API_USER", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "api"}, Key: "user"}}},
+				{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password"}}},
+				{Name: "URL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}, Key: "url"}}},
+			},
+			EnvFrom: []corev1.EnvFromSource{
+				{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "tls"}}},
+			},
+		},
+	}
+	baseResources := parser.KubernetesResources{Deployments: []appsv1.Deployment{deployment}}
+	sealedSecrets, err := ParseSealedSecrets(map[string]string{"db.yaml": testSealedSecret})
+	if err != nil {
+		t.Fatalf("TestGetSecrets() unexpected error: %v", err)
+	}
+
+	// Without a store, only the Secrets with a SealedSecret are rendered
+	secrets := getSecrets(baseResources, SecretSource{}, sealedSecrets, nil)
+	if len(secrets) != 1 || secrets[0]["kind"] != "SealedSecret" {
+		t.Errorf("TestGetSecrets() error: unexpected secrets %v", secrets)
+	}
+
+	secrets = getSecrets(baseResources, SecretSource{Store: "vault", KeyPrefix: "staging/"}, sealedSecrets, map[string]string{"app.kubernetes.io/name": "backend"})
+	var kinds []interface{}
+	for _, secret := range secrets {
+		kinds = append(kinds, secret["kind"])
+	}
+	if !reflect.DeepEqual(kinds, []interface{}{"ExternalSecret", "SealedSecret", "ExternalSecret"}) {
+		t.Fatalf("TestGetSecrets() error: unexpected secrets %v", secrets)
+	}
+	apiSpec := secrets[0]["spec"].(map[string]interface{})
+	wantData := []interface{}{
+		map[string]interface{}{"secretKey": "token", "remoteRef": map[string]interface{}{"key": "staging/api", "property": "token"}},
+		map[string]interface{}{"secretKey": "user", "remoteRef": map[string]interface{}{"key": "staging/api", "property": "user"}},
+	}
+	if !reflect.DeepEqual(apiSpec["data"], wantData) || apiSpec["secretStoreRef"].(map[string]interface{})["kind"] != "SecretStore" || apiSpec["refreshInterval"] != "1h" {
+		t.Errorf("TestGetSecrets() error: unexpected ExternalSecret spec %v", apiSpec)
+	}
+	tlsSpec := secrets[2]["spec"].(map[string]interface{})
+	wantDataFrom := []interface{}{map[string]interface{}{"extract": map[string]interface{}{"key": "staging/tls"}}}
+	if !reflect.DeepEqual(tlsSpec["dataFrom"], wantDataFrom) || tlsSpec["data"] != nil {
+		t.Errorf("TestGetSecrets() error: unexpected ExternalSecret spec %v", tlsSpec)
+	}
+}
+
+func TestKustomizeGenerateOverlaySecrets(t *testing.T) {
+	fs := ioutils.NewMemoryFilesystem()
+	outputFolder := "/gitops/components/backend/overlays/staging"
+	k := resources.Kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	k.AddResources("../../base")
+	if err := writeYAMLFile(fs, outputFolder+"/kustomization.yaml", k); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlaySecrets() unexpected error: %v", err)
+	}
+	overlay := OverlayOptions{
+		Secrets: []map[string]interface{}{
+			getExternalSecret("api", []string{"token"}, SecretSource{Store: "vault"}, nil),
+			getExternalSecret("tls", nil, SecretSource{Store: "vault"}, nil),
+		},
+	}
+	if err := (Kustomize{}).GenerateOverlayOptions(fs, outputFolder, gitopsv1alpha1.GeneratorOptions{Name: "backend"}, overlay); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlaySecrets() unexpected error: %v", err)
+	}
+	if err := readYAMLFile(fs, outputFolder+"/kustomization.yaml", &k); err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlaySecrets() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(k.Resources, []string{"../../base", secretsFileName}) {
+		t.Errorf("TestKustomizeGenerateOverlaySecrets() error: unexpected resources %v", k.Resources)
+	}
+	content, err := fs.ReadFile(outputFolder + "/" + secretsFileName)
+	if err != nil {
+		t.Fatalf("TestKustomizeGenerateOverlaySecrets() unexpected error: %v", err)
+	}
+	if documents := strings.Split(string(content), "---\n"); len(documents) != 2 || !strings.Contains(documents[1], "name: tls") {
+		t.Errorf("TestKustomizeGenerateOverlaySecrets() error: unexpected secrets\n%s", content)
+	}
+}
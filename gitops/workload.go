@@ -0,0 +1,98 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+)
+
+// workloadFiles are the files of the workloads that Components are deployed with, in their kustomize base
+var workloadFiles = map[string]bool{
+	"deployment.yaml":  true,
+	"statefulset.yaml": true,
+	"daemonset.yaml":   true,
+}
+
+// workloadPatchFileName returns the name of the file that patches the given workload file in a kustomize overlay
+func workloadPatchFileName(file string) string {
+	return strings.TrimSuffix(file, ".yaml") + "-patch.yaml"
+}
+
+// readWorkload returns the workload in the given file, or nil if the file doesn't exist
+func readWorkload(fs afero.Afero, file string) (map[string]interface{}, error) {
+	if exists, err := fs.Exists(file); err != nil || !exists {
+		return nil, err
+	}
+	var workload map[string]interface{}
+	if err := readYAMLFile(fs, file, &workload); err != nil {
+		return nil, err
+	}
+	return workload, nil
+}
+
+// workloadContainer returns the first container of the given workload, or nil if it has none
+func workloadContainer(workload map[string]interface{}) map[string]interface{} {
+	spec, _ := workload["spec"].(map[string]interface{})
+	template, _ := spec["template"].(map[string]interface{})
+	podSpec, _ := template["spec"].(map[string]interface{})
+	containers, _ := podSpec["containers"].([]interface{})
+	if len(containers) == 0 {
+		return nil
+	}
+	container, _ := containers[0].(map[string]interface{})
+	return container
+}
+
+// envVarName returns the name of the given environment variable of a container
+func envVarName(variable interface{}) string {
+	fields, _ := variable.(map[string]interface{})
+	name, _ := fields["name"].(string)
+	return name
+}
+
+// readYAMLFile unmarshals the given YAML file into out
+func readYAMLFile(fs afero.Afero, file string, out interface{}) error {
+	content, err := fs.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("failed to unmarshal items from %q: %v", file, err)
+	}
+	return nil
+}
+
+// writeYAMLFile marshals the given item into a YAML file
+func writeYAMLFile(fs afero.Afero, file string, item interface{}) error {
+	content, err := yaml.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return writeFile(fs, file, content)
+}
+
+// writeFile writes the given file, creating its folder if needed
+func writeFile(fs afero.Afero, file string, content []byte) error {
+	if err := fs.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return fs.WriteFile(file, content, 0644)
+}
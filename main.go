@@ -25,6 +25,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	spiapi "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 
@@ -38,6 +40,7 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/redhat-appstudio/operator-toolkit/webhook"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -51,7 +54,11 @@ import (
 	cdqanalysis "github.com/redhat-appstudio/application-service/cdq-analysis/pkg"
 	"github.com/redhat-appstudio/application-service/controllers"
 	"github.com/redhat-appstudio/application-service/controllers/webhooks"
+	"github.com/redhat-appstudio/application-service/gitops"
+	"github.com/redhat-appstudio/application-service/pkg/gitcache"
 	"github.com/redhat-appstudio/application-service/pkg/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitlab"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	"github.com/redhat-appstudio/application-service/pkg/spi"
 	"github.com/redhat-appstudio/application-service/pkg/util/ioutils"
 
@@ -66,6 +73,9 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// defaultGitOpsRepoCacheMaxSize is the size that the GitOps repository cache is bounded by, if GITOPS_REPO_CACHE_MAX_SIZE isn't set
+const defaultGitOpsRepoCacheMaxSize = "5Gi"
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -160,6 +170,21 @@ func main() {
 		}
 	}
 
+	// Retrieve the URLs of the GitHub instance to use, defaults to github.com
+	err = github.ParseGitHubURLs()
+	if err != nil {
+		setupLog.Error(err, "unable to parse the GitHub URLs")
+		os.Exit(1)
+	}
+	if gitHubURLs := github.GetGitHubURLs(); gitHubURLs.IsEnterprise() {
+		setupLog.Info(fmt.Sprintf("Using the GitHub Enterprise Server instance at %v", gitHubURLs.BaseURL))
+		err = cdqanalysis.SetGitHubEnterpriseURLs(gitHubURLs.BaseURL, gitHubURLs.RawURL)
+		if err != nil {
+			setupLog.Error(err, "unable to set the GitHub Enterprise Server URLs for cdq analysis")
+			os.Exit(1)
+		}
+	}
+
 	// Parse any passed in tokens and set up a client for handling the github tokens
 	err = github.ParseGitHubTokens()
 	if err != nil {
@@ -167,28 +192,150 @@ func main() {
 		os.Exit(1)
 	}
 	ghTokenClient := github.GitHubTokenClient{}
-	setupLog.Info(fmt.Sprintf("There are %v token(s) available", len(github.Clients)))
+	setupLog.Info(fmt.Sprintf("There are %v token(s) available", github.GetTokenCount()))
+
+	// If the GitHub token Secret is mounted, reload the token pool whenever it changes, so tokens can be rotated without a restart
+	if tokenSecretPath := os.Getenv("GITHUB_TOKEN_SECRET_PATH"); tokenSecretPath != "" {
+		if err := mgr.Add(&github.TokenReloader{SecretPath: tokenSecretPath}); err != nil {
+			setupLog.Error(err, "unable to set up github token reloading")
+			os.Exit(1)
+		}
+	}
+
+	// Retrieve the Git provider to generate GitOps repositories on, defaults to GitHub
+	gitOpsProvider, err := gitprovider.ValidateProviderName(os.Getenv("GITOPS_GIT_PROVIDER"))
+	if err != nil {
+		setupLog.Error(err, "unable to parse $GITOPS_GIT_PROVIDER")
+		os.Exit(1)
+	}
+
+	// Set up the GitLab client, if a GitLab token was provided
+	var gitLabClient gitprovider.GitProvider
+	gitLabGroup := os.Getenv("GITLAB_GROUP")
+	if gitLabToken := os.Getenv("GITLAB_TOKEN"); gitLabToken != "" {
+		if gitLabGroup == "" {
+			setupLog.Error(fmt.Errorf("GITLAB_GROUP must be set when GITLAB_TOKEN is set"), "unable to set up gitlab client")
+			os.Exit(1)
+		}
+		gitLabClient = gitlab.NewGitLabClient(os.Getenv("GITLAB_URL"), gitLabToken, "GITLAB_TOKEN")
+	} else if gitOpsProvider == gitprovider.GitLab {
+		setupLog.Error(fmt.Errorf("GITLAB_TOKEN must be set when GITOPS_GIT_PROVIDER is gitlab"), "unable to set up gitlab client")
+		os.Exit(1)
+	}
+
+	// Retrieve the settings, such as the visibility, access and template repository, of generated GitOps repositories
+	gitOpsRepoOptions, err := gitprovider.NewRepositoryOptions(os.Getenv("GITOPS_REPO_VISIBILITY"), os.Getenv("GITOPS_REPO_TEAMS"),
+		os.Getenv("GITOPS_REPO_PROTECTED_BRANCH"), os.Getenv("GITOPS_REPO_TOPICS"), os.Getenv("GITOPS_REPO_TEMPLATE"))
+	if err != nil {
+		setupLog.Error(err, "unable to parse the GitOps repository settings")
+		os.Exit(1)
+	}
+	// The owner topic marks the repositories generated by this instance, for orphaned repositories in orgs shared by several instances
+	gitOpsRepoOptions.OwnerTopic = strings.ToLower(strings.TrimSpace(os.Getenv("GITOPS_REPO_OWNER_TOPIC")))
+
+	// Retrieve what happens to generated GitOps repositories when their Application is deleted, defaults to deleting them
+	gitOpsRepoDeletionPolicy, err := gitprovider.NewDeletionPolicy(os.Getenv("GITOPS_REPO_DELETION_POLICY"), os.Getenv("GITOPS_REPO_DELETION_GRACE_PERIOD"))
+	if err != nil {
+		setupLog.Error(err, "unable to parse the GitOps repository deletion policy")
+		os.Exit(1)
+	}
+
+	// Archived GitOps repositories are tracked in a ConfigMap in the operator's namespace, and deleted once their grace period expires
+	var archivedRepositories *controllers.ArchivedRepositoryTracker
+	if podNamespace := os.Getenv("POD_NAMESPACE"); podNamespace != "" {
+		archivedRepositories = &controllers.ArchivedRepositoryTracker{Client: mgr.GetClient(), Namespace: podNamespace}
+		if err := mgr.Add(&controllers.ArchivedRepositorySweeper{
+			Tracker:           archivedRepositories,
+			GitHubTokenClient: ghTokenClient,
+			GitLabClient:      gitLabClient,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up the archived GitOps repository sweeper")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("POD_NAMESPACE is not set, archived GitOps repositories will not be tracked for deletion")
+	}
+
+	// Optionally look for generated GitOps repositories that no longer belong to an Application, and report, archive or delete them
+	if os.Getenv("ENABLE_GITOPS_REPO_GC") == "true" {
+		orphanedRepoCollector, err := newOrphanedRepositoryCollector(mgr, archivedRepositories, ghTokenClient, ghOrg, gitLabClient, gitLabGroup, gitOpsRepoOptions.OwnerTopic)
+		if err != nil {
+			setupLog.Error(err, "unable to parse the orphaned GitOps repository garbage collection settings")
+			os.Exit(1)
+		}
+		if err := mgr.Add(orphanedRepoCollector); err != nil {
+			setupLog.Error(err, "unable to set up the orphaned GitOps repository garbage collector")
+			os.Exit(1)
+		}
+	}
 
 	if err = (&controllers.ApplicationReconciler{
-		Client:            mgr.GetClient(),
-		Scheme:            mgr.GetScheme(),
-		Log:               ctrl.Log.WithName("controllers").WithName("Application"),
-		GitHubTokenClient: ghTokenClient,
-		GitHubOrg:         ghOrg,
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		Log:                      ctrl.Log.WithName("controllers").WithName("Application"),
+		GitHubTokenClient:        ghTokenClient,
+		GitHubOrg:                ghOrg,
+		GitLabClient:             gitLabClient,
+		GitLabGroup:              gitLabGroup,
+		GitOpsProvider:           gitOpsProvider,
+		GitOpsRepoOptions:        gitOpsRepoOptions,
+		GitOpsRepoDeletionPolicy: gitOpsRepoDeletionPolicy,
+		ArchivedRepositories:     archivedRepositories,
 	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Application")
 		os.Exit(1)
 	}
+
+	// Optionally sign the commits pushed to GitOps repositories, and override their author and committer
+	commitConfig, err := configureGitOpsCommits()
+	if err != nil {
+		setupLog.Error(err, "unable to configure the GitOps commit signing key")
+		os.Exit(1)
+	}
+
+	// Render the messages of GitOps commits from the configured templates, or the default ones
+	commitMessages, err := gitops.NewCommitMessages(os.Getenv("GITOPS_COMPONENT_COMMIT_MESSAGE_TEMPLATE"), os.Getenv("GITOPS_BINDING_COMMIT_MESSAGE_TEMPLATE"))
+	if err != nil {
+		setupLog.Error(err, "unable to parse the GitOps commit message templates")
+		os.Exit(1)
+	}
+
+	// Optionally post-process the Kubernetes resources of Components with the chain of post-processors in the mounted ConfigMap
+	postProcessors, err := gitops.LoadPostProcessors(os.Getenv("GITOPS_POST_PROCESSORS_FILE"))
+	if err != nil {
+		setupLog.Error(err, "unable to load the GitOps post-processors")
+		os.Exit(1)
+	}
+
+	// Optionally check out GitOps repositories from a cache of their mirrors, rather than cloning them on every reconcile
+	gitOpsGenerator, err := newGitOpsGenerator()
+	if err != nil {
+		setupLog.Error(err, "unable to set up the GitOps repository cache")
+		os.Exit(1)
+	}
+	gitOpsGenerator = gitops.NewCommitConfigGenerator(gitOpsGenerator, commitConfig)
+
+	// Optionally coalesce the GitOps writes of the Components of an Application, which requires Components to be reconciled concurrently
+	gitOpsWriteQueue, componentMaxConcurrentReconciles, err := newGitOpsWriteQueue(gitOpsGenerator, commitMessages)
+	if err != nil {
+		setupLog.Error(err, "unable to parse the GitOps write batching settings")
+		os.Exit(1)
+	}
 	if err = (&controllers.ComponentReconciler{
 		Client:            mgr.GetClient(),
 		Scheme:            mgr.GetScheme(),
 		Log:               ctrl.Log.WithName("controllers").WithName("Component"),
-		Generator:         gitopsgen.NewGitopsGen(),
+		Generator:         gitOpsGenerator,
 		AppFS:             ioutils.NewFilesystem(),
 		GitHubTokenClient: ghTokenClient,
+		GitLabClient:      gitLabClient,
 		SPIClient: spi.SPIClient{
 			K8sClient: mgr.GetClient(),
 		},
+		GitOpsWriteQueue:        gitOpsWriteQueue,
+		MaxConcurrentReconciles: componentMaxConcurrentReconciles,
+		CommitMessages:          commitMessages,
+		PostProcessors:          postProcessors,
 	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Component")
 		os.Exit(1)
@@ -232,13 +379,29 @@ func main() {
 		Client:            mgr.GetClient(),
 		Scheme:            mgr.GetScheme(),
 		Log:               ctrl.Log.WithName("controllers").WithName("SnapshotEnvironmentBinding"),
-		Generator:         gitopsgen.NewGitopsGen(),
+		Generator:         gitOpsGenerator,
 		AppFS:             ioutils.NewFilesystem(),
 		GitHubTokenClient: ghTokenClient,
+		GitLabClient:      gitLabClient,
+		CommitMessages:    commitMessages,
+		PostProcessors:    postProcessors,
 	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SnapshotEnvironmentBinding")
 		os.Exit(1)
 	}
+
+	// Optionally check the GitOps repositories for hand edits of the generated resources, and revert them
+	if os.Getenv("ENABLE_GITOPS_DRIFT_DETECTION") == "true" {
+		driftDetector, err := newGitOpsDriftDetector(mgr, gitOpsGenerator, gitOpsWriteQueue, ghTokenClient, gitLabClient, commitMessages, postProcessors)
+		if err != nil {
+			setupLog.Error(err, "unable to parse the GitOps drift detection settings")
+			os.Exit(1)
+		}
+		if err := mgr.Add(driftDetector); err != nil {
+			setupLog.Error(err, "unable to set up the GitOps drift detector")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -281,3 +444,138 @@ func setUpWebhooks(mgr ctrl.Manager) {
 		)
 	}
 }
+
+// newOrphanedRepositoryCollector sets up the orphaned GitOps repository garbage collector, from the GITOPS_REPO_GC_* environment variables
+func newOrphanedRepositoryCollector(mgr ctrl.Manager, archivedRepositories *controllers.ArchivedRepositoryTracker, ghTokenClient github.GitHubToken,
+	ghOrg string, gitLabClient gitprovider.GitProvider, gitLabGroup string, ownerTopic string) (*controllers.OrphanedRepositoryCollector, error) {
+	action, err := controllers.ValidateOrphanedRepositoryAction(os.Getenv("GITOPS_REPO_GC_ACTION"))
+	if err != nil {
+		return nil, err
+	}
+	if err := controllers.ValidateOrphanedRepositoryOwnerTopic(action, ownerTopic); err != nil {
+		return nil, fmt.Errorf("%v, set GITOPS_REPO_OWNER_TOPIC", err)
+	}
+	collector := &controllers.OrphanedRepositoryCollector{
+		Client:               mgr.GetClient(),
+		ArchivedRepositories: archivedRepositories,
+		GitHubTokenClient:    ghTokenClient,
+		GitHubOrg:            ghOrg,
+		GitLabClient:         gitLabClient,
+		GitLabGroup:          gitLabGroup,
+		OwnerTopic:           ownerTopic,
+		Action:               action,
+		DryRun:               os.Getenv("GITOPS_REPO_GC_DRY_RUN") == "true",
+	}
+	if interval := os.Getenv("GITOPS_REPO_GC_INTERVAL"); interval != "" {
+		if collector.Interval, err = time.ParseDuration(interval); err != nil {
+			return nil, fmt.Errorf("invalid GITOPS_REPO_GC_INTERVAL %q: %v", interval, err)
+		}
+	}
+	if minAge := os.Getenv("GITOPS_REPO_GC_MIN_AGE"); minAge != "" {
+		if collector.MinAge, err = time.ParseDuration(minAge); err != nil {
+			return nil, fmt.Errorf("invalid GITOPS_REPO_GC_MIN_AGE %q: %v", minAge, err)
+		}
+	}
+	return collector, nil
+}
+
+// newGitOpsDriftDetector sets up the GitOps drift detector, from the GITOPS_DRIFT_* environment variables. If GitOps writes are
+// batched, drift is healed under the lock of the write queue's branch
+func newGitOpsDriftDetector(mgr ctrl.Manager, generator gitopsgen.Generator, writeQueue *controllers.GitOpsWriteQueue, ghTokenClient github.GitHubToken, gitLabClient gitprovider.GitProvider, commitMessages *gitops.CommitMessages, postProcessors gitops.PostProcessors) (*controllers.GitOpsDriftDetector, error) {
+	detector := &controllers.GitOpsDriftDetector{
+		Client:            mgr.GetClient(),
+		AppFS:             ioutils.NewFilesystem(),
+		Generator:         generator,
+		GitHubTokenClient: ghTokenClient,
+		GitLabClient:      gitLabClient,
+		CommitMessages:    commitMessages,
+		PostProcessors:    postProcessors,
+		AutoHeal:          os.Getenv("GITOPS_DRIFT_AUTO_HEAL") == "true",
+		WriteQueue:        writeQueue,
+	}
+	if interval := os.Getenv("GITOPS_DRIFT_CHECK_INTERVAL"); interval != "" {
+		var err error
+		if detector.Interval, err = time.ParseDuration(interval); err != nil {
+			return nil, fmt.Errorf("invalid GITOPS_DRIFT_CHECK_INTERVAL %q: %v", interval, err)
+		}
+	}
+	return detector, nil
+}
+
+// newGitOpsWriteQueue sets up the GitOps write queue, and the number of Components to reconcile concurrently, from the
+// ENABLE_GITOPS_WRITE_BATCHING, GITOPS_WRITE_BATCH_WINDOW and COMPONENT_MAX_CONCURRENT_RECONCILES environment variables.
+// No queue is returned if batching isn't enabled. Batching requires Components to be reconciled concurrently, so a single
+// concurrent reconcile is rejected when it's enabled.
+func newGitOpsWriteQueue(generator gitopsgen.Generator, commitMessages *gitops.CommitMessages) (*controllers.GitOpsWriteQueue, int, error) {
+	maxConcurrentReconciles := 1
+	var queue *controllers.GitOpsWriteQueue
+	if os.Getenv("ENABLE_GITOPS_WRITE_BATCHING") == "true" {
+		window := controllers.DefaultGitOpsWriteBatchWindow
+		if windowStr := os.Getenv("GITOPS_WRITE_BATCH_WINDOW"); windowStr != "" {
+			var err error
+			if window, err = time.ParseDuration(windowStr); err != nil || window <= 0 {
+				return nil, 0, fmt.Errorf("invalid GITOPS_WRITE_BATCH_WINDOW %q, must be a positive duration", windowStr)
+			}
+		}
+		queue = controllers.NewGitOpsWriteQueue(generator, ioutils.NewFilesystem(), ctrl.Log.WithName("controllers").WithName("GitOpsWriteQueue"), window)
+		queue.CommitMessages = commitMessages
+
+		// Writes can only be coalesced if the Components that make them are reconciled at the same time
+		maxConcurrentReconciles = 10
+	}
+	if maxStr := os.Getenv("COMPONENT_MAX_CONCURRENT_RECONCILES"); maxStr != "" {
+		max, err := strconv.Atoi(maxStr)
+		if err != nil || max < 1 {
+			return nil, 0, fmt.Errorf("invalid COMPONENT_MAX_CONCURRENT_RECONCILES %q, must be a positive number", maxStr)
+		}
+		maxConcurrentReconciles = max
+	}
+	if queue != nil && maxConcurrentReconciles < 2 {
+		return nil, 0, fmt.Errorf("COMPONENT_MAX_CONCURRENT_RECONCILES must be at least 2 when ENABLE_GITOPS_WRITE_BATCHING is true, as only the writes of Components reconciled at the same time are batched")
+	}
+	return queue, maxConcurrentReconciles, nil
+}
+
+// configureGitOpsCommits returns the git config that signs the commits pushed to GitOps repositories with the key in
+// GITOPS_COMMIT_SIGNING_KEY, and makes them with the identity in GITOPS_COMMIT_AUTHOR_NAME, GITOPS_COMMIT_AUTHOR_EMAIL,
+// GITOPS_COMMITTER_NAME and GITOPS_COMMITTER_EMAIL. The config is set on the GitOps repositories, rather than in the environment
+// of the manager, so that it doesn't apply to any other git command.
+func configureGitOpsCommits() ([]string, error) {
+	options := gitops.CommitOptions{
+		SigningKey:     []byte(os.Getenv("GITOPS_COMMIT_SIGNING_KEY")),
+		AuthorName:     os.Getenv("GITOPS_COMMIT_AUTHOR_NAME"),
+		AuthorEmail:    os.Getenv("GITOPS_COMMIT_AUTHOR_EMAIL"),
+		CommitterName:  os.Getenv("GITOPS_COMMITTER_NAME"),
+		CommitterEmail: os.Getenv("GITOPS_COMMITTER_EMAIL"),
+	}
+	// The key is written to a file for git, so keep it out of the environment of the git commands
+	if err := os.Unsetenv("GITOPS_COMMIT_SIGNING_KEY"); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "gitops-commit-")
+	if err != nil {
+		return nil, err
+	}
+	return gitops.CommitConfig(options, dir)
+}
+
+// newGitOpsGenerator returns the gitops generator for the controllers. If GITOPS_REPO_CACHE_DIR is set, GitOps repositories are
+// checked out from a cache of their mirrors in that directory, bounded by GITOPS_REPO_CACHE_MAX_SIZE.
+func newGitOpsGenerator() (gitopsgen.Generator, error) {
+	cacheDir := os.Getenv("GITOPS_REPO_CACHE_DIR")
+	if cacheDir == "" {
+		return gitopsgen.NewGitopsGen(), nil
+	}
+	maxSize := resource.MustParse(defaultGitOpsRepoCacheMaxSize)
+	if maxSizeStr := os.Getenv("GITOPS_REPO_CACHE_MAX_SIZE"); maxSizeStr != "" {
+		var err error
+		if maxSize, err = resource.ParseQuantity(maxSizeStr); err != nil || maxSize.Sign() < 0 {
+			return nil, fmt.Errorf("invalid GITOPS_REPO_CACHE_MAX_SIZE %q, must be a quantity such as 5Gi", maxSizeStr)
+		}
+	}
+	cache, err := gitcache.NewCache(cacheDir, maxSize.Value(), ctrl.Log.WithName("gitcache"))
+	if err != nil {
+		return nil, err
+	}
+	return gitops.NewCachingGenerator(gitopsgen.NewGitopsGen(), cache), nil
+}
@@ -0,0 +1,246 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/devfile/api/v2/pkg/attributes"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MinReplicasAnnotation is the annotation of a Component, Environment or SnapshotEnvironmentBinding that sets the minimum replicas of the autoscaler
+	MinReplicasAnnotation = "autoscaling-min-replicas"
+
+	// MaxReplicasAnnotation is the annotation of a Component, Environment or SnapshotEnvironmentBinding that sets the maximum replicas of the autoscaler
+	MaxReplicasAnnotation = "autoscaling-max-replicas"
+
+	// TargetCPUUtilizationAnnotation is the annotation of a Component, Environment or SnapshotEnvironmentBinding that sets the target average cpu
+	// utilization of the autoscaler, as a percentage of the cpu requests
+	TargetCPUUtilizationAnnotation = "autoscaling-target-cpu-utilization"
+
+	// TargetMemoryUtilizationAnnotation is the annotation of a Component, Environment or SnapshotEnvironmentBinding that sets the target average
+	// memory utilization of the autoscaler, as a percentage of the memory requests
+	TargetMemoryUtilizationAnnotation = "autoscaling-target-memory-utilization"
+)
+
+// AutoscalingAnnotations are the annotations that configure the autoscaling of a Component
+var AutoscalingAnnotations = []string{MinReplicasAnnotation, MaxReplicasAnnotation, TargetCPUUtilizationAnnotation, TargetMemoryUtilizationAnnotation}
+
+// Autoscaling is the autoscaling of the Deployment of a Component. Unset fields are left as they are when it's applied to a
+// HorizontalPodAutoscaler, so that the autoscaling of an Environment only has to set the fields it overrides.
+type Autoscaling struct {
+	MinReplicas             *int32
+	MaxReplicas             *int32
+	TargetCPUUtilization    *int32
+	TargetMemoryUtilization *int32
+}
+
+// IsSet returns true if any field of the autoscaling is set
+func (a Autoscaling) IsSet() bool {
+	return a.MinReplicas != nil || a.MaxReplicas != nil || a.TargetCPUUtilization != nil || a.TargetMemoryUtilization != nil
+}
+
+// Merge returns the autoscaling with the fields that are set in override replaced by them
+func (a Autoscaling) Merge(override Autoscaling) Autoscaling {
+	if override.MinReplicas != nil {
+		a.MinReplicas = override.MinReplicas
+	}
+	if override.MaxReplicas != nil {
+		a.MaxReplicas = override.MaxReplicas
+	}
+	if override.TargetCPUUtilization != nil {
+		a.TargetCPUUtilization = override.TargetCPUUtilization
+	}
+	if override.TargetMemoryUtilization != nil {
+		a.TargetMemoryUtilization = override.TargetMemoryUtilization
+	}
+	return a
+}
+
+// Equal returns true if both autoscalings set the same fields to the same values
+func (a Autoscaling) Equal(other Autoscaling) bool {
+	return equalInt32(a.MinReplicas, other.MinReplicas) && equalInt32(a.MaxReplicas, other.MaxReplicas) &&
+		equalInt32(a.TargetCPUUtilization, other.TargetCPUUtilization) && equalInt32(a.TargetMemoryUtilization, other.TargetMemoryUtilization)
+}
+
+// PutAttributes sets the devfile attributes of the fields of the autoscaling that are set
+func (a Autoscaling) PutAttributes(attrs attributes.Attributes) attributes.Attributes {
+	for key, value := range a.fields() {
+		if *value != nil {
+			attrs = attrs.PutInteger(key, int(**value))
+		}
+	}
+	return attrs
+}
+
+// ApplyTo sets the fields of the given HorizontalPodAutoscaler that are set in the autoscaling. The target utilizations replace the
+// resource metrics of the same resource, keeping the other metrics.
+func (a Autoscaling) ApplyTo(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+	if a.MinReplicas != nil {
+		minReplicas := *a.MinReplicas
+		hpa.Spec.MinReplicas = &minReplicas
+	}
+	if a.MaxReplicas != nil {
+		hpa.Spec.MaxReplicas = *a.MaxReplicas
+	}
+	targets := []struct {
+		resourceName corev1.ResourceName
+		utilization  *int32
+	}{
+		{corev1.ResourceCPU, a.TargetCPUUtilization},
+		{corev1.ResourceMemory, a.TargetMemoryUtilization},
+	}
+	for _, target := range targets {
+		if target.utilization == nil {
+			continue
+		}
+		resourceName := target.resourceName
+		averageUtilization := *target.utilization
+		metric := autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: resourceName,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &averageUtilization,
+				},
+			},
+		}
+		replaced := false
+		for i, existing := range hpa.Spec.Metrics {
+			if existing.Type == autoscalingv2.ResourceMetricSourceType && existing.Resource != nil && existing.Resource.Name == resourceName {
+				hpa.Spec.Metrics[i] = metric
+				replaced = true
+			}
+		}
+		if !replaced {
+			hpa.Spec.Metrics = append(hpa.Spec.Metrics, metric)
+		}
+	}
+}
+
+// GetHorizontalPodAutoscaler returns the HorizontalPodAutoscaler of the Deployment with the given name, scaled with the given autoscaling
+func GetHorizontalPodAutoscaler(name string, labels map[string]string, autoscaling Autoscaling) (autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpa := autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: v1.TypeMeta{
+			Kind:       "HorizontalPodAutoscaler",
+			APIVersion: "autoscaling/v2",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       name,
+			},
+		},
+	}
+	autoscaling.ApplyTo(&hpa)
+	if err := ValidateHorizontalPodAutoscaler(hpa); err != nil {
+		return autoscalingv2.HorizontalPodAutoscaler{}, err
+	}
+	return hpa, nil
+}
+
+// ValidateHorizontalPodAutoscaler checks that the replicas of the given HorizontalPodAutoscaler are valid
+func ValidateHorizontalPodAutoscaler(hpa autoscalingv2.HorizontalPodAutoscaler) error {
+	if hpa.Spec.MaxReplicas < 1 {
+		return fmt.Errorf("the maximum replicas of the autoscaler of %s must be set to at least 1", hpa.Name)
+	}
+	if hpa.Spec.MinReplicas != nil && *hpa.Spec.MinReplicas > hpa.Spec.MaxReplicas {
+		return fmt.Errorf("the minimum replicas of the autoscaler of %s can't be greater than its maximum replicas", hpa.Name)
+	}
+	return nil
+}
+
+// GetAutoscalingFromAnnotations returns the autoscaling set by the given annotations
+func GetAutoscalingFromAnnotations(annotations map[string]string) (Autoscaling, error) {
+	var autoscaling Autoscaling
+	for annotation, value := range autoscaling.annotationFields() {
+		if annotations[annotation] == "" {
+			continue
+		}
+		number, err := strconv.ParseInt(strings.TrimSpace(annotations[annotation]), 10, 32)
+		if err != nil || number < 1 {
+			return Autoscaling{}, fmt.Errorf("the %s annotation must be a positive integer, got %q", annotation, annotations[annotation])
+		}
+		setInt32(value, number)
+	}
+	return autoscaling, nil
+}
+
+// GetAutoscalingFromAttributes returns the autoscaling set by the given devfile attributes
+func GetAutoscalingFromAttributes(attrs attributes.Attributes) (Autoscaling, error) {
+	var autoscaling Autoscaling
+	for key, value := range autoscaling.fields() {
+		var err error
+		number := attrs.GetNumber(key, &err)
+		if err != nil {
+			if _, ok := err.(*attributes.KeyNotFoundError); ok {
+				continue
+			}
+			return Autoscaling{}, err
+		}
+		if number < 1 {
+			return Autoscaling{}, fmt.Errorf("the %s attribute must be a positive integer, got %v", key, number)
+		}
+		setInt32(value, int64(number))
+	}
+	return autoscaling, nil
+}
+
+// fields returns the fields of the autoscaling by the key of their devfile attribute
+func (a *Autoscaling) fields() map[string]**int32 {
+	return map[string]**int32{
+		MinReplicasKey:             &a.MinReplicas,
+		MaxReplicasKey:             &a.MaxReplicas,
+		TargetCPUUtilizationKey:    &a.TargetCPUUtilization,
+		TargetMemoryUtilizationKey: &a.TargetMemoryUtilization,
+	}
+}
+
+// annotationFields returns the fields of the autoscaling by their annotation
+func (a *Autoscaling) annotationFields() map[string]**int32 {
+	return map[string]**int32{
+		MinReplicasAnnotation:             &a.MinReplicas,
+		MaxReplicasAnnotation:             &a.MaxReplicas,
+		TargetCPUUtilizationAnnotation:    &a.TargetCPUUtilization,
+		TargetMemoryUtilizationAnnotation: &a.TargetMemoryUtilization,
+	}
+}
+
+// setInt32 sets the given field to the given number
+func setInt32(field **int32, number int64) {
+	value := int32(number)
+	*field = &value
+}
+
+// equalInt32 returns true if both numbers are unset, or set to the same value
+func equalInt32(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,171 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devfile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/devfile/api/v2/pkg/attributes"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestGetAutoscalingFromAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        Autoscaling
+		wantErr     bool
+	}{
+		{
+			name: "No annotations",
+		},
+		{
+			name: "All annotations",
+			annotations: map[string]string{
+				MinReplicasAnnotation:             "2",
+				MaxReplicasAnnotation:             " 5 ",
+				TargetCPUUtilizationAnnotation:    "75",
+				TargetMemoryUtilizationAnnotation: "80",
+			},
+			want: Autoscaling{MinReplicas: int32Ptr(2), MaxReplicas: int32Ptr(5), TargetCPUUtilization: int32Ptr(75), TargetMemoryUtilization: int32Ptr(80)},
+		},
+		{
+			name:        "Invalid number",
+			annotations: map[string]string{MaxReplicasAnnotation: "five"},
+			wantErr:     true,
+		},
+		{
+			name:        "Zero replicas",
+			annotations: map[string]string{MinReplicasAnnotation: "0"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			autoscaling, err := GetAutoscalingFromAnnotations(tt.annotations)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestGetAutoscalingFromAnnotations() unexpected error value: %v", err)
+			}
+			if !autoscaling.Equal(tt.want) {
+				t.Errorf("TestGetAutoscalingFromAnnotations() error: expected %+v got %+v", tt.want, autoscaling)
+			}
+		})
+	}
+}
+
+func TestAutoscalingAttributes(t *testing.T) {
+	autoscaling := Autoscaling{MinReplicas: int32Ptr(1), MaxReplicas: int32Ptr(3), TargetCPUUtilization: int32Ptr(60)}
+	attrs := autoscaling.PutAttributes(attributes.Attributes{}.PutInteger(ReplicaKey, 2))
+	if attrs.Exists(TargetMemoryUtilizationKey) {
+		t.Errorf("TestAutoscalingAttributes() error: expected the unset target memory utilization to have no attribute")
+	}
+	got, err := GetAutoscalingFromAttributes(attrs)
+	if err != nil {
+		t.Fatalf("TestAutoscalingAttributes() unexpected error: %v", err)
+	}
+	if !got.Equal(autoscaling) {
+		t.Errorf("TestAutoscalingAttributes() error: expected %+v got %+v", autoscaling, got)
+	}
+
+	if _, err := GetAutoscalingFromAttributes(attributes.Attributes{}.PutInteger(MaxReplicasKey, -1)); err == nil {
+		t.Errorf("TestAutoscalingAttributes() error: expected an error for negative maximum replicas")
+	}
+}
+
+func TestGetHorizontalPodAutoscaler(t *testing.T) {
+	tests := []struct {
+		name            string
+		autoscaling     Autoscaling
+		wantMinReplicas *int32
+		wantMaxReplicas int32
+		wantMetrics     map[corev1.ResourceName]int32
+		wantErr         bool
+	}{
+		{
+			name:            "Maximum replicas only",
+			autoscaling:     Autoscaling{MaxReplicas: int32Ptr(4)},
+			wantMaxReplicas: 4,
+			wantMetrics:     map[corev1.ResourceName]int32{},
+		},
+		{
+			name:            "Replicas and utilizations",
+			autoscaling:     Autoscaling{MinReplicas: int32Ptr(2), MaxReplicas: int32Ptr(6), TargetCPUUtilization: int32Ptr(70), TargetMemoryUtilization: int32Ptr(85)},
+			wantMinReplicas: int32Ptr(2),
+			wantMaxReplicas: 6,
+			wantMetrics:     map[corev1.ResourceName]int32{corev1.ResourceCPU: 70, corev1.ResourceMemory: 85},
+		},
+		{
+			name:        "No maximum replicas",
+			autoscaling: Autoscaling{MinReplicas: int32Ptr(2)},
+			wantErr:     true,
+		},
+		{
+			name:        "Minimum greater than maximum",
+			autoscaling: Autoscaling{MinReplicas: int32Ptr(5), MaxReplicas: int32Ptr(3)},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hpa, err := GetHorizontalPodAutoscaler("backend", map[string]string{"app.kubernetes.io/name": "backend"}, tt.autoscaling)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestGetHorizontalPodAutoscaler() unexpected error value: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if hpa.APIVersion != "autoscaling/v2" || hpa.Spec.ScaleTargetRef.Kind != "Deployment" || hpa.Spec.ScaleTargetRef.Name != "backend" {
+				t.Errorf("TestGetHorizontalPodAutoscaler() error: unexpected autoscaler %+v", hpa)
+			}
+			if !equalInt32(hpa.Spec.MinReplicas, tt.wantMinReplicas) || hpa.Spec.MaxReplicas != tt.wantMaxReplicas {
+				t.Errorf("TestGetHorizontalPodAutoscaler() error: unexpected replicas %v-%v", hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+			}
+			metrics := map[corev1.ResourceName]int32{}
+			for _, metric := range hpa.Spec.Metrics {
+				metrics[metric.Resource.Name] = *metric.Resource.Target.AverageUtilization
+			}
+			if !reflect.DeepEqual(metrics, tt.wantMetrics) {
+				t.Errorf("TestGetHorizontalPodAutoscaler() error: expected metrics %v got %v", tt.wantMetrics, metrics)
+			}
+		})
+	}
+}
+
+func TestAutoscalingApplyTo(t *testing.T) {
+	podsMetric := autoscalingv2.MetricSpec{Type: autoscalingv2.PodsMetricSourceType}
+	hpa, err := GetHorizontalPodAutoscaler("backend", nil, Autoscaling{MinReplicas: int32Ptr(2), MaxReplicas: int32Ptr(6), TargetCPUUtilization: int32Ptr(70)})
+	if err != nil {
+		t.Fatalf("TestAutoscalingApplyTo() unexpected error: %v", err)
+	}
+	hpa.Spec.Metrics = append(hpa.Spec.Metrics, podsMetric)
+
+	// The override only sets the maximum replicas and cpu utilization, leaving the rest of the autoscaler as it is
+	Autoscaling{MaxReplicas: int32Ptr(10), TargetCPUUtilization: int32Ptr(50)}.ApplyTo(&hpa)
+	if *hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 10 {
+		t.Errorf("TestAutoscalingApplyTo() error: unexpected replicas %v-%v", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+	}
+	if len(hpa.Spec.Metrics) != 2 || *hpa.Spec.Metrics[0].Resource.Target.AverageUtilization != 50 || !reflect.DeepEqual(hpa.Spec.Metrics[1], podsMetric) {
+		t.Errorf("TestAutoscalingApplyTo() error: unexpected metrics %+v", hpa.Spec.Metrics)
+	}
+}
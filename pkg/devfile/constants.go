@@ -45,4 +45,22 @@ const (
 
 	// ContainerENVKey is the key to reference container environment variables
 	ContainerENVKey = "deployment/containerENV"
+
+	// MinReplicasKey is the key to reference the minimum replicas of the autoscaler
+	MinReplicasKey = "deployment/minReplicas"
+
+	// MaxReplicasKey is the key to reference the maximum replicas of the autoscaler
+	MaxReplicasKey = "deployment/maxReplicas"
+
+	// TargetCPUUtilizationKey is the key to reference the target average cpu utilization of the autoscaler
+	TargetCPUUtilizationKey = "deployment/targetCPUUtilization"
+
+	// TargetMemoryUtilizationKey is the key to reference the target average memory utilization of the autoscaler
+	TargetMemoryUtilizationKey = "deployment/targetMemoryUtilization"
+
+	// PodDisruptionBudgetKey is the key to reference whether the component gets a pod disruption budget
+	PodDisruptionBudgetKey = "deployment/podDisruptionBudget"
+
+	// NetworkPolicyKey is the key to reference whether the component gets a network policy
+	NetworkPolicyKey = "deployment/networkPolicy"
 )
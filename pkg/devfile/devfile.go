@@ -207,6 +207,7 @@ func GetResourceFromDevfile(log logr.Logger, devfileData data.DevfileData, deplo
 								if containerEnv.Name == devfileEnv.Name {
 									isPresent = true
 									resources.Deployments[0].Spec.Template.Spec.Containers[0].Env[i].Value = devfileEnv.Value
+									resources.Deployments[0].Spec.Template.Spec.Containers[0].Env[i].ValueFrom = devfileEnv.ValueFrom
 								}
 							}
 
@@ -323,6 +324,23 @@ func GetResourceFromDevfile(log logr.Logger, devfileData data.DevfileData, deplo
 					}
 				}
 
+				if len(resources.Deployments) > 0 {
+					// generate the autoscaler of the deployment, if its autoscaling is set
+					autoscaling, err := GetAutoscalingFromAttributes(component.Attributes)
+					if err != nil {
+						return parser.KubernetesResources{}, err
+					}
+					if autoscaling.IsSet() {
+						hpa, err := GetHorizontalPodAutoscaler(compName, k8sLabels, autoscaling)
+						if err != nil {
+							return parser.KubernetesResources{}, err
+						}
+						resources.Others = append(resources.Others, hpa)
+						// the replicas are managed by the autoscaler, rather than synced from the GitOps repository
+						resources.Deployments[0].Spec.Replicas = nil
+					}
+				}
+
 				if len(resources.Services) > 0 {
 					// replace the service metadata.name to use the component name
 					resources.Services[0].ObjectMeta.Name = compName
@@ -357,7 +375,7 @@ func GetResourceFromDevfile(log logr.Logger, devfileData data.DevfileData, deplo
 
 						if !isPresent {
 							if portNameMap[servicePort.Name] {
-								generatedName := fmt.Sprintf("%s-%s", servicePort.Name, util.GetRandomString(4, true))
+								generatedName := fmt.Sprintf("%s-%s", servicePort.Name, util.GetStableString(4, appName, compName, servicePort.Name))
 								portNameMap[generatedName] = true
 								servicePort.Name = generatedName
 							}
@@ -369,7 +387,7 @@ func GetResourceFromDevfile(log logr.Logger, devfileData data.DevfileData, deplo
 									// because name is required if there is more than one port
 									portName := strconv.Itoa(int(port.Port))
 									if portNameMap[portName] {
-										portName = fmt.Sprintf("%s-%s", portName, util.GetRandomString(4, true))
+										portName = fmt.Sprintf("%s-%s", portName, util.GetStableString(4, appName, compName, portName))
 										portNameMap[portName] = true
 									}
 									resources.Services[0].Spec.Ports[i].Name = portName
@@ -383,7 +401,7 @@ func GetResourceFromDevfile(log logr.Logger, devfileData data.DevfileData, deplo
 					// Trim the route name if needed
 					routeName := compName
 					if len(routeName) >= 30 {
-						routeName = routeName[0:25] + util.GetRandomString(4, true)
+						routeName = routeName[0:25] + util.GetStableString(4, appName, compName)
 					}
 
 					resources.Routes[0].ObjectMeta.Name = routeName
@@ -3720,6 +3720,12 @@ schemaVersion: 2.2.0`
 						if !strings.Contains(actualResources.Routes[0].Name, "component-sample-comp") {
 							t.Errorf("Expected route name to contain %v, but got %v", "component-sample-comp", actualResources.Routes[0].Name)
 						}
+						otherResources, err := GetResourceFromDevfile(logger, devfileData, deployAssociatedComponents, tt.componentName, tt.appName, tt.image, tt.hostname)
+						if err != nil {
+							t.Errorf("TestGetResourceFromDevfile() unexpected get resource from devfile error: %v", err)
+						} else if otherResources.Routes[0].Name != actualResources.Routes[0].Name {
+							t.Errorf("Expected the same route name %v to be generated every time, but got %v", actualResources.Routes[0].Name, otherResources.Routes[0].Name)
+						}
 					} else {
 						assert.Equal(t, tt.wantRoute, actualResources.Routes[0], "First Route did not match")
 					}
@@ -3729,6 +3735,72 @@ schemaVersion: 2.2.0`
 	}
 }
 
+func TestGetResourceFromDevfileEnvValueFrom(t *testing.T) {
+	devfileString := `
+schemaVersion: 2.2.0
+metadata:
+  name: backend
+components:
+- name: kubernetes-deploy
+  attributes:
+    deployment/containerENV:
+    - name: PASSWORD
+      valueFrom:
+        secretKeyRef:
+          name: db
+          key: password
+    - name: URL
+      valueFrom:
+        configMapKeyRef:
+          name: settings
+          key: url
+  kubernetes:
+    inlined: |-
+      apiVersion: apps/v1
+      kind: Deployment
+      metadata:
+        name: deploy-sample
+      spec:
+        template:
+          spec:
+            containers:
+            - name: container-image
+              env:
+              - name: PASSWORD
+                value: password
+`
+	devfileData, err := cdqanalysis.ParseDevfileWithParserArgs(&parser.ParserArgs{Data: []byte(devfileString)})
+	if err != nil {
+		t.Fatalf("TestGetResourceFromDevfileEnvValueFrom() unexpected parse error: %v", err)
+	}
+	deployAssociatedComponents, err := parser.GetDeployComponents(devfileData)
+	if err != nil {
+		t.Fatalf("TestGetResourceFromDevfileEnvValueFrom() unexpected get deploy components error: %v", err)
+	}
+	logger := ctrl.Log.WithName("TestGetResourceFromDevfileEnvValueFrom")
+	resources, err := GetResourceFromDevfile(logger, devfileData, deployAssociatedComponents, "backend", "application-sample", "image1", "")
+	if err != nil {
+		t.Fatalf("TestGetResourceFromDevfileEnvValueFrom() unexpected get resource from devfile error: %v", err)
+	}
+
+	// The references replace the value of the inlined Deployment, and are kept as references
+	wantEnv := []corev1.EnvVar{
+		{
+			Name: "PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password"},
+			},
+		},
+		{
+			Name: "URL",
+			ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}, Key: "url"},
+			},
+		},
+	}
+	assert.Equal(t, wantEnv, resources.Deployments[0].Spec.Template.Spec.Containers[0].Env, "Deployment env did not match")
+}
+
 func TestUpdateLocalDockerfileURItoAbsolute(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -0,0 +1,268 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devfile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/devfile/api/v2/pkg/apis/workspaces/v1alpha2"
+	"github.com/devfile/api/v2/pkg/attributes"
+	"github.com/devfile/library/v2/pkg/devfile/parser/data"
+	"github.com/devfile/library/v2/pkg/devfile/parser/data/v2/common"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// PodDisruptionBudgetAnnotation is the annotation of a Component, or Environment for its default, that sets whether the Component
+	// gets a PodDisruptionBudget
+	PodDisruptionBudgetAnnotation = "pod-disruption-budget"
+
+	// NetworkPolicyAnnotation is the annotation of a Component, or Environment for its default, that sets whether the Component gets
+	// a NetworkPolicy
+	NetworkPolicyAnnotation = "network-policy"
+)
+
+// PolicyAnnotations are the annotations that configure the policies of a Component
+var PolicyAnnotations = []string{PodDisruptionBudgetAnnotation, NetworkPolicyAnnotation}
+
+// Policies are whether a Component gets a PodDisruptionBudget and a NetworkPolicy in the Environments it's deployed to. Unset fields
+// fall back to the defaults of the Environment.
+type Policies struct {
+	PodDisruptionBudget *bool
+	NetworkPolicy       *bool
+}
+
+// IsSet returns true if any field of the policies is set
+func (p Policies) IsSet() bool {
+	return p.PodDisruptionBudget != nil || p.NetworkPolicy != nil
+}
+
+// Merge returns the policies with the fields that are set in override replaced by them
+func (p Policies) Merge(override Policies) Policies {
+	if override.PodDisruptionBudget != nil {
+		p.PodDisruptionBudget = override.PodDisruptionBudget
+	}
+	if override.NetworkPolicy != nil {
+		p.NetworkPolicy = override.NetworkPolicy
+	}
+	return p
+}
+
+// Equal returns true if both policies set the same fields to the same values
+func (p Policies) Equal(other Policies) bool {
+	return equalBool(p.PodDisruptionBudget, other.PodDisruptionBudget) && equalBool(p.NetworkPolicy, other.NetworkPolicy)
+}
+
+// PutAttributes sets the devfile attributes of the fields of the policies that are set
+func (p Policies) PutAttributes(attrs attributes.Attributes) attributes.Attributes {
+	for key, value := range p.fields() {
+		if *value != nil {
+			attrs = attrs.PutBoolean(key, **value)
+		}
+	}
+	return attrs
+}
+
+// GetPoliciesFromAnnotations returns the policies set by the given annotations
+func GetPoliciesFromAnnotations(annotations map[string]string) (Policies, error) {
+	var policies Policies
+	for annotation, value := range policies.annotationFields() {
+		if annotations[annotation] == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(annotations[annotation]))
+		if err != nil {
+			return Policies{}, fmt.Errorf("the %s annotation must be true or false, got %q", annotation, annotations[annotation])
+		}
+		*value = &enabled
+	}
+	return policies, nil
+}
+
+// GetPoliciesFromAttributes returns the policies set by the given devfile attributes
+func GetPoliciesFromAttributes(attrs attributes.Attributes) (Policies, error) {
+	var policies Policies
+	for key, value := range policies.fields() {
+		var err error
+		enabled := attrs.GetBoolean(key, &err)
+		if err != nil {
+			if _, ok := err.(*attributes.KeyNotFoundError); ok {
+				continue
+			}
+			return Policies{}, err
+		}
+		*value = &enabled
+	}
+	return policies, nil
+}
+
+// GetPoliciesFromDevfile returns the policies set by the attributes of the Kubernetes components of the devfile that are deployed
+func GetPoliciesFromDevfile(devfileData data.DevfileData, deployAssociatedComponents map[string]string) (Policies, error) {
+	kubernetesComponents, err := devfileData.GetComponents(common.DevfileOptions{
+		ComponentOptions: common.ComponentOptions{
+			ComponentType: v1alpha2.KubernetesComponentType,
+		},
+	})
+	if err != nil {
+		return Policies{}, err
+	}
+	// a single kubernetes component is deployed without a deploy command, as in GetResourceFromDevfile
+	deployedWithoutCommand := len(kubernetesComponents) == 1 && len(deployAssociatedComponents) == 0
+	var policies Policies
+	for _, component := range kubernetesComponents {
+		if _, ok := deployAssociatedComponents[component.Name]; !ok && !deployedWithoutCommand {
+			continue
+		}
+		componentPolicies, err := GetPoliciesFromAttributes(component.Attributes)
+		if err != nil {
+			return Policies{}, err
+		}
+		policies = policies.Merge(componentPolicies)
+	}
+	return policies, nil
+}
+
+// GetPodDisruptionBudget returns the PodDisruptionBudget of the Deployment with the given name, which has at least the given replicas.
+// More than one replica keeps the majority of them available, and a single replica may be disrupted, so that it doesn't block the
+// draining of its node.
+func GetPodDisruptionBudget(name string, labels map[string]string, replicas int32) policyv1.PodDisruptionBudget {
+	pdb := policyv1.PodDisruptionBudget{
+		TypeMeta: v1.TypeMeta{
+			Kind:       "PodDisruptionBudget",
+			APIVersion: "policy/v1",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &v1.LabelSelector{
+				MatchLabels: getMatchLabel(name),
+			},
+		},
+	}
+	if replicas > 1 {
+		minAvailable := intstr.FromInt(int(replicas+1) / 2)
+		pdb.Spec.MinAvailable = &minAvailable
+	} else {
+		maxUnavailable := intstr.FromInt(1)
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	}
+	return pdb
+}
+
+// GetNetworkPolicy returns the NetworkPolicy of the Component with the given name. It allows ingress to the given exposed ports
+// from anywhere, and to any port from the pods of the other given Components of the same Application.
+func GetNetworkPolicy(name string, labels map[string]string, ports []int32, applicationComponents []string) networkingv1.NetworkPolicy {
+	networkPolicy := networkingv1.NetworkPolicy{
+		TypeMeta: v1.TypeMeta{
+			Kind:       "NetworkPolicy",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: v1.LabelSelector{
+				MatchLabels: getMatchLabel(name),
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{},
+		},
+	}
+
+	var exposedPorts []networkingv1.NetworkPolicyPort
+	for _, port := range uniqueSortedPorts(ports) {
+		protocol := corev1.ProtocolTCP
+		portNumber := intstr.FromInt(int(port))
+		exposedPorts = append(exposedPorts, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &portNumber})
+	}
+	if len(exposedPorts) > 0 {
+		networkPolicy.Spec.Ingress = append(networkPolicy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{Ports: exposedPorts})
+	}
+
+	var otherComponents []string
+	for _, component := range applicationComponents {
+		if component != name {
+			otherComponents = append(otherComponents, component)
+		}
+	}
+	if len(otherComponents) > 0 {
+		sort.Strings(otherComponents)
+		networkPolicy.Spec.Ingress = append(networkPolicy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			From: []networkingv1.NetworkPolicyPeer{
+				{
+					PodSelector: &v1.LabelSelector{
+						MatchExpressions: []v1.LabelSelectorRequirement{
+							{
+								Key:      "app.kubernetes.io/instance",
+								Operator: v1.LabelSelectorOpIn,
+								Values:   otherComponents,
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+	return networkPolicy
+}
+
+// fields returns the fields of the policies by the key of their devfile attribute
+func (p *Policies) fields() map[string]**bool {
+	return map[string]**bool{
+		PodDisruptionBudgetKey: &p.PodDisruptionBudget,
+		NetworkPolicyKey:       &p.NetworkPolicy,
+	}
+}
+
+// annotationFields returns the fields of the policies by their annotation
+func (p *Policies) annotationFields() map[string]**bool {
+	return map[string]**bool{
+		PodDisruptionBudgetAnnotation: &p.PodDisruptionBudget,
+		NetworkPolicyAnnotation:       &p.NetworkPolicy,
+	}
+}
+
+// equalBool returns true if both booleans are unset, or set to the same value
+func equalBool(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// uniqueSortedPorts returns the given ports in order, without duplicates or unset ports
+func uniqueSortedPorts(ports []int32) []int32 {
+	seen := make(map[int32]bool)
+	var unique []int32
+	for _, port := range ports {
+		if port > 0 && !seen[port] {
+			seen[port] = true
+			unique = append(unique, port)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i] < unique[j] })
+	return unique
+}
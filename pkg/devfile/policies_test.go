@@ -0,0 +1,160 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package devfile
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/devfile/api/v2/pkg/attributes"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGetPoliciesFromAnnotations(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        Policies
+		wantErr     bool
+	}{
+		{
+			name: "No annotations",
+		},
+		{
+			name:        "All annotations",
+			annotations: map[string]string{PodDisruptionBudgetAnnotation: "true", NetworkPolicyAnnotation: " false "},
+			want:        Policies{PodDisruptionBudget: &enabled, NetworkPolicy: &disabled},
+		},
+		{
+			name:        "Invalid boolean",
+			annotations: map[string]string{NetworkPolicyAnnotation: "sometimes"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := GetPoliciesFromAnnotations(tt.annotations)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("TestGetPoliciesFromAnnotations() unexpected error value: %v", err)
+			}
+			if !policies.Equal(tt.want) {
+				t.Errorf("TestGetPoliciesFromAnnotations() error: expected %+v got %+v", tt.want, policies)
+			}
+		})
+	}
+}
+
+func TestPoliciesAttributes(t *testing.T) {
+	enabled := true
+	policies := Policies{NetworkPolicy: &enabled}
+	attrs := policies.PutAttributes(attributes.Attributes{})
+	if attrs.Exists(PodDisruptionBudgetKey) {
+		t.Errorf("TestPoliciesAttributes() error: expected the unset pod disruption budget to have no attribute")
+	}
+	got, err := GetPoliciesFromAttributes(attrs)
+	if err != nil {
+		t.Fatalf("TestPoliciesAttributes() unexpected error: %v", err)
+	}
+	if !got.Equal(policies) {
+		t.Errorf("TestPoliciesAttributes() error: expected %+v got %+v", policies, got)
+	}
+
+	// The Component's own policies take precedence over the defaults of the Environment
+	disabled := false
+	if merged := (Policies{PodDisruptionBudget: &enabled, NetworkPolicy: &enabled}).Merge(Policies{NetworkPolicy: &disabled}); *merged.PodDisruptionBudget != true || *merged.NetworkPolicy != false {
+		t.Errorf("TestPoliciesAttributes() error: unexpected merged policies %+v", merged)
+	}
+}
+
+func TestGetPodDisruptionBudget(t *testing.T) {
+	tests := []struct {
+		name               string
+		replicas           int32
+		wantMinAvailable   *intstr.IntOrString
+		wantMaxUnavailable *intstr.IntOrString
+	}{
+		{
+			name:               "Single replica",
+			replicas:           1,
+			wantMaxUnavailable: &intstr.IntOrString{IntVal: 1},
+		},
+		{
+			name:             "Even replicas",
+			replicas:         4,
+			wantMinAvailable: &intstr.IntOrString{IntVal: 2},
+		},
+		{
+			name:             "Odd replicas",
+			replicas:         3,
+			wantMinAvailable: &intstr.IntOrString{IntVal: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdb := GetPodDisruptionBudget("backend", nil, tt.replicas)
+			if pdb.APIVersion != "policy/v1" || !reflect.DeepEqual(pdb.Spec.Selector.MatchLabels, map[string]string{"app.kubernetes.io/instance": "backend"}) {
+				t.Errorf("TestGetPodDisruptionBudget() error: unexpected pod disruption budget %+v", pdb)
+			}
+			if !reflect.DeepEqual(pdb.Spec.MinAvailable, tt.wantMinAvailable) || !reflect.DeepEqual(pdb.Spec.MaxUnavailable, tt.wantMaxUnavailable) {
+				t.Errorf("TestGetPodDisruptionBudget() error: unexpected spec %+v", pdb.Spec)
+			}
+		})
+	}
+}
+
+func TestGetNetworkPolicy(t *testing.T) {
+	tests := []struct {
+		name                  string
+		ports                 []int32
+		applicationComponents []string
+		wantRules             int
+	}{
+		{
+			name:                  "Exposed ports and other Components",
+			ports:                 []int32{8080, 0, 8080},
+			applicationComponents: []string{"backend", "frontend"},
+			wantRules:             2,
+		},
+		{
+			name:                  "Only Component of the Application, without exposed ports",
+			applicationComponents: []string{"backend"},
+			wantRules:             0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			networkPolicy := GetNetworkPolicy("backend", nil, tt.ports, tt.applicationComponents)
+			if networkPolicy.APIVersion != "networking.k8s.io/v1" || len(networkPolicy.Spec.PolicyTypes) != 1 || networkPolicy.Spec.PolicyTypes[0] != "Ingress" {
+				t.Errorf("TestGetNetworkPolicy() error: unexpected network policy %+v", networkPolicy)
+			}
+			if len(networkPolicy.Spec.Ingress) != tt.wantRules {
+				t.Fatalf("TestGetNetworkPolicy() error: expected %d ingress rules got %+v", tt.wantRules, networkPolicy.Spec.Ingress)
+			}
+			if tt.wantRules == 2 {
+				if ports := networkPolicy.Spec.Ingress[0].Ports; len(ports) != 1 || ports[0].Port.IntVal != 8080 {
+					t.Errorf("TestGetNetworkPolicy() error: unexpected ports %+v", ports)
+				}
+				if from := networkPolicy.Spec.Ingress[1].From[0].PodSelector.MatchExpressions[0].Values; !reflect.DeepEqual(from, []string{"frontend"}) {
+					t.Errorf("TestGetNetworkPolicy() error: unexpected ingress from %v", from)
+				}
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ package devfile
 import (
 	"fmt"
 	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // GetIngressHostName gets the ingress host name from the component name, namepsace and ingress domain
@@ -34,3 +36,31 @@ func GetIngressHostName(componentName, namespace, ingressDomain string) (string,
 
 	return host, nil
 }
+
+// ValidateEnvVarSource validates the value source of the given env var. The value of an env var may be taken from a key of a Secret or
+// ConfigMap, which only stores the reference to the key in the devfile and GitOps resources of the Component.
+func ValidateEnvVarSource(env corev1.EnvVar) error {
+	if env.ValueFrom == nil {
+		return nil
+	}
+	if env.Value != "" {
+		return fmt.Errorf("env %s can't set both value and valueFrom", env.Name)
+	}
+	source := env.ValueFrom
+	if source.FieldRef != nil || source.ResourceFieldRef != nil {
+		return fmt.Errorf("env %s only supports secretKeyRef and configMapKeyRef in valueFrom", env.Name)
+	}
+	if source.SecretKeyRef != nil && source.ConfigMapKeyRef != nil {
+		return fmt.Errorf("env %s can't set both secretKeyRef and configMapKeyRef", env.Name)
+	}
+	if source.SecretKeyRef != nil && (source.SecretKeyRef.Name == "" || source.SecretKeyRef.Key == "") {
+		return fmt.Errorf("env %s must set the name and key of its secretKeyRef", env.Name)
+	}
+	if source.ConfigMapKeyRef != nil && (source.ConfigMapKeyRef.Name == "" || source.ConfigMapKeyRef.Key == "") {
+		return fmt.Errorf("env %s must set the name and key of its configMapKeyRef", env.Name)
+	}
+	if source.SecretKeyRef == nil && source.ConfigMapKeyRef == nil {
+		return fmt.Errorf("env %s must set a secretKeyRef or configMapKeyRef in valueFrom", env.Name)
+	}
+	return nil
+}
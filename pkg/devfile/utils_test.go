@@ -18,6 +18,8 @@ package devfile
 import (
 	"reflect"
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestGetIngressHostName(t *testing.T) {
@@ -67,3 +69,61 @@ func TestGetIngressHostName(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateEnvVarSource(t *testing.T) {
+	secretKeyRef := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password"}
+	configMapKeyRef := &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}, Key: "url"}
+
+	tests := []struct {
+		name    string
+		env     corev1.EnvVar
+		wantErr bool
+	}{
+		{
+			name: "Plain value",
+			env:  corev1.EnvVar{Name: "FOO", Value: "foo"},
+		},
+		{
+			name: "Secret key reference",
+			env:  corev1.EnvVar{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secretKeyRef}},
+		},
+		{
+			name: "ConfigMap key reference",
+			env:  corev1.EnvVar{Name: "URL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: configMapKeyRef}},
+		},
+		{
+			name:    "Value and reference",
+			env:     corev1.EnvVar{Name: "PASSWORD", Value: "password", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secretKeyRef}},
+			wantErr: true,
+		},
+		{
+			name:    "Secret and ConfigMap key references",
+			env:     corev1.EnvVar{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secretKeyRef, ConfigMapKeyRef: configMapKeyRef}},
+			wantErr: true,
+		},
+		{
+			name:    "Secret key reference without a key",
+			env:     corev1.EnvVar{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "Field reference",
+			env:     corev1.EnvVar{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+			wantErr: true,
+		},
+		{
+			name:    "Empty value source",
+			env:     corev1.EnvVar{Name: "EMPTY", ValueFrom: &corev1.EnvVarSource{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEnvVarSource(tt.env)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestValidateEnvVarSource() unexpected error value: %v", err)
+			}
+		})
+	}
+}
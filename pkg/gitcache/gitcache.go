@@ -0,0 +1,299 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// mirrorRefSpec fetches every branch of a remote into the branch of the same name in its mirror
+const mirrorRefSpec = "+refs/heads/*:refs/heads/*"
+
+// Cache is an on-disk cache of bare mirrors of git repositories, keyed by their remote URL. Checking out a repository fetches
+// the changes to its mirror, rather than cloning it again, and then clones the mirror locally into a working copy for the
+// operation. Working copies are regular clones of the mirror, rather than git worktrees, because git doesn't allow a branch to
+// be checked out in more than one worktree at a time. When the cache and the working copy are on the same filesystem, the
+// local clone hardlinks the mirror's objects, so the working copy is unaffected if the mirror is later evicted.
+type Cache struct {
+	// Dir is the directory that the mirrors are stored in
+	Dir string
+
+	// MaxSize is the size, in bytes, that the mirrors are evicted down to after each checkout, least recently used first.
+	// Zero means the cache is unbounded
+	MaxSize int64
+
+	Log logr.Logger
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewCache returns a cache of the git repository mirrors stored in the given directory, creating it if needed
+func NewCache(dir string, maxSize int64, log logr.Logger) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("unable to create the git repository cache directory %s: %v", dir, err)
+	}
+	return &Cache{
+		Dir:     dir,
+		MaxSize: maxSize,
+		Log:     log,
+		locks:   make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// Checkout checks out the given branch of the remote repository into the repoDir folder of outputPath, from the repository's
+// mirror. The mirror is created if it isn't cached yet, updated from the remote otherwise, and recreated if it's corrupted.
+// The working copy's origin is the remote, so its changes can be pulled and pushed as if it had been cloned from the remote.
+// If the branch doesn't exist, it's created.
+func (c *Cache) Checkout(remote string, outputPath string, repoDir string, branch string) error {
+	key := MirrorKey(remote)
+	mirrorPath := filepath.Join(c.Dir, key+".git")
+	repoPath := filepath.Join(outputPath, repoDir)
+
+	lock := c.repoLock(key)
+	lock.Lock()
+	err := c.checkout(remote, mirrorPath, repoPath)
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+	c.evict()
+
+	// Checkout the specified branch
+	if _, err := git(repoPath, "switch", branch); err != nil {
+		if out, err := git(repoPath, "checkout", "-b", branch); err != nil {
+			return fmt.Errorf("unable to check out branch %s: %v: %s", branch, err, out)
+		}
+	}
+	return nil
+}
+
+// checkout updates the mirror at mirrorPath and clones it into repoPath. It's called with the mirror's lock held.
+func (c *Cache) checkout(remote string, mirrorPath string, repoPath string) error {
+	if err := c.updateMirror(remote, mirrorPath); err != nil {
+		return err
+	}
+	if err := cloneMirror(remote, mirrorPath, repoPath); err != nil {
+		// The mirror may be corrupted in a way that a fetch doesn't notice, so recreate it and try once more
+		c.Log.Info(fmt.Sprintf("Unable to check out the mirror of %s, recreating it: %v", sanitizeURL(remote), err))
+		if err := os.RemoveAll(repoPath); err != nil {
+			return err
+		}
+		if err := c.recreateMirror(remote, mirrorPath); err != nil {
+			return err
+		}
+		return cloneMirror(remote, mirrorPath, repoPath)
+	}
+	return nil
+}
+
+// updateMirror fetches the branches of the remote into its mirror, creating the mirror if it doesn't exist, or recreating it if
+// it's corrupted
+func (c *Cache) updateMirror(remote string, mirrorPath string) error {
+	if _, err := os.Stat(mirrorPath); os.IsNotExist(err) {
+		return createMirror(remote, mirrorPath)
+	} else if err != nil {
+		return err
+	}
+
+	// Mark the mirror as used, for eviction
+	now := time.Now()
+	if err := os.Chtimes(mirrorPath, now, now); err != nil {
+		return err
+	}
+	if fetchErr := fetchMirror(remote, mirrorPath); fetchErr != nil {
+		// A fetch from a healthy mirror fails because of the remote, so only recreate mirrors that are corrupted
+		if _, err := git(mirrorPath, "fsck", "--connectivity-only", "--no-progress"); err == nil {
+			return fetchErr
+		}
+		c.Log.Info(fmt.Sprintf("The mirror of %s is corrupted, recreating it", sanitizeURL(remote)))
+		return c.recreateMirror(remote, mirrorPath)
+	}
+	return nil
+}
+
+func (c *Cache) recreateMirror(remote string, mirrorPath string) error {
+	if err := os.RemoveAll(mirrorPath); err != nil {
+		return fmt.Errorf("unable to remove the mirror of %s: %v", sanitizeURL(remote), err)
+	}
+	return createMirror(remote, mirrorPath)
+}
+
+// createMirror creates the mirror of the remote in a temporary folder, and only moves it to mirrorPath once it's complete, so that
+// an interrupted fetch never leaves a partial mirror in the cache
+func createMirror(remote string, mirrorPath string) error {
+	tempPath, err := os.MkdirTemp(filepath.Dir(mirrorPath), filepath.Base(mirrorPath)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempPath)
+
+	if out, err := git(tempPath, "init", "--bare"); err != nil {
+		return fmt.Errorf("unable to create the mirror of %s: %v: %s", sanitizeURL(remote), err, out)
+	}
+	if err := fetchMirror(remote, tempPath); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, mirrorPath)
+}
+
+// fetchMirror fetches the branches of the remote into the mirror. The remote is passed on the command line, rather than stored
+// in the mirror's config, so that its credentials are never written to disk.
+func fetchMirror(remote string, mirrorPath string) error {
+	if out, err := git(mirrorPath, "fetch", "--prune", "--no-tags", remote, mirrorRefSpec); err != nil {
+		return fmt.Errorf("unable to fetch %s: %v: %s", sanitizeURL(remote), err, strings.ReplaceAll(string(out), remote, sanitizeURL(remote)))
+	}
+
+	// Point the mirror's HEAD at the remote's default branch, so that working copies start from it, as they would if cloned from the remote
+	out, err := git(mirrorPath, "ls-remote", "--symref", remote, "HEAD")
+	if err != nil {
+		return fmt.Errorf("unable to get the default branch of %s: %v: %s", sanitizeURL(remote), err, strings.ReplaceAll(string(out), remote, sanitizeURL(remote)))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD" {
+			if out, err := git(mirrorPath, "symbolic-ref", "HEAD", fields[1]); err != nil {
+				return fmt.Errorf("unable to set the default branch of the mirror: %v: %s", err, out)
+			}
+		}
+	}
+	return nil
+}
+
+// cloneMirror clones the mirror into repoPath, and points the clone's origin at the remote
+func cloneMirror(remote string, mirrorPath string, repoPath string) error {
+	if out, err := git(filepath.Dir(repoPath), "clone", "--quiet", mirrorPath, repoPath); err != nil {
+		return fmt.Errorf("unable to clone the mirror: %v: %s", err, out)
+	}
+	if out, err := git(repoPath, "remote", "set-url", "origin", remote); err != nil {
+		return fmt.Errorf("unable to set the origin of the working copy: %v: %s", err, strings.ReplaceAll(string(out), remote, sanitizeURL(remote)))
+	}
+	return nil
+}
+
+// repoLock returns the lock for the mirror with the given key, which serializes the updates of a repository's mirror
+func (c *Cache) repoLock(key string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lock, ok := c.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.locks[key] = lock
+	}
+	return lock
+}
+
+type cachedMirror struct {
+	key      string
+	path     string
+	size     int64
+	lastUsed time.Time
+}
+
+// evict removes the least recently used mirrors until the cache fits in its maximum size. Mirrors that are in use are skipped.
+func (c *Cache) evict() {
+	if c.MaxSize <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		c.Log.Error(err, "unable to list the git repository cache")
+		return
+	}
+
+	var mirrors []cachedMirror
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		mirror := cachedMirror{key: strings.TrimSuffix(entry.Name(), ".git"), path: filepath.Join(c.Dir, entry.Name()), lastUsed: info.ModTime()}
+		mirror.size = dirSize(mirror.path)
+		total += mirror.size
+		mirrors = append(mirrors, mirror)
+	}
+
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].lastUsed.Before(mirrors[j].lastUsed) })
+	for _, mirror := range mirrors {
+		if total <= c.MaxSize {
+			return
+		}
+		lock := c.repoLock(mirror.key)
+		if !lock.TryLock() {
+			continue
+		}
+		if err := os.RemoveAll(mirror.path); err != nil {
+			c.Log.Error(err, fmt.Sprintf("unable to evict git repository mirror %s", mirror.path))
+		} else {
+			total -= mirror.size
+		}
+		lock.Unlock()
+	}
+}
+
+// dirSize returns the total size of the files under the given directory
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// MirrorKey returns the key that the mirror of the given remote is cached under. Credentials are ignored, so that a repository
+// keeps its mirror when the token used to access it is rotated.
+func MirrorKey(remote string) string {
+	normalized := strings.TrimSuffix(strings.TrimSuffix(sanitizeURL(remote), "/"), ".git")
+	sum := sha256.Sum256([]byte(strings.ToLower(normalized)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// sanitizeURL removes any credentials from the given remote URL
+func sanitizeURL(remote string) string {
+	parsed, err := url.Parse(remote)
+	if err != nil || parsed.User == nil {
+		return remote
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+/* #nosec G204 -- the git commands and their arguments are set by the callers in this package */
+func git(dir string, args ...string) ([]byte, error) {
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	// set env to skip authentication prompt and directly error out
+	c.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=/bin/echo")
+	return c.CombinedOutput()
+}
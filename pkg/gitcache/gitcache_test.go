@@ -0,0 +1,213 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitcache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+// newRemote creates a bare repository with a main branch containing the given file, to be used as the remote of the cache
+func newRemote(t *testing.T, fileName string) string {
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	work := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--bare", "--initial-branch=main", remote},
+		{"clone", "--quiet", remote, work},
+	} {
+		if out, err := git(t.TempDir(), args...); err != nil {
+			t.Fatalf("unable to set up the remote repository: %v: %s", err, out)
+		}
+	}
+	commitFile(t, work, fileName)
+	return remote
+}
+
+// commitFile commits a new file to the main branch of the given working copy, and pushes it to its remote
+func commitFile(t *testing.T, work string, fileName string) {
+	if err := os.WriteFile(filepath.Join(work, fileName), []byte(fileName), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"checkout", "-B", "main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "Add " + fileName},
+		{"push", "--quiet", "origin", "main"},
+	} {
+		if out, err := git(work, args...); err != nil {
+			t.Fatalf("unable to commit %s: %v: %s", fileName, err, out)
+		}
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	tests := []struct {
+		name      string
+		branch    string
+		corrupt   func(mirrorPath string) error
+		wantFiles []string
+	}{
+		{
+			name:      "Existing branch",
+			branch:    "main",
+			wantFiles: []string{"kustomization.yaml"},
+		},
+		{
+			name:   "New branch is created from the default branch",
+			branch: "appstudio/component/test-component",
+		},
+		{
+			name:   "Corrupted mirror is recreated",
+			branch: "main",
+			corrupt: func(mirrorPath string) error {
+				if err := os.RemoveAll(filepath.Join(mirrorPath, "objects")); err != nil {
+					return err
+				}
+				return os.MkdirAll(filepath.Join(mirrorPath, "objects"), 0750)
+			},
+			wantFiles: []string{"kustomization.yaml"},
+		},
+		{
+			name:   "Mirror that isn't a git repository is recreated",
+			branch: "main",
+			corrupt: func(mirrorPath string) error {
+				if err := os.RemoveAll(mirrorPath); err != nil {
+					return err
+				}
+				return os.MkdirAll(mirrorPath, 0750)
+			},
+			wantFiles: []string{"kustomization.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newRemote(t, "kustomization.yaml")
+			cache, err := NewCache(t.TempDir(), 0, logr.Discard())
+			if err != nil {
+				t.Fatalf("TestCheckout() unexpected error creating the cache: %v", err)
+			}
+
+			// Populate the cache, then corrupt it
+			if err := cache.Checkout(remote, t.TempDir(), "first", "main"); err != nil {
+				t.Fatalf("TestCheckout() unexpected error populating the cache: %v", err)
+			}
+			if tt.corrupt != nil {
+				if err := tt.corrupt(filepath.Join(cache.Dir, MirrorKey(remote)+".git")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			outputPath := t.TempDir()
+			if err := cache.Checkout(remote, outputPath, "test-application", tt.branch); err != nil {
+				t.Fatalf("TestCheckout() unexpected error: %v", err)
+			}
+			repoPath := filepath.Join(outputPath, "test-application")
+			if branch, _ := git(repoPath, "rev-parse", "--abbrev-ref", "HEAD"); strings.TrimSpace(string(branch)) != tt.branch {
+				t.Errorf("TestCheckout() error: expected branch %v got %s", tt.branch, branch)
+			}
+			if origin, _ := git(repoPath, "remote", "get-url", "origin"); strings.TrimSpace(string(origin)) != remote {
+				t.Errorf("TestCheckout() error: expected origin %v got %s", remote, origin)
+			}
+			for _, file := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(repoPath, file)); err != nil {
+					t.Errorf("TestCheckout() error: expected file %v to be checked out: %v", file, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckoutFetchesChanges(t *testing.T) {
+	remote := newRemote(t, "kustomization.yaml")
+	cache, err := NewCache(t.TempDir(), 0, logr.Discard())
+	if err != nil {
+		t.Fatalf("TestCheckoutFetchesChanges() unexpected error creating the cache: %v", err)
+	}
+	first := t.TempDir()
+	if err := cache.Checkout(remote, first, "repo", "main"); err != nil {
+		t.Fatalf("TestCheckoutFetchesChanges() unexpected error: %v", err)
+	}
+
+	// Push a change to the remote from the first working copy, and check it's in the next one
+	commitFile(t, filepath.Join(first, "repo"), "deployment.yaml")
+	second := t.TempDir()
+	if err := cache.Checkout(remote, second, "repo", "main"); err != nil {
+		t.Fatalf("TestCheckoutFetchesChanges() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(second, "repo", "deployment.yaml")); err != nil {
+		t.Errorf("TestCheckoutFetchesChanges() error: expected the pushed change to be checked out: %v", err)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 1, logr.Discard())
+	if err != nil {
+		t.Fatalf("TestEvict() unexpected error creating the cache: %v", err)
+	}
+	remote := newRemote(t, "kustomization.yaml")
+	outputPath := t.TempDir()
+	if err := cache.Checkout(remote, outputPath, "repo", "main"); err != nil {
+		t.Fatalf("TestEvict() unexpected error: %v", err)
+	}
+
+	// The working copy survives the eviction of its mirror
+	if _, err := os.Stat(filepath.Join(cache.Dir, MirrorKey(remote)+".git")); !os.IsNotExist(err) {
+		t.Errorf("TestEvict() error: expected the mirror to be evicted: %v", err)
+	}
+	if out, err := git(filepath.Join(outputPath, "repo"), "log", "--oneline"); err != nil {
+		t.Errorf("TestEvict() error: expected the working copy to be intact: %v: %s", err, out)
+	}
+}
+
+func TestMirrorKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		remote   string
+		other    string
+		wantSame bool
+	}{
+		{
+			name:     "Rotated token",
+			remote:   "https://token1@github.com/redhat-appstudio-appdata/test-application",
+			other:    "https://token2@github.com/redhat-appstudio-appdata/test-application",
+			wantSame: true,
+		},
+		{
+			name:     "Trailing .git",
+			remote:   "https://github.com/redhat-appstudio-appdata/test-application.git",
+			other:    "https://github.com/redhat-appstudio-appdata/test-application",
+			wantSame: true,
+		},
+		{
+			name:   "Different repositories",
+			remote: "https://github.com/redhat-appstudio-appdata/test-application",
+			other:  "https://github.com/redhat-appstudio-appdata/other-application",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := MirrorKey(tt.remote) == MirrorKey(tt.other); same != tt.wantSame {
+				t.Errorf("TestMirrorKey() error: expected the keys of %v and %v to be the same: %v", tt.remote, tt.other, tt.wantSame)
+			}
+		})
+	}
+}
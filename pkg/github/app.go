@@ -0,0 +1,272 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// appTokenPrefix is prepended to the organization name to build the name of a GitHub App installation token
+	appTokenPrefix = "app-installation-"
+
+	// installationTokenRefreshWindow is how long before its expiry an installation token is refreshed
+	installationTokenRefreshWindow = 5 * time.Minute
+)
+
+// GitHubAppConfig holds the configuration needed to authenticate as a GitHub App
+type GitHubAppConfig struct {
+	AppID      int64
+	PrivateKey *rsa.PrivateKey
+
+	// Orgs restricts the installations that tokens are minted for. If empty, tokens are minted for every installation of the app
+	Orgs []string
+}
+
+// ParseGitHubAppConfig reads the GitHub App configuration from the environment.
+// GITHUB_APP_ID must be set, along with either GITHUB_APP_PRIVATE_KEY (the PEM encoded key) or GITHUB_APP_PRIVATE_KEY_PATH (a path to it).
+// GITHUB_APP_INSTALLATION_ORGS optionally restricts the organizations that installation tokens are minted for.
+// It returns nil if no GitHub App is configured.
+func ParseGitHubAppConfig() (*GitHubAppConfig, error) {
+	appIDStr := os.Getenv("GITHUB_APP_ID")
+	if appIDStr == "" {
+		return nil, nil
+	}
+
+	privateKeyPEM := []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY"))
+	if privateKeyPath := os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH"); len(privateKeyPEM) == 0 && privateKeyPath != "" {
+		var err error
+		privateKeyPEM, err = os.ReadFile(privateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the GitHub App private key: %v", err)
+		}
+	}
+	return newGitHubAppConfig(appIDStr, privateKeyPEM, os.Getenv("GITHUB_APP_INSTALLATION_ORGS"))
+}
+
+// newGitHubAppConfig parses the GitHub App ID, PEM encoded private key and comma separated list of installation organizations
+func newGitHubAppConfig(appIDStr string, privateKeyPEM []byte, installationOrgs string) (*GitHubAppConfig, error) {
+	appID, err := strconv.ParseInt(strings.TrimSpace(appIDStr), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse GITHUB_APP_ID %q: %v", appIDStr, err)
+	}
+	if len(privateKeyPEM) == 0 {
+		return nil, fmt.Errorf("GITHUB_APP_ID is set, but neither GITHUB_APP_PRIVATE_KEY nor GITHUB_APP_PRIVATE_KEY_PATH were provided")
+	}
+	privateKey, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	var orgs []string
+	for _, org := range strings.Split(installationOrgs, ",") {
+		if org = strings.TrimSpace(org); org != "" {
+			orgs = append(orgs, org)
+		}
+	}
+
+	return &GitHubAppConfig{
+		AppID:      appID,
+		PrivateKey: privateKey,
+		Orgs:       orgs,
+	}, nil
+}
+
+// parsePrivateKey parses a PEM encoded RSA private key, in either the PKCS1 or the PKCS8 format
+func parsePrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("unable to decode the GitHub App private key, it must be PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the GitHub App private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the GitHub App private key must be an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// generateAppJWT returns a signed JSON Web Token used to authenticate as the GitHub App itself
+func generateAppJWT(appID int64, privateKey *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	// Backdate the issued-at time to allow for clock drift, GitHub rejects tokens that expire more than 10 minutes after issue
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hashed := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appTransport authenticates requests to the GitHub API as the GitHub App, with a freshly signed JWT
+type appTransport struct {
+	appID      int64
+	privateKey *rsa.PrivateKey
+	base       http.RoundTripper
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := generateAppJWT(t.appID, t.privateKey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.base.RoundTrip(req)
+}
+
+// newAppClient returns a Go-GitHub client authenticated as the GitHub App. If base is nil, the default transport is used
+func newAppClient(config *GitHubAppConfig, base http.RoundTripper) (*github.Client, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return newGoGitHubClient(&http.Client{Transport: &appTransport{appID: config.AppID, privateKey: config.PrivateKey, base: base}})
+}
+
+// installationTokenSource mints installation access tokens for a single GitHub App installation.
+// Tokens are cached, and refreshed shortly before they expire.
+type installationTokenSource struct {
+	appClient      *github.Client
+	appID          int64
+	privateKey     *rsa.PrivateKey
+	installationID int64
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// Token returns a valid installation token, minting a new one if the cached token is about to expire
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && time.Until(s.token.Expiry) > installationTokenRefreshWindow {
+		return s.token, nil
+	}
+
+	installationToken, _, err := s.appClient.Apps.CreateInstallationToken(context.Background(), s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a token for GitHub App installation %d: %v", s.installationID, err)
+	}
+	s.token = &oauth2.Token{
+		AccessToken: installationToken.GetToken(),
+		TokenType:   "token",
+		Expiry:      installationToken.GetExpiresAt().Time,
+	}
+	return s.token, nil
+}
+
+// createGitHubAppClients creates a GitHub client for each installation of the configured GitHub App.
+// The clients are keyed by token name, of the form app-installation-<org>, so that rate limits are tracked per installation.
+// Clients in existing for the same installation of the same GitHub App are reused, so that their rate limit state is preserved.
+func createGitHubAppClients(config *GitHubAppConfig, base http.RoundTripper, existing map[string]*GitHubClient) (map[string]*GitHubClient, error) {
+	appClient, err := newAppClient(config, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var installations []*github.Installation
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := appClient.Apps.ListInstallations(context.Background(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the installations of GitHub App %d: %v", config.AppID, err)
+		}
+		installations = append(installations, page...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	allowedOrgs := make(map[string]bool)
+	for _, org := range config.Orgs {
+		allowedOrgs[strings.ToLower(org)] = true
+	}
+
+	clients := make(map[string]*GitHubClient)
+	for _, installation := range installations {
+		org := installation.GetAccount().GetLogin()
+		if len(allowedOrgs) > 0 && !allowedOrgs[strings.ToLower(org)] {
+			continue
+		}
+
+		tokenName := appTokenPrefix + org
+		if ghClient := existing[tokenName]; ghClient != nil {
+			if src, ok := ghClient.tokenSource.(*installationTokenSource); ok && src.installationID == installation.GetID() &&
+				src.appID == config.AppID && src.privateKey.Equal(config.PrivateKey) {
+				clients[tokenName] = ghClient
+				continue
+			}
+		}
+
+		tokenSource := &installationTokenSource{appClient: appClient, appID: config.AppID, privateKey: config.PrivateKey, installationID: installation.GetID()}
+		token, err := tokenSource.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var transport http.RoundTripper = &oauth2.Transport{Source: tokenSource, Base: base}
+		ghClient, err := createGitHubClientFromToken(&transport, token.AccessToken, tokenName)
+		if err != nil {
+			return nil, err
+		}
+		ghClient.tokenSource = tokenSource
+		clients[tokenName] = ghClient
+	}
+
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("GitHub App %d has no installations in the configured organizations", config.AppID)
+	}
+	return clients, nil
+}
@@ -0,0 +1,203 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate private key: %v", err)
+	}
+	pkcs8Bytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("unable to marshal private key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		keyPEM  []byte
+		wantErr bool
+	}{
+		{
+			name:   "PKCS1 private key",
+			keyPEM: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}),
+		},
+		{
+			name:   "PKCS8 private key",
+			keyPEM: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Bytes}),
+		},
+		{
+			name:    "Not PEM encoded",
+			keyPEM:  []byte("not-a-key"),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid key",
+			keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("not-a-key")}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parsePrivateKey(tt.keyPEM)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestParsePrivateKey() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && !key.Equal(privateKey) {
+				t.Errorf("TestParsePrivateKey() error: parsed key does not match")
+			}
+		})
+	}
+}
+
+func TestGenerateAppJWT(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate private key: %v", err)
+	}
+	now := time.Now()
+
+	jwt, err := generateAppJWT(12345, privateKey, now)
+	if err != nil {
+		t.Fatalf("TestGenerateAppJWT() unexpected error: %v", err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	assert.Equal(t, 3, len(parts))
+
+	// Verify the signature with the public key
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NoError(t, err)
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, hashed[:], signature))
+
+	// Verify the claims
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, err)
+	claims := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(claimBytes, &claims))
+	assert.Equal(t, "12345", claims["iss"])
+	assert.Equal(t, float64(now.Add(9*time.Minute).Unix()), claims["exp"])
+}
+
+func TestCreateGitHubAppClients(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate private key: %v", err)
+	}
+
+	tokensMinted := 0
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetAppInstallations,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+					WriteError(w, http.StatusUnauthorized, "missing jwt")
+					return
+				}
+				/* #nosec G104 -- test code */
+				w.Write(mock.MustMarshal([]github.Installation{
+					{ID: github.Int64(1), Account: &github.User{Login: github.String("redhat-appstudio-appdata")}},
+					{ID: github.Int64(2), Account: &github.User{Login: github.String("another-org")}},
+				}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostAppInstallationsAccessTokensByInstallationId,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				tokensMinted++
+				/* #nosec G104 -- test code */
+				w.Write(mock.MustMarshal(github.InstallationToken{
+					Token:     github.String("ghs_installationtoken"),
+					ExpiresAt: &github.Timestamp{Time: time.Now().Add(time.Hour)},
+				}))
+			}),
+		),
+	)
+
+	tests := []struct {
+		name           string
+		orgs           []string
+		wantTokenNames []string
+		wantErr        bool
+	}{
+		{
+			name:           "All installations",
+			wantTokenNames: []string{"app-installation-redhat-appstudio-appdata", "app-installation-another-org"},
+		},
+		{
+			name:           "Installations restricted to one org",
+			orgs:           []string{"Redhat-Appstudio-Appdata"},
+			wantTokenNames: []string{"app-installation-redhat-appstudio-appdata"},
+		},
+		{
+			name:    "No installation in the configured orgs",
+			orgs:    []string{"fake-org"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &GitHubAppConfig{AppID: 12345, PrivateKey: privateKey, Orgs: tt.orgs}
+			clients, err := createGitHubAppClients(config, mockedHTTPClient.Transport, nil)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestCreateGitHubAppClients() unexpected error value: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, len(tt.wantTokenNames), len(clients))
+			for _, tokenName := range tt.wantTokenNames {
+				client := clients[tokenName]
+				if assert.NotNil(t, client) {
+					assert.Equal(t, tokenName, client.TokenName)
+					assert.Equal(t, "ghs_installationtoken", client.Token)
+				}
+			}
+		})
+	}
+
+	// The installation token is valid for another hour, so it should be served from the cache rather than minted again
+	appClient, err := newAppClient(&GitHubAppConfig{AppID: 12345, PrivateKey: privateKey}, mockedHTTPClient.Transport)
+	assert.NoError(t, err)
+	source := &installationTokenSource{appClient: appClient, installationID: 1}
+	before := tokensMinted
+	_, err = source.Token()
+	assert.NoError(t, err)
+	_, err = source.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, before+1, tokensMinted)
+}
@@ -0,0 +1,159 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v52/github"
+)
+
+const (
+	// DefaultGitHubURL is the URL of the public GitHub instance
+	DefaultGitHubURL = "https://github.com"
+
+	// DefaultGitHubRawURL is the URL that raw file contents are served from on the public GitHub instance
+	DefaultGitHubRawURL = "https://raw.githubusercontent.com"
+)
+
+// GitHubURLs are the URLs of the GitHub instance that application-service talks to
+type GitHubURLs struct {
+	// BaseURL is the web URL of the GitHub instance, that repository URLs are built from, e.g. https://github.example.com
+	BaseURL string
+
+	// APIURL is the URL of the GitHub REST API. It is empty for the public GitHub instance
+	APIURL string
+
+	// UploadURL is the URL that GitHub uploads are sent to. It is empty for the public GitHub instance
+	UploadURL string
+
+	// RawURL is the URL that raw file contents are served from
+	RawURL string
+}
+
+var (
+	gitHubURLs   = GitHubURLs{BaseURL: DefaultGitHubURL, RawURL: DefaultGitHubRawURL}
+	gitHubURLsMu sync.RWMutex
+)
+
+// ParseGitHubURLs reads the URLs of the GitHub instance from the GITHUB_URL, GITHUB_API_URL and GITHUB_RAW_URL environment
+// variables. If GITHUB_URL isn't set, the public GitHub instance is used.
+func ParseGitHubURLs() error {
+	urls, err := NewGitHubURLs(os.Getenv("GITHUB_URL"), os.Getenv("GITHUB_API_URL"), os.Getenv("GITHUB_RAW_URL"))
+	if err != nil {
+		return err
+	}
+	SetGitHubURLs(urls)
+	return nil
+}
+
+// NewGitHubURLs validates the URLs of a GitHub instance, and defaults any URL that isn't set. For a GitHub Enterprise Server
+// instance, the API URL defaults to <baseURL>/api/v3/, uploads to <baseURL>/api/uploads/ and raw file contents to <baseURL>/raw.
+func NewGitHubURLs(baseURL string, apiURL string, rawURL string) (GitHubURLs, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultGitHubURL
+	}
+	if err := validateGitHubURL("GitHub", baseURL); err != nil {
+		return GitHubURLs{}, err
+	}
+
+	if baseURL == DefaultGitHubURL {
+		if apiURL != "" {
+			return GitHubURLs{}, fmt.Errorf("a GitHub API URL can only be set for GitHub Enterprise Server instances")
+		}
+		if rawURL == "" {
+			rawURL = DefaultGitHubRawURL
+		}
+		if err := validateGitHubURL("GitHub raw content", rawURL); err != nil {
+			return GitHubURLs{}, err
+		}
+		return GitHubURLs{BaseURL: baseURL, RawURL: strings.TrimSuffix(rawURL, "/")}, nil
+	}
+
+	urls := GitHubURLs{BaseURL: baseURL, UploadURL: baseURL + "/api/uploads/"}
+	if apiURL == "" {
+		apiURL = baseURL + "/api/v3/"
+	}
+	if err := validateGitHubURL("GitHub API", apiURL); err != nil {
+		return GitHubURLs{}, err
+	}
+	urls.APIURL = apiURL
+	if rawURL == "" {
+		rawURL = baseURL + "/raw"
+	}
+	if err := validateGitHubURL("GitHub raw content", rawURL); err != nil {
+		return GitHubURLs{}, err
+	}
+	urls.RawURL = strings.TrimSuffix(rawURL, "/")
+	return urls, nil
+}
+
+func validateGitHubURL(name string, rawURL string) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid %s URL %q: %v", name, rawURL, err)
+	}
+	if parsedURL.Scheme != "https" && parsedURL.Scheme != "http" {
+		return fmt.Errorf("invalid %s URL %q: the scheme must be http or https", name, rawURL)
+	}
+	if parsedURL.Host == "" {
+		return fmt.Errorf("invalid %s URL %q: the host must be set", name, rawURL)
+	}
+	return nil
+}
+
+// SetGitHubURLs sets the URLs of the GitHub instance used by all GitHub clients created afterwards
+func SetGitHubURLs(urls GitHubURLs) {
+	gitHubURLsMu.Lock()
+	defer gitHubURLsMu.Unlock()
+	gitHubURLs = urls
+}
+
+// GetGitHubURLs returns the URLs of the GitHub instance
+func GetGitHubURLs() GitHubURLs {
+	gitHubURLsMu.RLock()
+	defer gitHubURLsMu.RUnlock()
+	return gitHubURLs
+}
+
+// IsEnterprise returns true if the URLs point to a GitHub Enterprise Server instance rather than the public GitHub instance
+func (u GitHubURLs) IsEnterprise() bool {
+	return u.APIURL != ""
+}
+
+// Host returns the host of the GitHub instance, e.g. github.com
+func (u GitHubURLs) Host() string {
+	parsedURL, err := url.Parse(u.BaseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Host)
+}
+
+// newGoGitHubClient returns a Go-GitHub client for the configured GitHub instance
+func newGoGitHubClient(httpClient *http.Client) (*github.Client, error) {
+	urls := GetGitHubURLs()
+	if !urls.IsEnterprise() {
+		return github.NewClient(httpClient), nil
+	}
+	return github.NewEnterpriseClient(urls.APIURL, urls.UploadURL, httpClient)
+}
@@ -0,0 +1,113 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGitHubURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		apiURL   string
+		rawURL   string
+		wantURLs GitHubURLs
+		wantHost string
+		wantErr  bool
+	}{
+		{
+			name:     "No URLs set, public GitHub is used",
+			wantURLs: GitHubURLs{BaseURL: DefaultGitHubURL, RawURL: DefaultGitHubRawURL},
+			wantHost: "github.com",
+		},
+		{
+			name:     "GitHub Enterprise Server URL set, other URLs are defaulted",
+			baseURL:  "https://ghe.example.com/",
+			wantURLs: GitHubURLs{BaseURL: "https://ghe.example.com", APIURL: "https://ghe.example.com/api/v3/", UploadURL: "https://ghe.example.com/api/uploads/", RawURL: "https://ghe.example.com/raw"},
+			wantHost: "ghe.example.com",
+		},
+		{
+			name:     "GitHub Enterprise Server URLs set",
+			baseURL:  "https://GHE.example.com",
+			apiURL:   "https://api.ghe.example.com/",
+			rawURL:   "https://raw.ghe.example.com/",
+			wantURLs: GitHubURLs{BaseURL: "https://GHE.example.com", APIURL: "https://api.ghe.example.com/", UploadURL: "https://GHE.example.com/api/uploads/", RawURL: "https://raw.ghe.example.com"},
+			wantHost: "ghe.example.com",
+		},
+		{
+			name:    "API URL set for public GitHub",
+			apiURL:  "https://api.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "Base URL without a scheme",
+			baseURL: "ghe.example.com",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid raw URL",
+			baseURL: "https://ghe.example.com",
+			rawURL:  "ftp://ghe.example.com/raw",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := NewGitHubURLs(tt.baseURL, tt.apiURL, tt.rawURL)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantURLs, urls)
+			assert.Equal(t, tt.wantHost, urls.Host())
+			assert.Equal(t, tt.baseURL != "", urls.IsEnterprise())
+		})
+	}
+}
+
+func TestGitHubEnterpriseClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// GitHub Enterprise Server serves its REST API under /api/v3
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/v3/repos/test-org/test-repo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	urls, err := NewGitHubURLs(server.URL, "", "")
+	assert.NoError(t, err)
+	SetGitHubURLs(urls)
+	defer SetGitHubURLs(GitHubURLs{BaseURL: DefaultGitHubURL, RawURL: DefaultGitHubRawURL})
+
+	transport := http.DefaultTransport
+	ghClient, err := createGitHubClientFromToken(&transport, "ghp_token", "token1")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/api/v3/", ghClient.Client.BaseURL.String())
+
+	err = ghClient.DeleteRepository(context.WithValue(context.Background(), GHClientKey, "token1"), "test-org", "test-repo")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/test-org/test-repo", GetRepositoryURL("test-org", "test-repo"))
+}
@@ -17,15 +17,21 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/google/go-github/v52/github"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	"github.com/redhat-appstudio/application-service/pkg/metrics"
 	"github.com/redhat-appstudio/application-service/pkg/util"
+	"golang.org/x/oauth2"
 )
 
 const AppStudioAppDataOrg = "redhat-appstudio-appdata"
@@ -36,11 +42,24 @@ type GitHubClient struct {
 	Token              string
 	Client             *github.Client
 	SecondaryRateLimit SecondaryRateLimit
-	PrimaryRateLimited bool // flag to denote if the token has been near primary rate limited
+	PrimaryRateLimited bool // flag to denote if the token has been near primary rate limited, only changed through setPrimaryRateLimited
+
+	// primaryRateLimitedMu guards PrimaryRateLimited, which concurrent token selections update
+	primaryRateLimitedMu sync.Mutex
+
+	// tokenSource is set for GitHub App installation clients, whose tokens are short-lived and must be refreshed
+	tokenSource oauth2.TokenSource
+
+	// weight scales the token's remaining quota when selecting a token from the pool. Zero is treated as a weight of 1
+	weight int
+
+	// rateLimits caches the token's rate limits, as reported by the GitHub API
+	rateLimits rateLimitCache
 }
 
 type SecondaryRateLimit struct {
 	isLimitReached bool
+	resetTime      time.Time
 	mu             sync.Mutex
 }
 
@@ -67,12 +86,42 @@ func GenerateNewRepositoryName(displayName, uniqueHash string) string {
 	return repoName
 }
 
-func (g *GitHubClient) GenerateNewRepository(ctx context.Context, orgName string, repoName string, description string) (string, error) {
-	isPrivate := false
-	appStudioAppDataURL := "https://github.com/" + orgName + "/"
+// generatedRepositoryNameRegex matches the names returned by GenerateNewRepositoryName: a sanitized name, a five character
+// URL-safe base64 hash and two verbs
+var generatedRepositoryNameRegex = regexp.MustCompile(`^[a-z0-9._-]+-[A-Za-z0-9_-]{5}-[a-z]+-[a-z]+$`)
+
+// IsGeneratedRepositoryName returns true if the repository name matches the names returned by GenerateNewRepositoryName
+func IsGeneratedRepositoryName(repoName string) bool {
+	return generatedRepositoryNameRegex.MatchString(repoName)
+}
+
+// GenerateNewRepository creates a new repository under the given org with the given visibility and access settings, and returns its URL.
+// If a template repository is set, the repository is generated from it. GitHub copies the template's files asynchronously, so if they
+// haven't been copied yet, the URL is returned along with a RepositoryPendingError, and the access settings are applied by FinishNewRepository.
+// If the access settings can't be applied, the repository is deleted, rather than being left with the wrong settings.
+func (g *GitHubClient) GenerateNewRepository(ctx context.Context, orgName string, repoName string, description string, opts gitprovider.RepositoryOptions) (string, error) {
+	isPrivate := opts.IsPrivate()
 	metrics.GitOpsRepoCreationTotalReqs.Inc()
-	r := &github.Repository{Name: &repoName, Private: &isPrivate, Description: &description}
-	_, resp, err := g.Client.Repositories.Create(ctx, orgName, r)
+	var repo *github.Repository
+	var resp *github.Response
+	var err error
+	if opts.Template != "" {
+		// Generate the repository from the template repository, so that it starts with the template's files
+		templateOrg, templateRepo := gitprovider.SplitTemplate(opts.Template)
+		templateReq := &github.TemplateRepoRequest{Name: &repoName, Owner: &orgName, Description: &description, Private: &isPrivate}
+		repo, resp, err = g.Client.Repositories.CreateFromTemplate(ctx, templateOrg, templateRepo, templateReq)
+	} else {
+		r := &github.Repository{Name: &repoName, Private: &isPrivate, Description: &description}
+		if opts.Visibility == gitprovider.VisibilityInternal {
+			r.Visibility = &opts.Visibility
+		}
+		if opts.ProtectedBranch != "" {
+			// Branches can only be protected once they exist, so initialize the repository with a commit on its default branch
+			autoInit := true
+			r.AutoInit = &autoInit
+		}
+		repo, resp, err = g.Client.Repositories.Create(ctx, orgName, r)
+	}
 
 	if resp != nil && 500 <= resp.StatusCode && resp.StatusCode <= 599 {
 		// return custom error
@@ -85,11 +134,119 @@ func (g *GitHubClient) GenerateNewRepository(ctx context.Context, orgName string
 	if err != nil {
 		return "", err
 	}
-	repoURL := appStudioAppDataURL + repoName
+
+	if opts.Template != "" && opts.Visibility == gitprovider.VisibilityInternal {
+		// Repositories can't be generated from a template as internal, so change their visibility once they're created
+		if _, _, err := g.Client.Repositories.Edit(ctx, orgName, repoName, &github.Repository{Visibility: &opts.Visibility}); err != nil {
+			return "", g.deleteUnfinishedRepository(ctx, orgName, repoName, fmt.Errorf("unable to make the repository internal: %v", err))
+		}
+	}
+
+	repoURL := GetRepositoryURL(orgName, repoName)
+	if err := g.applyRepositoryOptions(ctx, orgName, repoName, repo.GetDefaultBranch(), opts); err != nil {
+		if gitprovider.IsRepositoryPending(err) {
+			return repoURL, err
+		}
+		return "", g.deleteUnfinishedRepository(ctx, orgName, repoName, err)
+	}
+
 	metrics.GitOpsRepoCreationSucceeded.Inc()
 	return repoURL, nil
 }
 
+// FinishNewRepository applies the access settings of a repository generated from a template once the template's files have been
+// copied, returning a RepositoryPendingError until then. If the access settings can't be applied, the repository is deleted.
+func (g *GitHubClient) FinishNewRepository(ctx context.Context, orgName string, repoName string, opts gitprovider.RepositoryOptions) error {
+	repo, _, err := g.Client.Repositories.Get(ctx, orgName, repoName)
+	if err != nil {
+		return fmt.Errorf("unable to get repo %s under %s: %v", repoName, orgName, err)
+	}
+	if err := g.applyRepositoryOptions(ctx, orgName, repoName, repo.GetDefaultBranch(), opts); err != nil {
+		if gitprovider.IsRepositoryPending(err) {
+			return err
+		}
+		return g.deleteUnfinishedRepository(ctx, orgName, repoName, err)
+	}
+
+	metrics.GitOpsRepoCreationSucceeded.Inc()
+	return nil
+}
+
+// deleteUnfinishedRepository deletes a new repository whose access settings failed to be applied with the given error
+func (g *GitHubClient) deleteUnfinishedRepository(ctx context.Context, orgName string, repoName string, err error) error {
+	if _, deleteErr := g.Client.Repositories.Delete(ctx, orgName, repoName); deleteErr != nil {
+		return fmt.Errorf("failed to apply the access settings of repo %s under %s: %v, and failed to delete it: %v", repoName, orgName, err, deleteErr)
+	}
+	return fmt.Errorf("failed to apply the access settings of repo %s under %s: %v", repoName, orgName, err)
+}
+
+// GetRepositoryURL returns the URL of the given repository on the configured GitHub instance
+func GetRepositoryURL(orgName string, repoName string) string {
+	return GetGitHubURLs().BaseURL + "/" + orgName + "/" + repoName
+}
+
+// applyRepositoryOptions grants teams access to a newly created repository, protects its GitOps branch and sets its topics.
+// It returns a RepositoryPendingError if the repository is generated from a template that hasn't been copied yet
+func (g *GitHubClient) applyRepositoryOptions(ctx context.Context, orgName string, repoName string, defaultBranch string, opts gitprovider.RepositoryOptions) error {
+	if opts.Template != "" {
+		if err := g.checkTemplateCopied(ctx, orgName, repoName, defaultBranch); err != nil {
+			return err
+		}
+	}
+
+	for _, team := range opts.SortedTeams() {
+		teamOpts := &github.TeamAddTeamRepoOptions{Permission: opts.TeamPermissions[team]}
+		if _, err := g.Client.Teams.AddTeamRepoBySlug(ctx, orgName, team, orgName, repoName, teamOpts); err != nil {
+			return fmt.Errorf("unable to grant team %s %s access: %v", team, opts.TeamPermissions[team], err)
+		}
+	}
+
+	if opts.ProtectedBranch != "" {
+		if defaultBranch != "" && opts.ProtectedBranch != defaultBranch {
+			// Create the GitOps branch from the initial commit on the default branch, so that it can be protected
+			branch, _, err := g.Client.Repositories.GetBranch(ctx, orgName, repoName, defaultBranch, false)
+			if err != nil {
+				return fmt.Errorf("unable to get branch %s: %v", defaultBranch, err)
+			}
+			newRef := &github.Reference{Ref: github.String("refs/heads/" + opts.ProtectedBranch), Object: &github.GitObject{SHA: branch.GetCommit().SHA}}
+			if _, _, err := g.Client.Git.CreateRef(ctx, orgName, repoName, newRef); err != nil {
+				return fmt.Errorf("unable to create branch %s: %v", opts.ProtectedBranch, err)
+			}
+		}
+		// Block force pushes and deletion of the GitOps branch, without requiring reviews or status checks for application-service's own pushes
+		protection := &github.ProtectionRequest{
+			AllowForcePushes: github.Bool(false),
+			AllowDeletions:   github.Bool(false),
+		}
+		if _, _, err := g.Client.Repositories.UpdateBranchProtection(ctx, orgName, repoName, opts.ProtectedBranch, protection); err != nil {
+			return fmt.Errorf("unable to protect branch %s: %v", opts.ProtectedBranch, err)
+		}
+	}
+
+	if topics := opts.AllTopics(); len(topics) > 0 {
+		if _, _, err := g.Client.Repositories.ReplaceAllTopics(ctx, orgName, repoName, topics); err != nil {
+			return fmt.Errorf("unable to set topics: %v", err)
+		}
+	}
+	return nil
+}
+
+// checkTemplateCopied returns a RepositoryPendingError if the given branch of a repository generated from a template doesn't exist yet.
+// Repositories are generated from templates asynchronously, and their default branch is only created once the template's files are copied
+func (g *GitHubClient) checkTemplateCopied(ctx context.Context, orgName string, repoName string, branchName string) error {
+	if branchName == "" {
+		return nil
+	}
+	_, resp, err := g.Client.Repositories.GetBranch(ctx, orgName, repoName, branchName, false)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusConflict) {
+			return &gitprovider.RepositoryPendingError{RepoURL: GetRepositoryURL(orgName, repoName)}
+		}
+		return fmt.Errorf("unable to get branch %s: %v", branchName, err)
+	}
+	return nil
+}
+
 // GetRepoNameFromURL returns the repository name from the Git repo URL
 func GetRepoNameFromURL(repoURL string, orgName string) (string, error) {
 	parts := strings.Split(repoURL, orgName+"/")
@@ -99,6 +256,11 @@ func GetRepoNameFromURL(repoURL string, orgName string) (string, error) {
 	return parts[1], nil
 }
 
+// GetRepoNameFromURL returns the repository name from the Git repo URL
+func (g *GitHubClient) GetRepoNameFromURL(repoURL string, orgName string) (string, error) {
+	return GetRepoNameFromURL(repoURL, orgName)
+}
+
 // GetRepoAndOrgFromURL returns both the github org and repository name from a given github URL
 // Format must be of the form: <github-domain>/owner/repository(.git)
 // If .git is appended to the end, it will be removed from the returned repo name
@@ -124,6 +286,11 @@ func GetRepoAndOrgFromURL(repoURL string) (string, string, error) {
 	return repoName, orgName, nil
 }
 
+// GetRepoAndOrgFromURL returns both the github org and repository name from a given github URL
+func (g *GitHubClient) GetRepoAndOrgFromURL(repoURL string) (string, string, error) {
+	return GetRepoAndOrgFromURL(repoURL)
+}
+
 // GetDefaultBranchFromURL returns the default branch of a given repoURL
 func (g *GitHubClient) GetDefaultBranchFromURL(repoURL string, ctx context.Context) (string, error) {
 	repoName, orgName, err := GetRepoAndOrgFromURL(repoURL)
@@ -154,6 +321,15 @@ func (g *GitHubClient) GetBranchFromURL(repoURL string, ctx context.Context, bra
 	return branch, nil
 }
 
+// GetBranchNameFromURL returns the name of the requested branch of a given repoURL
+func (g *GitHubClient) GetBranchNameFromURL(repoURL string, ctx context.Context, branchName string) (string, error) {
+	branch, err := g.GetBranchFromURL(repoURL, ctx, branchName)
+	if err != nil {
+		return "", err
+	}
+	return branch.GetName(), nil
+}
+
 // GetLatestCommitSHAFromRepository gets the latest Commit SHA from the repository
 func (g *GitHubClient) GetLatestCommitSHAFromRepository(ctx context.Context, repoName string, orgName string, branch string) (string, error) {
 	commitSHA, _, err := g.Client.Repositories.GetCommitSHA1(ctx, orgName, repoName, branch, "")
@@ -172,3 +348,160 @@ func (g *GitHubClient) DeleteRepository(ctx context.Context, orgName string, rep
 	}
 	return nil
 }
+
+// ListRepositories returns the repositories of the given org
+func (g *GitHubClient) ListRepositories(ctx context.Context, orgName string) ([]gitprovider.Repository, error) {
+	var repos []gitprovider.Repository
+	opts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := g.Client.Repositories.ListByOrg(ctx, orgName, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the repositories of org %s: %v", orgName, err)
+		}
+		for _, repo := range page {
+			repos = append(repos, gitprovider.Repository{
+				Name:      repo.GetName(),
+				URL:       repo.GetHTMLURL(),
+				CreatedAt: repo.GetCreatedAt().Time,
+				Archived:  repo.GetArchived(),
+				Topics:    repo.Topics,
+			})
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return repos, nil
+}
+
+// IsNotFound returns true if err is a GitHub API error for a repository (or other resource) that doesn't exist
+func IsNotFound(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// ArchiveRepository archives the given repository under the given org, making it read-only while keeping its history.
+// It succeeds if the repository is already archived, as GitHub rejects any edit of an archived repository.
+func (g *GitHubClient) ArchiveRepository(ctx context.Context, orgName string, repoName string) error {
+	repo, _, err := g.Client.Repositories.Get(ctx, orgName, repoName)
+	if err != nil {
+		return err
+	}
+	if repo.GetArchived() {
+		return nil
+	}
+	_, _, err = g.Client.Repositories.Edit(ctx, orgName, repoName, &github.Repository{Archived: github.Bool(true)})
+	return err
+}
+
+// CreateOrUpdatePullRequest opens a pull request from the head branch to the base branch of the given repository, or updates the title
+// and body of the open pull request between them. It returns nil if the head branch has no commits that aren't on the base branch.
+func (g *GitHubClient) CreateOrUpdatePullRequest(ctx context.Context, orgName string, repoName string, opts gitprovider.PullRequestOptions) (*gitprovider.PullRequest, error) {
+	comparison, _, err := g.Client.Repositories.CompareCommits(ctx, orgName, repoName, opts.BaseBranch, opts.HeadBranch, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare branch %s with %s in repo %s under %s: %v", opts.HeadBranch, opts.BaseBranch, repoName, orgName, err)
+	}
+	if comparison.GetAheadBy() == 0 {
+		return nil, nil
+	}
+
+	pullRequests, _, err := g.Client.PullRequests.List(ctx, orgName, repoName, &github.PullRequestListOptions{
+		State: "open",
+		Head:  orgName + ":" + opts.HeadBranch,
+		Base:  opts.BaseBranch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the pull requests of repo %s under %s: %v", repoName, orgName, err)
+	}
+
+	var pullRequest *github.PullRequest
+	if len(pullRequests) > 0 {
+		pullRequest, _, err = g.Client.PullRequests.Edit(ctx, orgName, repoName, pullRequests[0].GetNumber(), &github.PullRequest{
+			Title: github.String(opts.Title),
+			Body:  github.String(opts.Body),
+		})
+	} else {
+		pullRequest, _, err = g.Client.PullRequests.Create(ctx, orgName, repoName, &github.NewPullRequest{
+			Title: github.String(opts.Title),
+			Body:  github.String(opts.Body),
+			Head:  github.String(opts.HeadBranch),
+			Base:  github.String(opts.BaseBranch),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return convertPullRequest(pullRequest), nil
+}
+
+// GetPullRequest returns the pull request with the given number of the given repository
+func (g *GitHubClient) GetPullRequest(ctx context.Context, orgName string, repoName string, number int) (*gitprovider.PullRequest, error) {
+	pullRequest, _, err := g.Client.PullRequests.Get(ctx, orgName, repoName, number)
+	if err != nil {
+		return nil, err
+	}
+	return convertPullRequest(pullRequest), nil
+}
+
+// ResetBranch points the given branch of the repository at the latest commit of the base branch, creating the branch if it doesn't exist
+func (g *GitHubClient) ResetBranch(ctx context.Context, orgName string, repoName string, branchName string, baseBranch string) error {
+	base, _, err := g.Client.Repositories.GetBranch(ctx, orgName, repoName, baseBranch, false)
+	if err != nil {
+		return fmt.Errorf("unable to get branch %s of repo %s under %s: %v", baseBranch, repoName, orgName, err)
+	}
+	ref := &github.Reference{Ref: github.String("refs/heads/" + branchName), Object: &github.GitObject{SHA: base.GetCommit().SHA}}
+	_, resp, err := g.Client.Git.UpdateRef(ctx, orgName, repoName, ref, true)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+		// The branch doesn't exist yet
+		_, _, err = g.Client.Git.CreateRef(ctx, orgName, repoName, ref)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to reset branch %s of repo %s under %s to %s: %v", branchName, repoName, orgName, baseBranch, err)
+	}
+	return nil
+}
+
+func convertPullRequest(pullRequest *github.PullRequest) *gitprovider.PullRequest {
+	converted := &gitprovider.PullRequest{
+		Number: pullRequest.GetNumber(),
+		URL:    pullRequest.GetHTMLURL(),
+		State:  gitprovider.PullRequestStateOpen,
+	}
+	if pullRequest.GetMerged() || pullRequest.MergedAt != nil {
+		converted.State = gitprovider.PullRequestStateMerged
+		converted.MergeCommitSHA = pullRequest.GetMergeCommitSHA()
+	} else if pullRequest.GetState() == "closed" {
+		converted.State = gitprovider.PullRequestStateClosed
+	}
+	return converted
+}
+
+// GetTokenName returns the name of the GitHub token used to initialize the client
+func (g *GitHubClient) GetTokenName() string {
+	return g.TokenName
+}
+
+// GetToken returns the GitHub token used to initialize the client
+func (g *GitHubClient) GetToken() string {
+	return g.Token
+}
+
+// HostsRepository returns true if the given repository URL is on the GitHub instance of the client: github.com, or the
+// GitHub Enterprise host that the client's API is served from
+func (g *GitHubClient) HostsRepository(repoURL string) bool {
+	parsedURL, err := url.Parse(repoURL)
+	if err != nil {
+		return false
+	}
+	apiHost := "api.github.com"
+	if g.Client != nil && g.Client.BaseURL != nil {
+		apiHost = g.Client.BaseURL.Host
+	}
+	return strings.EqualFold(parsedURL.Host, strings.TrimPrefix(apiHost, "api."))
+}
+
+// GetRemoteCredentials returns the credentials for git remotes on GitHub, which take the token as the username
+func (g *GitHubClient) GetRemoteCredentials() *url.Userinfo {
+	return url.User(g.Token)
+}
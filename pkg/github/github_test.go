@@ -17,12 +17,17 @@ package github
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-github/v52/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	"github.com/redhat-appstudio/application-service/pkg/metrics"
 	"github.com/redhat-appstudio/application-service/pkg/util"
 	"github.com/stretchr/testify/assert"
@@ -162,7 +167,7 @@ func TestGenerateNewRepository(t *testing.T) {
 		Clients["mock"].SecondaryRateLimit.mu.Lock()
 
 		t.Run(tt.name, func(t *testing.T) {
-			repoURL, err := mockedClient.GenerateNewRepository(tt.ctx, tt.orgName, tt.repoName, "")
+			repoURL, err := mockedClient.GenerateNewRepository(tt.ctx, tt.orgName, tt.repoName, "", gitprovider.RepositoryOptions{})
 
 			if err != nil && tt.wantErr {
 				if _, ok := err.(*ServerError); ok {
@@ -244,6 +249,40 @@ func TestDeleteRepository(t *testing.T) {
 	}
 }
 
+func TestArchiveRepository(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoName string
+		orgName  string
+		wantErr  bool
+	}{
+		{
+			name:     "Simple repo name",
+			repoName: "test-repo-1",
+			orgName:  "redhat-appstudio-appdata",
+		},
+		{
+			name:     "Error archiving repo",
+			repoName: "test-error-response",
+			orgName:  "redhat-appstudio-appdata",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		mockedClient := GitHubClient{
+			Client: GetMockedClient(),
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := mockedClient.ArchiveRepository(context.Background(), tt.orgName, tt.repoName)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestArchiveRepository() unexpected error value: %v", err)
+			}
+		})
+	}
+}
+
 func TestGetRepoNameFromURL(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -516,3 +555,463 @@ func TestGetBranchFromURL(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateNewRepositoryWithOptions(t *testing.T) {
+	var requests []string
+	var createRequest, editRequest map[string]interface{}
+	var topics []string
+	// The branch of a repository generated from a template is missing until the template is copied
+	branchMissing := false
+	recordRequest := func(req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.PostOrgsReposByOrg,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				/* #nosec G104 -- test code */
+				json.NewDecoder(req.Body).Decode(&createRequest)
+				w.Write(mock.MustMarshal(github.Repository{Name: github.String("test-repo"), DefaultBranch: github.String("main")}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostReposGenerateByTemplateOwnerByTemplateRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				/* #nosec G104 -- test code */
+				json.NewDecoder(req.Body).Decode(&createRequest)
+				w.Write(mock.MustMarshal(github.Repository{Name: github.String("test-repo"), DefaultBranch: github.String("main")}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PatchReposByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				/* #nosec G104 -- test code */
+				json.NewDecoder(req.Body).Decode(&editRequest)
+				w.Write(mock.MustMarshal(github.Repository{Name: github.String("test-repo")}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PutOrgsTeamsReposByOrgByTeamSlugByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				if strings.Contains(req.URL.Path, "missing-team") {
+					WriteError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposBranchesByOwnerByRepoByBranch,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				if branchMissing {
+					branchMissing = false
+					WriteError(w, http.StatusNotFound, "Branch not found")
+					return
+				}
+				w.Write(mock.MustMarshal(github.Branch{Name: github.String("main"), Commit: &github.RepositoryCommit{SHA: github.String("ca82a6dff817ec66f44342007202690a93763949")}}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				w.Write(mock.MustMarshal(github.Repository{Name: github.String("test-repo"), DefaultBranch: github.String("main")}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostReposGitRefsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				w.Write(mock.MustMarshal(github.Reference{Ref: github.String("refs/heads/gitops")}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PutReposBranchesProtectionByOwnerByRepoByBranch,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				w.Write(mock.MustMarshal(github.Protection{}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.PutReposTopicsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				var body struct {
+					Names []string `json:"names"`
+				}
+				/* #nosec G104 -- test code */
+				json.NewDecoder(req.Body).Decode(&body)
+				topics = body.Names
+				w.Write(mock.MustMarshal(body))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.DeleteReposByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				recordRequest(req)
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		),
+	)
+	mockedClient := GitHubClient{Client: github.NewClient(mockedHTTPClient), TokenName: "mock"}
+
+	tests := []struct {
+		name         string
+		opts         gitprovider.RepositoryOptions
+		wantErr      bool
+		wantPending  bool
+		wantPrivate  bool
+		wantInternal bool
+		wantAutoInit bool
+		wantRequests []string
+		wantTopics   []string
+		wantEdit     map[string]interface{}
+	}{
+		{
+			name:         "Public repository, no access settings",
+			opts:         gitprovider.RepositoryOptions{},
+			wantRequests: []string{"POST /orgs/test-org/repos"},
+		},
+		{
+			name: "Private repository with team access, a protected default branch and topics",
+			opts: gitprovider.RepositoryOptions{
+				Visibility:      gitprovider.VisibilityPrivate,
+				TeamPermissions: map[string]string{"devs": gitprovider.PermissionPush, "admins": gitprovider.PermissionAdmin},
+				ProtectedBranch: "main",
+				Topics:          []string{"gitops", "appstudio"},
+			},
+			wantPrivate:  true,
+			wantAutoInit: true,
+			wantRequests: []string{
+				"POST /orgs/test-org/repos",
+				"PUT /orgs/test-org/teams/admins/repos/test-org/test-repo",
+				"PUT /orgs/test-org/teams/devs/repos/test-org/test-repo",
+				"PUT /repos/test-org/test-repo/branches/main/protection",
+				"PUT /repos/test-org/test-repo/topics",
+			},
+			wantTopics: []string{"gitops", "appstudio"},
+		},
+		{
+			name:         "Internal repository with a protected branch that isn't the default branch",
+			opts:         gitprovider.RepositoryOptions{Visibility: gitprovider.VisibilityInternal, ProtectedBranch: "gitops"},
+			wantPrivate:  true,
+			wantInternal: true,
+			wantAutoInit: true,
+			wantRequests: []string{
+				"POST /orgs/test-org/repos",
+				"GET /repos/test-org/test-repo/branches/main",
+				"POST /repos/test-org/test-repo/git/refs",
+				"PUT /repos/test-org/test-repo/branches/gitops/protection",
+			},
+		},
+		{
+			name:        "Private repository generated from a template, with a protected branch and topics",
+			opts:        gitprovider.RepositoryOptions{Visibility: gitprovider.VisibilityPrivate, ProtectedBranch: "main", Topics: []string{"gitops"}, Template: "templates/gitops-template"},
+			wantPrivate: true,
+			wantRequests: []string{
+				"POST /repos/templates/gitops-template/generate",
+				"GET /repos/test-org/test-repo/branches/main",
+				"PUT /repos/test-org/test-repo/branches/main/protection",
+				"PUT /repos/test-org/test-repo/topics",
+			},
+			wantTopics: []string{"gitops"},
+		},
+		{
+			name:        "Repository generated from a template, finished once the template is copied",
+			opts:        gitprovider.RepositoryOptions{Visibility: gitprovider.VisibilityPrivate, Topics: []string{"gitops"}, Template: "templates/gitops-template"},
+			wantPending: true,
+			wantPrivate: true,
+			wantRequests: []string{
+				"POST /repos/templates/gitops-template/generate",
+				"GET /repos/test-org/test-repo/branches/main",
+				"GET /repos/test-org/test-repo",
+				"GET /repos/test-org/test-repo/branches/main",
+				"PUT /repos/test-org/test-repo/topics",
+			},
+			wantTopics: []string{"gitops"},
+		},
+		{
+			name:        "Internal repository generated from a template",
+			opts:        gitprovider.RepositoryOptions{Visibility: gitprovider.VisibilityInternal, Template: "templates/gitops-template"},
+			wantPrivate: true,
+			wantRequests: []string{
+				"POST /repos/templates/gitops-template/generate",
+				"PATCH /repos/test-org/test-repo",
+				"GET /repos/test-org/test-repo/branches/main",
+			},
+			wantEdit: map[string]interface{}{"visibility": "internal"},
+		},
+		{
+			name:        "Team does not exist, the repository is deleted",
+			opts:        gitprovider.RepositoryOptions{Visibility: gitprovider.VisibilityPrivate, TeamPermissions: map[string]string{"missing-team": gitprovider.PermissionPull}},
+			wantErr:     true,
+			wantPrivate: true,
+			wantRequests: []string{
+				"POST /orgs/test-org/repos",
+				"PUT /orgs/test-org/teams/missing-team/repos/test-org/test-repo",
+				"DELETE /repos/test-org/test-repo",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, createRequest, editRequest, topics, branchMissing = nil, nil, nil, nil, tt.wantPending
+			ctx := context.WithValue(context.Background(), GHClientKey, "mock")
+			repoURL, err := mockedClient.GenerateNewRepository(ctx, "test-org", "test-repo", "GitOps Repository", tt.opts)
+			if tt.wantPending {
+				// The repository is returned before the template is copied, and its access settings are applied once it's copied
+				assert.True(t, gitprovider.IsRepositoryPending(err))
+				err = mockedClient.FinishNewRepository(ctx, "test-org", "test-repo", tt.opts)
+			}
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "https://github.com/test-org/test-repo", repoURL)
+			}
+			assert.Equal(t, tt.wantRequests, requests)
+			assert.Equal(t, tt.wantPrivate, createRequest["private"])
+			if tt.wantInternal {
+				assert.Equal(t, gitprovider.VisibilityInternal, createRequest["visibility"])
+			} else {
+				assert.Nil(t, createRequest["visibility"])
+			}
+			if tt.wantAutoInit {
+				assert.Equal(t, true, createRequest["auto_init"])
+			} else {
+				assert.Nil(t, createRequest["auto_init"])
+			}
+			assert.Equal(t, tt.wantTopics, topics)
+			assert.Equal(t, tt.wantEdit, editRequest)
+			if tt.opts.Template != "" {
+				assert.Equal(t, "test-org", createRequest["owner"])
+				assert.Equal(t, "test-repo", createRequest["name"])
+			}
+		})
+	}
+}
+
+func TestIsGeneratedRepositoryName(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoName string
+		want     bool
+	}{
+		{
+			name:     "Generated repository name",
+			repoName: GenerateNewRepositoryName("Test Application", util.GenerateUniqueHashForWorkloadImageTag("test-namespace")),
+			want:     true,
+		},
+		{
+			name:     "Hash with URL-safe base64 characters",
+			repoName: "petclinic-a_B-9-run-jump",
+			want:     true,
+		},
+		{
+			name:     "Hand named repository",
+			repoName: "gitops-template",
+		},
+		{
+			name:     "Repository name with a hash that is too short",
+			repoName: "petclinic-abc-run-jump",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGeneratedRepositoryName(tt.repoName); got != tt.want {
+				t.Errorf("TestIsGeneratedRepositoryName() error: expected %v got %v for %v", tt.want, got, tt.repoName)
+			}
+		})
+	}
+}
+
+func TestListRepositories(t *testing.T) {
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchPages(
+			mock.GetOrgsReposByOrg,
+			[]github.Repository{
+				{Name: github.String("test-repo-1"), HTMLURL: github.String("https://github.com/test-org/test-repo-1"), CreatedAt: &github.Timestamp{Time: createdAt}, Topics: []string{"has-cluster-a"}},
+			},
+			[]github.Repository{
+				{Name: github.String("test-repo-2"), HTMLURL: github.String("https://github.com/test-org/test-repo-2"), CreatedAt: &github.Timestamp{Time: createdAt}, Archived: github.Bool(true)},
+			},
+		),
+	)
+	mockedClient := GitHubClient{Client: github.NewClient(mockedHTTPClient)}
+
+	repos, err := mockedClient.ListRepositories(context.Background(), "test-org")
+	assert.NoError(t, err)
+	assert.Equal(t, []gitprovider.Repository{
+		{Name: "test-repo-1", URL: "https://github.com/test-org/test-repo-1", CreatedAt: createdAt, Topics: []string{"has-cluster-a"}},
+		{Name: "test-repo-2", URL: "https://github.com/test-org/test-repo-2", CreatedAt: createdAt, Archived: true},
+	}, repos)
+}
+
+func TestCreateOrUpdatePullRequest(t *testing.T) {
+	opts := gitprovider.PullRequestOptions{Title: "Update GitOps resources", Body: "body", HeadBranch: "work-branch", BaseBranch: "main"}
+	openPullRequest := github.PullRequest{Number: github.Int(2), HTMLURL: github.String("https://github.com/test-org/test-repo/pull/2"), State: github.String("open")}
+
+	tests := []struct {
+		name             string
+		aheadBy          int
+		openPullRequests []github.PullRequest
+		want             *gitprovider.PullRequest
+	}{
+		{
+			name:    "No changes on the work branch, no pull request is opened",
+			aheadBy: 0,
+		},
+		{
+			name:    "No open pull request, a pull request is opened",
+			aheadBy: 1,
+			want:    &gitprovider.PullRequest{Number: 1, URL: "https://github.com/test-org/test-repo/pull/1", State: gitprovider.PullRequestStateOpen},
+		},
+		{
+			name:             "Open pull request, the pull request is updated",
+			aheadBy:          2,
+			openPullRequests: []github.PullRequest{openPullRequest},
+			want:             &gitprovider.PullRequest{Number: 2, URL: "https://github.com/test-org/test-repo/pull/2", State: gitprovider.PullRequestStateOpen},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedHTTPClient := mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(
+					mock.GetReposCompareByOwnerByRepoByBasehead,
+					github.CommitsComparison{AheadBy: github.Int(tt.aheadBy)},
+				),
+				mock.WithRequestMatch(
+					mock.GetReposPullsByOwnerByRepo,
+					tt.openPullRequests,
+				),
+				mock.WithRequestMatch(
+					mock.PostReposPullsByOwnerByRepo,
+					github.PullRequest{Number: github.Int(1), HTMLURL: github.String("https://github.com/test-org/test-repo/pull/1"), State: github.String("open")},
+				),
+				mock.WithRequestMatch(
+					mock.PatchReposPullsByOwnerByRepoByPullNumber,
+					openPullRequest,
+				),
+			)
+			mockedClient := GitHubClient{Client: github.NewClient(mockedHTTPClient)}
+
+			pullRequest, err := mockedClient.CreateOrUpdatePullRequest(context.Background(), "test-org", "test-repo", opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, pullRequest)
+		})
+	}
+}
+
+func TestGetPullRequest(t *testing.T) {
+	mergedAt := github.Timestamp{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name        string
+		pullRequest github.PullRequest
+		want        *gitprovider.PullRequest
+	}{
+		{
+			name:        "Open pull request",
+			pullRequest: github.PullRequest{Number: github.Int(1), HTMLURL: github.String("https://github.com/test-org/test-repo/pull/1"), State: github.String("open")},
+			want:        &gitprovider.PullRequest{Number: 1, URL: "https://github.com/test-org/test-repo/pull/1", State: gitprovider.PullRequestStateOpen},
+		},
+		{
+			name:        "Merged pull request",
+			pullRequest: github.PullRequest{Number: github.Int(1), HTMLURL: github.String("https://github.com/test-org/test-repo/pull/1"), State: github.String("closed"), MergedAt: &mergedAt, MergeCommitSHA: github.String("ca82a6dff817ec66f44342007202690a93763949")},
+			want:        &gitprovider.PullRequest{Number: 1, URL: "https://github.com/test-org/test-repo/pull/1", State: gitprovider.PullRequestStateMerged, MergeCommitSHA: "ca82a6dff817ec66f44342007202690a93763949"},
+		},
+		{
+			name:        "Closed pull request",
+			pullRequest: github.PullRequest{Number: github.Int(1), HTMLURL: github.String("https://github.com/test-org/test-repo/pull/1"), State: github.String("closed"), MergeCommitSHA: github.String("ca82a6dff817ec66f44342007202690a93763949")},
+			want:        &gitprovider.PullRequest{Number: 1, URL: "https://github.com/test-org/test-repo/pull/1", State: gitprovider.PullRequestStateClosed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedHTTPClient := mock.NewMockedHTTPClient(
+				mock.WithRequestMatch(
+					mock.GetReposPullsByOwnerByRepoByPullNumber,
+					tt.pullRequest,
+				),
+			)
+			mockedClient := GitHubClient{Client: github.NewClient(mockedHTTPClient)}
+
+			pullRequest, err := mockedClient.GetPullRequest(context.Background(), "test-org", "test-repo", 1)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, pullRequest)
+		})
+	}
+}
+
+func TestResetBranch(t *testing.T) {
+	tests := []struct {
+		name         string
+		branchExists bool
+		wantRequests []string
+	}{
+		{
+			name:         "Existing work branch is force-updated to the base branch",
+			branchExists: true,
+			wantRequests: []string{"GET /repos/test-org/test-repo/branches/main", "PATCH /repos/test-org/test-repo/git/refs/heads/work-branch"},
+		},
+		{
+			name:         "Missing work branch is created from the base branch",
+			wantRequests: []string{"GET /repos/test-org/test-repo/branches/main", "PATCH /repos/test-org/test-repo/git/refs/heads/work-branch", "POST /repos/test-org/test-repo/git/refs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			var refRequest map[string]interface{}
+			mockedHTTPClient := mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.GetReposBranchesByOwnerByRepoByBranch,
+					http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+						requests = append(requests, req.Method+" "+req.URL.Path)
+						/* #nosec G104 -- test code */
+						w.Write(mock.MustMarshal(github.Branch{Name: github.String("main"), Commit: &github.RepositoryCommit{SHA: github.String("ca82a6dff817ec66f44342007202690a93763949")}}))
+					}),
+				),
+				mock.WithRequestMatchHandler(
+					mock.PatchReposGitRefsByOwnerByRepoByRef,
+					http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+						requests = append(requests, req.Method+" "+req.URL.Path)
+						if !tt.branchExists {
+							WriteError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+							return
+						}
+						/* #nosec G104 -- test code */
+						json.NewDecoder(req.Body).Decode(&refRequest)
+						w.Write(mock.MustMarshal(github.Reference{Ref: github.String("refs/heads/work-branch")}))
+					}),
+				),
+				mock.WithRequestMatchHandler(
+					mock.PostReposGitRefsByOwnerByRepo,
+					http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+						requests = append(requests, req.Method+" "+req.URL.Path)
+						/* #nosec G104 -- test code */
+						json.NewDecoder(req.Body).Decode(&refRequest)
+						w.Write(mock.MustMarshal(github.Reference{Ref: github.String("refs/heads/work-branch")}))
+					}),
+				),
+			)
+			mockedClient := GitHubClient{Client: github.NewClient(mockedHTTPClient)}
+
+			err := mockedClient.ResetBranch(context.Background(), "test-org", "test-repo", "work-branch", "main")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRequests, requests)
+			assert.Equal(t, "ca82a6dff817ec66f44342007202690a93763949", refRequest["sha"])
+		})
+	}
+}
@@ -80,6 +80,23 @@ func GetMockedClient() *github.Client {
 				}))
 			}),
 		),
+		mock.WithRequestMatchHandler(
+			mock.PatchReposByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if strings.Contains(req.RequestURI, "test-error-response") {
+					mock.WriteError(w,
+						http.StatusInternalServerError,
+						"github went belly up or something",
+					)
+					return
+				}
+				/* #nosec G104 -- test code */
+				w.Write(mock.MustMarshal(github.Repository{
+					Name:     github.String("test-repo-1"),
+					Archived: github.Bool(true),
+				}))
+			}),
+		),
 		mock.WithRequestMatchHandler(
 			mock.GetRateLimit,
 			http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -0,0 +1,203 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+)
+
+const (
+	// Tokens with less than this many remaining core or search requests are considered to be primary rate limited
+	coreRateLimitThreshold   = 10
+	searchRateLimitThreshold = 2
+
+	headerRateLimit     = "X-RateLimit-Limit"
+	headerRateRemaining = "X-RateLimit-Remaining"
+	headerRateReset     = "X-RateLimit-Reset"
+	headerRateResource  = "X-RateLimit-Resource"
+)
+
+// TokensExhaustedError is returned when every token in the token pool has been rate limited
+type TokensExhaustedError struct {
+	// ResetTime is the earliest time at which one of the tokens' rate limit resets. It is zero if the reset time is unknown
+	ResetTime time.Time
+}
+
+func (e *TokensExhaustedError) Error() string {
+	if e.ResetTime.IsZero() {
+		return "all GitHub tokens have been rate limited"
+	}
+	return fmt.Sprintf("all GitHub tokens have been rate limited until %v", e.ResetTime.UTC().Format(time.RFC3339))
+}
+
+// GetRateLimitResetTime returns true if err was caused by a GitHub rate limit, either reported by the GitHub API (a primary or
+// secondary rate limit), or by the token pool when every token is rate limited. It also returns the time at which the rate limit
+// is lifted, which is zero if it isn't known.
+func GetRateLimitResetTime(err error) (time.Time, bool) {
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	var tokensExhaustedErr *TokensExhaustedError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		return rateLimitErr.Rate.Reset.Time, true
+	case errors.As(err, &abuseRateLimitErr):
+		if abuseRateLimitErr.RetryAfter != nil {
+			return time.Now().Add(*abuseRateLimitErr.RetryAfter), true
+		}
+		return time.Time{}, true
+	case errors.As(err, &tokensExhaustedErr):
+		return tokensExhaustedErr.ResetTime, true
+	}
+	return time.Time{}, false
+}
+
+// rateLimitCache caches the primary rate limit state of a token, as last reported by the GitHub API
+type rateLimitCache struct {
+	mu     sync.Mutex
+	core   *github.Rate
+	search *github.Rate
+
+	// fetchMu serializes the queries of the rate limit API, so that concurrent selections of an unused token query it only once
+	fetchMu sync.Mutex
+}
+
+// set replaces the cached rate limits. Nil rates are left unchanged
+func (c *rateLimitCache) set(core *github.Rate, search *github.Rate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if core != nil {
+		coreCopy := *core
+		c.core = &coreCopy
+	}
+	if search != nil {
+		searchCopy := *search
+		c.search = &searchCopy
+	}
+}
+
+// get returns copies of the cached rate limits, with the remaining requests restored for any rate limit whose reset time has passed
+func (c *rateLimitCache) get(now time.Time) (*github.Rate, *github.Rate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return currentRate(c.core, now), currentRate(c.search, now)
+}
+
+func currentRate(rate *github.Rate, now time.Time) *github.Rate {
+	if rate == nil {
+		return nil
+	}
+	rateCopy := *rate
+	if !rateCopy.Reset.IsZero() && now.After(rateCopy.Reset.Time) {
+		rateCopy.Remaining = rateCopy.Limit
+	}
+	return &rateCopy
+}
+
+// updateFromHeaders caches the rate limit reported in the headers of a GitHub API response
+func (c *rateLimitCache) updateFromHeaders(header http.Header) {
+	limit, err := strconv.Atoi(header.Get(headerRateLimit))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(header.Get(headerRateRemaining))
+	if err != nil {
+		return
+	}
+	rate := &github.Rate{Limit: limit, Remaining: remaining}
+	if reset, err := strconv.ParseInt(header.Get(headerRateReset), 10, 64); err == nil {
+		rate.Reset = github.Timestamp{Time: time.Unix(reset, 0)}
+	}
+
+	switch header.Get(headerRateResource) {
+	case "", "core":
+		c.set(rate, nil)
+	case "search":
+		c.set(nil, rate)
+	}
+}
+
+// rateLimitTransport records the rate limit headers of every GitHub API response, so that tokens can be
+// scheduled based on their remaining quota without querying the rate limit API
+type rateLimitTransport struct {
+	base  http.RoundTripper
+	cache *rateLimitCache
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp != nil && t.cache != nil {
+		t.cache.updateFromHeaders(resp.Header)
+	}
+	return resp, err
+}
+
+// getRateLimits returns the token's primary rate limits. The rate limit API is only queried if no rate limit has been cached for the token yet
+func (g *GitHubClient) getRateLimits(ctx context.Context, now time.Time) (*github.Rate, *github.Rate, error) {
+	core, search := g.rateLimits.get(now)
+	if core != nil {
+		return core, search, nil
+	}
+
+	// Another selection may have queried the rate limit API while this one waited for it
+	g.rateLimits.fetchMu.Lock()
+	defer g.rateLimits.fetchMu.Unlock()
+	if core, search = g.rateLimits.get(now); core != nil {
+		return core, search, nil
+	}
+	rl, _, err := g.Client.RateLimits(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rl != nil {
+		g.rateLimits.set(rl.Core, rl.Search)
+	}
+	core, search = g.rateLimits.get(now)
+	return core, search, nil
+}
+
+// isPrimaryRateLimited returns true if the token has too few remaining core or search requests, along with the time at which that rate limit resets
+func isPrimaryRateLimited(core *github.Rate, search *github.Rate) (bool, time.Time) {
+	if core != nil && core.Remaining < coreRateLimitThreshold {
+		return true, core.Reset.Time
+	}
+	if search != nil && search.Remaining < searchRateLimitThreshold {
+		return true, search.Reset.Time
+	}
+	return false, time.Time{}
+}
+
+// limitedUntil returns true if the secondary rate limit has been reached, along with the time at which it is lifted
+func (s *SecondaryRateLimit) limitedUntil() (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isLimitReached, s.resetTime
+}
+
+// earliest returns the earlier of two times, ignoring zero times
+func earliest(a time.Time, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,183 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v52/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCachedClient returns a GitHub client whose rate limit has already been cached, so that selecting it doesn't query the GitHub API
+func newCachedClient(tokenName string, remaining int, reset time.Time, weight int) *GitHubClient {
+	ghClient := &GitHubClient{TokenName: tokenName, Token: tokenName, weight: weight}
+	ghClient.rateLimits.set(&github.Rate{Limit: 5000, Remaining: remaining, Reset: github.Timestamp{Time: reset}}, nil)
+	return ghClient
+}
+
+func TestSelectClientQuota(t *testing.T) {
+	now := time.Now()
+	inAnHour := now.Add(time.Hour).Truncate(time.Second)
+	inTenMinutes := now.Add(10 * time.Minute).Truncate(time.Second)
+
+	secondaryRateLimited := newCachedClient("quota-secondary", 5000, inAnHour, 0)
+	secondaryRateLimited.SecondaryRateLimit.isLimitReached = true
+	secondaryRateLimited.SecondaryRateLimit.resetTime = now.Add(time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name          string
+		clientPool    map[string]*GitHubClient
+		wantTokenName string
+		wantResetTime time.Time
+		wantExhausted bool
+	}{
+		{
+			name: "Token with the most remaining quota is selected",
+			clientPool: map[string]*GitHubClient{
+				"quota-low":  newCachedClient("quota-low", 100, inAnHour, 0),
+				"quota-high": newCachedClient("quota-high", 4000, inAnHour, 0),
+			},
+			wantTokenName: "quota-high",
+		},
+		{
+			name: "Remaining quota is scaled by the token weight",
+			clientPool: map[string]*GitHubClient{
+				"quota-weighted": newCachedClient("quota-weighted", 1000, inAnHour, 5),
+				"quota-high":     newCachedClient("quota-high", 4000, inAnHour, 0),
+			},
+			wantTokenName: "quota-weighted",
+		},
+		{
+			name: "Token whose rate limit has reset is selected",
+			clientPool: map[string]*GitHubClient{
+				"quota-reset": newCachedClient("quota-reset", 0, now.Add(-time.Minute), 0),
+				"quota-low":   newCachedClient("quota-low", 100, inAnHour, 0),
+			},
+			wantTokenName: "quota-reset",
+		},
+		{
+			name: "Rate limited tokens are skipped",
+			clientPool: map[string]*GitHubClient{
+				"quota-exhausted": newCachedClient("quota-exhausted", 5, inAnHour, 10),
+				"quota-secondary": secondaryRateLimited,
+				"quota-low":       newCachedClient("quota-low", 100, inAnHour, 0),
+			},
+			wantTokenName: "quota-low",
+		},
+		{
+			name: "All tokens primary rate limited, earliest reset time is returned",
+			clientPool: map[string]*GitHubClient{
+				"quota-exhausted":        newCachedClient("quota-exhausted", 5, inAnHour, 0),
+				"quota-exhausted-sooner": newCachedClient("quota-exhausted-sooner", 0, inTenMinutes, 0),
+			},
+			wantExhausted: true,
+			wantResetTime: inTenMinutes,
+		},
+		{
+			name: "All tokens primary or secondary rate limited, earliest reset time is returned",
+			clientPool: map[string]*GitHubClient{
+				"quota-exhausted": newCachedClient("quota-exhausted", 5, inAnHour, 0),
+				"quota-secondary": secondaryRateLimited,
+			},
+			wantExhausted: true,
+			wantResetTime: secondaryRateLimited.SecondaryRateLimit.resetTime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ghClient, err := selectClient(tt.clientPool)
+			if tt.wantExhausted {
+				exhaustedErr, ok := err.(*TokensExhaustedError)
+				if !ok {
+					t.Fatalf("TestSelectClientQuota() error: expected a TokensExhaustedError, got %v", err)
+				}
+				if !exhaustedErr.ResetTime.Equal(tt.wantResetTime) {
+					t.Errorf("TestSelectClientQuota() error: expected reset time %v got %v", tt.wantResetTime, exhaustedErr.ResetTime)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestSelectClientQuota() unexpected error: %v", err)
+			}
+			if ghClient.TokenName != tt.wantTokenName {
+				t.Errorf("TestSelectClientQuota() error: expected token %v got %v", tt.wantTokenName, ghClient.TokenName)
+			}
+		})
+	}
+}
+
+func TestRateLimitCacheUpdateFromHeaders(t *testing.T) {
+	reset := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantCore   *github.Rate
+		wantSearch *github.Rate
+	}{
+		{
+			name: "Core rate limit headers",
+			header: map[string]string{
+				headerRateLimit:     "5000",
+				headerRateRemaining: "4321",
+				headerRateReset:     strconv.FormatInt(reset.Unix(), 10),
+				headerRateResource:  "core",
+			},
+			wantCore: &github.Rate{Limit: 5000, Remaining: 4321, Reset: github.Timestamp{Time: reset}},
+		},
+		{
+			name: "Search rate limit headers",
+			header: map[string]string{
+				headerRateLimit:     "30",
+				headerRateRemaining: "12",
+				headerRateReset:     strconv.FormatInt(reset.Unix(), 10),
+				headerRateResource:  "search",
+			},
+			wantSearch: &github.Rate{Limit: 30, Remaining: 12, Reset: github.Timestamp{Time: reset}},
+		},
+		{
+			name: "Rate limit of another resource is ignored",
+			header: map[string]string{
+				headerRateLimit:     "5000",
+				headerRateRemaining: "4321",
+				headerRateResource:  "graphql",
+			},
+		},
+		{
+			name:   "No rate limit headers",
+			header: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			cache := &rateLimitCache{}
+			cache.updateFromHeaders(header)
+			core, search := cache.get(time.Now())
+			assert.Equal(t, tt.wantCore, core)
+			assert.Equal(t, tt.wantSearch, search)
+		})
+	}
+}
@@ -21,14 +21,16 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redhat-appstudio/application-service/pkg/metrics"
 
 	"github.com/gofri/go-github-ratelimit/github_ratelimit"
-	"github.com/google/go-github/v52/github"
 	"golang.org/x/oauth2"
 
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -41,136 +43,306 @@ type GitHubToken interface {
 type GitHubTokenClient struct {
 }
 
-// Tokens is mapping of token names to GitHub tokens
+// Clients is a mapping of token names to GitHub clients
+// The token pool can be reloaded while in use, so outside of tests it must only be accessed through getClient, getClients and setClients
 var Clients map[string]*GitHubClient
 
+// clientsMu guards the Clients map
+var clientsMu sync.RWMutex
+
+// tokenConfig holds the raw GitHub token configuration, read from either the environment or a mounted Secret
+type tokenConfig struct {
+	authToken string
+	tokenList string
+	appConfig *GitHubAppConfig
+}
+
 // ParseGitHubTokens parses all of the possible GitHub tokens available to HAS and makes them available within the "github" package
+// Tokens are either personal access tokens, or installation tokens minted for a GitHub App (see ParseGitHubAppConfig)
+// If GITHUB_TOKEN_SECRET_PATH is set, the tokens are read from the Secret mounted at that path rather than from the environment,
+// and can be reloaded afterwards by a TokenReloader.
 // This function should *only* be called once: at operator startup.
 func ParseGitHubTokens() error {
-	githubToken := os.Getenv("GITHUB_AUTH_TOKEN")
-	githubTokenList := os.Getenv("GITHUB_TOKEN_LIST")
-	if githubToken == "" && githubTokenList == "" {
-		return fmt.Errorf("no GitHub tokens were provided. Either GITHUB_TOKEN_LIST or GITHUB_AUTH_TOKEN (legacy) must be set")
+	var config tokenConfig
+	var err error
+	if secretPath := os.Getenv("GITHUB_TOKEN_SECRET_PATH"); secretPath != "" {
+		config, err = tokenConfigFromDir(secretPath)
+	} else {
+		config, err = tokenConfigFromEnv()
+	}
+	if err != nil {
+		return err
 	}
 
-	Clients = make(map[string]*GitHubClient)
-	if githubToken != "" {
+	clients, err := buildGitHubClients(config, nil)
+	if err != nil {
+		return err
+	}
+	setClients(clients)
+	return nil
+}
+
+// tokenConfigFromEnv reads the GitHub token configuration from the GITHUB_AUTH_TOKEN, GITHUB_TOKEN_LIST and GITHUB_APP_* environment variables
+func tokenConfigFromEnv() (tokenConfig, error) {
+	appConfig, err := ParseGitHubAppConfig()
+	if err != nil {
+		return tokenConfig{}, err
+	}
+	return tokenConfig{
+		authToken: os.Getenv("GITHUB_AUTH_TOKEN"),
+		tokenList: os.Getenv("GITHUB_TOKEN_LIST"),
+		appConfig: appConfig,
+	}, nil
+}
+
+// buildGitHubClients creates a GitHub client for each of the tokens in the token configuration.
+// Clients in existing whose token is unchanged are reused as-is, so that their rate limit state is preserved.
+func buildGitHubClients(config tokenConfig, existing map[string]*GitHubClient) (map[string]*GitHubClient, error) {
+	if config.authToken == "" && config.tokenList == "" && config.appConfig == nil {
+		return nil, fmt.Errorf("no GitHub tokens were provided. Either GITHUB_TOKEN_LIST, GITHUB_AUTH_TOKEN (legacy) or GITHUB_APP_ID must be set")
+	}
+
+	clients := make(map[string]*GitHubClient)
+	if config.authToken != "" {
 		// The old token format, stored in 'GITHUB_AUTH_TOKEN', didn't require a key/'name' for the token
 		// So use the key 'GITHUB_AUTH_TOKEN' for it
-		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: githubToken})
-		tc := oauth2.NewClient(context.Background(), ts)
-		token, err := createGitHubClientFromToken(&tc.Transport, githubToken, "GITHUB_AUTH_TOKEN")
+		token, err := getOrCreateTokenClient(existing, config.authToken, "GITHUB_AUTH_TOKEN", 0)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		Clients["GITHUB_AUTH_TOKEN"] = token
+		clients["GITHUB_AUTH_TOKEN"] = token
 	}
 
 	// Parse any tokens passed in through the 'GITHUB_TOKEN_LIST' environment variable
 	// e.g. GITHUB_TOKEN_LIST=token1:ghp_faketoken,token2:ghp_anothertoken
-	//emptyGitHubClient := GitHubClient{}
-	if githubTokenList != "" {
+	// Each token may optionally be given a weight, e.g. token1:ghp_faketoken:3, to favour it when selecting tokens
+	if config.tokenList != "" {
 		// Each token key-value pair is separated by a comma, so split the string based on commas and loop over each key-value pair
-		tokenKeyValuePairs := strings.Split(githubTokenList, ",")
+		tokenKeyValuePairs := strings.Split(config.tokenList, ",")
 		for _, tokenKeyValuePair := range tokenKeyValuePairs {
 			// Each token key-value pair is separated by a colon, so split the key-value pair and
-			// If the key-value pair doesn't split cleanly (i.e. only two strings returned), return an error
+			// If the key-value pair doesn't split cleanly (i.e. only two strings, or three with a weight, returned), return an error
 			// If the key has already been added, return an error
 			splitTokenKeyValuePair := strings.Split(tokenKeyValuePair, ":")
-			if len(splitTokenKeyValuePair) != 2 {
-				return fmt.Errorf("unable to parse github token from key-value pair. Please ensure the GitHub secret is formatted correctly according to the documentation")
+			if len(splitTokenKeyValuePair) != 2 && len(splitTokenKeyValuePair) != 3 {
+				return nil, fmt.Errorf("unable to parse github token from key-value pair. Please ensure the GitHub secret is formatted correctly according to the documentation")
 			}
 			tokenKey := splitTokenKeyValuePair[0]
 			tokenValue := splitTokenKeyValuePair[1]
+			var tokenWeight int
+			if len(splitTokenKeyValuePair) == 3 {
+				weight, err := strconv.Atoi(splitTokenKeyValuePair[2])
+				if err != nil || weight < 1 {
+					return nil, fmt.Errorf("unable to parse the weight of github token '%s', it must be a positive integer", tokenKey)
+				}
+				tokenWeight = weight
+			}
 
-			if Clients[tokenKey] != nil {
-				return fmt.Errorf("a token with the key '%s' already exists. Each token must have a unique key", tokenKey)
+			if clients[tokenKey] != nil {
+				return nil, fmt.Errorf("a token with the key '%s' already exists. Each token must have a unique key", tokenKey)
 			}
 
-			ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tokenValue})
-			tc := oauth2.NewClient(context.Background(), ts)
-			token, err := createGitHubClientFromToken(&tc.Transport, tokenValue, tokenKey)
+			token, err := getOrCreateTokenClient(existing, tokenValue, tokenKey, tokenWeight)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			Clients[tokenKey] = token
+			clients[tokenKey] = token
 		}
 	}
 
-	return nil
+	// Mint installation tokens for each organization the GitHub App is installed in, if a GitHub App was configured
+	if config.appConfig != nil {
+		appClients, err := createGitHubAppClients(config.appConfig, nil, existing)
+		if err != nil {
+			return nil, err
+		}
+		for tokenName, appClient := range appClients {
+			if clients[tokenName] != nil {
+				return nil, fmt.Errorf("a token with the key '%s' already exists. Each token must have a unique key", tokenName)
+			}
+			clients[tokenName] = appClient
+		}
+	}
+
+	return clients, nil
 }
 
-// getRandomClient randomly retrieves a token from all of the tokens available to HAS
-// It returns the token and the name/key of the token
-func getRandomClient(clientPool map[string]*GitHubClient) (*GitHubClient, error) {
-	if len(clientPool) == 0 {
-		return nil, fmt.Errorf("no GitHub tokens available")
+// getOrCreateTokenClient returns the client in existing for the given token name if its token and weight are unchanged, otherwise it creates a new client for the token
+func getOrCreateTokenClient(existing map[string]*GitHubClient, ghToken string, ghTokenName string, weight int) (*GitHubClient, error) {
+	if ghClient := existing[ghTokenName]; ghClient != nil && ghClient.tokenSource == nil && ghClient.Token == ghToken && ghClient.weight == weight {
+		return ghClient, nil
 	}
-	/* #nosec G404 -- not used for cryptographic purposes*/
-	index := rand.Intn(len(clientPool))
-
-	i := 0
-	var ghClient *GitHubClient
-	for _, v := range clientPool {
-		if i == index {
-			ghClient = v
-		}
-		i++
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken})
+	tc := oauth2.NewClient(context.Background(), ts)
+	ghClient, err := createGitHubClientFromToken(&tc.Transport, ghToken, ghTokenName)
+	if err != nil {
+		return nil, err
 	}
+	ghClient.weight = weight
+	return ghClient, nil
+}
 
-	// Check the Primary rate limit
-	rl, _, err := ghClient.Client.RateLimits(context.Background())
+// reloadGitHubTokens rebuilds the token pool from the token configuration, and atomically replaces the current pool with it.
+// Clients for unchanged tokens are carried over with their rate limit state. Removed clients are retired: they are no longer
+// handed out, but requests already in flight with them are left to complete.
+// It returns the names of the tokens that were added and removed.
+func reloadGitHubTokens(config tokenConfig) ([]string, []string, error) {
+	existing := getClients()
+	clients, err := buildGitHubClients(config, existing)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	setClients(clients)
 
-	prlTokenMetric := metrics.TokenPoolGauge.With(prometheus.Labels{"rateLimited": "primary", "tokenName": ghClient.TokenName})
-	if rl != nil && (rl.Core != nil && rl.Core.Remaining < 10) || (rl.Search != nil && rl.Search.Remaining < 2) {
-		newClientPool := make(map[string]*GitHubClient)
-		for k, v := range clientPool {
-			if k != ghClient.TokenName {
-				newClientPool[k] = v
-			}
+	var added, removed []string
+	for tokenName := range clients {
+		if existing[tokenName] != clients[tokenName] {
+			added = append(added, tokenName)
 		}
-
-		// if token has not been previously rate limited, increment the metric
-		if !ghClient.PrimaryRateLimited {
-			prlTokenMetric.Inc()
-			ghClient.PrimaryRateLimited = true
+	}
+	for tokenName, ghClient := range existing {
+		if clients[tokenName] == ghClient {
+			continue
 		}
+		removed = append(removed, tokenName)
+		// A retired token is no longer part of the pool, so it should no longer count towards the rate limited tokens.
+		// Secondary rate limits clear themselves once the rate limit callback's sleep completes.
+		ghClient.setPrimaryRateLimited(false)
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed, nil
+}
 
-		return getRandomClient(newClientPool)
+// getClient returns the client for the given token name from the token pool, or nil if no such token exists
+func getClient(tokenName string) *GitHubClient {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	return Clients[tokenName]
+}
+
+// getClients returns a copy of the token pool, that is safe to iterate over while the pool is being reloaded
+func getClients() map[string]*GitHubClient {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	clients := make(map[string]*GitHubClient, len(Clients))
+	for k, v := range Clients {
+		clients[k] = v
 	}
+	return clients
+}
 
-	// if token has been previously rate limited, then decrement the counter and reset the boolean
-	if ghClient.PrimaryRateLimited {
-		prlTokenMetric.Dec()
-		ghClient.PrimaryRateLimited = false
+// setClients replaces the token pool
+func setClients(clients map[string]*GitHubClient) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	Clients = clients
+}
+
+// GetTokenCount returns the number of tokens in the token pool
+func GetTokenCount() int {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	return len(Clients)
+}
+
+// selectClient selects the token with the most remaining quota from the given token pool, scaled by each token's weight.
+// Rate limits are read from the headers of previous GitHub API responses, and the rate limit API is only queried for tokens
+// that haven't been used yet. Tokens that are primary or secondary rate limited are skipped, and if every token is rate limited,
+// a TokensExhaustedError with the earliest reset time is returned.
+func selectClient(clientPool map[string]*GitHubClient) (*GitHubClient, error) {
+	if len(clientPool) == 0 {
+		return nil, fmt.Errorf("no GitHub tokens available")
 	}
 
-	// Check the secondary rate limit
-	var isSecondaryRl bool
-	ghClient.SecondaryRateLimit.mu.Lock()
-	isSecondaryRl = ghClient.SecondaryRateLimit.isLimitReached
-	ghClient.SecondaryRateLimit.mu.Unlock()
+	// Visit the tokens in a random order, so that load is spread across tokens with the same quota
+	tokenNames := make([]string, 0, len(clientPool))
+	for tokenName := range clientPool {
+		tokenNames = append(tokenNames, tokenName)
+	}
+	/* #nosec G404 -- not used for cryptographic purposes*/
+	rand.Shuffle(len(tokenNames), func(i, j int) { tokenNames[i], tokenNames[j] = tokenNames[j], tokenNames[i] })
+
+	now := time.Now()
+	var selected *GitHubClient
+	var selectedScore int64 = -1
+	var rateLimited bool
+	var resetTime time.Time
+	var lastErr error
+	for _, tokenName := range tokenNames {
+		ghClient := clientPool[tokenName]
+
+		// Check the secondary rate limit
+		if isSecondaryRl, until := ghClient.SecondaryRateLimit.limitedUntil(); isSecondaryRl {
+			rateLimited = true
+			resetTime = earliest(resetTime, until)
+			continue
+		}
 
-	if isSecondaryRl {
-		newClientPool := make(map[string]*GitHubClient)
-		for k, v := range clientPool {
-			if k != ghClient.TokenName {
-				newClientPool[k] = v
-			}
+		// Check the primary rate limit
+		core, search, err := ghClient.getRateLimits(context.Background(), now)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		isPrimaryRl, until := isPrimaryRateLimited(core, search)
+		ghClient.setPrimaryRateLimited(isPrimaryRl)
+		if isPrimaryRl {
+			rateLimited = true
+			resetTime = earliest(resetTime, until)
+			continue
 		}
 
-		return getRandomClient(newClientPool)
+		var score int64
+		if core != nil {
+			score = int64(core.Remaining) * int64(ghClient.getWeight())
+		}
+		if score > selectedScore {
+			selected = ghClient
+			selectedScore = score
+		}
+	}
+
+	if selected != nil {
+		return selected, nil
+	}
+	if rateLimited {
+		return nil, &TokensExhaustedError{ResetTime: resetTime}
+	}
+	return nil, lastErr
+}
+
+// setPrimaryRateLimited records whether the token is primary rate limited, and updates the primary rate limited tokens metric
+// when that changes. Tokens are selected concurrently, so the check and the update are made under the token's lock, for the
+// metric to be updated exactly once per change.
+func (g *GitHubClient) setPrimaryRateLimited(rateLimited bool) {
+	g.primaryRateLimitedMu.Lock()
+	defer g.primaryRateLimitedMu.Unlock()
+	if g.PrimaryRateLimited == rateLimited {
+		return
+	}
+	g.PrimaryRateLimited = rateLimited
+	prlTokenMetric := metrics.TokenPoolGauge.With(prometheus.Labels{"rateLimited": "primary", "tokenName": g.TokenName})
+	if rateLimited {
+		prlTokenMetric.Inc()
+	} else {
+		prlTokenMetric.Dec()
 	}
-	return ghClient, nil
+}
+
+// getWeight returns the token's weight when selecting tokens from the pool, defaulting to 1
+func (g *GitHubClient) getWeight() int {
+	if g.weight <= 0 {
+		return 1
+	}
+	return g.weight
 }
 
 // GetNewGitHubClient returns a Go-GitHub client
 // If a token is passed in (non-empty string) it will use that token for the GitHub client
-// If no token is passed in (empty string), a token will be randomly selected by HAS.
-// It returns the GitHub client, and (if a token was randomly selected) the name of the token used for the client
+// If no token is passed in (empty string), the token with the most remaining quota will be selected by HAS (see selectClient).
+// It returns the GitHub client, and (if a token was selected) the name of the token used for the client
+// If every token in the pool is rate limited, a TokensExhaustedError is returned
 // If an error is encountered retrieving the token, or initializing the client, an error is returned
 func (g GitHubTokenClient) GetNewGitHubClient(token string) (*GitHubClient, error) {
 	var ghToken string
@@ -184,28 +356,47 @@ func (g GitHubTokenClient) GetNewGitHubClient(token string) (*GitHubClient, erro
 		}
 		return ghClient, nil
 	} else {
-		ghClient, err := getRandomClient(Clients)
+		ghClient, err := selectClient(getClients())
 		if err != nil {
 			return nil, err
 		}
+		if ghClient.tokenSource != nil {
+			// GitHub App installation tokens are short-lived, so return a copy of the client with a valid token
+			// The copy shares the underlying Go-GitHub client, and its rate limit state is still tracked through the token pool
+			token, err := ghClient.tokenSource.Token()
+			if err != nil {
+				return nil, err
+			}
+			return &GitHubClient{
+				TokenName: ghClient.TokenName,
+				Token:     token.AccessToken,
+				Client:    ghClient.Client,
+			}, nil
+		}
 		return ghClient, nil
 	}
 }
 
 func createGitHubClientFromToken(roundTripper *http.RoundTripper, ghToken string, ghTokenName string) (*GitHubClient, error) {
-	rateLimiter, err := github_ratelimit.NewRateLimitWaiterClient(*roundTripper, github_ratelimit.WithSingleSleepLimit(0, rateLimitCallBackfunc))
+	// Record the rate limit headers of each response, so the token's remaining quota is known without querying the rate limit API
+	rateLimitRecorder := &rateLimitTransport{base: *roundTripper}
+	rateLimiter, err := github_ratelimit.NewRateLimitWaiterClient(rateLimitRecorder, github_ratelimit.WithSingleSleepLimit(0, rateLimitCallBackfunc))
 
 	if err != nil {
 		return nil, err
 	}
-	client := github.NewClient(rateLimiter)
-	githubClient := GitHubClient{
+	client, err := newGoGitHubClient(rateLimiter)
+	if err != nil {
+		return nil, err
+	}
+	githubClient := &GitHubClient{
 		TokenName: ghTokenName,
 		Token:     ghToken,
 		Client:    client,
 	}
+	rateLimitRecorder.cache = &githubClient.rateLimits
 
-	return &githubClient, nil
+	return githubClient, nil
 }
 
 func rateLimitCallBackfunc(cbContext *github_ratelimit.CallbackContext) {
@@ -222,11 +413,11 @@ func rateLimitCallBackfunc(cbContext *github_ratelimit.CallbackContext) {
 		return
 	}
 	ghClientName := ghClientNameObj.(string)
-	ghClient := Clients[ghClientName]
+	ghClient := getClient(ghClientName)
 	if ghClient == nil {
-		// Likewise, the GitHub client should never be nil, it's directly set from the calling Go-GitHub client
-		// But if it is nil, returning prematurely is preferable to panicking.
-		log.Error(fmt.Errorf("a Go-GitHub client with the name %v as set in the GitHub API request does not exist, cannot execute secondary rate limit callback", ghClientName), "")
+		// The token may have been removed from the pool by a reload while the request was in flight.
+		// A retired token won't be handed out again, so there's no rate limit state left to track for it.
+		log.Info(fmt.Sprintf("a Go-GitHub client with the name %v as set in the GitHub API request is no longer in the token pool, skipping secondary rate limit callback", ghClientName))
 		return
 	}
 
@@ -236,6 +427,7 @@ func rateLimitCallBackfunc(cbContext *github_ratelimit.CallbackContext) {
 
 		client.SecondaryRateLimit.mu.Lock()
 		client.SecondaryRateLimit.isLimitReached = true
+		client.SecondaryRateLimit.resetTime = *cbContext.SleepUntil
 		srlTokenMetric.Inc()
 		client.SecondaryRateLimit.mu.Unlock()
 
@@ -43,7 +43,7 @@ func (g MockGitHubTokenClient) GetNewGitHubClient(token string) (*GitHubClient,
 		Client:    GetMockedClient(),
 	}
 
-	return getRandomClient(fakeClients)
+	return selectClient(fakeClients)
 }
 
 func (g MockPrimaryRateLimitGitHubTokenClient) GetNewGitHubClient(token string) (*GitHubClient, error) {
@@ -54,7 +54,7 @@ func (g MockPrimaryRateLimitGitHubTokenClient) GetNewGitHubClient(token string)
 		Client:    GetMockedPrimaryRateLimitedClient(),
 	}
 
-	return getRandomClient(fakeClients)
+	return selectClient(fakeClients)
 }
 
 func (g MockResetPrimaryRateLimitGitHubTokenClient) GetNewGitHubClient(token string) (*GitHubClient, error) {
@@ -65,5 +65,5 @@ func (g MockResetPrimaryRateLimitGitHubTokenClient) GetNewGitHubClient(token str
 		Client:    GetMockedResetPrimaryRateLimitedClient(),
 	}
 
-	return getRandomClient(fakeClients)
+	return selectClient(fakeClients)
 }
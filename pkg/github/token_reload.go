@@ -0,0 +1,166 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Keys of the GitHub token Secret, which are the file names when the Secret is mounted as a volume
+const (
+	SecretKeyToken         = "token"
+	SecretKeyTokenList     = "tokens"
+	SecretKeyAppID         = "app-id"
+	SecretKeyAppPrivateKey = "app-private-key"
+)
+
+// DefaultTokenReloadInterval is how often a TokenReloader checks the mounted Secret for changes, if no interval is set.
+// The kubelet only periodically syncs mounted Secrets, so checking more often than this has little benefit.
+const DefaultTokenReloadInterval = time.Minute
+
+var tokenSecretKeys = []string{SecretKeyToken, SecretKeyTokenList, SecretKeyAppID, SecretKeyAppPrivateKey}
+
+// TokenReloader watches the GitHub token Secret mounted at SecretPath, and rebuilds the token pool whenever its contents change.
+// This allows tokens to be added, removed or rotated without restarting the operator.
+// It implements controller-runtime's manager.Runnable, and runs on every replica, regardless of leader election.
+type TokenReloader struct {
+	// SecretPath is the directory the GitHub token Secret is mounted at
+	SecretPath string
+
+	// Interval is how often the Secret is checked for changes. Defaults to DefaultTokenReloadInterval
+	Interval time.Duration
+
+	// lastHash is the hash of the Secret contents the token pool was last built from
+	lastHash string
+}
+
+// Start checks the mounted Secret for changes every interval, until the context is cancelled
+func (r *TokenReloader) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("github-token-reloader")
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultTokenReloadInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			added, removed, err := r.reload()
+			if err != nil {
+				// Keep serving requests with the existing token pool, the reload is retried on the next tick
+				log.Error(err, "unable to reload the GitHub tokens, continuing with the existing token pool")
+				continue
+			}
+			if len(added) > 0 || len(removed) > 0 {
+				log.Info(fmt.Sprintf("Reloaded the GitHub token pool, there are %v token(s) available", GetTokenCount()), "added", added, "removed", removed)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, as every replica needs an up to date token pool
+func (r *TokenReloader) NeedLeaderElection() bool {
+	return false
+}
+
+// reload rebuilds the token pool if the contents of the mounted Secret have changed since the last reload.
+// It returns the names of the tokens that were added to and removed from the pool.
+func (r *TokenReloader) reload() ([]string, []string, error) {
+	hash, err := hashTokenSecret(r.SecretPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hash == r.lastHash {
+		return nil, nil, nil
+	}
+
+	config, err := tokenConfigFromDir(r.SecretPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	added, removed, err := reloadGitHubTokens(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.lastHash = hash
+	return added, removed, nil
+}
+
+// tokenConfigFromDir reads the GitHub token configuration from a Secret mounted at dir.
+// Any of the Secret's keys may be missing. GITHUB_APP_INSTALLATION_ORGS is still read from the environment.
+func tokenConfigFromDir(dir string) (tokenConfig, error) {
+	values := make(map[string][]byte)
+	for _, key := range tokenSecretKeys {
+		value, err := readSecretKey(dir, key)
+		if err != nil {
+			return tokenConfig{}, err
+		}
+		values[key] = value
+	}
+
+	config := tokenConfig{
+		authToken: strings.TrimSpace(string(values[SecretKeyToken])),
+		tokenList: strings.TrimSpace(string(values[SecretKeyTokenList])),
+	}
+	if appID := strings.TrimSpace(string(values[SecretKeyAppID])); appID != "" {
+		appConfig, err := newGitHubAppConfig(appID, values[SecretKeyAppPrivateKey], os.Getenv("GITHUB_APP_INSTALLATION_ORGS"))
+		if err != nil {
+			return tokenConfig{}, err
+		}
+		config.appConfig = appConfig
+	}
+	return config, nil
+}
+
+// hashTokenSecret returns a hash of the contents of the Secret mounted at dir, used to detect changes to it
+func hashTokenSecret(dir string) (string, error) {
+	hash := sha256.New()
+	for _, key := range tokenSecretKeys {
+		value, err := readSecretKey(dir, key)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s:%d:", key, len(value))
+		hash.Write(value)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readSecretKey reads the given key of the Secret mounted at dir, returning nil if the key is not set
+func readSecretKey(dir string, key string) ([]byte, error) {
+	value, err := os.ReadFile(filepath.Join(dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read key %s of the GitHub token secret: %v", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,117 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenReloader(t *testing.T) {
+	secretPath := t.TempDir()
+	writeSecretKey := func(key string, value string) {
+		if err := os.WriteFile(filepath.Join(secretPath, key), []byte(value), 0600); err != nil {
+			t.Fatalf("unable to write secret key %s: %v", key, err)
+		}
+	}
+
+	writeSecretKey(SecretKeyTokenList, "token1:ghp_token1,token2:ghp_token2")
+	config, err := tokenConfigFromDir(secretPath)
+	assert.NoError(t, err)
+	clients, err := buildGitHubClients(config, nil)
+	assert.NoError(t, err)
+	setClients(clients)
+	token1Client := getClient("token1")
+	token1Client.SecondaryRateLimit.isLimitReached = true
+
+	reloader := &TokenReloader{SecretPath: secretPath}
+
+	tests := []struct {
+		name        string
+		tokens      string
+		authToken   string
+		wantAdded   []string
+		wantRemoved []string
+		wantTokens  []string
+		wantErr     bool
+	}{
+		{
+			name:       "First reload, tokens unchanged",
+			tokens:     "token1:ghp_token1,token2:ghp_token2",
+			wantTokens: []string{"token1", "token2"},
+		},
+		{
+			name:        "Token added and token removed",
+			tokens:      "token1:ghp_token1,token3:ghp_token3",
+			wantAdded:   []string{"token3"},
+			wantRemoved: []string{"token2"},
+			wantTokens:  []string{"token1", "token3"},
+		},
+		{
+			name:        "Token rotated, and legacy token added",
+			tokens:      "token1:ghp_token1,token3:ghp_rotated",
+			authToken:   "ghp_legacy",
+			wantAdded:   []string{"GITHUB_AUTH_TOKEN", "token3"},
+			wantRemoved: []string{"token3"},
+			wantTokens:  []string{"GITHUB_AUTH_TOKEN", "token1", "token3"},
+		},
+		{
+			name:       "Secret unchanged",
+			tokens:     "token1:ghp_token1,token3:ghp_rotated",
+			authToken:  "ghp_legacy",
+			wantTokens: []string{"GITHUB_AUTH_TOKEN", "token1", "token3"},
+		},
+		{
+			name:       "Invalid secret keeps the existing token pool",
+			tokens:     "token1:ghp_token1,token3",
+			authToken:  "ghp_legacy",
+			wantErr:    true,
+			wantTokens: []string{"GITHUB_AUTH_TOKEN", "token1", "token3"},
+		},
+		{
+			name:       "All tokens removed keeps the existing token pool",
+			wantErr:    true,
+			wantTokens: []string{"GITHUB_AUTH_TOKEN", "token1", "token3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeSecretKey(SecretKeyTokenList, tt.tokens)
+			writeSecretKey(SecretKeyToken, tt.authToken)
+
+			added, removed, err := reloader.reload()
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestTokenReloader() unexpected error value: %v", err)
+			}
+			assert.Equal(t, tt.wantAdded, added)
+			assert.Equal(t, tt.wantRemoved, removed)
+
+			clients := getClients()
+			assert.Equal(t, len(tt.wantTokens), len(clients))
+			for _, tokenName := range tt.wantTokens {
+				assert.NotNil(t, clients[tokenName], "expected token %v in the token pool", tokenName)
+			}
+
+			// token1 is never changed, so its client and rate limit state must be carried over on every reload
+			assert.Same(t, token1Client, clients["token1"])
+			assert.True(t, clients["token1"].SecondaryRateLimit.isLimitReached)
+		})
+	}
+}
@@ -19,11 +19,13 @@ import (
 	"context"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
 	"github.com/redhat-appstudio/application-service/pkg/metrics"
 	"github.com/stretchr/testify/assert"
 )
@@ -221,7 +223,32 @@ func TestGetNewGitHubClient(t *testing.T) {
 	}
 }
 
-func TestGetRandomClient(t *testing.T) {
+func TestSelectClientConcurrently(t *testing.T) {
+	clientPool := map[string]*GitHubClient{
+		"fake_concurrent": {
+			TokenName: "fake_concurrent",
+			Token:     "fake_concurrent",
+			Client:    GetMockedPrimaryRateLimitedClient(),
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := selectClient(clientPool); err == nil {
+				t.Error("TestSelectClientConcurrently() error: expected the rate limited token not to be selected")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The token is only counted once, however many selections found it rate limited
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.TokenPoolGauge.With(prometheus.Labels{"rateLimited": "primary", "tokenName": "fake_concurrent"})))
+}
+
+func TestSelectClient(t *testing.T) {
 	ctx := context.WithValue(context.Background(), GHClientKey, "mock")
 	tests := []struct {
 		name               string
@@ -289,17 +316,17 @@ func TestGetRandomClient(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			client, err := getRandomClient(tt.clientPool)
+			client, err := selectClient(tt.clientPool)
 			if tt.wantErr != (err != nil) && tt.name != "secondary-rate-limit" {
-				t.Errorf("TestGetRandomClient() error: unexpected error value %v", err)
+				t.Errorf("TestSelectClient() error: unexpected error value %v", err)
 			}
 			if tt.name == "secondary-rate-limit" {
 				Clients = tt.clientPool
 				// Deliberately lock the secondary rate limit object until we need to test the related fields
 				client.SecondaryRateLimit.mu.Lock()
-				_, err := client.GenerateNewRepository(ctx, "test-org", "test-repo", "test description")
+				_, err := client.GenerateNewRepository(ctx, "test-org", "test-repo", "test description", gitprovider.RepositoryOptions{})
 				if err == nil {
-					t.Error("TestGetRandomClient() error: expected err not to be nil")
+					t.Error("TestSelectClient() error: expected err not to be nil")
 				}
 
 				client.SecondaryRateLimit.mu.Unlock()
@@ -307,9 +334,9 @@ func TestGetRandomClient(t *testing.T) {
 
 				//verify SRL metric has been incremented
 				assert.Equal(t, float64(tt.wantNumSRLTokens), testutil.ToFloat64(metrics.TokenPoolGauge.With(prometheus.Labels{"rateLimited": "secondary", "tokenName": tt.passedInToken})))
-				_, err = getRandomClient(tt.clientPool)
+				_, err = selectClient(tt.clientPool)
 				if err == nil {
-					t.Error("TestGetRandomClient() error: unexpected err not to be nil")
+					t.Error("TestSelectClient() error: unexpected err not to be nil")
 				}
 
 				//verify SRL metric has been decremented
@@ -0,0 +1,550 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+	"github.com/redhat-appstudio/application-service/pkg/metrics"
+)
+
+// DefaultGitLabURL is the URL of the public GitLab instance
+const DefaultGitLabURL = "https://gitlab.com"
+
+// listPageSize is the number of items requested per page when listing, which is the maximum allowed by GitLab
+const listPageSize = 100
+
+// GitLabClient is a client for the GitLab REST API (v4), along with the name of the GitLab token that was used to initialize it
+type GitLabClient struct {
+	TokenName  string
+	Token      string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// ServerError is used to identify gitops repo creation failures caused by server errors
+type ServerError struct {
+	err error
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Errorf("failed to create gitops repo due to error: %v", e.err).Error()
+}
+
+// APIError is returned when the GitLab API responds with a non-successful status code
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gitlab api request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if err is a GitLab API error for a project (or other resource) that doesn't exist
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+type project struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	WebURL        string    `json:"web_url"`
+	DefaultBranch string    `json:"default_branch"`
+	ImportStatus  string    `json:"import_status"`
+	Archived      bool      `json:"archived"`
+	CreatedAt     time.Time `json:"created_at"`
+	Topics        []string  `json:"topics"`
+}
+
+type mergeRequest struct {
+	IID             int    `json:"iid"`
+	WebURL          string `json:"web_url"`
+	State           string `json:"state"`
+	MergeCommitSHA  string `json:"merge_commit_sha"`
+	SquashCommitSHA string `json:"squash_commit_sha"`
+}
+
+type comparison struct {
+	Commits []struct {
+		ID string `json:"id"`
+	} `json:"commits"`
+}
+
+type namespace struct {
+	ID int `json:"id"`
+}
+
+type branch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+// NewGitLabClient returns a GitLab client for the GitLab instance at baseURL, authenticated with the given token.
+// If baseURL is empty, the public GitLab instance is used.
+func NewGitLabClient(baseURL string, token string, tokenName string) *GitLabClient {
+	if baseURL == "" {
+		baseURL = DefaultGitLabURL
+	}
+	return &GitLabClient{
+		TokenName:  tokenName,
+		Token:      token,
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GenerateNewRepository creates a new project under the given GitLab group with the given visibility and access settings, and returns its URL.
+// If a template project is set, the project is forked from it (and unlinked from the template). GitLab forks projects asynchronously, so if the
+// fork hasn't completed yet, the URL is returned along with a RepositoryPendingError, and the access settings are applied by FinishNewRepository.
+// If the access settings can't be applied, the project is deleted, rather than being left with the wrong settings.
+func (g *GitLabClient) GenerateNewRepository(ctx context.Context, orgName string, repoName string, description string, opts gitprovider.RepositoryOptions) (string, error) {
+	metrics.GitOpsRepoCreationTotalReqs.Inc()
+
+	var ns namespace
+	if err := g.do(ctx, http.MethodGet, "/namespaces/"+url.PathEscape(orgName), nil, &ns); err != nil {
+		return "", fmt.Errorf("failed to get gitlab group %s, error: %v", orgName, err)
+	}
+
+	visibility := opts.Visibility
+	if visibility == "" {
+		visibility = gitprovider.VisibilityPublic
+	}
+	body := map[string]interface{}{
+		"name":         repoName,
+		"path":         repoName,
+		"namespace_id": ns.ID,
+		"description":  description,
+		"visibility":   visibility,
+	}
+	if topics := opts.AllTopics(); len(topics) > 0 && opts.Template == "" {
+		body["topics"] = topics
+	}
+	var created project
+	var err error
+	if opts.Template != "" {
+		// Fork the template project, so that the project starts with the template's files
+		err = g.do(ctx, http.MethodPost, "/projects/"+url.PathEscape(opts.Template)+"/fork", body, &created)
+	} else {
+		err = g.do(ctx, http.MethodPost, "/projects", body, &created)
+	}
+	if err != nil {
+		if apiErr, ok := err.(*APIError); ok && 500 <= apiErr.StatusCode && apiErr.StatusCode <= 599 {
+			metrics.GitOpsRepoCreationFailed.Inc()
+			return "", &ServerError{err: err}
+		}
+		return "", err
+	}
+
+	repoURL := g.BaseURL + "/" + orgName + "/" + repoName
+	if err := g.applyRepositoryOptions(ctx, orgName, repoName, opts); err != nil {
+		if gitprovider.IsRepositoryPending(err) {
+			return repoURL, err
+		}
+		return "", g.deleteUnfinishedRepository(ctx, orgName, repoName, err)
+	}
+
+	metrics.GitOpsRepoCreationSucceeded.Inc()
+	return repoURL, nil
+}
+
+// FinishNewRepository applies the access settings of a project forked from a template once the fork has completed, returning a
+// RepositoryPendingError until then. If the access settings can't be applied, the project is deleted.
+func (g *GitLabClient) FinishNewRepository(ctx context.Context, orgName string, repoName string, opts gitprovider.RepositoryOptions) error {
+	if err := g.applyRepositoryOptions(ctx, orgName, repoName, opts); err != nil {
+		if gitprovider.IsRepositoryPending(err) {
+			return err
+		}
+		return g.deleteUnfinishedRepository(ctx, orgName, repoName, err)
+	}
+
+	metrics.GitOpsRepoCreationSucceeded.Inc()
+	return nil
+}
+
+// deleteUnfinishedRepository deletes a new project whose access settings failed to be applied with the given error
+func (g *GitLabClient) deleteUnfinishedRepository(ctx context.Context, orgName string, repoName string, err error) error {
+	if deleteErr := g.DeleteRepository(ctx, orgName, repoName); deleteErr != nil {
+		return fmt.Errorf("failed to apply the access settings of repo %s under %s: %v, and failed to delete it: %v", repoName, orgName, err, deleteErr)
+	}
+	return fmt.Errorf("failed to apply the access settings of repo %s under %s: %v", repoName, orgName, err)
+}
+
+// accessLevels maps team permissions to the closest GitLab access level: reporter (20), developer (30) or maintainer (40)
+var accessLevels = map[string]int{
+	gitprovider.PermissionPull:     20,
+	gitprovider.PermissionTriage:   20,
+	gitprovider.PermissionPush:     30,
+	gitprovider.PermissionMaintain: 40,
+	gitprovider.PermissionAdmin:    40,
+}
+
+// applyRepositoryOptions finishes generating a project from its template, shares the project with groups and protects its GitOps branch.
+// Topics are set when the project is created, unless it's forked from a template. It returns a RepositoryPendingError if the project is
+// forked from a template and the fork hasn't completed yet
+func (g *GitLabClient) applyRepositoryOptions(ctx context.Context, orgName string, repoName string, opts gitprovider.RepositoryOptions) error {
+	if opts.Template != "" {
+		if err := g.checkForkCompleted(ctx, orgName, repoName); err != nil {
+			return err
+		}
+		// Unlink the project from the template, so that it's a standalone project rather than a fork
+		if err := g.do(ctx, http.MethodDelete, "/projects/"+projectID(orgName, repoName)+"/fork", nil, nil); err != nil {
+			return fmt.Errorf("unable to remove the fork relationship with template %s: %v", opts.Template, err)
+		}
+		if topics := opts.AllTopics(); len(topics) > 0 {
+			// Topics can't be set when forking a project, so set them once it's forked
+			if err := g.do(ctx, http.MethodPut, "/projects/"+projectID(orgName, repoName), map[string]interface{}{"topics": topics}, nil); err != nil {
+				return fmt.Errorf("unable to set topics: %v", err)
+			}
+		}
+	}
+
+	for _, team := range opts.SortedTeams() {
+		var group namespace
+		if err := g.do(ctx, http.MethodGet, "/groups/"+url.PathEscape(team), nil, &group); err != nil {
+			return fmt.Errorf("unable to get group %s: %v", team, err)
+		}
+		share := map[string]interface{}{
+			"group_id":     group.ID,
+			"group_access": accessLevels[opts.TeamPermissions[team]],
+		}
+		if err := g.do(ctx, http.MethodPost, "/projects/"+projectID(orgName, repoName)+"/share", share, nil); err != nil {
+			return fmt.Errorf("unable to grant group %s %s access: %v", team, opts.TeamPermissions[team], err)
+		}
+	}
+
+	if opts.ProtectedBranch != "" {
+		// GitLab protects the default branch of new projects, so remove that protection first, in case it's the GitOps branch
+		branchPath := "/projects/" + projectID(orgName, repoName) + "/protected_branches/" + url.PathEscape(opts.ProtectedBranch)
+		if err := g.do(ctx, http.MethodDelete, branchPath, nil, nil); err != nil && !IsNotFound(err) {
+			return fmt.Errorf("unable to protect branch %s: %v", opts.ProtectedBranch, err)
+		}
+		// Block force pushes and deletion of the GitOps branch, while still allowing application-service to push to it
+		protection := map[string]interface{}{
+			"name":               opts.ProtectedBranch,
+			"push_access_level":  30,
+			"merge_access_level": 30,
+			"allow_force_push":   false,
+		}
+		if err := g.do(ctx, http.MethodPost, "/projects/"+projectID(orgName, repoName)+"/protected_branches", protection, nil); err != nil {
+			return fmt.Errorf("unable to protect branch %s: %v", opts.ProtectedBranch, err)
+		}
+	}
+	return nil
+}
+
+// checkForkCompleted returns a RepositoryPendingError if the fork of a template project is still in progress. Projects are forked from
+// templates asynchronously
+func (g *GitLabClient) checkForkCompleted(ctx context.Context, orgName string, repoName string) error {
+	var p project
+	if err := g.do(ctx, http.MethodGet, "/projects/"+projectID(orgName, repoName), nil, &p); err != nil {
+		return fmt.Errorf("unable to get the status of the template fork: %v", err)
+	}
+	switch p.ImportStatus {
+	case "", "none", "finished":
+		return nil
+	case "failed":
+		return fmt.Errorf("the fork of the template project failed")
+	}
+	return &gitprovider.RepositoryPendingError{RepoURL: g.BaseURL + "/" + orgName + "/" + repoName}
+}
+
+// DeleteRepository deletes the given project under the given GitLab group
+func (g *GitLabClient) DeleteRepository(ctx context.Context, orgName string, repoName string) error {
+	return g.do(ctx, http.MethodDelete, "/projects/"+projectID(orgName, repoName), nil, nil)
+}
+
+// ArchiveRepository archives the given project under the given GitLab group, making it read-only while keeping its history
+func (g *GitLabClient) ArchiveRepository(ctx context.Context, orgName string, repoName string) error {
+	return g.do(ctx, http.MethodPost, "/projects/"+projectID(orgName, repoName)+"/archive", nil, nil)
+}
+
+// ListRepositories returns the projects directly under the given GitLab group, excluding those in its subgroups
+func (g *GitLabClient) ListRepositories(ctx context.Context, orgName string) ([]gitprovider.Repository, error) {
+	var repos []gitprovider.Repository
+	for page := 1; ; page++ {
+		var projects []project
+		path := fmt.Sprintf("/groups/%s/projects?per_page=%d&page=%d", url.PathEscape(orgName), listPageSize, page)
+		if err := g.do(ctx, http.MethodGet, path, nil, &projects); err != nil {
+			return nil, fmt.Errorf("unable to list the projects of group %s: %v", orgName, err)
+		}
+		for _, p := range projects {
+			repos = append(repos, gitprovider.Repository{
+				Name:      p.Path,
+				URL:       p.WebURL,
+				CreatedAt: p.CreatedAt,
+				Archived:  p.Archived,
+				Topics:    p.Topics,
+			})
+		}
+		if len(projects) < listPageSize {
+			break
+		}
+	}
+	return repos, nil
+}
+
+// CreateOrUpdatePullRequest opens a merge request from the head branch to the base branch of the given project, or updates the title
+// and description of the open merge request between them. It returns nil if the head branch has no commits that aren't on the base branch.
+func (g *GitLabClient) CreateOrUpdatePullRequest(ctx context.Context, orgName string, repoName string, opts gitprovider.PullRequestOptions) (*gitprovider.PullRequest, error) {
+	var compared comparison
+	comparePath := fmt.Sprintf("/projects/%s/repository/compare?from=%s&to=%s", projectID(orgName, repoName), url.QueryEscape(opts.BaseBranch), url.QueryEscape(opts.HeadBranch))
+	if err := g.do(ctx, http.MethodGet, comparePath, nil, &compared); err != nil {
+		return nil, fmt.Errorf("unable to compare branch %s with %s in repo %s under %s: %v", opts.HeadBranch, opts.BaseBranch, repoName, orgName, err)
+	}
+	if len(compared.Commits) == 0 {
+		return nil, nil
+	}
+
+	var mergeRequests []mergeRequest
+	listPath := fmt.Sprintf("/projects/%s/merge_requests?state=opened&source_branch=%s&target_branch=%s", projectID(orgName, repoName), url.QueryEscape(opts.HeadBranch), url.QueryEscape(opts.BaseBranch))
+	if err := g.do(ctx, http.MethodGet, listPath, nil, &mergeRequests); err != nil {
+		return nil, fmt.Errorf("unable to list the merge requests of repo %s under %s: %v", repoName, orgName, err)
+	}
+
+	var mr mergeRequest
+	var err error
+	if len(mergeRequests) > 0 {
+		body := map[string]interface{}{
+			"title":       opts.Title,
+			"description": opts.Body,
+		}
+		err = g.do(ctx, http.MethodPut, fmt.Sprintf("/projects/%s/merge_requests/%d", projectID(orgName, repoName), mergeRequests[0].IID), body, &mr)
+	} else {
+		body := map[string]interface{}{
+			"title":         opts.Title,
+			"description":   opts.Body,
+			"source_branch": opts.HeadBranch,
+			"target_branch": opts.BaseBranch,
+		}
+		err = g.do(ctx, http.MethodPost, "/projects/"+projectID(orgName, repoName)+"/merge_requests", body, &mr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mr.convert(), nil
+}
+
+// GetPullRequest returns the merge request with the given IID of the given project
+func (g *GitLabClient) GetPullRequest(ctx context.Context, orgName string, repoName string, number int) (*gitprovider.PullRequest, error) {
+	var mr mergeRequest
+	if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/projects/%s/merge_requests/%d", projectID(orgName, repoName), number), nil, &mr); err != nil {
+		return nil, err
+	}
+	return mr.convert(), nil
+}
+
+// convert maps the merge request to a pull request. Merge requests that are locked (while being merged) are still open
+func (mr mergeRequest) convert() *gitprovider.PullRequest {
+	pullRequest := &gitprovider.PullRequest{
+		Number: mr.IID,
+		URL:    mr.WebURL,
+		State:  gitprovider.PullRequestStateOpen,
+	}
+	switch mr.State {
+	case "merged":
+		pullRequest.State = gitprovider.PullRequestStateMerged
+		// Squashed merge requests are merged with the squash commit if fast-forward merges are used
+		pullRequest.MergeCommitSHA = mr.MergeCommitSHA
+		if pullRequest.MergeCommitSHA == "" {
+			pullRequest.MergeCommitSHA = mr.SquashCommitSHA
+		}
+	case "closed":
+		pullRequest.State = gitprovider.PullRequestStateClosed
+	}
+	return pullRequest
+}
+
+// ResetBranch points the given branch of the project at the latest commit of the base branch, by deleting and recreating the branch
+func (g *GitLabClient) ResetBranch(ctx context.Context, orgName string, repoName string, branchName string, baseBranch string) error {
+	branchesPath := "/projects/" + projectID(orgName, repoName) + "/repository/branches"
+	if err := g.do(ctx, http.MethodDelete, branchesPath+"/"+url.PathEscape(branchName), nil, nil); err != nil && !IsNotFound(err) {
+		return fmt.Errorf("unable to delete branch %s of repo %s under %s: %v", branchName, repoName, orgName, err)
+	}
+	body := map[string]interface{}{
+		"branch": branchName,
+		"ref":    baseBranch,
+	}
+	if err := g.do(ctx, http.MethodPost, branchesPath, body, nil); err != nil {
+		return fmt.Errorf("unable to create branch %s from %s in repo %s under %s: %v", branchName, baseBranch, repoName, orgName, err)
+	}
+	return nil
+}
+
+// GetRepoNameFromURL returns the project name from the GitLab project URL
+func (g *GitLabClient) GetRepoNameFromURL(repoURL string, orgName string) (string, error) {
+	parts := strings.Split(repoURL, orgName+"/")
+	if len(parts) < 2 || parts[1] == "" {
+		return "", fmt.Errorf("error: unable to parse Git repository URL: %v", repoURL)
+	}
+	return strings.TrimSuffix(parts[1], ".git"), nil
+}
+
+// GetRepoAndOrgFromURL returns both the project name and its group, including any subgroups, from a given GitLab URL
+func (g *GitLabClient) GetRepoAndOrgFromURL(repoURL string) (string, string, error) {
+	return GetRepoAndGroupFromURL(repoURL)
+}
+
+// GetDefaultBranchFromURL returns the default branch of a given project URL
+func (g *GitLabClient) GetDefaultBranchFromURL(repoURL string, ctx context.Context) (string, error) {
+	repoName, orgName, err := GetRepoAndGroupFromURL(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	var p project
+	if err := g.do(ctx, http.MethodGet, "/projects/"+projectID(orgName, repoName), nil, &p); err != nil {
+		return "", fmt.Errorf("failed to get repo %s under %s, error: %v", repoName, orgName, err)
+	}
+	return p.DefaultBranch, nil
+}
+
+// GetBranchNameFromURL returns the name of the requested branch of a given project URL
+func (g *GitLabClient) GetBranchNameFromURL(repoURL string, ctx context.Context, branchName string) (string, error) {
+	repoName, orgName, err := GetRepoAndGroupFromURL(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	var b branch
+	if err := g.do(ctx, http.MethodGet, "/projects/"+projectID(orgName, repoName)+"/repository/branches/"+url.PathEscape(branchName), nil, &b); err != nil {
+		return "", fmt.Errorf("failed to get branch %s from repo %s under %s, error: %v", branchName, repoName, orgName, err)
+	}
+	return b.Name, nil
+}
+
+// GetLatestCommitSHAFromRepository gets the latest Commit SHA of the given branch of the project
+func (g *GitLabClient) GetLatestCommitSHAFromRepository(ctx context.Context, repoName string, orgName string, branchName string) (string, error) {
+	var b branch
+	if err := g.do(ctx, http.MethodGet, "/projects/"+projectID(orgName, repoName)+"/repository/branches/"+url.PathEscape(branchName), nil, &b); err != nil {
+		return "", err
+	}
+	return b.Commit.ID, nil
+}
+
+// GetTokenName returns the name of the GitLab token used to initialize the client
+func (g *GitLabClient) GetTokenName() string {
+	return g.TokenName
+}
+
+// GetToken returns the GitLab token used to initialize the client
+func (g *GitLabClient) GetToken() string {
+	return g.Token
+}
+
+// HostsRepository returns true if the given project URL is on the GitLab instance of the client
+func (g *GitLabClient) HostsRepository(repoURL string) bool {
+	parsedURL, err := url.Parse(repoURL)
+	if err != nil {
+		return false
+	}
+	baseURL, err := url.Parse(g.BaseURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(parsedURL.Host, baseURL.Host)
+}
+
+// GetRemoteCredentials returns the credentials for git remotes on GitLab, which take the token as the password of the
+// oauth2 user
+func (g *GitLabClient) GetRemoteCredentials() *url.Userinfo {
+	return url.UserPassword("oauth2", g.Token)
+}
+
+// GetRepoAndGroupFromURL returns both the GitLab group and project name from a given GitLab URL
+// Format must be of the form: <gitlab-domain>/group(/subgroup)/project(.git)
+// If .git is appended to the end, it will be removed from the returned project name
+func GetRepoAndGroupFromURL(repoURL string) (string, string, error) {
+	parsedURL, err := url.Parse(repoURL)
+	if err != nil {
+		return "", "", fmt.Errorf("error: invalid URL: %v", repoURL)
+	}
+
+	path := strings.Trim(parsedURL.Path, "/")
+	lastSlash := strings.LastIndex(path, "/")
+	if lastSlash <= 0 {
+		return "", "", fmt.Errorf("error: unable to parse Git repository URL: %v", repoURL)
+	}
+	groupName := path[:lastSlash]
+	repoName := strings.TrimSuffix(path[lastSlash+1:], ".git")
+	if repoName == "" {
+		return "", "", fmt.Errorf("error: unable to retrieve repository name from URL: %v", repoURL)
+	}
+	return repoName, groupName, nil
+}
+
+// projectID returns the URL-encoded path of the project, which the GitLab API accepts in place of the numeric project ID
+func projectID(orgName string, repoName string) string {
+	return url.PathEscape(orgName + "/" + repoName)
+}
+
+// do sends a request to the GitLab API, and decodes the JSON response into out (if non-nil)
+func (g *GitLabClient) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		reqBody, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(reqBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.BaseURL+"/api/v4"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := g.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+	}
+
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
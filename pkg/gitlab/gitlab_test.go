@@ -0,0 +1,704 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redhat-appstudio/application-service/pkg/gitprovider"
+)
+
+// newMockedGitLabServer returns a test server that mocks the subset of the GitLab API used by the GitLab client
+func newMockedGitLabServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/namespaces/", func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.Path, "fake-group") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		/* #nosec G104 -- test code */
+		w.Write([]byte(`{"id": 42}`))
+	})
+	mux.HandleFunc("/api/v4/projects", func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		if strings.Contains(string(b), "test-server-error-response") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(b, &body); err != nil || body["namespace_id"] != float64(42) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		/* #nosec G104 -- test code */
+		w.Write([]byte(`{"id": 1, "name": "test-repo-1"}`))
+	})
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.EscapedPath()
+		switch {
+		case strings.Contains(path, "test-error-response"):
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.HasSuffix(path, "/repository/branches/main"):
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"name": "main", "commit": {"id": "ca82a6dff817ec66f44342007202690a93763949"}}`))
+		case strings.Contains(path, "/repository/branches/"):
+			w.WriteHeader(http.StatusNotFound)
+		case req.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"id": 1, "name": "test-repo-1", "default_branch": "main"}`))
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestGenerateNewRepository(t *testing.T) {
+	server := newMockedGitLabServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		orgName         string
+		repoName        string
+		want            string
+		wantErr         bool
+		wantServerError bool
+	}{
+		{
+			name:     "Simple repo name",
+			orgName:  "redhat-appstudio-appdata",
+			repoName: "test-repo-1",
+			want:     server.URL + "/redhat-appstudio-appdata/test-repo-1",
+		},
+		{
+			name:     "Group does not exist",
+			orgName:  "fake-group",
+			repoName: "test-repo-1",
+			wantErr:  true,
+		},
+		{
+			name:            "Server error",
+			orgName:         "redhat-appstudio-appdata",
+			repoName:        "test-server-error-response",
+			wantErr:         true,
+			wantServerError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+			repoURL, err := client.GenerateNewRepository(context.Background(), tt.orgName, tt.repoName, "GitOps Repository", gitprovider.RepositoryOptions{})
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestGenerateNewRepository() unexpected error value: %v", err)
+			}
+			if _, ok := err.(*ServerError); ok != tt.wantServerError {
+				t.Errorf("TestGenerateNewRepository() expected server error %v, got %v", tt.wantServerError, err)
+			}
+			if !tt.wantErr && repoURL != tt.want {
+				t.Errorf("TestGenerateNewRepository() error: expected %v got %v", tt.want, repoURL)
+			}
+		})
+	}
+}
+
+func TestGenerateNewRepositoryWithOptions(t *testing.T) {
+	var requests []string
+	var projectBody, shareBody, protectionBody map[string]interface{}
+	forkPolls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/", func(w http.ResponseWriter, req *http.Request) {
+		path := strings.TrimPrefix(req.URL.EscapedPath(), "/api/v4")
+		requests = append(requests, req.Method+" "+path)
+		b, _ := io.ReadAll(req.Body)
+		switch {
+		case path == "/groups/missing-team":
+			w.WriteHeader(http.StatusNotFound)
+		case path == "/projects" || (req.Method == http.MethodPost && strings.HasSuffix(path, "/fork")):
+			/* #nosec G104 -- test code */
+			json.Unmarshal(b, &projectBody)
+		case req.Method == http.MethodGet && path == "/projects/test-group%2Ftest-repo":
+			// The fork completes on the second check
+			forkPolls++
+			if forkPolls == 1 {
+				/* #nosec G104 -- test code */
+				w.Write([]byte(`{"id": 7, "import_status": "started"}`))
+				return
+			}
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"id": 7, "import_status": "finished"}`))
+			return
+		case strings.HasSuffix(path, "/share"):
+			/* #nosec G104 -- test code */
+			json.Unmarshal(b, &shareBody)
+		case strings.HasSuffix(path, "/protected_branches/gitops"):
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case strings.HasSuffix(path, "/protected_branches"):
+			/* #nosec G104 -- test code */
+			json.Unmarshal(b, &protectionBody)
+		}
+		/* #nosec G104 -- test code */
+		w.Write([]byte(`{"id": 7}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		opts           gitprovider.RepositoryOptions
+		wantErr        bool
+		wantPending    bool
+		wantRequests   []string
+		wantProject    map[string]interface{}
+		wantShare      map[string]interface{}
+		wantProtection map[string]interface{}
+	}{
+		{
+			name: "Private project with group access, a protected branch and topics",
+			opts: gitprovider.RepositoryOptions{
+				Visibility:      gitprovider.VisibilityPrivate,
+				TeamPermissions: map[string]string{"my-group/devs": gitprovider.PermissionPush},
+				ProtectedBranch: "main",
+				Topics:          []string{"gitops"},
+				OwnerTopic:      "has-cluster-a",
+			},
+			wantRequests: []string{
+				"GET /namespaces/test-group",
+				"POST /projects",
+				"GET /groups/my-group%2Fdevs",
+				"POST /projects/test-group%2Ftest-repo/share",
+				"DELETE /projects/test-group%2Ftest-repo/protected_branches/main",
+				"POST /projects/test-group%2Ftest-repo/protected_branches",
+			},
+			wantProject:    map[string]interface{}{"visibility": "private", "topics": []interface{}{"gitops", "has-cluster-a"}},
+			wantShare:      map[string]interface{}{"group_id": float64(7), "group_access": float64(30)},
+			wantProtection: map[string]interface{}{"name": "main", "push_access_level": float64(30), "merge_access_level": float64(30), "allow_force_push": false},
+		},
+		{
+			name: "Protecting a branch that isn't protected yet",
+			opts: gitprovider.RepositoryOptions{Visibility: gitprovider.VisibilityInternal, ProtectedBranch: "gitops"},
+			wantRequests: []string{
+				"GET /namespaces/test-group",
+				"POST /projects",
+				"DELETE /projects/test-group%2Ftest-repo/protected_branches/gitops",
+				"POST /projects/test-group%2Ftest-repo/protected_branches",
+			},
+			wantProject:    map[string]interface{}{"visibility": "internal"},
+			wantProtection: map[string]interface{}{"name": "gitops", "push_access_level": float64(30), "merge_access_level": float64(30), "allow_force_push": false},
+		},
+		{
+			name:        "Project forked from a template, finished once the fork completes",
+			opts:        gitprovider.RepositoryOptions{Visibility: gitprovider.VisibilityPrivate, Topics: []string{"gitops"}, Template: "templates/gitops-template"},
+			wantPending: true,
+			wantRequests: []string{
+				"GET /namespaces/test-group",
+				"POST /projects/templates%2Fgitops-template/fork",
+				"GET /projects/test-group%2Ftest-repo",
+				"GET /projects/test-group%2Ftest-repo",
+				"DELETE /projects/test-group%2Ftest-repo/fork",
+				"PUT /projects/test-group%2Ftest-repo",
+			},
+			wantProject: map[string]interface{}{"visibility": "private", "name": "test-repo", "namespace_id": float64(7)},
+		},
+		{
+			name:    "Group does not exist, the project is deleted",
+			opts:    gitprovider.RepositoryOptions{TeamPermissions: map[string]string{"missing-team": gitprovider.PermissionPull}},
+			wantErr: true,
+			wantRequests: []string{
+				"GET /namespaces/test-group",
+				"POST /projects",
+				"GET /groups/missing-team",
+				"DELETE /projects/test-group%2Ftest-repo",
+			},
+			wantProject: map[string]interface{}{"visibility": "public"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, projectBody, shareBody, protectionBody, forkPolls = nil, nil, nil, nil, 0
+			client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+			repoURL, err := client.GenerateNewRepository(context.Background(), "test-group", "test-repo", "GitOps Repository", tt.opts)
+			if tt.wantPending {
+				// The project is returned while the fork is in progress, and its access settings are applied once the fork completes
+				if !gitprovider.IsRepositoryPending(err) || repoURL != server.URL+"/test-group/test-repo" {
+					t.Fatalf("TestGenerateNewRepositoryWithOptions() error: expected pending repository, got %v, %v", repoURL, err)
+				}
+				err = client.FinishNewRepository(context.Background(), "test-group", "test-repo", tt.opts)
+			}
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestGenerateNewRepositoryWithOptions() unexpected error value: %v", err)
+			}
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("TestGenerateNewRepositoryWithOptions() error: expected requests %v got %v", tt.wantRequests, requests)
+			}
+			for key, want := range tt.wantProject {
+				if !reflect.DeepEqual(projectBody[key], want) {
+					t.Errorf("TestGenerateNewRepositoryWithOptions() error: expected project %s %v got %v", key, want, projectBody[key])
+				}
+			}
+			if _, ok := tt.wantProject["topics"]; !ok && projectBody["topics"] != nil {
+				t.Errorf("TestGenerateNewRepositoryWithOptions() error: expected no project topics, got %v", projectBody["topics"])
+			}
+			if !reflect.DeepEqual(shareBody, tt.wantShare) {
+				t.Errorf("TestGenerateNewRepositoryWithOptions() error: expected share %v got %v", tt.wantShare, shareBody)
+			}
+			if !reflect.DeepEqual(protectionBody, tt.wantProtection) {
+				t.Errorf("TestGenerateNewRepositoryWithOptions() error: expected protection %v got %v", tt.wantProtection, protectionBody)
+			}
+		})
+	}
+}
+
+func TestDeleteRepository(t *testing.T) {
+	server := newMockedGitLabServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		orgName  string
+		repoName string
+		wantErr  bool
+	}{
+		{
+			name:     "Simple repo name",
+			orgName:  "redhat-appstudio-appdata",
+			repoName: "test-repo-1",
+		},
+		{
+			name:     "Error deleting repo",
+			orgName:  "redhat-appstudio-appdata",
+			repoName: "test-error-response",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+			err := client.DeleteRepository(context.Background(), tt.orgName, tt.repoName)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestDeleteRepository() unexpected error value: %v", err)
+			}
+		})
+	}
+}
+
+func TestArchiveRepository(t *testing.T) {
+	var archivePath string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.EscapedPath(), "test-error-response") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if req.Method == http.MethodPost {
+			archivePath = req.URL.EscapedPath()
+		}
+		/* #nosec G104 -- test code */
+		w.Write([]byte(`{"id": 1, "name": "test-repo-1", "archived": true}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		orgName  string
+		repoName string
+		wantPath string
+		wantErr  bool
+	}{
+		{
+			name:     "Project in a subgroup",
+			orgName:  "redhat-appstudio-appdata/gitops",
+			repoName: "test-repo-1",
+			wantPath: "/api/v4/projects/redhat-appstudio-appdata%2Fgitops%2Ftest-repo-1/archive",
+		},
+		{
+			name:     "Error archiving project",
+			orgName:  "redhat-appstudio-appdata",
+			repoName: "test-error-response",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath = ""
+			client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+			err := client.ArchiveRepository(context.Background(), tt.orgName, tt.repoName)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestArchiveRepository() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && archivePath != tt.wantPath {
+				t.Errorf("TestArchiveRepository() error: expected request to %v got %v", tt.wantPath, archivePath)
+			}
+		})
+	}
+}
+
+func TestListRepositories(t *testing.T) {
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/groups/", func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.EscapedPath()+"?"+req.URL.RawQuery)
+		if strings.Contains(req.URL.EscapedPath(), "test-error-response") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// Return a full first page, so that the second page is requested
+		count := listPageSize
+		if req.URL.Query().Get("page") == "2" {
+			count = 1
+		}
+		var projects []string
+		for i := 0; i < count; i++ {
+			projects = append(projects, `{"id": 1, "name": "Test Repo", "path": "test-repo", "web_url": "https://gitlab.com/appdata/test-repo", "archived": true, "created_at": "2023-01-01T00:00:00Z", "topics": ["has-cluster-a"]}`)
+		}
+		/* #nosec G104 -- test code */
+		w.Write([]byte("[" + strings.Join(projects, ",") + "]"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+	repos, err := client.ListRepositories(context.Background(), "appdata/gitops")
+	if err != nil {
+		t.Fatalf("TestListRepositories() unexpected error: %v", err)
+	}
+	if len(repos) != listPageSize+1 {
+		t.Errorf("TestListRepositories() error: expected %v projects got %v", listPageSize+1, len(repos))
+	}
+	want := gitprovider.Repository{Name: "test-repo", URL: "https://gitlab.com/appdata/test-repo", CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Archived: true, Topics: []string{"has-cluster-a"}}
+	if !reflect.DeepEqual(repos[0], want) {
+		t.Errorf("TestListRepositories() error: expected %v got %v", want, repos[0])
+	}
+	wantRequests := []string{
+		"/api/v4/groups/appdata%2Fgitops/projects?per_page=100&page=1",
+		"/api/v4/groups/appdata%2Fgitops/projects?per_page=100&page=2",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("TestListRepositories() error: expected requests %v got %v", wantRequests, requests)
+	}
+
+	if _, err := client.ListRepositories(context.Background(), "test-error-response"); err == nil {
+		t.Errorf("TestListRepositories() error: expected an error listing the projects")
+	}
+}
+
+func TestGetRepoAndGroupFromURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		repoURL   string
+		wantRepo  string
+		wantGroup string
+		wantErr   bool
+	}{
+		{
+			name:      "Simple repo url",
+			repoURL:   "https://gitlab.com/redhat-appstudio-appdata/test-repo-1",
+			wantRepo:  "test-repo-1",
+			wantGroup: "redhat-appstudio-appdata",
+		},
+		{
+			name:      "Repo url with subgroup and .git suffix",
+			repoURL:   "https://gitlab.com/redhat-appstudio/appdata/test-repo-1.git",
+			wantRepo:  "test-repo-1",
+			wantGroup: "redhat-appstudio/appdata",
+		},
+		{
+			name:    "Repo url without group",
+			repoURL: "https://gitlab.com/test-repo-1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, group, err := GetRepoAndGroupFromURL(tt.repoURL)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestGetRepoAndGroupFromURL() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && (repo != tt.wantRepo || group != tt.wantGroup) {
+				t.Errorf("TestGetRepoAndGroupFromURL() error: expected %v/%v got %v/%v", tt.wantGroup, tt.wantRepo, group, repo)
+			}
+		})
+	}
+}
+
+func TestHostsRepository(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		repoURL string
+		want    bool
+	}{
+		{
+			name:    "Project on gitlab.com",
+			repoURL: "https://gitlab.com/redhat-appstudio/appdata/test-repo-1",
+			want:    true,
+		},
+		{
+			name:    "Project on a self-hosted GitLab instance",
+			baseURL: "https://gitlab.example.com/",
+			repoURL: "https://gitlab.example.com/redhat-appstudio-appdata/test-repo-1",
+			want:    true,
+		},
+		{
+			name:    "Repository on GitHub",
+			repoURL: "https://github.com/redhat-appstudio-appdata/test-repo-1",
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewGitLabClient(tt.baseURL, "token", "GITLAB_TOKEN")
+			if got := client.HostsRepository(tt.repoURL); got != tt.want {
+				t.Errorf("TestHostsRepository() error: expected %v got %v", tt.want, got)
+			}
+		})
+	}
+
+	if credentials := NewGitLabClient("", "token", "GITLAB_TOKEN").GetRemoteCredentials().String(); credentials != "oauth2:token" {
+		t.Errorf("TestHostsRepository() error: unexpected remote credentials %v", credentials)
+	}
+}
+
+func TestGetBranches(t *testing.T) {
+	server := newMockedGitLabServer(t)
+	defer server.Close()
+	client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+	repoURL := server.URL + "/redhat-appstudio-appdata/test-repo-1"
+
+	defaultBranch, err := client.GetDefaultBranchFromURL(repoURL, context.Background())
+	if err != nil || defaultBranch != "main" {
+		t.Errorf("GetDefaultBranchFromURL() error: expected main got %v, error: %v", defaultBranch, err)
+	}
+
+	branchName, err := client.GetBranchNameFromURL(repoURL, context.Background(), "main")
+	if err != nil || branchName != "main" {
+		t.Errorf("GetBranchNameFromURL() error: expected main got %v, error: %v", branchName, err)
+	}
+
+	if _, err := client.GetBranchNameFromURL(repoURL, context.Background(), "does-not-exist"); err == nil {
+		t.Errorf("GetBranchNameFromURL() expected an error for a missing branch")
+	}
+
+	commitSHA, err := client.GetLatestCommitSHAFromRepository(context.Background(), "test-repo-1", "redhat-appstudio-appdata", "main")
+	if err != nil || commitSHA != "ca82a6dff817ec66f44342007202690a93763949" {
+		t.Errorf("GetLatestCommitSHAFromRepository() unexpected value %v, error: %v", commitSHA, err)
+	}
+}
+
+func TestCreateOrUpdatePullRequest(t *testing.T) {
+	var method string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, req *http.Request) {
+		path := req.URL.EscapedPath()
+		switch {
+		case strings.HasSuffix(path, "/repository/compare"):
+			if req.URL.Query().Get("to") == "unchanged-branch" {
+				/* #nosec G104 -- test code */
+				w.Write([]byte(`{"commits": []}`))
+				return
+			}
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"commits": [{"id": "ca82a6dff817ec66f44342007202690a93763949"}]}`))
+		case strings.HasSuffix(path, "/merge_requests") && req.Method == http.MethodGet:
+			if req.URL.Query().Get("source_branch") == "open-branch" && req.URL.Query().Get("state") == "opened" {
+				/* #nosec G104 -- test code */
+				w.Write([]byte(`[{"iid": 2, "web_url": "https://gitlab.com/test-group/test-repo/-/merge_requests/2", "state": "opened"}]`))
+				return
+			}
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`[]`))
+		case strings.HasSuffix(path, "/merge_requests/2") && req.Method == http.MethodPut:
+			method = req.Method
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"iid": 2, "web_url": "https://gitlab.com/test-group/test-repo/-/merge_requests/2", "state": "opened"}`))
+		case strings.HasSuffix(path, "/merge_requests") && req.Method == http.MethodPost:
+			method = req.Method
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"iid": 1, "web_url": "https://gitlab.com/test-group/test-repo/-/merge_requests/1", "state": "opened"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		headBranch string
+		wantMethod string
+		want       *gitprovider.PullRequest
+	}{
+		{
+			name:       "No changes on the work branch, no merge request is opened",
+			headBranch: "unchanged-branch",
+		},
+		{
+			name:       "No open merge request, a merge request is opened",
+			headBranch: "new-branch",
+			wantMethod: http.MethodPost,
+			want:       &gitprovider.PullRequest{Number: 1, URL: "https://gitlab.com/test-group/test-repo/-/merge_requests/1", State: gitprovider.PullRequestStateOpen},
+		},
+		{
+			name:       "Open merge request, the merge request is updated",
+			headBranch: "open-branch",
+			wantMethod: http.MethodPut,
+			want:       &gitprovider.PullRequest{Number: 2, URL: "https://gitlab.com/test-group/test-repo/-/merge_requests/2", State: gitprovider.PullRequestStateOpen},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method = ""
+			client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+			opts := gitprovider.PullRequestOptions{Title: "Update GitOps resources", HeadBranch: tt.headBranch, BaseBranch: "main"}
+			pullRequest, err := client.CreateOrUpdatePullRequest(context.Background(), "test-group", "test-repo", opts)
+			if err != nil {
+				t.Errorf("TestCreateOrUpdatePullRequest() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(pullRequest, tt.want) {
+				t.Errorf("TestCreateOrUpdatePullRequest() error: expected %v got %v", tt.want, pullRequest)
+			}
+			if method != tt.wantMethod {
+				t.Errorf("TestCreateOrUpdatePullRequest() error: expected a %q request got %q", tt.wantMethod, method)
+			}
+		})
+	}
+}
+
+func TestGetPullRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/merge_requests/1"):
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"iid": 1, "web_url": "https://gitlab.com/test-group/test-repo/-/merge_requests/1", "state": "merged", "merge_commit_sha": "ca82a6dff817ec66f44342007202690a93763949"}`))
+		case strings.HasSuffix(req.URL.Path, "/merge_requests/2"):
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"iid": 2, "web_url": "https://gitlab.com/test-group/test-repo/-/merge_requests/2", "state": "merged", "squash_commit_sha": "085bb3bcb608e1e8451d4b2432f8ecbe6306e7e7"}`))
+		case strings.HasSuffix(req.URL.Path, "/merge_requests/3"):
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"iid": 3, "web_url": "https://gitlab.com/test-group/test-repo/-/merge_requests/3", "state": "closed"}`))
+		case strings.HasSuffix(req.URL.Path, "/merge_requests/4"):
+			/* #nosec G104 -- test code */
+			w.Write([]byte(`{"iid": 4, "web_url": "https://gitlab.com/test-group/test-repo/-/merge_requests/4", "state": "locked"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		number  int
+		want    *gitprovider.PullRequest
+		wantErr bool
+	}{
+		{
+			name:   "Merged merge request",
+			number: 1,
+			want:   &gitprovider.PullRequest{Number: 1, URL: "https://gitlab.com/test-group/test-repo/-/merge_requests/1", State: gitprovider.PullRequestStateMerged, MergeCommitSHA: "ca82a6dff817ec66f44342007202690a93763949"},
+		},
+		{
+			name:   "Fast-forward merged merge request, squash commit is used",
+			number: 2,
+			want:   &gitprovider.PullRequest{Number: 2, URL: "https://gitlab.com/test-group/test-repo/-/merge_requests/2", State: gitprovider.PullRequestStateMerged, MergeCommitSHA: "085bb3bcb608e1e8451d4b2432f8ecbe6306e7e7"},
+		},
+		{
+			name:   "Closed merge request",
+			number: 3,
+			want:   &gitprovider.PullRequest{Number: 3, URL: "https://gitlab.com/test-group/test-repo/-/merge_requests/3", State: gitprovider.PullRequestStateClosed},
+		},
+		{
+			name:   "Locked merge request is still open",
+			number: 4,
+			want:   &gitprovider.PullRequest{Number: 4, URL: "https://gitlab.com/test-group/test-repo/-/merge_requests/4", State: gitprovider.PullRequestStateOpen},
+		},
+		{
+			name:    "Merge request that doesn't exist",
+			number:  5,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+			pullRequest, err := client.GetPullRequest(context.Background(), "test-group", "test-repo", tt.number)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestGetPullRequest() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(pullRequest, tt.want) {
+				t.Errorf("TestGetPullRequest() error: expected %v got %v", tt.want, pullRequest)
+			}
+		})
+	}
+}
+
+func TestResetBranch(t *testing.T) {
+	var requests []string
+	var createRequest map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.EscapedPath())
+		switch req.Method {
+		case http.MethodDelete:
+			if strings.Contains(req.URL.Path, "missing-repo") {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPost:
+			/* #nosec G104 -- test code */
+			json.NewDecoder(req.Body).Decode(&createRequest)
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := NewGitLabClient(server.URL, "fake-token", "gitlab")
+
+	for _, repoName := range []string{"test-repo", "missing-repo"} {
+		requests, createRequest = nil, nil
+		if err := client.ResetBranch(context.Background(), "test-group", repoName, "appstudio/component/backend", "main"); err != nil {
+			t.Fatalf("TestResetBranch() unexpected error: %v", err)
+		}
+		wantRequests := []string{
+			"DELETE /api/v4/projects/test-group%2F" + repoName + "/repository/branches/appstudio%2Fcomponent%2Fbackend",
+			"POST /api/v4/projects/test-group%2F" + repoName + "/repository/branches",
+		}
+		if !reflect.DeepEqual(requests, wantRequests) {
+			t.Errorf("TestResetBranch() error: expected requests %v got %v", wantRequests, requests)
+		}
+		if createRequest["branch"] != "appstudio/component/backend" || createRequest["ref"] != "main" {
+			t.Errorf("TestResetBranch() error: unexpected branch creation request %v", createRequest)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitprovider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Deletion policies, for what happens to a generated GitOps repository when its Application is deleted
+	DeletionPolicyDelete                 = "delete"
+	DeletionPolicyArchive                = "archive"
+	DeletionPolicyRetain                 = "retain"
+	DeletionPolicyDeleteAfterGracePeriod = "delete-after-grace-period"
+
+	// Annotations on the Application CR that override the operator's deletion policy for its generated GitOps repository
+	RepoDeletionPolicyAnnotation      = "gitops-repo-deletion-policy"
+	RepoDeletionGracePeriodAnnotation = "gitops-repo-deletion-grace-period"
+
+	// DefaultDeletionGracePeriod is how long an archived repository is kept for under the delete-after-grace-period policy, if no grace period is set
+	DefaultDeletionGracePeriod = 30 * 24 * time.Hour
+)
+
+// DeletionPolicy is what happens to a generated GitOps repository when its Application is deleted
+type DeletionPolicy struct {
+	// Policy is one of delete, archive, retain or delete-after-grace-period. Defaults to delete
+	Policy string
+
+	// GracePeriod is how long the repository is kept archived for before it's deleted, under the delete-after-grace-period policy
+	GracePeriod time.Duration
+}
+
+// NewDeletionPolicy parses and validates a deletion policy from its string form: a policy name and a grace period, e.g. "720h"
+func NewDeletionPolicy(policy string, gracePeriod string) (DeletionPolicy, error) {
+	var p DeletionPolicy
+	var err error
+	if p.Policy, err = ValidateDeletionPolicy(policy); err != nil {
+		return DeletionPolicy{}, err
+	}
+	if p.GracePeriod, err = ParseGracePeriod(gracePeriod); err != nil {
+		return DeletionPolicy{}, err
+	}
+	return p, nil
+}
+
+// WithOverrides returns a copy of the deletion policy, with any setting overridden by the given Application annotations
+func (p DeletionPolicy) WithOverrides(annotations map[string]string) (DeletionPolicy, error) {
+	var err error
+	if value, ok := annotations[RepoDeletionPolicyAnnotation]; ok {
+		if p.Policy, err = ValidateDeletionPolicy(value); err != nil {
+			return DeletionPolicy{}, fmt.Errorf("invalid %s annotation: %v", RepoDeletionPolicyAnnotation, err)
+		}
+	}
+	if value, ok := annotations[RepoDeletionGracePeriodAnnotation]; ok {
+		if p.GracePeriod, err = ParseGracePeriod(value); err != nil {
+			return DeletionPolicy{}, fmt.Errorf("invalid %s annotation: %v", RepoDeletionGracePeriodAnnotation, err)
+		}
+	}
+	return p, nil
+}
+
+// ValidateDeletionPolicy validates a deletion policy, defaulting to delete if it's empty
+func ValidateDeletionPolicy(policy string) (string, error) {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	switch policy {
+	case "":
+		return DeletionPolicyDelete, nil
+	case DeletionPolicyDelete, DeletionPolicyArchive, DeletionPolicyRetain, DeletionPolicyDeleteAfterGracePeriod:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported deletion policy %q, must be one of %q, %q, %q or %q", policy,
+			DeletionPolicyDelete, DeletionPolicyArchive, DeletionPolicyRetain, DeletionPolicyDeleteAfterGracePeriod)
+	}
+}
+
+// ParseGracePeriod parses a deletion grace period, in the form of a Go duration such as "720h". It defaults to DefaultDeletionGracePeriod if it's empty
+func ParseGracePeriod(gracePeriod string) (time.Duration, error) {
+	gracePeriod = strings.TrimSpace(gracePeriod)
+	if gracePeriod == "" {
+		return DefaultDeletionGracePeriod, nil
+	}
+	duration, err := time.ParseDuration(gracePeriod)
+	if err != nil {
+		return 0, fmt.Errorf("invalid grace period %q: %v", gracePeriod, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("invalid grace period %q, it must be positive", gracePeriod)
+	}
+	return duration, nil
+}
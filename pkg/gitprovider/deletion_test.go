@@ -0,0 +1,123 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitprovider
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewDeletionPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		gracePeriod string
+		want        DeletionPolicy
+		wantErr     bool
+	}{
+		{
+			name: "No settings, repositories are deleted",
+			want: DeletionPolicy{Policy: DeletionPolicyDelete, GracePeriod: DefaultDeletionGracePeriod},
+		},
+		{
+			name:        "Delete after a grace period",
+			policy:      " Delete-After-Grace-Period ",
+			gracePeriod: "168h",
+			want:        DeletionPolicy{Policy: DeletionPolicyDeleteAfterGracePeriod, GracePeriod: 168 * time.Hour},
+		},
+		{
+			name:    "Invalid policy",
+			policy:  "orphan",
+			wantErr: true,
+		},
+		{
+			name:        "Invalid grace period",
+			policy:      DeletionPolicyDeleteAfterGracePeriod,
+			gracePeriod: "30d",
+			wantErr:     true,
+		},
+		{
+			name:        "Negative grace period",
+			policy:      DeletionPolicyDeleteAfterGracePeriod,
+			gracePeriod: "-1h",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewDeletionPolicy(tt.policy, tt.gracePeriod)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestNewDeletionPolicy() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(policy, tt.want) {
+				t.Errorf("TestNewDeletionPolicy() error: expected %v got %v", tt.want, policy)
+			}
+		})
+	}
+}
+
+func TestDeletionPolicyWithOverrides(t *testing.T) {
+	defaults := DeletionPolicy{Policy: DeletionPolicyArchive, GracePeriod: DefaultDeletionGracePeriod}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        DeletionPolicy
+		wantErr     bool
+	}{
+		{
+			name: "No annotations, operator settings are used",
+			want: defaults,
+		},
+		{
+			name: "All settings overridden",
+			annotations: map[string]string{
+				RepoDeletionPolicyAnnotation:      DeletionPolicyDeleteAfterGracePeriod,
+				RepoDeletionGracePeriodAnnotation: "24h",
+			},
+			want: DeletionPolicy{Policy: DeletionPolicyDeleteAfterGracePeriod, GracePeriod: 24 * time.Hour},
+		},
+		{
+			name:        "Retain the repository",
+			annotations: map[string]string{RepoDeletionPolicyAnnotation: "retain"},
+			want:        DeletionPolicy{Policy: DeletionPolicyRetain, GracePeriod: DefaultDeletionGracePeriod},
+		},
+		{
+			name:        "Invalid policy annotation",
+			annotations: map[string]string{RepoDeletionPolicyAnnotation: "orphan"},
+			wantErr:     true,
+		},
+		{
+			name:        "Invalid grace period annotation",
+			annotations: map[string]string{RepoDeletionGracePeriodAnnotation: "soon"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := defaults.WithOverrides(tt.annotations)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestDeletionPolicyWithOverrides() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(policy, tt.want) {
+				t.Errorf("TestDeletionPolicyWithOverrides() error: expected %v got %v", tt.want, policy)
+			}
+		})
+	}
+}
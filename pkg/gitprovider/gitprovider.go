@@ -0,0 +1,144 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// GitHub is the name of the GitHub Git provider, and the default provider for generated GitOps repositories
+	GitHub = "github"
+
+	// GitLab is the name of the GitLab Git provider
+	GitLab = "gitlab"
+
+	// GitOpsProviderAnnotation is the annotation on the Application CR used to select the Git provider that
+	// its generated GitOps repository is created on, overriding the operator's default provider
+	GitOpsProviderAnnotation = "gitops-provider"
+)
+
+// GitProvider is the provider-agnostic interface used by the controllers to manage the repositories hosted on a Git provider
+type GitProvider interface {
+	// GenerateNewRepository creates a new repository under the given org (or group) with the given visibility and access
+	// settings, and returns the URL of the repository. If the repository is generated from a template that the Git provider is
+	// still copying, its URL is returned along with a RepositoryPendingError, and its access settings are left to FinishNewRepository
+	GenerateNewRepository(ctx context.Context, orgName string, repoName string, description string, opts RepositoryOptions) (string, error)
+
+	// FinishNewRepository applies the access settings of a repository generated by GenerateNewRepository from a template, once the
+	// template has been copied. Like GenerateNewRepository, it returns a RepositoryPendingError while the copy is in progress
+	FinishNewRepository(ctx context.Context, orgName string, repoName string, opts RepositoryOptions) error
+
+	// DeleteRepository deletes the given repository under the given org (or group)
+	DeleteRepository(ctx context.Context, orgName string, repoName string) error
+
+	// ArchiveRepository archives the given repository under the given org (or group), making it read-only
+	ArchiveRepository(ctx context.Context, orgName string, repoName string) error
+
+	// ListRepositories returns the repositories directly under the given org (or group)
+	ListRepositories(ctx context.Context, orgName string) ([]Repository, error)
+
+	// CreateOrUpdatePullRequest opens a pull request from the head branch to the base branch of the given repository, or updates the
+	// open pull request between them if there's one already. It returns nil if the head branch has no changes to merge
+	CreateOrUpdatePullRequest(ctx context.Context, orgName string, repoName string, opts PullRequestOptions) (*PullRequest, error)
+
+	// GetPullRequest returns the pull request with the given number of the given repository
+	GetPullRequest(ctx context.Context, orgName string, repoName string, number int) (*PullRequest, error)
+
+	// ResetBranch points the given branch of the repository at the latest commit of the base branch, discarding the commits that
+	// are only on the branch. The branch is created if it doesn't exist
+	ResetBranch(ctx context.Context, orgName string, repoName string, branchName string, baseBranch string) error
+
+	// GetRepoNameFromURL returns the repository name from the Git repo URL
+	GetRepoNameFromURL(repoURL string, orgName string) (string, error)
+
+	// GetRepoAndOrgFromURL returns both the repository name and its org (or group) from the Git repo URL
+	GetRepoAndOrgFromURL(repoURL string) (string, string, error)
+
+	// GetDefaultBranchFromURL returns the default branch of the given repository URL
+	GetDefaultBranchFromURL(repoURL string, ctx context.Context) (string, error)
+
+	// GetBranchNameFromURL returns the name of the requested branch of the given repository URL, or an error if it does not exist
+	GetBranchNameFromURL(repoURL string, ctx context.Context, branchName string) (string, error)
+
+	// GetLatestCommitSHAFromRepository returns the latest commit SHA of the given branch of the repository
+	GetLatestCommitSHAFromRepository(ctx context.Context, repoName string, orgName string, branch string) (string, error)
+
+	// GetTokenName returns the name of the token used by the client, used for metrics and logging
+	GetTokenName() string
+
+	// GetToken returns the token used by the client to authenticate against the Git provider
+	GetToken() string
+
+	// HostsRepository returns true if the given repository URL is on the Git provider of the client
+	HostsRepository(repoURL string) bool
+
+	// GetRemoteCredentials returns the credentials that git uses to clone from and push to the repositories of the Git provider
+	GetRemoteCredentials() *url.Userinfo
+}
+
+// Repository is a repository hosted on a Git provider
+type Repository struct {
+	// Name is the name of the repository, without its org (or group)
+	Name string
+
+	// URL is the web URL of the repository
+	URL string
+
+	// CreatedAt is when the repository was created
+	CreatedAt time.Time
+
+	// Archived is true if the repository is archived, and so read-only
+	Archived bool
+
+	// Topics are the topics of the repository
+	Topics []string
+}
+
+// RepositoryPendingError is returned when a repository generated from a template can't be set up yet, as the Git provider is
+// still copying the template into it. The repository exists, and is finished with FinishNewRepository once the copy completes
+type RepositoryPendingError struct {
+	RepoURL string
+}
+
+func (e *RepositoryPendingError) Error() string {
+	return fmt.Sprintf("repository %s is still being generated from its template", e.RepoURL)
+}
+
+// IsRepositoryPending returns true if err is a RepositoryPendingError
+func IsRepositoryPending(err error) bool {
+	var pendingErr *RepositoryPendingError
+	return errors.As(err, &pendingErr)
+}
+
+// ValidateProviderName returns the normalized name of the given Git provider, or an error if the provider is not supported.
+// An empty provider name defaults to GitHub.
+func ValidateProviderName(provider string) (string, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	switch provider {
+	case "":
+		return GitHub, nil
+	case GitHub, GitLab:
+		return provider, nil
+	default:
+		return "", fmt.Errorf("unsupported git provider %q, must be one of %q or %q", provider, GitHub, GitLab)
+	}
+}
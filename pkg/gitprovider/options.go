@@ -0,0 +1,218 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitprovider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Repository visibilities. Internal repositories are only visible to members of the enterprise (GitHub) or instance (GitLab)
+	VisibilityPublic   = "public"
+	VisibilityPrivate  = "private"
+	VisibilityInternal = "internal"
+
+	// Team permissions, using GitHub's names. On GitLab they are mapped to the closest access level
+	PermissionPull     = "pull"
+	PermissionTriage   = "triage"
+	PermissionPush     = "push"
+	PermissionMaintain = "maintain"
+	PermissionAdmin    = "admin"
+
+	// Annotations on the Application CR that override the operator's settings for its generated GitOps repository
+	RepoVisibilityAnnotation      = "gitops-repo-visibility"
+	RepoTeamsAnnotation           = "gitops-repo-teams"
+	RepoProtectedBranchAnnotation = "gitops-repo-protected-branch"
+	RepoTopicsAnnotation          = "gitops-repo-topics"
+	RepoTemplateAnnotation        = "gitops-repo-template"
+)
+
+// RepositoryOptions are the settings, such as its visibility and access, that a GitOps repository is generated with
+type RepositoryOptions struct {
+	// Visibility is one of public, private or internal. Defaults to public
+	Visibility string
+
+	// TeamPermissions maps the teams (GitHub team slugs or GitLab group paths) that are given access to the repository to their permission
+	TeamPermissions map[string]string
+
+	// ProtectedBranch is the branch to protect against force pushes and deletion. No branch is protected if it's empty
+	ProtectedBranch string
+
+	// Topics are the topics added to the repository
+	Topics []string
+
+	// Template is the template repository, in the form <org>/<repo>, that the repository is generated from. It is created empty if this is empty
+	Template string
+
+	// OwnerTopic is a topic that marks the repository as generated by this instance of the operator, so that orphaned repositories
+	// in an org shared by several instances can be told apart. It can't be overridden, and isn't added if it's empty
+	OwnerTopic string
+}
+
+// AllTopics returns the topics to add to the repository, including the owner topic
+func (o RepositoryOptions) AllTopics() []string {
+	if o.OwnerTopic == "" {
+		return o.Topics
+	}
+	topics := make([]string, 0, len(o.Topics)+1)
+	for _, topic := range o.Topics {
+		if topic != o.OwnerTopic {
+			topics = append(topics, topic)
+		}
+	}
+	return append(topics, o.OwnerTopic)
+}
+
+// IsPrivate returns true if the repository isn't publicly visible
+func (o RepositoryOptions) IsPrivate() bool {
+	return o.Visibility != "" && o.Visibility != VisibilityPublic
+}
+
+// SortedTeams returns the names of the teams with access to the repository, in a stable order
+func (o RepositoryOptions) SortedTeams() []string {
+	teams := make([]string, 0, len(o.TeamPermissions))
+	for team := range o.TeamPermissions {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+// NewRepositoryOptions parses and validates repository options from their string form: a visibility, a comma separated list
+// of team:permission pairs, a branch name, a comma separated list of topics and a template repository
+func NewRepositoryOptions(visibility string, teams string, protectedBranch string, topics string, template string) (RepositoryOptions, error) {
+	var opts RepositoryOptions
+	var err error
+	if opts.Visibility, err = ValidateVisibility(visibility); err != nil {
+		return RepositoryOptions{}, err
+	}
+	if opts.TeamPermissions, err = ParseTeamPermissions(teams); err != nil {
+		return RepositoryOptions{}, err
+	}
+	opts.ProtectedBranch = strings.TrimSpace(protectedBranch)
+	opts.Topics = ParseTopics(topics)
+	if opts.Template, err = ValidateTemplate(template); err != nil {
+		return RepositoryOptions{}, err
+	}
+	return opts, nil
+}
+
+// WithOverrides returns a copy of the options, with any setting overridden by the given Application annotations
+func (o RepositoryOptions) WithOverrides(annotations map[string]string) (RepositoryOptions, error) {
+	var err error
+	if value, ok := annotations[RepoVisibilityAnnotation]; ok {
+		if o.Visibility, err = ValidateVisibility(value); err != nil {
+			return RepositoryOptions{}, fmt.Errorf("invalid %s annotation: %v", RepoVisibilityAnnotation, err)
+		}
+	}
+	if value, ok := annotations[RepoTeamsAnnotation]; ok {
+		if o.TeamPermissions, err = ParseTeamPermissions(value); err != nil {
+			return RepositoryOptions{}, fmt.Errorf("invalid %s annotation: %v", RepoTeamsAnnotation, err)
+		}
+	}
+	if value, ok := annotations[RepoProtectedBranchAnnotation]; ok {
+		o.ProtectedBranch = strings.TrimSpace(value)
+	}
+	if value, ok := annotations[RepoTopicsAnnotation]; ok {
+		o.Topics = ParseTopics(value)
+	}
+	if value, ok := annotations[RepoTemplateAnnotation]; ok {
+		if o.Template, err = ValidateTemplate(value); err != nil {
+			return RepositoryOptions{}, fmt.Errorf("invalid %s annotation: %v", RepoTemplateAnnotation, err)
+		}
+	}
+	return o, nil
+}
+
+// ValidateVisibility validates a repository visibility, defaulting to public if it's empty
+func ValidateVisibility(visibility string) (string, error) {
+	visibility = strings.ToLower(strings.TrimSpace(visibility))
+	switch visibility {
+	case "":
+		return VisibilityPublic, nil
+	case VisibilityPublic, VisibilityPrivate, VisibilityInternal:
+		return visibility, nil
+	default:
+		return "", fmt.Errorf("unsupported repository visibility %q, must be one of %q, %q or %q", visibility, VisibilityPublic, VisibilityPrivate, VisibilityInternal)
+	}
+}
+
+// ParseTeamPermissions parses a comma separated list of team:permission pairs, e.g. "devs:push,admins:admin".
+// The permission defaults to pull if it's omitted.
+func ParseTeamPermissions(teams string) (map[string]string, error) {
+	teamPermissions := make(map[string]string)
+	for _, teamPermission := range strings.Split(teams, ",") {
+		teamPermission = strings.TrimSpace(teamPermission)
+		if teamPermission == "" {
+			continue
+		}
+		team, permission, _ := strings.Cut(teamPermission, ":")
+		team = strings.TrimSpace(team)
+		if team == "" {
+			return nil, fmt.Errorf("invalid team permission %q, the team name must be set", teamPermission)
+		}
+		permission = strings.ToLower(strings.TrimSpace(permission))
+		switch permission {
+		case "":
+			permission = PermissionPull
+		case PermissionPull, PermissionTriage, PermissionPush, PermissionMaintain, PermissionAdmin:
+		default:
+			return nil, fmt.Errorf("invalid permission %q for team %q, must be one of %q, %q, %q, %q or %q", permission, team,
+				PermissionPull, PermissionTriage, PermissionPush, PermissionMaintain, PermissionAdmin)
+		}
+		teamPermissions[team] = permission
+	}
+	if len(teamPermissions) == 0 {
+		return nil, nil
+	}
+	return teamPermissions, nil
+}
+
+// ParseTopics parses a comma separated list of repository topics. Topics are lowercased, as required by GitHub
+func ParseTopics(topics string) []string {
+	var parsedTopics []string
+	for _, topic := range strings.Split(topics, ",") {
+		topic = strings.ToLower(strings.TrimSpace(topic))
+		if topic != "" {
+			parsedTopics = append(parsedTopics, topic)
+		}
+	}
+	return parsedTopics
+}
+
+// ValidateTemplate validates a template repository, which must be of the form <org>/<repo>. On GitLab, the org can include subgroups
+func ValidateTemplate(template string) (string, error) {
+	template = strings.Trim(strings.TrimSpace(template), "/")
+	if template == "" {
+		return "", nil
+	}
+	org, repo := SplitTemplate(template)
+	if org == "" || repo == "" || strings.Contains(org, "//") {
+		return "", fmt.Errorf("invalid template repository %q, must be of the form <org>/<repo>", template)
+	}
+	return template, nil
+}
+
+// SplitTemplate returns the org (or group) and repository name of a template repository
+func SplitTemplate(template string) (string, string) {
+	lastSlash := strings.LastIndex(template, "/")
+	if lastSlash < 0 {
+		return "", template
+	}
+	return template[:lastSlash], template[lastSlash+1:]
+}
@@ -0,0 +1,200 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitprovider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewRepositoryOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		visibility      string
+		teams           string
+		protectedBranch string
+		topics          string
+		template        string
+		want            RepositoryOptions
+		wantErr         bool
+	}{
+		{
+			name: "No settings, public repository",
+			want: RepositoryOptions{Visibility: VisibilityPublic},
+		},
+		{
+			name:            "All settings",
+			visibility:      " Private ",
+			teams:           "devs:push, admins:ADMIN,readers",
+			protectedBranch: "main",
+			topics:          "GitOps, appstudio,,",
+			template:        "my-org/gitops-template/",
+			want: RepositoryOptions{
+				Visibility:      VisibilityPrivate,
+				TeamPermissions: map[string]string{"devs": PermissionPush, "admins": PermissionAdmin, "readers": PermissionPull},
+				ProtectedBranch: "main",
+				Topics:          []string{"gitops", "appstudio"},
+				Template:        "my-org/gitops-template",
+			},
+		},
+		{
+			name:     "Template in a GitLab subgroup",
+			template: "my-group/templates/gitops-template",
+			want:     RepositoryOptions{Visibility: VisibilityPublic, Template: "my-group/templates/gitops-template"},
+		},
+		{
+			name:     "Template without an org",
+			template: "gitops-template",
+			wantErr:  true,
+		},
+		{
+			name:       "Invalid visibility",
+			visibility: "secret",
+			wantErr:    true,
+		},
+		{
+			name:    "Invalid team permission",
+			teams:   "devs:write",
+			wantErr: true,
+		},
+		{
+			name:    "Team permission without a team",
+			teams:   ":push",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := NewRepositoryOptions(tt.visibility, tt.teams, tt.protectedBranch, tt.topics, tt.template)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestNewRepositoryOptions() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(opts, tt.want) {
+				t.Errorf("TestNewRepositoryOptions() error: expected %v got %v", tt.want, opts)
+			}
+		})
+	}
+}
+
+func TestRepositoryOptionsWithOverrides(t *testing.T) {
+	defaults := RepositoryOptions{
+		Visibility:      VisibilityPrivate,
+		TeamPermissions: map[string]string{"devs": PermissionPush},
+		ProtectedBranch: "main",
+		Topics:          []string{"gitops"},
+		OwnerTopic:      "has-cluster-a",
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        RepositoryOptions
+		wantErr     bool
+	}{
+		{
+			name: "No annotations, operator settings are used",
+			want: defaults,
+		},
+		{
+			name: "All settings overridden",
+			annotations: map[string]string{
+				RepoVisibilityAnnotation:      "public",
+				RepoTeamsAnnotation:           "ops:maintain",
+				RepoProtectedBranchAnnotation: "gitops",
+				RepoTopicsAnnotation:          "team-a",
+				RepoTemplateAnnotation:        "team-a/gitops-template",
+			},
+			want: RepositoryOptions{
+				Visibility:      VisibilityPublic,
+				TeamPermissions: map[string]string{"ops": PermissionMaintain},
+				ProtectedBranch: "gitops",
+				Topics:          []string{"team-a"},
+				Template:        "team-a/gitops-template",
+				OwnerTopic:      "has-cluster-a",
+			},
+		},
+		{
+			name: "Empty annotations clear the operator settings",
+			annotations: map[string]string{
+				RepoTeamsAnnotation:           "",
+				RepoProtectedBranchAnnotation: "",
+				RepoTopicsAnnotation:          "",
+			},
+			want: RepositoryOptions{Visibility: VisibilityPrivate, OwnerTopic: "has-cluster-a"},
+		},
+		{
+			name:        "Invalid template annotation",
+			annotations: map[string]string{RepoTemplateAnnotation: "gitops-template"},
+			wantErr:     true,
+		},
+		{
+			name:        "Invalid visibility annotation",
+			annotations: map[string]string{RepoVisibilityAnnotation: "secret"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := defaults.WithOverrides(tt.annotations)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestRepositoryOptionsWithOverrides() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(opts, tt.want) {
+				t.Errorf("TestRepositoryOptionsWithOverrides() error: expected %v got %v", tt.want, opts)
+			}
+		})
+	}
+}
+
+func TestRepositoryOptionsAllTopics(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RepositoryOptions
+		want []string
+	}{
+		{
+			name: "No owner topic",
+			opts: RepositoryOptions{Topics: []string{"gitops"}},
+			want: []string{"gitops"},
+		},
+		{
+			name: "Owner topic is added",
+			opts: RepositoryOptions{Topics: []string{"gitops"}, OwnerTopic: "has-cluster-a"},
+			want: []string{"gitops", "has-cluster-a"},
+		},
+		{
+			name: "Owner topic isn't duplicated",
+			opts: RepositoryOptions{Topics: []string{"has-cluster-a", "gitops"}, OwnerTopic: "has-cluster-a"},
+			want: []string{"gitops", "has-cluster-a"},
+		},
+		{
+			name: "Only an owner topic",
+			opts: RepositoryOptions{OwnerTopic: "has-cluster-a"},
+			want: []string{"has-cluster-a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topics := tt.opts.AllTopics()
+			if !reflect.DeepEqual(topics, tt.want) {
+				t.Errorf("TestRepositoryOptionsAllTopics() error: expected %v got %v", tt.want, topics)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitprovider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Pull request states. GitLab merge requests are mapped to the same states
+	PullRequestStateOpen   = "open"
+	PullRequestStateMerged = "merged"
+	PullRequestStateClosed = "closed"
+
+	// PullRequestModeAnnotation is the annotation on the Application or Environment CR that, when set to true, makes HAS push
+	// GitOps changes to a work branch and open a pull request against the GitOps branch, rather than pushing to it directly
+	PullRequestModeAnnotation = "gitops-pull-request-mode"
+
+	// PullRequestBranchPrefix is the prefix of the work branches that GitOps changes are pushed to in pull request mode
+	PullRequestBranchPrefix = "appstudio/"
+)
+
+// PullRequest is a pull request (or GitLab merge request) on a Git provider
+type PullRequest struct {
+	// Number is the number of the pull request, or the IID of the merge request on GitLab
+	Number int
+
+	// URL is the web URL of the pull request
+	URL string
+
+	// State is one of open, merged or closed
+	State string
+
+	// MergeCommitSHA is the SHA of the commit that the pull request was merged with. It's only set once it's merged
+	MergeCommitSHA string
+}
+
+// PullRequestOptions are the settings that a pull request is opened (or updated) with
+type PullRequestOptions struct {
+	// Title is the title of the pull request
+	Title string
+
+	// Body is the description of the pull request
+	Body string
+
+	// HeadBranch is the work branch containing the changes
+	HeadBranch string
+
+	// BaseBranch is the branch that the changes are merged into
+	BaseBranch string
+}
+
+// IsPullRequestModeEnabled returns true if pull request mode is enabled by the annotations of any of the given resources
+func IsPullRequestModeEnabled(annotations ...map[string]string) bool {
+	for _, resourceAnnotations := range annotations {
+		if enabled, err := strconv.ParseBool(resourceAnnotations[PullRequestModeAnnotation]); err == nil && enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// PullRequestBranchName returns the name of the work branch that the GitOps changes of the given resource are pushed to in pull request mode,
+// e.g. appstudio/component/my-component
+func PullRequestBranchName(kind string, name string) string {
+	return PullRequestBranchPrefix + strings.ToLower(kind) + "/" + name
+}
+
+// PullRequestNumberFromURL returns the number of the pull request from its web URL, e.g. https://github.com/org/repo/pull/3
+// or https://gitlab.com/group/project/-/merge_requests/3
+func PullRequestNumberFromURL(pullRequestURL string) (int, error) {
+	path := strings.TrimSuffix(pullRequestURL, "/")
+	number, err := strconv.Atoi(path[strings.LastIndex(path, "/")+1:])
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("unable to parse the pull request number from URL %q", pullRequestURL)
+	}
+	return number, nil
+}
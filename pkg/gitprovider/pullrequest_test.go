@@ -0,0 +1,97 @@
+//
+// Copyright 2023 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitprovider
+
+import (
+	"testing"
+)
+
+func TestIsPullRequestModeEnabled(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations []map[string]string
+		want        bool
+	}{
+		{
+			name: "No annotations",
+			want: false,
+		},
+		{
+			name:        "Enabled on the Application",
+			annotations: []map[string]string{{PullRequestModeAnnotation: "true"}, nil},
+			want:        true,
+		},
+		{
+			name:        "Enabled on the Environment",
+			annotations: []map[string]string{{PullRequestModeAnnotation: "false"}, {PullRequestModeAnnotation: "True"}},
+			want:        true,
+		},
+		{
+			name:        "Invalid value",
+			annotations: []map[string]string{{PullRequestModeAnnotation: "yes please"}},
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPullRequestModeEnabled(tt.annotations...); got != tt.want {
+				t.Errorf("TestIsPullRequestModeEnabled() error: expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPullRequestNumberFromURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "GitHub pull request",
+			url:  "https://github.com/my-org/my-repo/pull/12",
+			want: 12,
+		},
+		{
+			name: "GitLab merge request",
+			url:  "https://gitlab.com/my-group/my-project/-/merge_requests/3/",
+			want: 3,
+		},
+		{
+			name:    "Not a pull request URL",
+			url:     "https://github.com/my-org/my-repo",
+			wantErr: true,
+		},
+		{
+			name:    "Empty URL",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PullRequestNumberFromURL(tt.url)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestPullRequestNumberFromURL() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("TestPullRequestNumberFromURL() error: expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -156,6 +156,26 @@ var (
 			Help: "Number of successful import from git repository requests",
 		},
 	)
+
+	OrphanedGitOpsRepos = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "has_orphaned_gitops_repos",
+			Help: "Number of generated GitOps repositories that no longer belong to an Application, as of the last garbage collection",
+		},
+		//provider - the Git provider, github or gitlab
+		//org - the org (or group) the repositories were generated in
+		[]string{"provider", "org"},
+	)
+
+	OrphanedGitOpsReposCollected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "has_orphaned_gitops_repos_collected_total",
+			Help: "Number of orphaned GitOps repositories that were deleted or archived by the garbage collector",
+		},
+		//provider - the Git provider, github or gitlab
+		//action - delete or archive
+		[]string{"provider", "action"},
+	)
 )
 
 func init() {
@@ -163,7 +183,7 @@ func init() {
 	metrics.Registry.MustRegister(GitOpsRepoCreationTotalReqs, GitOpsRepoCreationFailed, GitOpsRepoCreationSucceeded, ControllerGitRequest, SecondaryRateLimitCounter,
 		PrimaryRateLimitCounter, TokenPoolGauge, ApplicationDeletionTotalReqs, ApplicationDeletionSucceeded, ApplicationDeletionFailed,
 		ApplicationCreationSucceeded, ApplicationCreationFailed, ApplicationCreationTotalReqs, ComponentDeletionTotalReqs, ComponentDeletionSucceeded, ComponentDeletionFailed,
-		ImportGitRepoTotalReqs, ImportGitRepoFailed, ImportGitRepoSucceeded)
+		ImportGitRepoTotalReqs, ImportGitRepoFailed, ImportGitRepoSucceeded, OrphanedGitOpsRepos, OrphanedGitOpsReposCollected)
 }
 
 // HandleRateLimitMetrics checks the error type to verify a primary or secondary rate limit has been encountered
@@ -18,9 +18,14 @@
 package ioutils
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/afero"
@@ -68,3 +73,101 @@ func RemoveFolderAndLogError(log logr.Logger, fs afero.Fs, path string) {
 		}
 	}
 }
+
+// HashTree returns a SHA-256 hash of the files under the given folder, covering their paths relative to the folder and their contents.
+// Files are walked in lexical order, so the hash only changes if the files do.
+func HashTree(fs afero.Afero, root string) (string, error) {
+	hash := sha256.New()
+	err := fs.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		file, err := fs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(relPath), info.Size())
+		_, err = io.Copy(hash, file)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to hash the files under %s: %v", root, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CopyTree copies the files under the src folder of srcFs to the dst folder of dstFs. Nothing is copied if src doesn't exist.
+func CopyTree(srcFs afero.Afero, src string, dstFs afero.Afero, dst string) error {
+	files, err := ReadTree(srcFs, src)
+	if err != nil {
+		return err
+	}
+	for relPath, content := range files {
+		dstPath := filepath.Join(dst, relPath)
+		if err := dstFs.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return err
+		}
+		if err := dstFs.WriteFile(dstPath, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffTrees returns the paths, relative to the folders, of the files that differ between the folder a of aFs and the folder b of bFs,
+// in lexical order. A file differs if it's only in one of the folders, or if its contents differ. A folder that doesn't exist is empty.
+func DiffTrees(aFs afero.Afero, a string, bFs afero.Afero, b string) ([]string, error) {
+	aFiles, err := ReadTree(aFs, a)
+	if err != nil {
+		return nil, err
+	}
+	bFiles, err := ReadTree(bFs, b)
+	if err != nil {
+		return nil, err
+	}
+	var diff []string
+	for relPath, aContent := range aFiles {
+		if bContent, ok := bFiles[relPath]; !ok || !bytes.Equal(aContent, bContent) {
+			diff = append(diff, relPath)
+		}
+	}
+	for relPath := range bFiles {
+		if _, ok := aFiles[relPath]; !ok {
+			diff = append(diff, relPath)
+		}
+	}
+	sort.Strings(diff)
+	return diff, nil
+}
+
+// ReadTree returns the contents of the files under the given folder, keyed by their slash separated paths relative to the folder
+func ReadTree(fs afero.Afero, root string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	if exists, err := fs.DirExists(root); err != nil || !exists {
+		return files, err
+	}
+	err := fs.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		content, err := fs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the files under %s: %v", root, err)
+	}
+	return files, nil
+}
@@ -199,3 +199,127 @@ func TestRemoveFolderAndLogError(t *testing.T) {
 		})
 	}
 }
+
+func TestHashTree(t *testing.T) {
+	writeTree := func(files map[string]string) afero.Afero {
+		fs := NewMemoryFilesystem()
+		for path, content := range files {
+			assert.NoError(t, fs.WriteFile(path, []byte(content), 0644))
+		}
+		return fs
+	}
+	baseTree := map[string]string{
+		"/gitops/components/backend/base/deployment.yaml":    "kind: Deployment",
+		"/gitops/components/backend/base/kustomization.yaml": "resources:\n- deployment.yaml",
+	}
+	baseHash, err := HashTree(writeTree(baseTree), "/gitops/components/backend/base")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		root     string
+		wantSame bool
+		wantErr  bool
+	}{
+		{
+			name:     "Same files under a different folder",
+			files:    map[string]string{"/tmp/base/deployment.yaml": "kind: Deployment", "/tmp/base/kustomization.yaml": "resources:\n- deployment.yaml"},
+			root:     "/tmp/base",
+			wantSame: true,
+		},
+		{
+			name:  "Changed content",
+			files: map[string]string{"/tmp/base/deployment.yaml": "kind: StatefulSet", "/tmp/base/kustomization.yaml": "resources:\n- deployment.yaml"},
+			root:  "/tmp/base",
+		},
+		{
+			name:  "Renamed file",
+			files: map[string]string{"/tmp/base/deploy.yaml": "kind: Deployment", "/tmp/base/kustomization.yaml": "resources:\n- deployment.yaml"},
+			root:  "/tmp/base",
+		},
+		{
+			name:    "Missing folder",
+			root:    "/tmp/missing",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := HashTree(writeTree(tt.files), tt.root)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("TestHashTree() unexpected error value: %v", err)
+			}
+			if !tt.wantErr && (hash == baseHash) != tt.wantSame {
+				t.Errorf("TestHashTree() error: expected the hash to be the same: %v", tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestCopyTreeAndDiffTrees(t *testing.T) {
+	repoFs := NewMemoryFilesystem()
+	for path, content := range map[string]string{
+		"/gitops/components/backend/base/deployment.yaml":                       "kind: Deployment",
+		"/gitops/components/backend/base/kustomization.yaml":                    "resources:\n- deployment.yaml",
+		"/gitops/components/backend/overlays/staging/deployment-patch.yaml":     "replicas: 2",
+		"/gitops/components/backend/overlays/staging/kustomization.yaml":        "resources:\n- ../../base",
+		"/gitops/components/frontend/base/deployment.yaml":                      "kind: Deployment",
+		"/gitops/components/frontend/overlays/development/kustomization.yaml":   "resources:\n- ../../base",
+		"/gitops/components/frontend/overlays/development/deployment-patch.yml": "replicas: 1",
+	} {
+		assert.NoError(t, repoFs.WriteFile(path, []byte(content), 0644))
+	}
+
+	tests := []struct {
+		name     string
+		src      string
+		changes  func(fs afero.Afero)
+		wantDiff []string
+	}{
+		{
+			name:    "Unchanged copy",
+			src:     "/gitops/components/backend",
+			changes: func(fs afero.Afero) {},
+		},
+		{
+			name: "Modified, added and removed files",
+			src:  "/gitops/components/backend",
+			changes: func(fs afero.Afero) {
+				assert.NoError(t, fs.WriteFile("/expected/base/deployment.yaml", []byte("kind: StatefulSet"), 0644))
+				assert.NoError(t, fs.WriteFile("/expected/base/service.yaml", []byte("kind: Service"), 0644))
+				assert.NoError(t, fs.Remove("/expected/overlays/staging/deployment-patch.yaml"))
+			},
+			wantDiff: []string{"base/deployment.yaml", "base/service.yaml", "overlays/staging/deployment-patch.yaml"},
+		},
+		{
+			name:    "Missing folder",
+			src:     "/gitops/components/missing",
+			changes: func(fs afero.Afero) {},
+		},
+		{
+			name: "Files added to a missing folder",
+			src:  "/gitops/components/missing",
+			changes: func(fs afero.Afero) {
+				assert.NoError(t, fs.WriteFile("/expected/base/deployment.yaml", []byte("kind: Deployment"), 0644))
+			},
+			wantDiff: []string{"base/deployment.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectedFs := NewMemoryFilesystem()
+			if err := CopyTree(repoFs, tt.src, expectedFs, "/expected"); err != nil {
+				t.Fatalf("TestCopyTreeAndDiffTrees() unexpected error copying: %v", err)
+			}
+			tt.changes(expectedFs)
+			diff, err := DiffTrees(expectedFs, "/expected", repoFs, tt.src)
+			if err != nil {
+				t.Fatalf("TestCopyTreeAndDiffTrees() unexpected error: %v", err)
+			}
+			assert.Equal(t, tt.wantDiff, diff)
+		})
+	}
+}
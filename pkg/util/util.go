@@ -53,8 +53,9 @@ func GetIntValue(intPtr *int) int {
 	return 0
 }
 
-// ProcessGitOpsStatus processes the GitOps status and returns the remote url, branch, context and the error
-func ProcessGitOpsStatus(gitopsStatus appstudiov1alpha1.GitOpsStatus, gitToken string) (string, string, string, error) {
+// ProcessGitOpsStatus processes the GitOps status and returns the remote url, branch, context and the error.
+// The remote url embeds the given credentials of the Git provider that hosts the GitOps repository.
+func ProcessGitOpsStatus(gitopsStatus appstudiov1alpha1.GitOpsStatus, credentials *url.Userinfo) (string, string, string, error) {
 	var gitOpsURL, gitOpsBranch, gitOpsContext string
 	gitOpsURL = gitopsStatus.RepositoryURL
 	if gitOpsURL == "" {
@@ -77,7 +78,7 @@ func ProcessGitOpsStatus(gitopsStatus appstudiov1alpha1.GitOpsStatus, gitToken s
 	if err != nil {
 		return "", "", "", err
 	}
-	parsedURL.User = url.User(gitToken)
+	parsedURL.User = credentials
 	remoteURL := parsedURL.String()
 
 	return remoteURL, gitOpsBranch, gitOpsContext, nil
@@ -125,6 +126,19 @@ func GetRandomString(n int, lower bool) string {
 	return randomString
 }
 
+const lowerSchemaBytes = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// GetStableString returns a lower case string which is n characters long, derived from a hash of the given values.
+// Unlike GetRandomString, the same values always return the same string, so names generated with it are reproducible.
+func GetStableString(n int, values ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = lowerSchemaBytes[int(hash[i%len(hash)])%len(lowerSchemaBytes)]
+	}
+	return string(b)
+}
+
 // GetMappedGitOpsComponent gets a mapped GeneratorOptions from the Component for GitOps resource generation
 func GetMappedGitOpsComponent(component appstudiov1alpha1.Component, kubernetesResources parser.KubernetesResources) gitopsgenv1alpha1.GeneratorOptions {
 	customK8sLabels := map[string]string{
@@ -188,18 +202,16 @@ func GenerateUniqueHashForWorkloadImageTag(namespace string) string {
 	return base64.URLEncoding.EncodeToString(h.Sum(nil))[0:5]
 }
 
-// GenerateRandomRouteName returns a random, trimmed route name based on the Component name based on the following criteria
+// GenerateRouteName returns a trimmed route name based on the Component name based on the following criteria
 // 1. Under 30 characters
-// 2. Contains 4 random characters
-func GenerateRandomRouteName(componentName string) string {
+// 2. Contains 4 characters derived from the Component, Application and Environment names, so that the same route name is
+// generated for a Component in an Environment every time
+func GenerateRouteName(componentName, applicationName, environmentName string) string {
 	routeName := componentName
 	if len(componentName) > 25 {
 		routeName = componentName[0:25]
-	} else {
-		routeName = componentName
 	}
 
-	// Append random characters to the route name
-	routeName = routeName + GetRandomString(4, true)
-	return routeName
+	// Append the stable characters to the route name
+	return routeName + GetStableString(4, componentName, applicationName, environmentName)
 }